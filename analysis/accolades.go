@@ -0,0 +1,80 @@
+package analysis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+var ruleAccolade = register(Rule{ID: "accolade-structure", Description: "A malformed accolade type: ranks outside 1-6 or out of order, rank modifiers that are not blocks, or effects that are not blocks.", Severity: lsp.Warning})
+
+// maxAccoladeRank is the highest rank an accolade can reach.
+const maxAccoladeRank = 6
+
+// checkAccolades validates accolade type rank tables and effects, and the
+// localization keys of accolade names.
+func checkAccolades(entry *index.FileEntry, env *Env) []lsp.Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	report := func(rng pdx.Range, format string, args ...interface{}) {
+		diagnostics = append(diagnostics, newDiagnostic(ruleAccolade, Range(rng), fmt.Sprintf(format, args...)))
+	}
+	switch vpath := entry.VirtualPath; {
+	case strings.HasPrefix(vpath, "common/accolade_types/"):
+		for _, def := range entry.File.Root.Fields {
+			if def.Block() == nil {
+				continue
+			}
+			for _, f := range def.Block().Fields {
+				if strings.HasPrefix(f.KeyText(), "on_") && f.Block() == nil {
+					report(f.Range(), "'%s' must be an effect block", f.KeyText())
+				}
+			}
+			ranks := def.Block().Get("ranks")
+			if ranks == nil || ranks.Block() == nil {
+				continue
+			}
+			prev := 0
+			for _, rank := range ranks.Block().Fields {
+				if rank.Key == nil {
+					continue
+				}
+				n, err := strconv.Atoi(rank.Key.Text)
+				switch {
+				case err != nil || n < 1 || n > maxAccoladeRank:
+					report(rank.Key.Loc, "accolade rank must be a number from 1 to %d", maxAccoladeRank)
+					continue
+				case n <= prev:
+					report(rank.Key.Loc, "rank %d follows rank %d; ranks must be listed once, in increasing order", n, prev)
+				}
+				prev = n
+				if rank.Block() == nil {
+					continue
+				}
+				for _, f := range rank.Block().Fields {
+					if strings.HasSuffix(f.KeyText(), "_modifier") && f.Block() == nil {
+						report(f.Range(), "'%s' must be a modifier block", f.KeyText())
+					}
+				}
+			}
+		}
+	case strings.HasPrefix(vpath, "common/accolade_names/"):
+		for _, def := range entry.File.Root.Fields {
+			if def.Block() == nil {
+				continue
+			}
+			key := def.Block().Get("key")
+			if key == nil || key.Scalar() == nil || !index.IsStaticName(key.Scalar().Text) {
+				continue
+			}
+			s := key.Scalar()
+			diagnostics = append(diagnostics, checkLocKey(env, Range(s.Loc), s.Text,
+				fmt.Sprintf("localization key '%s' is not defined", s.Text))...)
+		}
+	}
+	return diagnostics
+}
@@ -0,0 +1,184 @@
+// Package analysis produces diagnostics for indexed script files.
+package analysis
+
+import (
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/gui"
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+	"github.com/unLomTrois/gock3-lsp/spell"
+)
+
+// Source is the diagnostic source reported to the client.
+const Source = "gock3"
+
+// Env is everything checks may consult beyond the file being analyzed.
+type Env struct {
+	Index   *index.Index
+	Options *Options
+	// DataTypes is the game's data system dump, or nil if not configured.
+	DataTypes *gui.DataTypes
+	// GameVersion is the installed game version, or "" if unknown.
+	GameVersion string
+	// Dictionary is the spell checking dictionary, or nil if spell
+	// checking is off.
+	Dictionary *spell.Dictionary
+	// Analyzers run besides the registered ones, such as the external
+	// analyzers of the settings.
+	Analyzers []Analyzer
+	// CrossFile, if not nil, holds the diagnostics of the cross-file
+	// checks, such as those of the last save, which Run reports instead of
+	// running the checks again.
+	CrossFile []Diagnostic
+	// Generated maps tools, or AnyTool, to the rules whose diagnostics are
+	// dropped in the files a `# generated by <tool>` header marks as theirs.
+	Generated map[string][]string
+}
+
+// Diagnostic is an LSP diagnostic with the related information added in
+// LSP 3.7, which go-lsp lacks.
+type Diagnostic struct {
+	lsp.Diagnostic
+	RelatedInformation []RelatedInformation `json:"relatedInformation,omitempty"`
+}
+
+// RelatedInformation points to another location relevant to a diagnostic.
+type RelatedInformation struct {
+	Location lsp.Location `json:"location"`
+	Message  string       `json:"message"`
+}
+
+// Run returns all diagnostics for entry, resolving cross-file references
+// through env and filtering by its rule configuration and the suppression
+// directives of the file. The cross-file checks are only run if
+// env.CrossFile is nil.
+func Run(entry *index.FileEntry, env *Env) []Diagnostic {
+	diagnostics := syntaxErrors(entry)
+	if entry.File != nil {
+		diagnostics = append(diagnostics, checkMagnitudes(entry)...)
+		diagnostics = append(diagnostics, checkDescriptions(entry)...)
+		diagnostics = append(diagnostics, checkBindings(entry, env)...)
+		diagnostics = append(diagnostics, checkGUILayout(entry, env)...)
+		diagnostics = append(diagnostics, checkGenes(entry, env.Index)...)
+		diagnostics = append(diagnostics, checkDNA(entry, env.Index)...)
+		diagnostics = append(diagnostics, checkTitleHistory(entry, env.Index)...)
+		diagnostics = append(diagnostics, checkProvinceHistory(entry, env.Index)...)
+		diagnostics = append(diagnostics, checkLevels(entry)...)
+		diagnostics = append(diagnostics, checkEpidemics(entry)...)
+		diagnostics = append(diagnostics, checkNames(entry, env)...)
+		diagnostics = append(diagnostics, checkAccolades(entry, env)...)
+		diagnostics = append(diagnostics, checkScriptedRules(entry, env.Index)...)
+		diagnostics = append(diagnostics, checkDefines(entry, env.Index)...)
+		diagnostics = append(diagnostics, checkDLC(entry, env)...)
+		diagnostics = append(diagnostics, checkDescriptor(entry, env)...)
+		diagnostics = append(diagnostics, checkPerformance(entry)...)
+		diagnostics = append(diagnostics, checkScopes(entry)...)
+		diagnostics = append(diagnostics, checkEventDelays(entry)...)
+		diagnostics = append(diagnostics, checkOptions(entry)...)
+		diagnostics = append(diagnostics, checkIterators(entry, env)...)
+		diagnostics = append(diagnostics, checkAssertions(entry, env)...)
+	}
+	diagnostics = append(diagnostics, checkChecksum(entry, env.Index)...)
+	diagnostics = append(diagnostics, checkNaming(entry, env)...)
+	diagnostics = append(diagnostics, checkTodos(entry)...)
+	diagnostics = append(diagnostics, checkSpelling(entry, env)...)
+	all := make([]Diagnostic, 0, len(diagnostics))
+	for _, d := range diagnostics {
+		all = append(all, Diagnostic{Diagnostic: d})
+	}
+	crossFile := env.CrossFile
+	if crossFile == nil {
+		crossFile = CrossFile(entry, env)
+	}
+	all = append(all, crossFile...)
+	all = append(all, runAnalyzers(entry, env)...)
+	all = suppress(applyRules(all, env.Options, env.Index.Base() != nil), entry)
+	all = suppressGenerated(all, entry, env)
+	sortDiagnostics(all)
+	return truncate(all, env.Options)
+}
+
+// CrossFile runs the checks of entry that consult the whole workspace,
+// such as whether its references resolve or its translations keep up with
+// English, and returns their diagnostics unfiltered. They are the heavy
+// ones, which editors run when a document is saved rather than on every
+// change.
+func CrossFile(entry *index.FileEntry, env *Env) []Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	if entry.File != nil {
+		diagnostics = append(diagnostics, checkFlags(entry, env.Index)...)
+		diagnostics = append(diagnostics, checkDescriptionKeys(entry, env)...)
+		diagnostics = append(diagnostics, checkEvents(entry, env.Index)...)
+		diagnostics = append(diagnostics, checkOverrides(entry, env.Index)...)
+	}
+	diagnostics = append(diagnostics, checkConcepts(entry, env)...)
+	diagnostics = append(diagnostics, checkReferences(entry, env.Index)...)
+	diagnostics = append(diagnostics, checkDuplicates(entry, env.Index)...)
+	all := make([]Diagnostic, 0, len(diagnostics))
+	for _, d := range diagnostics {
+		all = append(all, Diagnostic{Diagnostic: d})
+	}
+	if entry.File != nil {
+		all = append(all, checkCycles(entry, env.Index)...)
+		all = append(all, checkEventScopes(entry, env.Index)...)
+	}
+	all = append(all, checkPlaceholders(entry, env.Index)...)
+	all = append(all, checkVersions(entry, env.Index)...)
+	return all
+}
+
+// sortDiagnostics orders diagnostics by position, then rule and message,
+// so that the output does not depend on the order of the checks or of map
+// iteration within them.
+func sortDiagnostics(diagnostics []Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i].Diagnostic, diagnostics[j].Diagnostic
+		if a.Range.Start != b.Range.Start {
+			if a.Range.Start.Line != b.Range.Start.Line {
+				return a.Range.Start.Line < b.Range.Start.Line
+			}
+			return a.Range.Start.Character < b.Range.Start.Character
+		}
+		if a.Code != b.Code {
+			return a.Code < b.Code
+		}
+		return a.Message < b.Message
+	})
+}
+
+func syntaxErrors(entry *index.FileEntry) []lsp.Diagnostic {
+	var errors []pdx.Error
+	if entry.File != nil {
+		errors = entry.File.Errors
+	} else if entry.Loc != nil {
+		errors = entry.Loc.Errors
+	}
+	var diagnostics []lsp.Diagnostic
+	for _, err := range errors {
+		diagnostics = append(diagnostics, newDiagnostic(ruleSyntax, Range(err.Range), err.Msg))
+	}
+	return diagnostics
+}
+
+// FileURI converts a local file path to a file URI.
+func FileURI(path string) lsp.DocumentURI {
+	p := filepath.ToSlash(path)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return lsp.DocumentURI("file://" + (&url.URL{Path: p}).EscapedPath())
+}
+
+// Range converts a source range to its LSP form.
+func Range(r pdx.Range) lsp.Range {
+	return lsp.Range{
+		Start: lsp.Position{Line: r.Start.Line, Character: r.Start.Col},
+		End:   lsp.Position{Line: r.End.Line, Character: r.End.Col},
+	}
+}
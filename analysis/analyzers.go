@@ -0,0 +1,98 @@
+package analysis
+
+import (
+	"fmt"
+	"sync"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+// Analyzer is a rule pack run over every file after the built-in checks.
+// Third-party packs implement it and call RegisterAnalyzer, from an init
+// function of a package compiled into the server or from a Go plugin the
+// server loads.
+type Analyzer interface {
+	// Name identifies the analyzer; it is the source of its diagnostics
+	// unless they set their own.
+	Name() string
+	// Rules lists the rules of the diagnostics Run reports, which users
+	// configure like the built-in ones.
+	Rules() []Rule
+	// Run returns the diagnostics of a file. Their Code must be the ID of
+	// one of the analyzer's rules. entry.File is the parsed script, or nil
+	// for localization files, and env.Index resolves other files.
+	Run(entry *index.FileEntry, env *Env) []Diagnostic
+}
+
+var analyzers struct {
+	mu    sync.RWMutex
+	names map[string]bool
+	list  []Analyzer
+}
+
+var ruleAnalyzerFailed = register(Rule{ID: "analyzer-failed", Description: "A third-party analyzer crashed while checking the file.", Severity: lsp.Warning})
+
+// RegisterAnalyzer adds an analyzer and its rules. It fails if another
+// analyzer has the same name or a rule ID is already taken.
+func RegisterAnalyzer(a Analyzer) error {
+	analyzers.mu.Lock()
+	defer analyzers.mu.Unlock()
+	if analyzers.names[a.Name()] {
+		return fmt.Errorf("analyzer %q is already registered", a.Name())
+	}
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	for _, r := range a.Rules() {
+		if _, ok := rules[r.ID]; ok {
+			return fmt.Errorf("analyzer %q: rule %q is already registered", a.Name(), r.ID)
+		}
+	}
+	for _, r := range a.Rules() {
+		rules[r.ID] = r
+	}
+	if analyzers.names == nil {
+		analyzers.names = make(map[string]bool)
+	}
+	analyzers.names[a.Name()] = true
+	analyzers.list = append(analyzers.list, a)
+	return nil
+}
+
+// runAnalyzers returns the diagnostics of every registered analyzer and
+// of those of env for entry. An analyzer that panics is reported on the file instead of taking
+// the server down.
+func runAnalyzers(entry *index.FileEntry, env *Env) []Diagnostic {
+	analyzers.mu.RLock()
+	list := append(analyzers.list[:len(analyzers.list):len(analyzers.list)], env.Analyzers...)
+	analyzers.mu.RUnlock()
+
+	var diagnostics []Diagnostic
+	for _, a := range list {
+		diagnostics = append(diagnostics, runAnalyzer(a, entry, env)...)
+	}
+	return diagnostics
+}
+
+func runAnalyzer(a Analyzer, entry *index.FileEntry, env *Env) (diagnostics []Diagnostic) {
+	defer func() {
+		if r := recover(); r != nil {
+			diagnostics = []Diagnostic{AnalyzerError(a.Name(), fmt.Errorf("%v", r))}
+		}
+	}()
+	diagnostics = a.Run(entry, env)
+	for i := range diagnostics {
+		if diagnostics[i].Source == "" {
+			diagnostics[i].Source = a.Name()
+		}
+	}
+	return diagnostics
+}
+
+// AnalyzerError returns the diagnostic reporting that the analyzer name
+// failed to check a file.
+func AnalyzerError(name string, err error) Diagnostic {
+	msg := fmt.Sprintf("Analyzer %s failed: %v", name, err)
+	return Diagnostic{Diagnostic: newDiagnostic(ruleAnalyzerFailed, lsp.Range{}, msg)}
+}
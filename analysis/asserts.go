@@ -0,0 +1,251 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+var ruleAssertionFailed = register(Rule{ID: "assertion-failed", Description: "An `assert` comparison in a tests/ file that is false when evaluated statically over script values and defines.", Severity: lsp.Error})
+
+// IsTestFile reports whether vpath is a script test file under tests/.
+func IsTestFile(vpath string) bool {
+	return strings.HasPrefix(vpath, "tests/") && index.IsScriptFile(vpath)
+}
+
+// Assertion is the outcome of one comparison of an `assert` block.
+type Assertion struct {
+	// Test is the name of the top-level block the assertion is in.
+	Test  string
+	Field *pdx.Field
+	// Evaluated is false when a side could not be worked out statically,
+	// in which case Passed is meaningless.
+	Evaluated bool
+	Passed    bool
+	Message   string
+}
+
+// maxEvalDepth bounds the script values followed when evaluating, so that
+// recursive definitions do not hang the server.
+const maxEvalDepth = 32
+
+// EvaluateAssertions evaluates every comparison inside the `assert = { ...
+// }` blocks of the tests of entry, each top-level block being a test.
+func EvaluateAssertions(entry *index.FileEntry, ix *index.Index) []Assertion {
+	if entry.File == nil || !IsTestFile(entry.VirtualPath) {
+		return nil
+	}
+	var assertions []Assertion
+	for _, test := range entry.File.Root.Fields {
+		if test.Block() == nil {
+			continue
+		}
+		pdx.Walk(test.Block(), func(f *pdx.Field) bool {
+			if f.KeyText() != "assert" || f.Block() == nil {
+				return true
+			}
+			for _, cmp := range f.Block().Fields {
+				if cmp.Key != nil && cmp.Scalar() != nil {
+					assertions = append(assertions, evaluateAssertion(ix, test.KeyText(), cmp))
+				}
+			}
+			return false
+		})
+	}
+	return assertions
+}
+
+func evaluateAssertion(ix *index.Index, test string, f *pdx.Field) Assertion {
+	a := Assertion{Test: test, Field: f}
+	left, ok := EvalValue(ix, f.Key.Text)
+	if !ok {
+		a.Message = fmt.Sprintf("%s cannot be evaluated statically", f.Key.Text)
+		return a
+	}
+	right, ok := EvalValue(ix, f.ValueText())
+	if !ok {
+		a.Message = fmt.Sprintf("%s cannot be evaluated statically", f.ValueText())
+		return a
+	}
+	a.Evaluated = true
+	switch f.Op {
+	case pdx.OpAssign, pdx.OpEqual:
+		a.Passed = left == right
+	case pdx.OpNotEqual:
+		a.Passed = left != right
+	case pdx.OpLess:
+		a.Passed = left < right
+	case pdx.OpLessEqual:
+		a.Passed = left <= right
+	case pdx.OpGreater:
+		a.Passed = left > right
+	case pdx.OpGreaterEqual:
+		a.Passed = left >= right
+	default:
+		a.Evaluated = false
+		a.Message = fmt.Sprintf("operator %s cannot be asserted", f.Op)
+		return a
+	}
+	a.Message = fmt.Sprintf("%s %s %s: %s is %s", f.Key.Text, f.Op, f.ValueText(), f.Key.Text, FormatNumber(left))
+	if f.ValueText() != FormatNumber(right) {
+		a.Message += fmt.Sprintf(", %s is %s", f.ValueText(), FormatNumber(right))
+	}
+	return a
+}
+
+// checkAssertions reports the assertions of a tests/ file that evaluate to
+// false.
+func checkAssertions(entry *index.FileEntry, env *Env) []lsp.Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	for _, a := range EvaluateAssertions(entry, env.Index) {
+		if a.Evaluated && !a.Passed {
+			diagnostics = append(diagnostics, newDiagnostic(ruleAssertionFailed, Range(a.Field.Range()),
+				fmt.Sprintf("assertion failed in %s: %s", a.Test, a.Message)))
+		}
+	}
+	return diagnostics
+}
+
+// EvalValue works out a number statically: a literal, a `define:NS|KEY`
+// reference, or a script value whose definition only does arithmetic on
+// such operands. ok is false for anything that depends on the game state.
+func EvalValue(ix *index.Index, text string) (float64, bool) {
+	return (&evaluator{ix: ix}).operand(text, 0)
+}
+
+// EvalAssuming works out a number like EvalValue, taking the operands that
+// depend on the game state, such as `gold` or `scope:actor.age`, from
+// assume. An operand is looked up as written, then by its last link. used
+// lists the assumptions the result depends on, sorted.
+func EvalAssuming(ix *index.Index, text string, assume map[string]float64) (n float64, used []string, ok bool) {
+	e := &evaluator{ix: ix, assume: assume, used: make(map[string]bool)}
+	n, ok = e.operand(text, 0)
+	for name := range e.used {
+		used = append(used, name)
+	}
+	sort.Strings(used)
+	return n, used, ok
+}
+
+// evaluator holds the state of one static evaluation.
+type evaluator struct {
+	ix     *index.Index
+	assume map[string]float64
+	used   map[string]bool
+}
+
+func (e *evaluator) operand(text string, depth int) (float64, bool) {
+	if depth > maxEvalDepth {
+		return 0, false
+	}
+	if n, err := strconv.ParseFloat(text, 64); err == nil {
+		return n, true
+	}
+	if rest, ok := strings.CutPrefix(text, "define:"); ok {
+		ns, key, ok := strings.Cut(rest, "|")
+		if !ok {
+			return 0, false
+		}
+		return e.definition(index.KindDefine, index.DefineName(ns, key), depth)
+	}
+	if n, ok := e.definition(index.KindScriptValue, text, depth); ok {
+		return n, true
+	}
+	return e.assumed(text)
+}
+
+// assumed returns the assumption for an operand, by its whole text or by
+// the link after its last dot.
+func (e *evaluator) assumed(text string) (float64, bool) {
+	for _, name := range []string{text, text[strings.LastIndex(text, ".")+1:]} {
+		if n, ok := e.assume[name]; ok {
+			e.used[name] = true
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// definition evaluates the effective definition of a define or script
+// value.
+func (e *evaluator) definition(kind index.Kind, name string, depth int) (float64, bool) {
+	defs := e.ix.Definitions(kind, name)
+	if len(defs) == 0 {
+		return 0, false
+	}
+	f := e.ix.FieldAt(defs[0].Location)
+	if f == nil {
+		return 0, false
+	}
+	if b := f.Block(); b != nil {
+		return e.block(b, depth+1)
+	}
+	return e.operand(f.ValueText(), depth+1)
+}
+
+// block evaluates the arithmetic of a script value block in order.
+func (e *evaluator) block(b *pdx.Block, depth int) (float64, bool) {
+	var acc float64
+	for _, f := range b.Fields {
+		key := f.KeyText()
+		switch key {
+		case "desc", "format":
+			continue
+		case "round", "ceiling", "floor", "abs":
+			if f.ValueText() != "yes" {
+				continue
+			}
+			acc = map[string]func(float64) float64{"round": math.Round, "ceiling": math.Ceil, "floor": math.Floor, "abs": math.Abs}[key](acc)
+			continue
+		}
+		var n float64
+		var ok bool
+		if sub := f.Block(); sub != nil {
+			n, ok = e.block(sub, depth+1)
+		} else {
+			n, ok = e.operand(f.ValueText(), depth+1)
+		}
+		if !ok {
+			return 0, false
+		}
+		switch key {
+		case "value":
+			acc = n
+		case "add":
+			acc += n
+		case "subtract":
+			acc -= n
+		case "multiply":
+			acc *= n
+		case "divide":
+			if n == 0 {
+				return 0, false
+			}
+			acc /= n
+		case "modulo":
+			if n == 0 {
+				return 0, false
+			}
+			acc = math.Mod(acc, n)
+		case "min":
+			acc = math.Max(acc, n)
+		case "max":
+			acc = math.Min(acc, n)
+		default:
+			return 0, false
+		}
+	}
+	return acc, true
+}
+
+// FormatNumber writes n without trailing zeros.
+func FormatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
@@ -0,0 +1,106 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/gui"
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+var (
+	ruleBindingSyntax   = register(Rule{ID: "gui-binding-syntax", Description: "Malformed data-binding expression in a .gui file.", Severity: lsp.Error})
+	ruleUnknownSGUI     = register(Rule{ID: "unknown-scripted-gui", Description: "GetScriptedGui refers to a scripted GUI that is not defined in common/scripted_guis.", Severity: lsp.Warning, Vanilla: true})
+	ruleUnknownDataFunc = register(Rule{ID: "unknown-data-function", Description: "A data-binding promote or function is not in the data types database (requires dataTypesPath).", Severity: lsp.Warning})
+)
+
+// bindingProperties are the GUI properties whose values are evaluated by the
+// data system.
+var bindingProperties = map[string]bool{
+	"datamodel": true, "datacontext": true, "visible": true, "enabled": true,
+	"onclick": true, "onrightclick": true, "ondoubleclick": true,
+	"onmousehierarchyenter": true, "onmousehierarchyleave": true,
+	"onselectionchanged": true, "onreturnpressed": true, "onfocusout": true,
+	"checked": true, "down": true, "frame": true, "value": true, "min": true, "max": true,
+	"text": true, "raw_text": true, "tooltip": true, "raw_tooltip": true, "texture": true,
+}
+
+// checkBindings validates data-binding strings of .gui files.
+func checkBindings(entry *index.FileEntry, env *Env) []lsp.Diagnostic {
+	if !index.IsGUIFile(entry.Path) {
+		return nil
+	}
+	var diagnostics []lsp.Diagnostic
+	pdx.Walk(entry.File.Root, func(f *pdx.Field) bool {
+		s := f.Scalar()
+		if s == nil || !s.Quoted || !bindingProperties[f.KeyText()] || strings.Contains(s.Text, "\n") {
+			return true
+		}
+		chains, errs := gui.ParseBindings(s.Text)
+		for _, err := range errs {
+			diagnostics = append(diagnostics, newDiagnostic(ruleBindingSyntax,
+				Range(index.StringSpan(s, err.Offset, min(err.Offset+1, len(s.Text)))), err.Msg))
+		}
+		for _, chain := range chains {
+			diagnostics = append(diagnostics, checkChain(s, chain, env)...)
+		}
+		return true
+	})
+	return diagnostics
+}
+
+// checkChain resolves each link of a chain against the data types database,
+// following return types where they are known.
+func checkChain(s *pdx.Scalar, chain *gui.Chain, env *Env) []lsp.Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	typ := ""
+	for i, seg := range chain.Segments {
+		span := Range(index.StringSpan(s, seg.Offset, seg.Offset+len(seg.Name)))
+		if seg.Name == "GetScriptedGui" && len(seg.Args) == 1 && seg.Args[0].Chain == nil {
+			name := seg.Args[0].Literal
+			if len(env.Index.Definitions(index.KindScriptedGUI, name)) == 0 {
+				arg := seg.Args[0]
+				diagnostics = append(diagnostics, newDiagnostic(ruleUnknownSGUI,
+					Range(index.StringSpan(s, arg.Offset+1, arg.Offset+1+len(name))),
+					fmt.Sprintf("scripted GUI '%s' is not defined", name)))
+			}
+		}
+		for _, arg := range seg.Args {
+			if arg.Chain != nil {
+				diagnostics = append(diagnostics, checkChain(s, arg.Chain, env)...)
+			}
+		}
+
+		dt := env.DataTypes
+		if dt == nil {
+			continue
+		}
+		if i == 0 {
+			if ret, ok := dt.Globals[seg.Name]; ok {
+				typ = ret
+				continue
+			}
+			if dt.IsType(seg.Name) {
+				typ = seg.Name
+				continue
+			}
+			diagnostics = append(diagnostics, newDiagnostic(ruleUnknownDataFunc, span,
+				fmt.Sprintf("unknown global promote or function '%s'", seg.Name)))
+			return diagnostics
+		}
+		ret, ok := dt.Lookup(typ, seg.Name)
+		if !ok {
+			msg := fmt.Sprintf("unknown promote or function '%s'", seg.Name)
+			if typ != "" {
+				msg = fmt.Sprintf("unknown promote or function '%s' on %s", seg.Name, typ)
+			}
+			diagnostics = append(diagnostics, newDiagnostic(ruleUnknownDataFunc, span, msg))
+			return diagnostics
+		}
+		typ = ret
+	}
+	return diagnostics
+}
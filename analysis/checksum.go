@@ -0,0 +1,24 @@
+package analysis
+
+import (
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+var ruleChecksumImpact = register(Rule{ID: "checksum-impact", Description: "A file that changes the game checksum, so the mod is no longer achievement compatible.", Severity: lsp.Hint, Optional: true})
+
+// checkChecksum marks the start of a mod file in a checksummed folder.
+func checkChecksum(entry *index.FileEntry, ix *index.Index) []lsp.Diagnostic {
+	if !index.AffectsChecksum(entry.VirtualPath) {
+		return nil
+	}
+	if base := ix.Base(); base != nil && base.File(entry.Path) == entry {
+		return nil
+	}
+	folder, _, _ := strings.Cut(entry.VirtualPath, "/")
+	return []lsp.Diagnostic{newDiagnostic(ruleChecksumImpact, lsp.Range{},
+		"files in "+folder+"/ change the game checksum and disable achievements")}
+}
@@ -0,0 +1,39 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+var ruleUnknownConcept = register(Rule{ID: "unknown-game-concept", Description: "Localization refers to a game concept that is not defined in common/game_concepts.", Severity: lsp.Warning, Vanilla: true})
+
+// checkConcepts reports unknown game concepts used by localization files
+// and concepts whose description has no localization.
+func checkConcepts(entry *index.FileEntry, env *Env) []lsp.Diagnostic {
+	ix := env.Index
+	var diagnostics []lsp.Diagnostic
+	for _, ref := range entry.Refs {
+		if ref.Kind != index.KindGameConcept || len(ix.Definitions(ref.Kind, ref.Name)) > 0 {
+			continue
+		}
+		diagnostics = append(diagnostics, newDiagnostic(ruleUnknownConcept, Range(ref.Range),
+			fmt.Sprintf("game concept '%s' is not defined", ref.Name)))
+	}
+	if entry.File == nil || !strings.HasPrefix(entry.VirtualPath, "common/game_concepts/") {
+		return diagnostics
+	}
+	for _, f := range entry.File.Root.Fields {
+		if f.Key == nil || f.Op != pdx.OpAssign || !index.IsStaticName(f.Key.Text) {
+			continue
+		}
+		desc := "game_concept_" + f.Key.Text + "_desc"
+		diagnostics = append(diagnostics, checkLocKey(env, Range(f.Key.Loc), desc,
+			fmt.Sprintf("game concept '%s' has no description '%s'", f.Key.Text, desc))...)
+	}
+	return diagnostics
+}
@@ -0,0 +1,162 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+var (
+	ruleRecursion = register(Rule{ID: "recursive-definition", Description: "A scripted effect, scripted trigger or script value that ends up calling itself, which the game cannot evaluate.", Severity: lsp.Warning})
+	ruleEventLoop = register(Rule{ID: "event-loop", Description: "Events that fire each other in a loop without any trigger, condition or random choice to end it.", Severity: lsp.Warning})
+)
+
+// node is a definition in the call or event graph.
+type node struct {
+	kind index.Kind
+	name string
+}
+
+// edge is a use of one definition inside another.
+type edge struct {
+	to  node
+	loc index.Location
+}
+
+// loopConditions are the blocks that decide whether their contents run, so
+// an event fired from inside one may not fire again.
+var loopConditions = map[string]bool{
+	"if": true, "else_if": true, "else": true, "random": true, "random_list": true,
+	"switch": true, "option": true, "trigger_if": true, "while": true,
+}
+
+// graph lazily builds the edges of definitions, remembering them for the
+// duration of one check.
+type graph struct {
+	ix    *index.Index
+	edges map[node][]edge
+}
+
+func (g *graph) out(n node) []edge {
+	if edges, ok := g.edges[n]; ok {
+		return edges
+	}
+	var edges []edge
+	for _, sym := range g.ix.Definitions(n.kind, n.name) {
+		f := g.ix.FieldAt(sym.Location)
+		if f == nil || f.Block() == nil {
+			continue
+		}
+		if n.kind == index.KindEvent {
+			edges = append(edges, g.eventEdges(sym.Path, f.Block())...)
+			continue
+		}
+		pdx.Walk(f.Block(), func(c *pdx.Field) bool {
+			if kind, name, rng, ok := g.ix.CallAt(c); ok {
+				edges = append(edges, edge{node{kind, name}, index.Location{Path: sym.Path, Range: rng}})
+			}
+			return true
+		})
+	}
+	g.edges[n] = edges
+	return edges
+}
+
+// eventEdges returns the events an event fires unconditionally. An event
+// with a trigger of its own can end a loop, so it has none.
+func (g *graph) eventEdges(path string, b *pdx.Block) []edge {
+	if b.Get("trigger") != nil {
+		return nil
+	}
+	var edges []edge
+	var walk func(b *pdx.Block)
+	walk = func(b *pdx.Block) {
+		for _, f := range b.Fields {
+			key := f.KeyText()
+			if loopConditions[key] || key == "limit" || key == "trigger" {
+				continue
+			}
+			if key == "trigger_event" {
+				if id := f.Scalar(); id != nil {
+					edges = append(edges, edge{node{index.KindEvent, id.Text}, index.Location{Path: path, Range: id.Loc}})
+				} else if fb := f.Block(); fb != nil {
+					if id := fb.Get("id"); id != nil && id.Scalar() != nil {
+						edges = append(edges, edge{node{index.KindEvent, id.ValueText()}, index.Location{Path: path, Range: id.Scalar().Loc}})
+					}
+				}
+				continue
+			}
+			if sub := f.Block(); sub != nil {
+				walk(sub)
+			}
+		}
+	}
+	walk(b)
+	return edges
+}
+
+// cycleFrom returns a path of edges leading from start back to itself, or
+// nil.
+func (g *graph) cycleFrom(start node) []edge {
+	visited := map[node]bool{}
+	var path []edge
+	var dfs func(n node) bool
+	dfs = func(n node) bool {
+		visited[n] = true
+		for _, e := range g.out(n) {
+			path = append(path, e)
+			if e.to == start || !visited[e.to] && dfs(e.to) {
+				return true
+			}
+			path = path[:len(path)-1]
+		}
+		return false
+	}
+	if dfs(start) {
+		return path
+	}
+	return nil
+}
+
+// checkCycles reports the definitions of entry that reach themselves
+// through calls, and events that fire themselves again unconditionally.
+func checkCycles(entry *index.FileEntry, ix *index.Index) []Diagnostic {
+	g := &graph{ix: ix, edges: map[node][]edge{}}
+	var diagnostics []Diagnostic
+	for _, sym := range entry.Symbols {
+		rule, what, verb := ruleRecursion, "calls itself", "uses"
+		switch sym.Kind {
+		case index.KindScriptedEffect, index.KindScriptedTrigger, index.KindScriptValue:
+		case index.KindEvent:
+			rule, what, verb = ruleEventLoop, "fires itself again with nothing to stop the loop", "fires"
+		default:
+			continue
+		}
+		start := node{sym.Kind, sym.Name}
+		cycle := g.cycleFrom(start)
+		if cycle == nil {
+			continue
+		}
+		names := []string{sym.Name}
+		var related []RelatedInformation
+		from := sym.Name
+		for _, e := range cycle {
+			names = append(names, e.to.name)
+			related = append(related, RelatedInformation{
+				Location: lsp.Location{URI: FileURI(e.loc.Path), Range: Range(e.loc.Range)},
+				Message:  fmt.Sprintf("%s %s %s here", from, verb, e.to.name),
+			})
+			from = e.to.name
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Diagnostic: newDiagnostic(rule, Range(sym.Range),
+				fmt.Sprintf("%s %s: %s", sym.Name, what, strings.Join(names, " → "))),
+			RelatedInformation: related,
+		})
+	}
+	return diagnostics
+}
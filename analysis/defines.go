@@ -0,0 +1,92 @@
+package analysis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+var (
+	ruleUnknownDefine = register(Rule{ID: "unknown-define", Description: "A define override or `define:` reference to a define that the game does not have (requires gamePath for overrides).", Severity: lsp.Warning})
+	ruleDefineType    = register(Rule{ID: "define-type", Description: "A define override whose value type (integer, number, string or list) differs from the vanilla define.", Severity: lsp.Warning})
+)
+
+// checkDefines validates `define:` references and, against the vanilla
+// defines, the keys and value types of define overrides.
+func checkDefines(entry *index.FileEntry, ix *index.Index) []lsp.Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	for _, ref := range entry.Refs {
+		if ref.Kind == index.KindDefine && len(ix.Definitions(ref.Kind, ref.Name)) == 0 {
+			diagnostics = append(diagnostics, newDiagnostic(ruleUnknownDefine, Range(ref.Range),
+				fmt.Sprintf("define '%s' does not exist", ref.Name)))
+		}
+	}
+	base := ix.Base()
+	if !index.IsDefinesFile(entry.VirtualPath) || base == nil || base.File(entry.Path) == entry {
+		return diagnostics
+	}
+	for _, sym := range entry.Symbols {
+		if sym.Kind != index.KindDefine {
+			continue
+		}
+		vanilla := base.Definitions(index.KindDefine, sym.Name)
+		if len(vanilla) == 0 {
+			msg := fmt.Sprintf("'%s' is not a vanilla define", sym.Name)
+			ns, _, _ := strings.Cut(sym.Name, ".")
+			var siblings []string
+			for _, name := range base.Names(index.KindDefine) {
+				if strings.HasPrefix(name, ns+".") {
+					siblings = append(siblings, name)
+				}
+			}
+			if s := suggest(sym.Name, siblings); s != "" {
+				msg += fmt.Sprintf("; did you mean '%s'?", s)
+			}
+			diagnostics = append(diagnostics, newDiagnostic(ruleUnknownDefine, Range(sym.Range), msg))
+			continue
+		}
+		f, v := ix.FieldAt(sym.Location), base.FieldAt(vanilla[0].Location)
+		if f == nil || v == nil {
+			continue
+		}
+		if got, want := DefineType(f), DefineType(v); got != want && !(got == "integer" && want == "number") {
+			diagnostics = append(diagnostics, newDiagnostic(ruleDefineType, Range(f.Value.Range()),
+				fmt.Sprintf("%s is %s in vanilla, but set to %s here", sym.Name, withArticle(want), withArticle(got))))
+		}
+	}
+	return diagnostics
+}
+
+// DefineType classifies the value of a define as "integer", "number",
+// "string", "list" or "value" (for anything else, such as yes/no).
+func DefineType(f *pdx.Field) string {
+	if f.Block() != nil {
+		return "list"
+	}
+	s := f.Scalar()
+	switch {
+	case s == nil:
+		return "value"
+	case s.Quoted:
+		return "string"
+	}
+	if _, err := strconv.Atoi(s.Text); err == nil {
+		return "integer"
+	}
+	if _, err := strconv.ParseFloat(s.Text, 64); err == nil {
+		return "number"
+	}
+	return "value"
+}
+
+func withArticle(noun string) string {
+	if strings.ContainsRune("aeiou", rune(noun[0])) {
+		return "an " + noun
+	}
+	return "a " + noun
+}
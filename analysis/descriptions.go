@@ -0,0 +1,204 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+var (
+	ruleDescStructure = register(Rule{ID: "desc-structure", Description: "Malformed first_valid, random_valid or triggered_desc description block.", Severity: lsp.Warning})
+	ruleMissingLoc    = register(Rule{ID: "missing-localization", Description: "A localization key used by script has no entry in any language, in the mod or the vanilla files, or none the game can fall back to in one of the `diagnostics.languages`.", Severity: lsp.Error, Vanilla: true})
+)
+
+// descRootKeys lists, per folder, the fields of a top-level object that take
+// a description. Event options are handled separately.
+var descRootKeys = map[string][]string{
+	"events/":           {"title", "desc"},
+	"common/decisions/": {"title", "desc", "selection_tooltip", "confirm_text"},
+}
+
+// descBlockKeys are the entries allowed inside a description block.
+var descBlockKeys = map[string]bool{
+	"desc":           true,
+	"first_valid":    true,
+	"random_valid":   true,
+	"triggered_desc": true,
+}
+
+// DescRoots returns the fields of a file that take a description: event
+// titles, descriptions and option names, and decision texts.
+func DescRoots(entry *index.FileEntry) []*pdx.Field {
+	if entry.File == nil {
+		return nil
+	}
+	var keys []string
+	for prefix, k := range descRootKeys {
+		if strings.HasPrefix(entry.VirtualPath, prefix) {
+			keys = k
+		}
+	}
+	if keys == nil {
+		return nil
+	}
+
+	var roots []*pdx.Field
+	for _, object := range entry.File.Root.Fields {
+		b := object.Block()
+		if b == nil || object.Op != pdx.OpAssign {
+			continue
+		}
+		for _, key := range keys {
+			roots = append(roots, b.All(key)...)
+		}
+		if strings.HasPrefix(entry.VirtualPath, "events/") {
+			for _, option := range b.All("option") {
+				if ob := option.Block(); ob != nil {
+					roots = append(roots, ob.All("name")...)
+				}
+			}
+		}
+	}
+	return roots
+}
+
+// DescLeaf is a localization key a description can resolve to, with a
+// human-readable summary of when it is chosen.
+type DescLeaf struct {
+	Key       *pdx.Scalar
+	Condition string
+}
+
+// DescLeaves returns every localization key reachable from a description
+// field, in source order.
+func DescLeaves(root *pdx.Field) []DescLeaf {
+	var leaves []DescLeaf
+	var visit func(f *pdx.Field, conditions []string)
+	visit = func(f *pdx.Field, conditions []string) {
+		if s := f.Scalar(); s != nil {
+			leaves = append(leaves, DescLeaf{Key: s, Condition: describeConditions(conditions)})
+			return
+		}
+		b := f.Block()
+		if b == nil {
+			return
+		}
+		n := 0
+		for _, child := range b.Fields {
+			key := child.KeyText()
+			if !isDescEntry(f, key) {
+				continue
+			}
+			n++
+			next := append([]string(nil), conditions...)
+			switch f.KeyText() {
+			case "first_valid":
+				next = append(next, fmt.Sprintf("first valid #%d", n))
+			case "random_valid":
+				next = append(next, "random")
+			}
+			if key == "triggered_desc" || (key == "text" && b.Get("trigger") != nil) {
+				next = append(next, "if trigger")
+			}
+			visit(child, next)
+		}
+	}
+	visit(root, nil)
+	return leaves
+}
+
+func describeConditions(conditions []string) string {
+	if len(conditions) == 0 {
+		return "always"
+	}
+	return strings.Join(conditions, ", ")
+}
+
+// checkDescriptions validates description blocks. It does not descend into
+// the trigger of a triggered_desc or option name: those are ordinary
+// trigger blocks, which checkScopes and checkIterators validate in the scope
+// of the event or decision like any other.
+func checkDescriptions(entry *index.FileEntry) []lsp.Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	for _, root := range DescRoots(entry) {
+		pdx.Walk(&pdx.Block{Fields: []*pdx.Field{root}}, func(f *pdx.Field) bool {
+			if f.KeyText() == "trigger" {
+				return false
+			}
+			diagnostics = append(diagnostics, checkDescBlock(f)...)
+			return true
+		})
+	}
+	return diagnostics
+}
+
+// checkDescriptionKeys reports description keys without localization.
+func checkDescriptionKeys(entry *index.FileEntry, env *Env) []lsp.Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	for _, root := range DescRoots(entry) {
+		for _, leaf := range DescLeaves(root) {
+			if !isLocKey(leaf.Key) {
+				continue
+			}
+			diagnostics = append(diagnostics, checkLocKey(env, Range(leaf.Key.Loc), leaf.Key.Text,
+				fmt.Sprintf("localization key '%s' is not defined", leaf.Key.Text))...)
+		}
+	}
+	return diagnostics
+}
+
+// checkDescBlock checks the entries of a single description block.
+func checkDescBlock(f *pdx.Field) []lsp.Diagnostic {
+	b := f.Block()
+	if b == nil {
+		return nil
+	}
+	var diagnostics []lsp.Diagnostic
+	if f.KeyText() == "triggered_desc" {
+		trigger := b.Get("trigger")
+		switch {
+		case trigger == nil:
+			diagnostics = append(diagnostics, newDiagnostic(ruleDescStructure, Range(f.Key.Loc), "triggered_desc has no trigger"))
+		case trigger.Block() == nil:
+			diagnostics = append(diagnostics, newDiagnostic(ruleDescStructure, Range(trigger.Range()), "trigger of triggered_desc must be a block"))
+		}
+		if b.Get("desc") == nil {
+			diagnostics = append(diagnostics, newDiagnostic(ruleDescStructure, Range(f.Key.Loc), "triggered_desc has no desc"))
+		}
+	}
+	for _, child := range b.Fields {
+		key := child.KeyText()
+		if isDescEntry(f, key) || (key == "trigger" && (f.KeyText() == "triggered_desc" || f.KeyText() == "name")) {
+			continue
+		}
+		diagnostics = append(diagnostics, newDiagnostic(ruleDescStructure, Range(child.Range()),
+			fmt.Sprintf("unexpected '%s' in %s; expected desc, triggered_desc, first_valid or random_valid", describeKey(child), f.KeyText())))
+	}
+	return diagnostics
+}
+
+// isDescEntry reports whether key may appear inside the description block
+// of f. Option names use `name = { trigger = { ... } text = key }`.
+func isDescEntry(f *pdx.Field, key string) bool {
+	return descBlockKeys[key] || (key == "text" && f.KeyText() == "name")
+}
+
+// isLocKey reports whether a description value is a localization key rather
+// than a scope, parameter or literal text.
+func isLocKey(s *pdx.Scalar) bool {
+	return index.IsStaticName(s.Text) && !strings.ContainsAny(s.Text, " :")
+}
+
+func describeKey(f *pdx.Field) string {
+	if f.Key != nil {
+		return f.Key.Text
+	}
+	if s := f.Scalar(); s != nil {
+		return s.Text
+	}
+	return "{ ... }"
+}
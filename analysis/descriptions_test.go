@@ -0,0 +1,59 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+// TestDescriptionTriggers checks that the triggers of triggered_desc and
+// option names get the same checks as other triggers, in the scope of the
+// event.
+func TestDescriptionTriggers(t *testing.T) {
+	ix := index.New()
+	entry := ix.UpdateFile("/mod/events/my_events.txt", `namespace = my
+my.0001 = {
+	type = empty
+	scope = landed_title
+	desc = {
+		first_valid = {
+			triggered_desc = {
+				trigger = {
+					holder = { any_vassal = { is_adult = yes } }
+					holder = { every_vassal = { is_adult = yes } }
+					primary_title = { is_landed = yes }
+				}
+				desc = my.0001.desc
+			}
+			desc = my.0001.desc
+		}
+	}
+	option = {
+		name = {
+			trigger = { any_bogus_list = { } }
+			text = my.0001.a
+		}
+	}
+}
+`)
+
+	want := map[int]string{
+		9:  ruleIteratorContext.ID,
+		10: ruleScopeMismatch.ID,
+		19: ruleUnknownIterator.ID,
+	}
+	for _, d := range Run(entry, &Env{Index: ix, Options: &Options{}}) {
+		code := d.Code
+		if code == ruleUnreachableEvent.ID || code == ruleMissingLoc.ID {
+			continue
+		}
+		if want[d.Range.Start.Line] != code {
+			t.Errorf("unexpected %s on line %d: %s", code, d.Range.Start.Line, d.Message)
+			continue
+		}
+		delete(want, d.Range.Start.Line)
+	}
+	for line, code := range want {
+		t.Errorf("no %s on line %d", code, line)
+	}
+}
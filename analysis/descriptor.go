@@ -0,0 +1,44 @@
+package analysis
+
+import (
+	"fmt"
+	"path/filepath"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/mod"
+)
+
+var (
+	ruleDescriptorMismatch = register(Rule{ID: "descriptor-mismatch", Description: "descriptor.mod and .metadata/metadata.json disagree on the name, version, tags or supported game version.", Severity: lsp.Warning})
+	ruleOutdatedVersion    = register(Rule{ID: "outdated-supported-version", Description: "The descriptor's supported_version is older than the installed game (requires gamePath).", Severity: lsp.Warning})
+)
+
+// checkDescriptor compares a mod's descriptor.mod with the installed game
+// version and with the metadata.json next to it, if there is one.
+func checkDescriptor(entry *index.FileEntry, env *Env) []lsp.Diagnostic {
+	if filepath.Base(entry.Path) != mod.DescriptorFile || entry.VirtualPath != mod.DescriptorFile {
+		return nil
+	}
+	d := mod.ParseDescriptor(entry.Path, entry.File.Text)
+	var diagnostics []lsp.Diagnostic
+	if f := entry.File.Root.Get("supported_version"); f != nil && mod.Outdated(d.SupportedVersion, env.GameVersion) {
+		diagnostics = append(diagnostics, newDiagnostic(ruleOutdatedVersion, Range(f.Value.Range()),
+			fmt.Sprintf("supported_version %s is older than the installed game %s", d.SupportedVersion, env.GameVersion)))
+	}
+	m, err := mod.ReadMetadata(filepath.Dir(entry.Path))
+	if err != nil {
+		return diagnostics
+	}
+	for _, mismatch := range mod.Mismatches(d, m) {
+		var r lsp.Range
+		if f := entry.File.Root.Get(mismatch.Key); f != nil {
+			r = Range(f.Range())
+		}
+		diagnostics = append(diagnostics, newDiagnostic(ruleDescriptorMismatch, r,
+			fmt.Sprintf("%s is '%s' here but '%s' in metadata.json; run gock3.syncDescriptor to update one from the other",
+				mismatch.Key, mismatch.Descriptor, mismatch.Metadata)))
+	}
+	return diagnostics
+}
@@ -0,0 +1,102 @@
+package analysis
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// Directives written in comments to silence diagnostics.
+const (
+	// DirectiveIgnore silences the listed rules, or every rule, on the
+	// line it ends, or on the next line when written on a line of its own.
+	DirectiveIgnore = "gock3-ignore"
+	// DirectiveIgnoreFile silences the listed rules, or every rule, in the
+	// whole file.
+	DirectiveIgnoreFile = "gock3-ignore-file"
+)
+
+// directivePattern matches a suppression comment and the rule IDs after
+// it.
+var directivePattern = regexp.MustCompile(`^\s*(gock3-ignore(?:-file)?)(?:\s+(.*))?$`)
+
+// suppression silences rules on a line, or in the whole file if line is
+// -1. A nil rules set silences every rule.
+type suppression struct {
+	line  int
+	rules map[string]bool
+}
+
+// suppressions returns the suppression directives of a file.
+func suppressions(entry *index.FileEntry) []suppression {
+	var comments []pdx.Token
+	switch {
+	case entry.File != nil:
+		comments = entry.File.Comments
+	case entry.Loc != nil:
+		comments = entry.Loc.Comments
+	}
+	var list []suppression
+	for _, c := range comments {
+		m := directivePattern.FindStringSubmatch(c.Text)
+		if m == nil {
+			continue
+		}
+		s := suppression{line: c.Range.Start.Line}
+		if m[1] == DirectiveIgnoreFile {
+			s.line = -1
+		} else if ownLine(entry, c) {
+			s.line++
+		}
+		for _, id := range strings.FieldsFunc(m[2], func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			if s.rules == nil {
+				s.rules = make(map[string]bool)
+			}
+			s.rules[id] = true
+		}
+		list = append(list, s)
+	}
+	return list
+}
+
+// ownLine reports whether comment c is alone on its line rather than
+// ending a line of script or localization.
+func ownLine(entry *index.FileEntry, c pdx.Token) bool {
+	if entry.File != nil {
+		start := c.Range.Start.Offset
+		for start > 0 && entry.File.Text[start-1] != '\n' {
+			start--
+		}
+		return strings.TrimSpace(entry.File.Text[start:c.Range.Start.Offset]) == ""
+	}
+	for _, e := range entry.Loc.Entries {
+		if e.KeyRange.Start.Line == c.Range.Start.Line {
+			return false
+		}
+	}
+	return true
+}
+
+// suppress drops the diagnostics that the directives of entry silence.
+func suppress(diagnostics []Diagnostic, entry *index.FileEntry) []Diagnostic {
+	list := suppressions(entry)
+	if len(list) == 0 {
+		return diagnostics
+	}
+	kept := diagnostics[:0]
+	for _, d := range diagnostics {
+		silenced := false
+		for _, s := range list {
+			if (s.line == -1 || s.line == d.Range.Start.Line) && (s.rules == nil || s.rules[d.Code]) {
+				silenced = true
+				break
+			}
+		}
+		if !silenced {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,63 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+var (
+	ruleUnknownDLC    = register(Rule{ID: "unknown-dlc", Description: "A has_dlc or has_dlc_feature argument that is not a DLC or feature the game knows (requires gamePath).", Severity: lsp.Warning})
+	ruleUndeclaredDLC = register(Rule{ID: "undeclared-dlc", Description: "A has_dlc or has_dlc_feature check for a DLC or feature not listed in the dlc setting.", Severity: lsp.Information, Optional: true})
+)
+
+// checkDLC validates has_dlc and has_dlc_feature arguments. The game defines
+// DLC features in code, so the known names are the DLC descriptors of the
+// installation and whatever the vanilla files themselves check.
+func checkDLC(entry *index.FileEntry, env *Env) []lsp.Diagnostic {
+	var declared map[string]bool
+	if env.Options != nil && len(env.Options.DLC) > 0 {
+		declared = make(map[string]bool, len(env.Options.DLC))
+		for _, name := range env.Options.DLC {
+			declared[name] = true
+		}
+	}
+	base := env.Index.Base()
+	known := map[index.Kind][]string{}
+	var diagnostics []lsp.Diagnostic
+	for _, ref := range entry.Refs {
+		if ref.Kind != index.KindDLC && ref.Kind != index.KindDLCFeature {
+			continue
+		}
+		noun := "DLC"
+		if ref.Kind == index.KindDLCFeature {
+			noun = "DLC feature"
+		}
+		if base != nil && base.File(entry.Path) != entry {
+			names, ok := known[ref.Kind]
+			if !ok {
+				names = base.ReferencedNames(ref.Kind)
+				if ref.Kind == index.KindDLC {
+					names = append(names, base.Names(index.KindDLC)...)
+				}
+				known[ref.Kind] = names
+			}
+			if !slices.Contains(names, ref.Name) {
+				msg := fmt.Sprintf("unknown %s '%s'", noun, ref.Name)
+				if s := suggest(ref.Name, names); s != "" {
+					msg += fmt.Sprintf("; did you mean '%s'?", s)
+				}
+				diagnostics = append(diagnostics, newDiagnostic(ruleUnknownDLC, Range(ref.Range), msg))
+				continue
+			}
+		}
+		if declared != nil && !declared[ref.Name] {
+			diagnostics = append(diagnostics, newDiagnostic(ruleUndeclaredDLC, Range(ref.Range),
+				fmt.Sprintf("%s '%s' is not among the DLC the mod declares", noun, ref.Name)))
+		}
+	}
+	return diagnostics
+}
@@ -0,0 +1,93 @@
+package analysis
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+var (
+	ruleDNAFormat  = register(Rule{ID: "dna-format", Description: "A corrupted DNA string or malformed DNA definition, which makes the game show a blank portrait.", Severity: lsp.Warning})
+	ruleUnknownDNA = register(Rule{ID: "unknown-dna", Description: "`dna = name` refers to a DNA that is not defined in common/dna_data.", Severity: lsp.Warning, Vanilla: true})
+)
+
+// checkDNA validates DNA strings, references to named DNA and the structure
+// of common/dna_data definitions.
+func checkDNA(entry *index.FileEntry, ix *index.Index) []lsp.Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	for _, ref := range entry.Refs {
+		if ref.Kind == index.KindDNA && len(ix.Definitions(ref.Kind, ref.Name)) == 0 {
+			diagnostics = append(diagnostics, newDiagnostic(ruleUnknownDNA, Range(ref.Range),
+				fmt.Sprintf("dna '%s' is not defined", ref.Name)))
+		}
+	}
+	pdx.Walk(entry.File.Root, func(f *pdx.Field) bool {
+		if index.IsDNAString(f) {
+			diagnostics = append(diagnostics, checkDNAString(f.Scalar())...)
+		}
+		if index.IsDNAGenes(f) || f.KeyText() != "genes" || f.Block() == nil {
+			return true
+		}
+		seen := make(map[string]bool)
+		for _, gene := range f.Block().Fields {
+			if gene.Key == nil {
+				continue
+			}
+			if seen[gene.Key.Text] {
+				diagnostics = append(diagnostics, newDiagnostic(ruleDNAFormat, Range(gene.Key.Loc),
+					fmt.Sprintf("gene '%s' is set more than once", gene.Key.Text)))
+			}
+			seen[gene.Key.Text] = true
+		}
+		return true
+	})
+	if strings.HasPrefix(entry.VirtualPath, "common/dna_data/") {
+		for _, f := range entry.File.Root.Fields {
+			b := f.Block()
+			if f.Key == nil || b == nil {
+				continue
+			}
+			info := b.Get("portrait_info")
+			switch {
+			case info == nil || info.Block() == nil:
+				diagnostics = append(diagnostics, newDiagnostic(ruleDNAFormat, Range(f.Key.Loc),
+					fmt.Sprintf("dna '%s' has no portrait_info block", f.Key.Text)))
+			case info.Block().Get("genes") == nil:
+				diagnostics = append(diagnostics, newDiagnostic(ruleDNAFormat, Range(info.Key.Loc),
+					fmt.Sprintf("portrait_info of '%s' has no genes block", f.Key.Text)))
+			}
+		}
+	}
+	return diagnostics
+}
+
+// checkDNAString checks that a persistent DNA string, as exported by the
+// ruler designer, is intact base64.
+func checkDNAString(s *pdx.Scalar) []lsp.Diagnostic {
+	if strings.Contains(s.Text, "\n") {
+		return []lsp.Diagnostic{newDiagnostic(ruleDNAFormat, Range(s.Loc), "DNA string spans several lines; it must be pasted as a single line")}
+	}
+	for i, r := range s.Text {
+		if !isBase64Rune(r) {
+			return []lsp.Diagnostic{newDiagnostic(ruleDNAFormat, Range(index.StringSpan(s, i, i+len(string(r)))),
+				fmt.Sprintf("invalid character %q in DNA string", r))}
+		}
+	}
+	if len(s.Text)%4 != 0 {
+		return []lsp.Diagnostic{newDiagnostic(ruleDNAFormat, Range(s.Loc),
+			fmt.Sprintf("DNA string is truncated: length %d is not a multiple of 4", len(s.Text)))}
+	}
+	if _, err := base64.StdEncoding.DecodeString(s.Text); err != nil {
+		return []lsp.Diagnostic{newDiagnostic(ruleDNAFormat, Range(s.Loc), "DNA string is not valid base64")}
+	}
+	return nil
+}
+
+func isBase64Rune(r rune) bool {
+	return r == '+' || r == '/' || r == '=' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+}
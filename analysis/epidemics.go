@@ -0,0 +1,64 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+var ruleEpidemic = register(Rule{ID: "epidemic-structure", Description: "A malformed epidemic type or outbreak: unknown outbreak intensities, or triggers and effects that are not blocks.", Severity: lsp.Warning})
+
+// outbreakIntensities are the intensities an epidemic outbreak may have.
+var outbreakIntensities = map[string]bool{"minor": true, "major": true, "apocalyptic": true}
+
+// checkEpidemics validates epidemic type definitions and the intensity of
+// create_epidemic_outbreak effects.
+func checkEpidemics(entry *index.FileEntry) []lsp.Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	report := func(rng pdx.Range, format string, args ...interface{}) {
+		diagnostics = append(diagnostics, newDiagnostic(ruleEpidemic, Range(rng), fmt.Sprintf(format, args...)))
+	}
+	pdx.Walk(entry.File.Root, func(f *pdx.Field) bool {
+		if f.KeyText() == "intensity" && f.ParentField().KeyText() == "create_epidemic_outbreak" && f.Scalar() != nil {
+			if s := f.Scalar(); index.IsStaticName(s.Text) && !outbreakIntensities[s.Text] {
+				report(s.Loc, "unknown outbreak intensity '%s'; expected minor, major or apocalyptic", s.Text)
+			}
+		}
+		return true
+	})
+	if !strings.HasPrefix(entry.VirtualPath, "common/epidemics/") {
+		return diagnostics
+	}
+	for _, def := range entry.File.Root.Fields {
+		if def.Key == nil || def.Block() == nil {
+			continue
+		}
+		intensities := def.Block().Get("outbreak_intensities")
+		if intensities == nil || intensities.Block() == nil {
+			report(def.Key.Loc, "epidemic '%s' has no outbreak_intensities block", def.Key.Text)
+		} else {
+			for _, f := range intensities.Block().Fields {
+				if f.Key != nil && !outbreakIntensities[f.Key.Text] {
+					report(f.Key.Loc, "unknown outbreak intensity '%s'; expected minor, major or apocalyptic", f.Key.Text)
+				}
+			}
+		}
+		for _, f := range def.Block().Fields {
+			key := f.KeyText()
+			isTrigger := strings.HasPrefix(key, "can_") || strings.HasSuffix(key, "_trigger")
+			isEffect := strings.HasPrefix(key, "on_")
+			if (isTrigger || isEffect) && f.Block() == nil {
+				what := "trigger"
+				if isEffect {
+					what = "effect"
+				}
+				report(f.Range(), "'%s' must be a %s block", key, what)
+			}
+		}
+	}
+	return diagnostics
+}
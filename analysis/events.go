@@ -0,0 +1,42 @@
+package analysis
+
+import (
+	"fmt"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+var ruleUnreachableEvent = register(Rule{ID: "unreachable-event", Description: "An event that no on_action, trigger_event, decision or interaction fires, so it can never happen.", Severity: lsp.Hint})
+
+// checkEvents reports the events of entry that are never fired, other than
+// by themselves.
+func checkEvents(entry *index.FileEntry, ix *index.Index) []lsp.Diagnostic {
+	if base := ix.Base(); base != nil && base.File(entry.Path) == entry {
+		return nil
+	}
+	var diagnostics []lsp.Diagnostic
+	for _, sym := range entry.Symbols {
+		if sym.Kind != index.KindEvent || firedElsewhere(sym, ix) {
+			continue
+		}
+		diagnostics = append(diagnostics, newDiagnostic(ruleUnreachableEvent, Range(sym.Range),
+			fmt.Sprintf("event %s is never fired: no on_action, trigger_event, decision or interaction refers to it", sym.Name)))
+	}
+	return diagnostics
+}
+
+// firedElsewhere reports whether an event is referenced from outside its
+// own definition.
+func firedElsewhere(sym index.Symbol, ix *index.Index) bool {
+	for _, ref := range ix.References(index.KindEvent, sym.Name) {
+		if ref.Path != sym.Path {
+			return true
+		}
+		if container, ok := ix.Container(ref.Location); !ok || container.Kind != index.KindEvent || container.Name != sym.Name {
+			return true
+		}
+	}
+	return false
+}
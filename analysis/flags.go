@@ -0,0 +1,25 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+// checkFlags warns about flags that are checked or removed but never set
+// anywhere in the workspace, which usually means the name is misspelled.
+func checkFlags(entry *index.FileEntry, ix *index.Index) []lsp.Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	for _, ref := range entry.Refs {
+		if !index.IsFlagKind(ref.Kind) || len(ix.Definitions(ref.Kind, ref.Name)) > 0 {
+			continue
+		}
+		kind := strings.ReplaceAll(string(ref.Kind), "_", " ")
+		diagnostics = append(diagnostics, newDiagnostic(ruleUnsetFlag, Range(ref.Range),
+			fmt.Sprintf("%s '%s' is checked but never set", kind, ref.Name)))
+	}
+	return diagnostics
+}
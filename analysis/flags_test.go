@@ -0,0 +1,23 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+func TestCheckFlags(t *testing.T) {
+	ix := index.New()
+	ix.UpdateFile("/mod/common/traits/my_traits.txt", "my_trait = {\n\tflag = can_fly\n}\n")
+	ix.UpdateFile("/mod/events/setter.txt", "namespace = setter\nsetter.0001 = {\n\timmediate = { add_character_flag = was_set }\n}\n")
+	entry := ix.UpdateFile("/mod/events/checker.txt", "namespace = checker\nchecker.0001 = {\n\ttrigger = {\n\t\thas_trait_flag = can_fly\n\t\thas_realm_law_flag = some_law_flag\n\t\thas_character_flag = was_set\n\t\thas_character_flag = never_set\n\t}\n}\n")
+
+	diagnostics := checkFlags(entry, ix)
+	if len(diagnostics) != 1 {
+		t.Fatalf("diagnostics = %+v, want one", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Code != ruleUnsetFlag.ID || d.Range.Start.Line != 6 || d.Message != "character flag 'never_set' is checked but never set" {
+		t.Errorf("diagnostic = %+v, want unset-flag for never_set on line 6", d)
+	}
+}
@@ -0,0 +1,73 @@
+package analysis
+
+import (
+	"regexp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// AnyTool stands for every tool in the settings of generated files.
+const AnyTool = "*"
+
+// generatedPattern matches the `# generated by <tool>` comment that tools
+// such as map converters write at the top of the files they generate.
+var generatedPattern = regexp.MustCompile(`(?i)^\s*generated\s+by\s+([^\s,;:]+)`)
+
+// GeneratedBy returns the tool named by a `# generated by <tool>` comment
+// in the header of entry, before its first line of script or localization.
+func GeneratedBy(entry *index.FileEntry) (string, bool) {
+	var comments []pdx.Token
+	first := -1
+	switch {
+	case entry.File != nil:
+		comments = entry.File.Comments
+		if len(entry.File.Root.Fields) > 0 {
+			first = entry.File.Root.Fields[0].Range().Start.Line
+		}
+	case entry.Loc != nil:
+		comments = entry.Loc.Comments
+		if len(entry.Loc.Entries) > 0 {
+			first = entry.Loc.Entries[0].KeyRange.Start.Line
+		}
+	}
+	for _, c := range comments {
+		if first >= 0 && c.Range.Start.Line >= first {
+			break
+		}
+		if m := generatedPattern.FindStringSubmatch(c.Text); m != nil {
+			return m[1], true
+		}
+	}
+	return "", false
+}
+
+// suppressGenerated drops the diagnostics of the rules that env.Generated
+// silences for the tool that generated entry, if any.
+func suppressGenerated(diagnostics []Diagnostic, entry *index.FileEntry, env *Env) []Diagnostic {
+	if len(env.Generated) == 0 {
+		return diagnostics
+	}
+	tool, ok := GeneratedBy(entry)
+	if !ok {
+		return diagnostics
+	}
+	rules, ok := env.Generated[tool]
+	if !ok {
+		rules = env.Generated[AnyTool]
+	}
+	if len(rules) == 0 {
+		return diagnostics
+	}
+	silenced := make(map[string]bool, len(rules))
+	for _, id := range rules {
+		silenced[id] = true
+	}
+	kept := diagnostics[:0]
+	for _, d := range diagnostics {
+		if !silenced[d.Code] && !silenced[AnyTool] {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
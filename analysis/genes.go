@@ -0,0 +1,116 @@
+package analysis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+var (
+	ruleUnknownGene = register(Rule{ID: "unknown-gene", Description: "A DNA or ethnicity entry uses a gene or gene template that is not defined in common/genes.", Severity: lsp.Warning, Vanilla: true})
+	ruleGeneRange   = register(Rule{ID: "gene-range", Description: "A gene value outside its range: 0-255 in DNA, 0.0-1.0 in ethnicities.", Severity: lsp.Warning})
+)
+
+// checkGenes validates gene references and values of DNA definitions and
+// ethnicities.
+func checkGenes(entry *index.FileEntry, ix *index.Index) []lsp.Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	for _, ref := range entry.Refs {
+		if (ref.Kind != index.KindGene && ref.Kind != index.KindGeneTemplate) || len(ix.Definitions(ref.Kind, ref.Name)) > 0 {
+			continue
+		}
+		msg := fmt.Sprintf("gene '%s' is not defined", ref.Name)
+		if gene, template, ok := strings.Cut(ref.Name, "/"); ok && ref.Kind == index.KindGeneTemplate {
+			if len(ix.Definitions(index.KindGene, gene)) == 0 {
+				continue // already reported for the gene
+			}
+			msg = fmt.Sprintf("gene '%s' has no template '%s'", gene, template)
+		}
+		diagnostics = append(diagnostics, newDiagnostic(ruleUnknownGene, Range(ref.Range), msg))
+	}
+
+	pdx.Walk(entry.File.Root, func(f *pdx.Field) bool {
+		switch {
+		case index.IsDNAGenes(f):
+			diagnostics = append(diagnostics, checkDNAGene(f)...)
+			return false
+		case index.IsEthnicityGene(entry, f):
+			diagnostics = append(diagnostics, checkEthnicityGene(f)...)
+			return false
+		}
+		return true
+	})
+	return diagnostics
+}
+
+// checkDNAGene checks a gene setting of a DNA definition: either
+// `{ "template" value "template" value }` for morph and accessory genes or
+// `{ x y x y }` for color genes, with values in 0-255.
+func checkDNAGene(f *pdx.Field) []lsp.Diagnostic {
+	b := f.Block()
+	if len(b.Fields) != 4 {
+		return []lsp.Diagnostic{newDiagnostic(ruleGeneRange, Range(b.Loc),
+			fmt.Sprintf("%s: expected 4 values (dominant and recessive), found %d", f.Key.Text, len(b.Fields)))}
+	}
+	templated := b.Fields[0].Scalar() != nil && b.Fields[0].Scalar().Quoted
+	var diagnostics []lsp.Diagnostic
+	for i, item := range b.Fields {
+		s := item.Scalar()
+		if templated && i%2 == 0 || s == nil || item.Key != nil {
+			continue
+		}
+		if n, err := strconv.Atoi(s.Text); err != nil || n < 0 || n > 255 {
+			diagnostics = append(diagnostics, newDiagnostic(ruleGeneRange, Range(s.Loc),
+				fmt.Sprintf("%s: '%s' is not an integer between 0 and 255", f.Key.Text, s.Text)))
+		}
+	}
+	return diagnostics
+}
+
+// checkEthnicityGene checks the weighted entries of an ethnicity gene:
+// `range = { min max }` of morph genes and the `{ x1 y1 x2 y2 }` rectangles
+// of color genes, all within 0.0-1.0.
+func checkEthnicityGene(f *pdx.Field) []lsp.Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	unit := func(b *pdx.Block, n int, what string) {
+		if len(b.Fields) != n {
+			diagnostics = append(diagnostics, newDiagnostic(ruleGeneRange, Range(b.Loc),
+				fmt.Sprintf("%s: expected %d values, found %d", what, n, len(b.Fields))))
+			return
+		}
+		prev := -1.0
+		for i, item := range b.Fields {
+			s := item.Scalar()
+			if s == nil {
+				continue
+			}
+			v, err := strconv.ParseFloat(s.Text, 64)
+			switch {
+			case err != nil || v < 0 || v > 1:
+				diagnostics = append(diagnostics, newDiagnostic(ruleGeneRange, Range(s.Loc),
+					fmt.Sprintf("%s: '%s' is not a number between 0.0 and 1.0", what, s.Text)))
+			case n == 2 && i == 1 && v < prev:
+				diagnostics = append(diagnostics, newDiagnostic(ruleGeneRange, Range(s.Loc),
+					fmt.Sprintf("%s: maximum %s is below minimum", what, s.Text)))
+			}
+			prev = v
+		}
+	}
+	for _, weight := range f.Block().Fields {
+		wb := weight.Block()
+		if wb == nil {
+			continue
+		}
+		if r := wb.Get("range"); r != nil && r.Block() != nil {
+			unit(r.Block(), 2, f.Key.Text+" range")
+		} else if wb.Get("name") == nil {
+			unit(wb, 4, f.Key.Text)
+		}
+	}
+	return diagnostics
+}
@@ -0,0 +1,155 @@
+package analysis
+
+import (
+	"fmt"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/gui"
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+var (
+	ruleGUIProperty = register(Rule{ID: "gui-unknown-property", Description: "A widget property that the game does not recognize and silently ignores.", Severity: lsp.Information})
+	ruleGUIValue    = register(Rule{ID: "gui-invalid-value", Description: "A GUI property value of the wrong shape, such as an invalid anchor or a size that is not { x y }.", Severity: lsp.Warning})
+	ruleGUIState    = register(Rule{ID: "gui-unknown-state", Description: "An animation state named like a built-in engine state (leading '_') that the engine never triggers.", Severity: lsp.Warning})
+)
+
+// guiChecker walks the widget tree of a .gui file.
+type guiChecker struct {
+	ix          *index.Index
+	diagnostics []lsp.Diagnostic
+}
+
+// checkGUILayout validates widget properties of .gui files against the
+// per-widget schemas.
+func checkGUILayout(entry *index.FileEntry, env *Env) []lsp.Diagnostic {
+	if !index.IsGUIFile(entry.Path) {
+		return nil
+	}
+	c := &guiChecker{ix: env.Index}
+	c.scan(entry.File.Root)
+	return c.diagnostics
+}
+
+func (c *guiChecker) report(r Rule, rng pdx.Range, format string, args ...interface{}) {
+	c.diagnostics = append(c.diagnostics, newDiagnostic(r, Range(rng), fmt.Sprintf(format, args...)))
+}
+
+// isWidgetKey reports whether key instantiates a widget.
+func (c *guiChecker) isWidgetKey(key string) bool {
+	return gui.IsWidget(key) || len(c.ix.Definitions(index.KindGUIType, key)) > 0
+}
+
+// scan looks for widgets, templates and type declarations in a block whose
+// own entries are not widget properties.
+func (c *guiChecker) scan(b *pdx.Block) {
+	if b == nil {
+		return
+	}
+	for _, f := range b.Fields {
+		fb := f.Block()
+		if fb == nil || f.Key == nil {
+			continue
+		}
+		switch key := f.Key.Text; {
+		case key == "template":
+			c.widget(fb, "")
+		case index.IsGUITypeDeclaration(f):
+			base := ""
+			if fb.Tag != nil {
+				base = fb.Tag.Text
+			}
+			c.widget(fb, base)
+		case c.isWidgetKey(key):
+			c.widget(fb, key)
+		default:
+			c.scan(fb)
+		}
+	}
+}
+
+// widget checks the properties of a widget block of the given built-in
+// type, or "" for templates and declared types.
+func (c *guiChecker) widget(b *pdx.Block, kind string) {
+	if !gui.IsWidget(kind) {
+		kind = ""
+	}
+	for _, f := range b.Fields {
+		if f.Key == nil {
+			continue
+		}
+		key := f.Key.Text
+		fb := f.Block()
+		switch {
+		case c.isWidgetKey(key) && fb != nil:
+			c.widget(fb, key)
+		case key == "state" && fb != nil:
+			c.state(fb)
+		case (key == "block" || key == "blockoverride") && fb != nil:
+			c.widget(fb, kind)
+		case gui.HasProperty(kind, key):
+			c.value(f)
+			if fb != nil {
+				c.scan(fb)
+			}
+		default:
+			if kind == "" {
+				c.report(ruleGUIProperty, f.Key.Loc, "unknown widget property '%s'", key)
+			} else {
+				c.report(ruleGUIProperty, f.Key.Loc, "unknown property '%s' for %s", key, kind)
+			}
+			c.scan(fb)
+		}
+	}
+}
+
+// state checks an animation state block.
+func (c *guiChecker) state(b *pdx.Block) {
+	for _, f := range b.Fields {
+		if f.Key == nil {
+			continue
+		}
+		key := f.Key.Text
+		if !gui.StateProperties[key] {
+			c.report(ruleGUIProperty, f.Key.Loc, "unknown state property '%s'", key)
+			continue
+		}
+		if key == "name" {
+			if name := f.ValueText(); len(name) > 0 && name[0] == '_' && !gui.BuiltinStates[name] {
+				c.report(ruleGUIState, f.Value.Range(), "'%s' is not a built-in state; names starting with '_' are reserved for engine events", name)
+			}
+		}
+		c.value(f)
+	}
+}
+
+// value checks the shape of a property value.
+func (c *guiChecker) value(f *pdx.Field) {
+	typ := gui.PropertyType(f.Key.Text)
+	if typ == gui.Any || f.Value == nil {
+		return
+	}
+	if s := f.Scalar(); s != nil {
+		if msg := gui.CheckScalar(typ, s.Text); msg != "" {
+			c.report(ruleGUIValue, s.Loc, "%s: %s", f.Key.Text, msg)
+		}
+		return
+	}
+	n := gui.Arity(typ)
+	b := f.Block()
+	if n == 0 || b == nil {
+		return
+	}
+	if len(b.Fields) != n {
+		c.report(ruleGUIValue, b.Loc, "%s: expected %d values, found %d", f.Key.Text, n, len(b.Fields))
+		return
+	}
+	for _, item := range b.Fields {
+		s := item.Scalar()
+		if item.Key != nil || s == nil || (!gui.IsNumber(s.Text) && s.Text[0] != '@' && s.Text[0] != '[') {
+			c.report(ruleGUIValue, item.Range(), "%s: expected a number", f.Key.Text)
+		}
+	}
+}
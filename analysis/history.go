@@ -0,0 +1,86 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+var ruleHolderNotAlive = register(Rule{ID: "holder-not-alive", Description: "A title history gives a title to a character before their birth or after their death.", Severity: lsp.Warning})
+
+// checkTitleHistory validates that holders in history/titles files are
+// alive when they receive a title.
+func checkTitleHistory(entry *index.FileEntry, ix *index.Index) []lsp.Diagnostic {
+	if !strings.HasPrefix(entry.VirtualPath, "history/titles/") {
+		return nil
+	}
+	var diagnostics []lsp.Diagnostic
+	for _, title := range entry.File.Root.Fields {
+		if title.Block() == nil {
+			continue
+		}
+		for _, date := range title.Block().Fields {
+			when, ok := index.ParseDate(date.KeyText())
+			if !ok || date.Block() == nil {
+				continue
+			}
+			holder := date.Block().Get("holder")
+			if holder == nil || holder.Scalar() == nil {
+				continue
+			}
+			birth, death, known := lifespan(ix, holder.Scalar().Text)
+			switch {
+			case !known:
+			case birth != nil && when.Before(*birth):
+				diagnostics = append(diagnostics, newDiagnostic(ruleHolderNotAlive, Range(holder.Scalar().Loc),
+					fmt.Sprintf("character %s is born on %s, after receiving the title on %s", holder.Scalar().Text, birth, when)))
+			case death != nil && !when.Before(*death):
+				diagnostics = append(diagnostics, newDiagnostic(ruleHolderNotAlive, Range(holder.Scalar().Loc),
+					fmt.Sprintf("character %s dies on %s, before receiving the title on %s", holder.Scalar().Text, death, when)))
+			}
+		}
+	}
+	return diagnostics
+}
+
+// lifespan returns the birth and death dates of a character from its
+// history entry; either may be nil if not recorded.
+func lifespan(ix *index.Index, id string) (birth, death *index.Date, ok bool) {
+	defs := ix.Definitions(index.KindCharacter, id)
+	if len(defs) == 0 {
+		return nil, nil, false
+	}
+	file := ix.File(defs[0].Path)
+	if file == nil || file.File == nil {
+		return nil, nil, false
+	}
+	character := file.File.Root.Get(id)
+	if character == nil || character.Block() == nil {
+		return nil, nil, false
+	}
+	for _, date := range character.Block().Fields {
+		when, ok := index.ParseDate(date.KeyText())
+		if !ok || date.Block() == nil {
+			continue
+		}
+		d := when
+		if isEvent(date.Block(), "birth") && birth == nil {
+			birth = &d
+		}
+		if isEvent(date.Block(), "death") && death == nil {
+			death = &d
+		}
+	}
+	return birth, death, true
+}
+
+// isEvent reports whether a history date block records key, either as
+// `key = yes` or with details such as `death = { death_reason = ... }`.
+func isEvent(b *pdx.Block, key string) bool {
+	f := b.Get(key)
+	return f != nil && f.ValueText() != "no"
+}
@@ -0,0 +1,87 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+	"github.com/unLomTrois/gock3-lsp/scope"
+)
+
+var (
+	ruleUnknownIterator  = register(Rule{ID: "unknown-iterator", Description: "An every_, random_, ordered_ or any_ iterator over a list the game does not have.", Severity: lsp.Warning})
+	ruleIteratorContext  = register(Rule{ID: "iterator-context", Description: "An any_ iterator among effects, or an every_, random_ or ordered_ iterator among triggers.", Severity: lsp.Warning})
+	ruleIteratorArgument = register(Rule{ID: "iterator-argument", Description: "An iterator argument in the wrong kind of iterator, such as `limit` in any_, `percent` outside any_ or `max` outside ordered_.", Severity: lsp.Warning})
+)
+
+// iteratorArguments maps the arguments only some iterators take to the
+// prefixes of those iterators.
+var iteratorArguments = map[string][]string{
+	"limit":              {"every", "random", "ordered"},
+	"alternative_limit":  {"every", "random", "ordered"},
+	"percent":            {"any"},
+	"count":              {"any"},
+	"max":                {"ordered"},
+	"min":                {"ordered"},
+	"order_by":           {"ordered"},
+	"position":           {"ordered"},
+	"check_range_bounds": {"ordered"},
+	"weight":             {"random"},
+}
+
+// checkIterators validates the lists iterators walk, the context they are
+// used in and the arguments they are given.
+func checkIterators(entry *index.FileEntry, env *Env) []lsp.Diagnostic {
+	if _, ok := scope.ScriptFolder(entry.VirtualPath); !ok {
+		return nil
+	}
+	var diagnostics []lsp.Diagnostic
+	pdx.Walk(entry.File.Root, func(f *pdx.Field) bool {
+		b := f.Block()
+		if b == nil || f.Key == nil || f.ParentField() == nil || scope.IsNonIterator(f.Key.Text) {
+			return true
+		}
+		key := f.Key.Text
+		prefix, _, name, ok := scope.Iterator(key)
+		if prefix == "" || isScripted(env.Index, key) {
+			return true
+		}
+		if !ok {
+			msg := fmt.Sprintf("%s iterates over an unknown list %s", key, name)
+			if s := suggest(name, scope.Lists(scope.Unknown)); s != "" {
+				msg += fmt.Sprintf("; did you mean %s_%s?", prefix, s)
+			}
+			diagnostics = append(diagnostics, newDiagnostic(ruleUnknownIterator, Range(f.Key.Loc), msg))
+		}
+		switch ctx := scope.ContextOf(f, entry.VirtualPath); {
+		case prefix == "any" && ctx == scope.ContextEffect:
+			diagnostics = append(diagnostics, newDiagnostic(ruleIteratorContext, Range(f.Key.Loc),
+				fmt.Sprintf("%s is a trigger but is used among effects; use every_%s or random_%s", key, name, name)))
+		case prefix != "any" && ctx == scope.ContextTrigger:
+			diagnostics = append(diagnostics, newDiagnostic(ruleIteratorContext, Range(f.Key.Loc),
+				fmt.Sprintf("%s is an effect but is used among triggers; use any_%s", key, name)))
+		}
+		for _, arg := range b.Fields {
+			prefixes, ok := iteratorArguments[arg.KeyText()]
+			if !ok || slices.Contains(prefixes, prefix) {
+				continue
+			}
+			msg := fmt.Sprintf("%s is not an argument of %s_ iterators", arg.Key.Text, prefix)
+			if prefix == "any" && arg.Key.Text == "limit" {
+				msg = fmt.Sprintf("%s takes its conditions directly; limit is not an argument of any_ iterators", key)
+			}
+			diagnostics = append(diagnostics, newDiagnostic(ruleIteratorArgument, Range(arg.Key.Loc), msg))
+		}
+		return true
+	})
+	return diagnostics
+}
+
+// isScripted reports whether name is a scripted effect or trigger, which
+// may well start with an iterator prefix.
+func isScripted(ix *index.Index, name string) bool {
+	return len(ix.Definitions(index.KindScriptedEffect, name)) > 0 || len(ix.Definitions(index.KindScriptedTrigger, name)) > 0
+}
@@ -0,0 +1,49 @@
+package analysis
+
+import (
+	"fmt"
+	"strconv"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+var ruleLevelThreshold = register(Rule{ID: "level-threshold", Description: "A threshold table, such as legitimacy levels or house unity stages, whose thresholds are not increasing or whose level names repeat.", Severity: lsp.Warning})
+
+// checkLevels validates the threshold tables of files in a level folder.
+func checkLevels(entry *index.FileEntry) []lsp.Diagnostic {
+	if _, ok := index.LevelKind(entry.VirtualPath); !ok {
+		return nil
+	}
+	var diagnostics []lsp.Diagnostic
+	for _, def := range entry.File.Root.Fields {
+		if def.Block() == nil {
+			continue
+		}
+		seen := make(map[string]bool)
+		prev, prevName := 0.0, ""
+		for _, level := range index.Levels(def) {
+			name := level.Key.Text
+			if seen[name] {
+				diagnostics = append(diagnostics, newDiagnostic(ruleLevelThreshold, Range(level.Key.Loc),
+					fmt.Sprintf("level '%s' is defined more than once", name)))
+			}
+			seen[name] = true
+			threshold := level.Block().Get("threshold").Scalar()
+			if threshold == nil {
+				continue
+			}
+			v, err := strconv.ParseFloat(threshold.Text, 64)
+			if err != nil {
+				continue // script value or constant
+			}
+			if prevName != "" && v <= prev {
+				diagnostics = append(diagnostics, newDiagnostic(ruleLevelThreshold, Range(threshold.Loc),
+					fmt.Sprintf("threshold %s of '%s' is not above %g of the previous level '%s'", threshold.Text, name, prev, prevName)))
+			}
+			prev, prevName = v, name
+		}
+	}
+	return diagnostics
+}
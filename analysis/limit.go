@@ -0,0 +1,58 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	lsp "github.com/sourcegraph/go-lsp"
+)
+
+// DefaultMaxPerFile is the number of diagnostics reported for a file when
+// diagnostics.maxPerFile is not set.
+const DefaultMaxPerFile = 1000
+
+var ruleTooManyProblems = register(Rule{ID: "too-many-problems", Description: "Summarizes the diagnostics left out of a file that has more than diagnostics.maxPerFile of them.", Severity: lsp.Information})
+
+// maxPerFile returns the number of diagnostics to report per file, or 0 for
+// no limit.
+func (o *Options) maxPerFile() int {
+	switch {
+	case o == nil || o.MaxPerFile == 0:
+		return DefaultMaxPerFile
+	case o.MaxPerFile < 0:
+		return 0
+	}
+	return o.MaxPerFile
+}
+
+// truncate keeps the most severe of sorted diagnostics up to the limit of
+// opts, in their order, after a summary of how many were left out, so that
+// a badly broken file does not flood the editor.
+func truncate(diagnostics []Diagnostic, opts *Options) []Diagnostic {
+	limit := opts.maxPerFile()
+	if limit <= 0 || len(diagnostics) <= limit {
+		return diagnostics
+	}
+	order := make([]int, len(diagnostics))
+	for i := range order {
+		order[i] = i
+	}
+	// Lower severities are more severe; position breaks ties.
+	sort.SliceStable(order, func(i, j int) bool {
+		return diagnostics[order[i]].Severity < diagnostics[order[j]].Severity
+	})
+	kept := order[:limit]
+	sort.Ints(kept)
+
+	truncated := make([]Diagnostic, 0, limit+1)
+	if sev := opts.severity(ruleTooManyProblems); sev != 0 {
+		d := newDiagnostic(ruleTooManyProblems, lsp.Range{},
+			fmt.Sprintf("%d more problems suppressed; raise diagnostics.maxPerFile to see them", len(diagnostics)-limit))
+		d.Severity = sev
+		truncated = append(truncated, Diagnostic{Diagnostic: d})
+	}
+	for _, i := range kept {
+		truncated = append(truncated, diagnostics[i])
+	}
+	return truncated
+}
@@ -0,0 +1,76 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+var ruleLocFallback = register(Rule{ID: "localization-fallback", Description: "A localization key used by script that the mod does not localize in one of its `diagnostics.languages`, so the game shows the vanilla text or falls back to English.", Severity: lsp.Hint})
+
+// modLanguages returns the localization languages of the mod in opts,
+// referenceLanguage alone unless configured.
+func modLanguages(opts *Options) []string {
+	if opts == nil || len(opts.Languages) == 0 {
+		return []string{referenceLanguage}
+	}
+	return opts.Languages
+}
+
+// checkLocKey checks that the localization key used at rng resolves in
+// every language of the mod, the way the game resolves it: from the mod,
+// else from the vanilla files, else from the English text. A key without
+// any text is missing, reported with the message missing; one the game
+// falls back on the vanilla files or English for is only hinted at.
+func checkLocKey(env *Env, rng lsp.Range, key, missing string) []lsp.Diagnostic {
+	defs := env.Index.Definitions(index.KindLocalization, key)
+	if len(defs) == 0 {
+		return []lsp.Diagnostic{newDiagnostic(ruleMissingLoc, rng, missing)}
+	}
+	local := map[string]bool{}
+	for _, sym := range env.Index.LocalDefinitions(index.KindLocalization, key) {
+		local[sym.Path] = true
+	}
+	mod, vanilla := map[string]bool{}, map[string]bool{}
+	for _, sym := range defs {
+		file := env.Index.File(sym.Path)
+		if file == nil || file.Loc == nil {
+			continue
+		}
+		if local[sym.Path] {
+			mod[file.Loc.Language] = true
+		} else {
+			vanilla[file.Loc.Language] = true
+		}
+	}
+
+	var untranslated, fallbacks []string
+	for _, language := range modLanguages(env.Options) {
+		name := strings.TrimPrefix(language, "l_")
+		if name != "" {
+			name = strings.ToUpper(name[:1]) + name[1:]
+		}
+		switch {
+		case mod[language]:
+		case vanilla[language]:
+			fallbacks = append(fallbacks, fmt.Sprintf("%s uses the vanilla text", name))
+		case mod[referenceLanguage] || vanilla[referenceLanguage]:
+			fallbacks = append(fallbacks, fmt.Sprintf("%s falls back to English", name))
+		default:
+			untranslated = append(untranslated, name)
+		}
+	}
+	var diagnostics []lsp.Diagnostic
+	if len(untranslated) > 0 {
+		diagnostics = append(diagnostics, newDiagnostic(ruleMissingLoc, rng,
+			fmt.Sprintf("localization key '%s' has no %s text, nor an English one to fall back to", key, strings.Join(untranslated, " or "))))
+	}
+	if len(fallbacks) > 0 {
+		diagnostics = append(diagnostics, newDiagnostic(ruleLocFallback, rng,
+			fmt.Sprintf("localization key '%s' is not localized by the mod in every language: %s", key, strings.Join(fallbacks, ", "))))
+	}
+	return diagnostics
+}
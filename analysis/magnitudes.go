@@ -0,0 +1,114 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+var ruleMagnitude = register(Rule{
+	ID:          "suspicious-magnitude",
+	Description: "A literal opinion, stress or dread value far outside the range the game uses, or a negative cooldown; often a missing decimal point.",
+	Severity:    lsp.Warning,
+	Optional:    true,
+})
+
+// magnitudeLimit is the largest absolute value considered plausible for a
+// key. Opinion is clamped to ±100 by the game and modifiers rarely exceed
+// ±200, stress levels top out at 300, and dread is capped at 100.
+type magnitudeLimit struct {
+	limit float64
+	what  string
+}
+
+var magnitudeLimits = map[string]magnitudeLimit{
+	"opinion":            {200, "opinion change"},
+	"add_stress":         {300, "stress change"},
+	"stress_loss":        {300, "stress loss"},
+	"stress_gain":        {300, "stress gain"},
+	"add_dread":          {100, "dread change"},
+	"dread_gain":         {100, "dread gain"},
+	"dread_loss":         {100, "dread loss"},
+	"dread_baseline_add": {100, "dread baseline"},
+}
+
+// opinionTriggers hold the compared amount in a `value` entry.
+var opinionTriggers = map[string]bool{
+	"opinion":         true,
+	"reverse_opinion": true,
+}
+
+// checkMagnitudes reports literal numbers that are implausibly large for
+// what they change, and negative cooldowns.
+func checkMagnitudes(entry *index.FileEntry) []lsp.Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	pdx.Walk(entry.File.Root, func(f *pdx.Field) bool {
+		key := f.KeyText()
+		value, ok := literalNumber(f)
+		if !ok {
+			if key == "cooldown" {
+				diagnostics = append(diagnostics, checkCooldown(f)...)
+			}
+			return true
+		}
+
+		if limit, ok := limitFor(f); ok && math.Abs(value) > limit.limit {
+			diagnostics = append(diagnostics, newDiagnostic(ruleMagnitude, Range(f.Value.Range()),
+				fmt.Sprintf("%s of %s exceeds ±%v; is a decimal point missing?", limit.what, f.ValueText(), limit.limit)))
+		}
+		return true
+	})
+	return diagnostics
+}
+
+// limitFor returns the plausibility limit for a numeric field, looking at
+// the enclosing block for `opinion = { value = N }` triggers and per-trait
+// `stress_impact = { trait = N }` entries.
+func limitFor(f *pdx.Field) (magnitudeLimit, bool) {
+	if limit, ok := magnitudeLimits[f.KeyText()]; ok {
+		return limit, true
+	}
+	parent := f.ParentField()
+	switch {
+	case parent == nil:
+		return magnitudeLimit{}, false
+	case f.KeyText() == "value" && opinionTriggers[parent.KeyText()]:
+		return magnitudeLimits["opinion"], true
+	case parent.KeyText() == "stress_impact":
+		return magnitudeLimit{magnitudeLimits["add_stress"].limit, "stress impact"}, true
+	}
+	return magnitudeLimit{}, false
+}
+
+// checkCooldown flags negative durations in `cooldown = { days = -5 }`.
+func checkCooldown(f *pdx.Field) []lsp.Diagnostic {
+	b := f.Block()
+	if b == nil {
+		return nil
+	}
+	var diagnostics []lsp.Diagnostic
+	for _, unit := range []string{"days", "months", "years"} {
+		for _, d := range b.All(unit) {
+			if v, ok := literalNumber(d); ok && v < 0 {
+				diagnostics = append(diagnostics, newDiagnostic(ruleMagnitude, Range(d.Value.Range()),
+					fmt.Sprintf("cooldown of %s %s is negative", d.ValueText(), unit)))
+			}
+		}
+	}
+	return diagnostics
+}
+
+// literalNumber returns the numeric value of an unquoted scalar field.
+func literalNumber(f *pdx.Field) (float64, bool) {
+	s := f.Scalar()
+	if s == nil || s.Quoted {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s.Text, 64)
+	return v, err == nil
+}
@@ -0,0 +1,82 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+var ruleDuplicateName = register(Rule{ID: "duplicate-name", Description: "A name listed more than once in a name list, which silently raises its weight.", Severity: lsp.Information})
+
+// nameListKeys are the name list blocks whose bare values are localization
+// keys. Duplicates are reported only in the character name lists.
+var nameListKeys = map[string]bool{
+	"male_names": true, "female_names": true,
+	"dynasty_names": false, "cadet_dynasty_names": false,
+}
+
+// nameListScalars are the name list entries whose value is a localization
+// key.
+var nameListScalars = map[string]bool{
+	"dynasty_of_location_prefix": true, "bastard_dynasty_prefix": true,
+}
+
+// checkNames validates the localization keys of name lists, dynasties and
+// dynasty houses, and reports duplicate character names.
+func checkNames(entry *index.FileEntry, env *Env) []lsp.Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	locKey := func(s *pdx.Scalar) {
+		if index.IsStaticName(s.Text) {
+			diagnostics = append(diagnostics, checkLocKey(env, Range(s.Loc), s.Text,
+				fmt.Sprintf("localization key '%s' is not defined", s.Text))...)
+		}
+	}
+	switch vpath := entry.VirtualPath; {
+	case strings.HasPrefix(vpath, "common/culture/name_lists/"):
+		for _, list := range entry.File.Root.Fields {
+			if list.Block() == nil {
+				continue
+			}
+			for _, f := range list.Block().Fields {
+				key := f.KeyText()
+				if s := f.Scalar(); s != nil && nameListScalars[key] {
+					locKey(s)
+				}
+				dedupe, ok := nameListKeys[key]
+				if !ok || f.Block() == nil {
+					continue
+				}
+				seen := make(map[string]bool)
+				pdx.Walk(f.Block(), func(item *pdx.Field) bool {
+					s := item.Scalar()
+					if item.Key != nil || s == nil {
+						return true
+					}
+					locKey(s)
+					if dedupe && seen[s.Text] {
+						diagnostics = append(diagnostics, newDiagnostic(ruleDuplicateName, Range(s.Loc),
+							fmt.Sprintf("'%s' is listed more than once in %s", s.Text, key)))
+					}
+					seen[s.Text] = true
+					return true
+				})
+			}
+		}
+	case strings.HasPrefix(vpath, "common/dynasties/"), strings.HasPrefix(vpath, "common/dynasty_houses/"):
+		for _, def := range entry.File.Root.Fields {
+			if def.Block() == nil {
+				continue
+			}
+			for _, key := range []string{"name", "prefix"} {
+				if f := def.Block().Get(key); f != nil && f.Scalar() != nil {
+					locKey(f.Scalar())
+				}
+			}
+		}
+	}
+	return diagnostics
+}
@@ -0,0 +1,62 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+var ruleNamingConvention = register(Rule{ID: "naming-convention", Description: "A localization key, event ID, scripted effect or other name that breaks the `diagnostics.naming` conventions.", Severity: lsp.Warning})
+
+// NamingConvention constrains the names of one kind of symbol.
+type NamingConvention struct {
+	// Prefixes are the allowed prefixes; when set, names must start with
+	// one of them.
+	Prefixes []string `json:"prefixes"`
+	// Forbidden holds the characters names must not contain.
+	Forbidden string `json:"forbidden"`
+	// MaxLength is the longest allowed name; 0 means no limit.
+	MaxLength int `json:"maxLength"`
+}
+
+// checkNaming reports the symbols a file defines whose names break the
+// convention configured for their kind.
+func checkNaming(entry *index.FileEntry, env *Env) []lsp.Diagnostic {
+	if env.Options == nil || len(env.Options.Naming) == 0 {
+		return nil
+	}
+	var diagnostics []lsp.Diagnostic
+	for _, sym := range entry.Symbols {
+		convention, ok := env.Options.Naming[string(sym.Kind)]
+		if !ok {
+			continue
+		}
+		if problem := convention.problem(sym.Name); problem != "" {
+			noun := strings.ReplaceAll(string(sym.Kind), "_", " ")
+			if sym.Kind == index.KindLocalization {
+				noun = "localization key"
+			}
+			diagnostics = append(diagnostics, newDiagnostic(ruleNamingConvention, Range(sym.Location.Range),
+				fmt.Sprintf("%s '%s' %s", noun, sym.Name, problem)))
+		}
+	}
+	return diagnostics
+}
+
+// problem describes how name breaks the convention, or returns "".
+func (c NamingConvention) problem(name string) string {
+	if len(c.Prefixes) > 0 && !slices.ContainsFunc(c.Prefixes, func(p string) bool { return strings.HasPrefix(name, p) }) {
+		return "does not start with " + strings.Join(c.Prefixes, " or ")
+	}
+	if i := strings.IndexAny(name, c.Forbidden); c.Forbidden != "" && i >= 0 {
+		return fmt.Sprintf("contains the forbidden character %q", name[i])
+	}
+	if c.MaxLength > 0 && len(name) > c.MaxLength {
+		return fmt.Sprintf("is %d characters long, more than the maximum of %d", len(name), c.MaxLength)
+	}
+	return ""
+}
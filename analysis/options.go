@@ -0,0 +1,57 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+var (
+	ruleNoFallbackOption   = register(Rule{ID: "no-fallback-option", Description: "An event whose every option has a trigger and none is `fallback = yes`, so if no trigger holds the player gets no option and the event cannot be closed.", Severity: lsp.Warning})
+	ruleUselessUnavailable = register(Rule{ID: "useless-show-as-unavailable", Description: "An option with `show_as_unavailable` but no trigger, which is therefore always available and never shown as unavailable.", Severity: lsp.Warning})
+)
+
+// checkOptions checks the options of the events of entry for the problems
+// the game only shows when players run into them: events that may be left
+// without a valid option, and show_as_unavailable blocks that can never
+// apply.
+func checkOptions(entry *index.FileEntry) []lsp.Diagnostic {
+	if !strings.HasPrefix(entry.VirtualPath, "events/") {
+		return nil
+	}
+	var diagnostics []lsp.Diagnostic
+	for _, event := range entry.File.Root.Fields {
+		b := event.Block()
+		if b == nil || event.Key == nil {
+			continue
+		}
+		options, guarded := 0, 0
+		fallback := false
+		for _, option := range b.All("option") {
+			ob := option.Block()
+			if ob == nil {
+				continue
+			}
+			options++
+			if f := ob.Get("fallback"); f != nil && f.ValueText() == "yes" {
+				fallback = true
+			}
+			if ob.Get("trigger") != nil {
+				guarded++
+				continue
+			}
+			if unavailable := ob.Get("show_as_unavailable"); unavailable != nil {
+				diagnostics = append(diagnostics, newDiagnostic(ruleUselessUnavailable, Range(unavailable.Key.Loc),
+					"show_as_unavailable has no effect without a trigger: the option is always available"))
+			}
+		}
+		if options > 0 && guarded == options && !fallback {
+			diagnostics = append(diagnostics, newDiagnostic(ruleNoFallbackOption, Range(event.Key.Loc),
+				fmt.Sprintf("every option of event %s has a trigger; add an option without one, or with fallback = yes, so the event can always be closed", event.Key.Text)))
+		}
+	}
+	return diagnostics
+}
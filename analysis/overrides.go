@@ -0,0 +1,65 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+var ruleIdenticalOverride = register(Rule{ID: "identical-override", Description: "An object of a file replacing a whole vanilla file that is identical to vanilla; the quick fix keeps only the changed objects in a smaller override.", Severity: lsp.Hint})
+
+// PartialOverrides reports whether the objects of files at vpath can be
+// overridden one by one, by a later file defining them again, rather than
+// only by replacing the vanilla file. That holds for the databases of
+// common/, except on_actions, whose definitions are merged.
+func PartialOverrides(vpath string) bool {
+	return strings.HasPrefix(vpath, "common/") && !strings.HasPrefix(vpath, "common/on_action/") && index.IsScriptFile(vpath)
+}
+
+// IdenticalObjects returns the top-level definitions of a file replacing
+// a vanilla file that are identical to those of the vanilla file, apart
+// from line endings. It returns nil for other files.
+func IdenticalObjects(entry *index.FileEntry, ix *index.Index) []*pdx.Field {
+	if entry.File == nil || !PartialOverrides(entry.VirtualPath) {
+		return nil
+	}
+	vanilla := ix.OverriddenFile(entry.Path)
+	if vanilla == nil || vanilla.File == nil {
+		return nil
+	}
+	texts := make(map[string]string)
+	for _, f := range vanilla.File.Root.Fields {
+		if f.Key != nil && f.Block() != nil {
+			texts[f.Key.Text] = fieldSource(vanilla.File, f)
+		}
+	}
+	var identical []*pdx.Field
+	for _, f := range entry.File.Root.Fields {
+		if text, ok := texts[f.KeyText()]; ok && f.Block() != nil && fieldSource(entry.File, f) == text {
+			identical = append(identical, f)
+		}
+	}
+	return identical
+}
+
+// fieldSource returns the source of a field with LF line endings.
+func fieldSource(file *pdx.File, f *pdx.Field) string {
+	r := f.Range()
+	return strings.ReplaceAll(file.Text[r.Start.Offset:r.End.Offset], "\r\n", "\n")
+}
+
+// checkOverrides flags the objects of a whole-file override that do not
+// change vanilla: they only make the mod conflict with others changing the
+// same file.
+func checkOverrides(entry *index.FileEntry, ix *index.Index) []lsp.Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	for _, f := range IdenticalObjects(entry, ix) {
+		diagnostics = append(diagnostics, newDiagnostic(ruleIdenticalOverride, Range(f.Key.Loc),
+			fmt.Sprintf("'%s' is identical to vanilla %s", f.Key.Text, entry.VirtualPath)))
+	}
+	return diagnostics
+}
@@ -0,0 +1,125 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+var (
+	rulePerfUnlimited = register(Rule{
+		ID:          "perf-unlimited-iterator",
+		Description: "An every_, random_ or ordered_ iterator over all characters, rulers or titles of the world without a `limit`, which runs its effects for thousands of objects.",
+		Severity:    lsp.Information,
+		Optional:    true,
+	})
+	rulePerfNestedLoop = register(Rule{
+		ID:          "perf-nested-loop",
+		Description: "An every_ loop inside another every_ loop; the cost multiplies with each level, so filter the outer loop or use a list.",
+		Severity:    lsp.Information,
+		Optional:    true,
+	})
+	rulePerfHeavyPulse = register(Rule{
+		ID:          "perf-heavy-pulse",
+		Description: "A yearly, quarterly or other pulse on_action whose effect iterates over many objects; pulses fire for every character or player and add up over a campaign.",
+		Severity:    lsp.Information,
+		Optional:    true,
+	})
+	rulePerfRandomList = register(Rule{
+		ID:          "perf-random-list-in-loop",
+		Description: "A random_list inside an every_ or while loop, which evaluates every option's weight and triggers on each iteration.",
+		Severity:    lsp.Information,
+		Optional:    true,
+	})
+)
+
+// globalIterators are the iterator targets that cover the whole world
+// rather than a character's relations or a realm.
+var globalIterators = map[string]bool{
+	"living_character": true, "character": true, "ruler": true, "independent_ruler": true,
+	"player": true, "county": true, "province": true, "barony": true, "landed_title": true,
+	"county_in_region": true, "duchy": true, "kingdom": true, "empire": true,
+	"character_with_royal_court": true, "religion_global": true, "faith": true, "culture_global": true,
+}
+
+// iterator splits a key like "every_living_character" into its prefix and
+// target.
+func iterator(key string) (prefix, target string, ok bool) {
+	for _, p := range []string{"every_", "random_", "ordered_"} {
+		if strings.HasPrefix(key, p) && key != "random_list" {
+			return strings.TrimSuffix(p, "_"), strings.TrimPrefix(key, p), true
+		}
+	}
+	return "", "", false
+}
+
+// pulseOnAction matches the names of on_actions fired on a timer.
+func pulseOnAction(name string) bool {
+	return strings.Contains(name, "_pulse") || strings.HasPrefix(name, "yearly_") || strings.HasPrefix(name, "quarterly_")
+}
+
+// checkPerformance reports script patterns known to slow the game down.
+func checkPerformance(entry *index.FileEntry) []lsp.Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	var walk func(b *pdx.Block, loops int)
+	walk = func(b *pdx.Block, loops int) {
+		for _, f := range b.Fields {
+			key := f.KeyText()
+			block := f.Block()
+			inner := loops
+			if prefix, target, ok := iterator(key); ok && block != nil {
+				if globalIterators[target] && block.Get("limit") == nil {
+					verb := "runs for"
+					if prefix != "every" {
+						verb = "considers"
+					}
+					diagnostics = append(diagnostics, newDiagnostic(rulePerfUnlimited, Range(f.Key.Loc),
+						fmt.Sprintf("%s has no limit and %s every %s in the game", key, verb, strings.ReplaceAll(target, "_", " "))))
+				}
+				if prefix == "every" {
+					if loops > 0 {
+						diagnostics = append(diagnostics, newDiagnostic(rulePerfNestedLoop, Range(f.Key.Loc),
+							fmt.Sprintf("%s is nested in another every_ loop; its cost multiplies with the outer loop", key)))
+					}
+					inner++
+				}
+			}
+			if key == "while" {
+				inner++
+			}
+			if key == "random_list" && loops > 0 {
+				diagnostics = append(diagnostics, newDiagnostic(rulePerfRandomList, Range(f.Key.Loc),
+					"random_list inside a loop evaluates all of its options on every iteration"))
+			}
+			if block != nil {
+				walk(block, inner)
+			}
+		}
+	}
+	walk(entry.File.Root, 0)
+
+	if strings.HasPrefix(entry.VirtualPath, "common/on_action/") {
+		for _, f := range entry.File.Root.Fields {
+			if f.Key == nil || f.Block() == nil || !pulseOnAction(f.Key.Text) {
+				continue
+			}
+			effect := f.Block().Get("effect")
+			if effect == nil || effect.Block() == nil {
+				continue
+			}
+			pdx.Walk(effect.Block(), func(g *pdx.Field) bool {
+				if _, target, ok := iterator(g.KeyText()); ok && globalIterators[target] {
+					diagnostics = append(diagnostics, newDiagnostic(rulePerfHeavyPulse, Range(g.Key.Loc),
+						fmt.Sprintf("%s in the effect of the pulse on_action %s runs on every pulse; move the work to an event with a trigger or spread it out", g.Key.Text, f.Key.Text)))
+					return false
+				}
+				return true
+			})
+		}
+	}
+	return diagnostics
+}
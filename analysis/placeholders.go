@@ -0,0 +1,76 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/loc"
+)
+
+var rulePlaceholderMismatch = register(Rule{ID: "placeholder-mismatch", Description: "A translation that drops or adds `$variables$` or `[DataFunctions]` compared with the English text of the same key.", Severity: lsp.Warning})
+
+// referenceLanguage is the language translations are compared with.
+const referenceLanguage = "l_english"
+
+// checkPlaceholders compares the placeholders of each entry of a
+// translation with those of the English entry of the same key.
+func checkPlaceholders(entry *index.FileEntry, ix *index.Index) []Diagnostic {
+	if entry.Loc == nil || entry.Loc.Language == referenceLanguage {
+		return nil
+	}
+	var diagnostics []Diagnostic
+	for _, e := range entry.Loc.Entries {
+		var ref *index.LocEntry
+		for _, candidate := range ix.Localizations(e.Key) {
+			if candidate.Language == referenceLanguage {
+				ref = &candidate
+				break
+			}
+		}
+		if ref == nil {
+			continue
+		}
+		want := placeholderSet(ref.Text)
+		have := placeholderSet(e.Text)
+		var dropped, added []string
+		for _, p := range loc.Placeholders(ref.Text) {
+			if !have[p.Text] && !slices.Contains(dropped, p.Text) {
+				dropped = append(dropped, p.Text)
+			}
+		}
+		for _, p := range loc.Placeholders(e.Text) {
+			if !want[p.Text] && !slices.Contains(added, p.Text) {
+				added = append(added, p.Text)
+			}
+		}
+		if len(dropped) == 0 && len(added) == 0 {
+			continue
+		}
+		var problems []string
+		if len(dropped) > 0 {
+			problems = append(problems, "drops "+strings.Join(dropped, ", "))
+		}
+		if len(added) > 0 {
+			problems = append(problems, "adds "+strings.Join(added, ", "))
+		}
+		d := newDiagnostic(rulePlaceholderMismatch, Range(e.KeyRange),
+			fmt.Sprintf("translation of '%s' %s compared with %s", e.Key, strings.Join(problems, " and "), strings.TrimPrefix(referenceLanguage, "l_")))
+		diagnostics = append(diagnostics, Diagnostic{Diagnostic: d, RelatedInformation: []RelatedInformation{{
+			Location: lsp.Location{URI: FileURI(ref.Path), Range: Range(ref.KeyRange)},
+			Message:  "English text",
+		}}})
+	}
+	return diagnostics
+}
+
+func placeholderSet(text string) map[string]bool {
+	set := map[string]bool{}
+	for _, p := range loc.Placeholders(text) {
+		set[p.Text] = true
+	}
+	return set
+}
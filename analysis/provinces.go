@@ -0,0 +1,83 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+var ruleProvinceHolding = register(Rule{ID: "province-holding", Description: "A province history holding or special building that cannot exist there: a holding on water terrain, or a special building without a special building slot.", Severity: lsp.Warning})
+
+// waterTerrains are the terrain types of provinces that can never hold a
+// holding.
+var waterTerrains = map[string]bool{"sea": true, "coastal_sea": true}
+
+// checkProvinceHistory validates holdings against each province's terrain
+// and special building assignments against building definitions.
+func checkProvinceHistory(entry *index.FileEntry, ix *index.Index) []lsp.Diagnostic {
+	if !strings.HasPrefix(entry.VirtualPath, "history/provinces/") {
+		return nil
+	}
+	var diagnostics []lsp.Diagnostic
+	report := func(s *pdx.Scalar, format string, args ...interface{}) {
+		diagnostics = append(diagnostics, newDiagnostic(ruleProvinceHolding, Range(s.Loc), fmt.Sprintf(format, args...)))
+	}
+	for _, province := range entry.File.Root.Fields {
+		if province.Key == nil || province.Block() == nil || !index.IsProvinceID(province.Key.Text) {
+			continue
+		}
+		id := province.Key.Text
+		terrain := ix.ProvinceTerrain(id)
+		hasSlot := false
+		var special []*pdx.Scalar
+		pdx.Walk(province.Block(), func(f *pdx.Field) bool {
+			s := f.Scalar()
+			if s == nil {
+				return true
+			}
+			switch f.KeyText() {
+			case "holding":
+				if waterTerrains[terrain] && s.Text != "none" {
+					report(s, "province %s has %s terrain and cannot have a holding", id, terrain)
+				}
+			case "special_building_slot":
+				hasSlot = true
+				if t := buildingType(ix, s.Text); t != "" && t != "special" {
+					report(s, "'%s' is not a special building", s.Text)
+				}
+			case "special_building":
+				special = append(special, s)
+			}
+			return true
+		})
+		for _, s := range special {
+			if !hasSlot {
+				report(s, "province %s has a special building but no special_building_slot", id)
+			} else if t := buildingType(ix, s.Text); t != "" && t != "special" {
+				report(s, "'%s' is not a special building", s.Text)
+			}
+		}
+	}
+	return diagnostics
+}
+
+// buildingType returns the `type` of a building definition: "special",
+// "duchy_capital", "regular" for buildings without one, or "" if the
+// building is not defined.
+func buildingType(ix *index.Index, name string) string {
+	for _, sym := range ix.Definitions(index.KindBuilding, name) {
+		f := ix.FieldAt(sym.Location)
+		if f == nil || f.Block() == nil {
+			continue
+		}
+		if t := f.Block().Get("type"); t != nil {
+			return t.ValueText()
+		}
+		return "regular"
+	}
+	return ""
+}
@@ -0,0 +1,102 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+var (
+	ruleUnknownTitle            = register(Rule{ID: "unknown-title", Description: "A landed title that is not defined in common/landed_titles.", Severity: lsp.Warning, Vanilla: true})
+	ruleUnknownCharacter        = register(Rule{ID: "unknown-character", Description: "A character ID that is not defined in history/characters.", Severity: lsp.Warning, Vanilla: true})
+	ruleUnknownLaw              = register(Rule{ID: "unknown-law", Description: "A law that is not defined in common/laws.", Severity: lsp.Warning, Vanilla: true})
+	ruleUnknownBuilding         = register(Rule{ID: "unknown-building", Description: "A building that is not defined in common/buildings.", Severity: lsp.Warning, Vanilla: true})
+	ruleUnknownHolding          = register(Rule{ID: "unknown-holding", Description: "A holding type that is not defined in common/holdings.", Severity: lsp.Warning, Vanilla: true})
+	ruleUnknownCulture          = register(Rule{ID: "unknown-culture", Description: "A culture that is not defined in common/culture/cultures.", Severity: lsp.Warning, Vanilla: true})
+	ruleUnknownTerrain          = register(Rule{ID: "unknown-terrain", Description: "A terrain type that is not defined in common/terrain_types.", Severity: lsp.Warning, Vanilla: true})
+	ruleUnknownDomicile         = register(Rule{ID: "unknown-domicile", Description: "A domicile type or domicile building that is not defined in common/domicile_types or common/domicile_buildings.", Severity: lsp.Warning, Vanilla: true})
+	ruleUnknownLevel            = register(Rule{ID: "unknown-level", Description: "A named level, such as a legitimacy level or house unity stage, that no threshold table defines.", Severity: lsp.Warning, Vanilla: true})
+	ruleUnknownEpidemic         = register(Rule{ID: "unknown-epidemic", Description: "An epidemic type that is not defined in common/epidemics.", Severity: lsp.Warning, Vanilla: true})
+	ruleUnknownRegion           = register(Rule{ID: "unknown-region", Description: "A geographical region that is not defined in map_data/geographical_regions.", Severity: lsp.Warning, Vanilla: true})
+	ruleUnknownDynasty          = register(Rule{ID: "unknown-dynasty", Description: "A dynasty or dynasty house that is not defined in common/dynasties or common/dynasty_houses.", Severity: lsp.Warning, Vanilla: true})
+	ruleUnknownAccolade         = register(Rule{ID: "unknown-accolade", Description: "An accolade type or accolade name that is not defined in common/accolade_types or common/accolade_names.", Severity: lsp.Warning, Vanilla: true})
+	ruleUnknownScriptedModifier = register(Rule{ID: "unknown-scripted-modifier", Description: "An ai_chance or weight entry that is neither a built-in nor a scripted modifier defined in common/scripted_modifiers.", Severity: lsp.Warning, Vanilla: true})
+	ruleDuplicateDef            = register(Rule{ID: "duplicate-definition", Description: "An object defined more than once by the mod; the game keeps only one of the definitions.", Severity: lsp.Warning})
+	ruleUnknownEvent            = register(Rule{ID: "unknown-event", Description: "An event fired by trigger_event or listed in an on_action that is not defined in events/.", Severity: lsp.Warning, Vanilla: true})
+	ruleUnknownFaith            = register(Rule{ID: "unknown-faith", Description: "A faith that is not defined in common/religion/religions.", Severity: lsp.Warning, Vanilla: true})
+)
+
+// unknownRules maps kinds whose references must resolve to the rule and
+// noun used to report them.
+var unknownRules = map[index.Kind]struct {
+	rule Rule
+	noun string
+}{
+	index.KindTitle:            {ruleUnknownTitle, "title"},
+	index.KindCharacter:        {ruleUnknownCharacter, "character"},
+	index.KindLaw:              {ruleUnknownLaw, "law"},
+	index.KindBuilding:         {ruleUnknownBuilding, "building"},
+	index.KindHolding:          {ruleUnknownHolding, "holding type"},
+	index.KindCulture:          {ruleUnknownCulture, "culture"},
+	index.KindFaith:            {ruleUnknownFaith, "faith"},
+	index.KindTerrain:          {ruleUnknownTerrain, "terrain type"},
+	index.KindDomicileType:     {ruleUnknownDomicile, "domicile type"},
+	index.KindDomicileBuilding: {ruleUnknownDomicile, "domicile building"},
+	index.KindLegitimacyLevel:  {ruleUnknownLevel, "legitimacy level"},
+	index.KindHouseUnityStage:  {ruleUnknownLevel, "house unity stage"},
+	index.KindEpidemic:         {ruleUnknownEpidemic, "epidemic type"},
+	index.KindRegion:           {ruleUnknownRegion, "geographical region"},
+	index.KindDynasty:          {ruleUnknownDynasty, "dynasty"},
+	index.KindHouse:            {ruleUnknownDynasty, "dynasty house"},
+	index.KindAccoladeType:     {ruleUnknownAccolade, "accolade type"},
+	index.KindAccoladeName:     {ruleUnknownAccolade, "accolade name"},
+	index.KindScriptedModifier: {ruleUnknownScriptedModifier, "scripted modifier"},
+	index.KindEvent:            {ruleUnknownEvent, "event"},
+}
+
+// checkReferences reports references of the kinds in unknownRules that
+// resolve to no definition.
+func checkReferences(entry *index.FileEntry, ix *index.Index) []lsp.Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	for _, ref := range entry.Refs {
+		unknown, ok := unknownRules[ref.Kind]
+		if !ok || len(ix.Definitions(ref.Kind, ref.Name)) > 0 {
+			continue
+		}
+		diagnostics = append(diagnostics, newDiagnostic(unknown.rule, Range(ref.Range),
+			fmt.Sprintf("%s '%s' is not defined", unknown.noun, ref.Name)))
+	}
+	return diagnostics
+}
+
+// uniqueKinds are the kinds whose objects a mod may define only once. Vanilla
+// definitions are not counted, since replacing them is intended.
+var uniqueKinds = map[index.Kind]bool{
+	index.KindDomicileType:     true,
+	index.KindDomicileBuilding: true,
+	index.KindHouseUnity:       true,
+	index.KindAppointment:      true,
+	index.KindTaxSlot:          true,
+}
+
+// checkDuplicates reports symbols of uniqueKinds that the mod defines more
+// than once.
+func checkDuplicates(entry *index.FileEntry, ix *index.Index) []lsp.Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	for _, sym := range entry.Symbols {
+		if !uniqueKinds[sym.Kind] {
+			continue
+		}
+		for _, other := range ix.LocalDefinitions(sym.Kind, sym.Name) {
+			if other.Location != sym.Location {
+				diagnostics = append(diagnostics, newDiagnostic(ruleDuplicateDef, Range(sym.Range),
+					fmt.Sprintf("%s '%s' is also defined in %s", strings.ReplaceAll(string(sym.Kind), "_", " "), sym.Name, ix.VirtualPath(other.Path))))
+				break
+			}
+		}
+	}
+	return diagnostics
+}
@@ -0,0 +1,151 @@
+package analysis
+
+import (
+	"sort"
+	"sync"
+
+	lsp "github.com/sourcegraph/go-lsp"
+)
+
+// Rule describes a diagnostic rule. Every diagnostic produced by a check
+// carries its rule ID as the diagnostic code so users can configure it.
+type Rule struct {
+	ID          string
+	Description string
+	Severity    lsp.DiagnosticSeverity
+	// Optional rules are off unless enabled in the settings.
+	Optional bool
+	// Vanilla rules report names the vanilla files may define, and are
+	// skipped while no vanilla layer is indexed.
+	Vanilla bool
+}
+
+// Options holds the user's diagnostic configuration.
+type Options struct {
+	// Rules maps rule IDs to "off", "on", or a severity name ("error",
+	// "warning", "information", "hint").
+	Rules map[string]string `json:"rules"`
+	// DLC lists the DLC names and features the mod declares support for;
+	// the undeclared-dlc rule reports checks for any other.
+	DLC []string `json:"dlc"`
+	// Naming maps symbol kinds ("localization", "event",
+	// "scripted_effect"...) to the naming convention their names follow.
+	Naming map[string]NamingConvention `json:"naming"`
+	// MaxPerFile is the number of diagnostics reported per file,
+	// DefaultMaxPerFile if 0; a negative value reports all of them.
+	MaxPerFile int `json:"maxPerFile"`
+	// Languages are the localization languages the mod ships, such as
+	// "l_english" and "l_french"; "l_english" alone if empty. The keys
+	// script uses are checked in each of them.
+	Languages []string `json:"languages"`
+}
+
+var (
+	// rulesMu guards rules, to which RegisterAnalyzer adds at run time.
+	rulesMu sync.RWMutex
+	rules   = map[string]Rule{}
+)
+
+func register(r Rule) Rule {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	rules[r.ID] = r
+	return r
+}
+
+// Rules returns every known rule sorted by ID.
+func Rules() []Rule {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	list := make([]Rule, 0, len(rules))
+	for _, r := range rules {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+var (
+	ruleSyntax    = register(Rule{ID: "syntax-error", Description: "Malformed script that the game cannot parse.", Severity: lsp.Error})
+	ruleUnsetFlag = register(Rule{ID: "unset-flag", Description: "A flag is checked or removed but never set anywhere in the workspace.", Severity: lsp.Warning})
+)
+
+var severityNames = map[string]lsp.DiagnosticSeverity{
+	"error":       lsp.Error,
+	"warning":     lsp.Warning,
+	"information": lsp.Information,
+	"info":        lsp.Information,
+	"hint":        lsp.Hint,
+}
+
+// severity returns the configured severity of a rule, or 0 if it is
+// disabled.
+func (o *Options) severity(r Rule) lsp.DiagnosticSeverity {
+	setting := ""
+	if o != nil {
+		setting = o.Rules[r.ID]
+	}
+	switch setting {
+	case "off":
+		return 0
+	case "on":
+		return r.Severity
+	case "":
+		if r.Optional {
+			return 0
+		}
+		return r.Severity
+	}
+	if sev, ok := severityNames[setting]; ok {
+		return sev
+	}
+	return r.Severity
+}
+
+// Enabled reports whether the rule with the given ID is turned on.
+func (o *Options) Enabled(id string) bool {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	r, ok := rules[id]
+	return ok && o.severity(r) != 0
+}
+
+// applyRules drops diagnostics of disabled rules, and of vanilla rules when
+// vanilla is false, and applies configured severities, also to the codes
+// of unregistered rules.
+func applyRules(diagnostics []Diagnostic, opts *Options, vanilla bool) []Diagnostic {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	kept := diagnostics[:0]
+	for _, d := range diagnostics {
+		r, ok := rules[d.Code]
+		if !ok {
+			// Codes of external analyzers are not registered, but may
+			// still be turned off or given another severity.
+			r = Rule{ID: d.Code, Severity: d.Severity}
+			if r.Severity == 0 {
+				r.Severity = lsp.Warning
+			}
+		}
+		if r.Vanilla && !vanilla {
+			continue
+		}
+		d.Severity = opts.severity(r)
+		if d.Severity == 0 {
+			continue
+		}
+		kept = append(kept, d)
+	}
+	return kept
+}
+
+// newDiagnostic builds a diagnostic for rule r with its default severity.
+func newDiagnostic(r Rule, rng lsp.Range, msg string) lsp.Diagnostic {
+	return lsp.Diagnostic{
+		Range:    rng,
+		Severity: r.Severity,
+		Code:     r.ID,
+		Source:   Source,
+		Message:  msg,
+	}
+}
@@ -0,0 +1,71 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+	"github.com/unLomTrois/gock3-lsp/scope"
+)
+
+var ruleScopeMismatch = register(Rule{ID: "scope-mismatch", Description: "An event target, iterator or link of a dotted chain like `root.liege.primary_title.holder` used in a scope it does not exist for.", Severity: lsp.Warning})
+
+// checkScopes reports event targets and iterators opened from a scope of
+// the wrong type, and dotted event target chains with a link that is not
+// valid for the scope the links before it lead to.
+func checkScopes(entry *index.FileEntry) []lsp.Diagnostic {
+	tree := scope.Analyze(entry.File, entry.VirtualPath)
+	if tree == nil {
+		return nil
+	}
+	var diagnostics []lsp.Diagnostic
+	pdx.Walk(entry.File.Root, func(f *pdx.Field) bool {
+		frame := tree.Parent(f)
+		if frame == nil || f.Key == nil {
+			return true
+		}
+		key := f.Key.Text
+		if scope.IsChain(key) {
+			diagnostics = append(diagnostics, checkTargetChain(frame, f.Key)...)
+		} else if f.Block() != nil {
+			var link scope.Link
+			var ok bool
+			if _, list, _, isList := scope.Iterator(key); isList {
+				link, ok = list, true
+			} else {
+				link, ok = scope.LookupLink(key)
+			}
+			if ok && !link.ValidFrom(frame.This) {
+				diagnostics = append(diagnostics, newDiagnostic(ruleScopeMismatch, Range(f.Key.Loc),
+					fmt.Sprintf("%s is not valid in %s scope; it needs %s", key, frame.This, scope.TypeList(link.From))))
+			}
+		}
+		if s := f.Scalar(); s != nil && !s.Quoted && scope.IsChain(s.Text) {
+			diagnostics = append(diagnostics, checkTargetChain(frame, s)...)
+		}
+		return true
+	})
+	return diagnostics
+}
+
+// checkTargetChain resolves the event target chain s from frame and
+// reports its first invalid link.
+func checkTargetChain(frame *scope.Frame, s *pdx.Scalar) []lsp.Diagnostic {
+	_, err := frame.Resolve(s.Text)
+	if err == nil {
+		return nil
+	}
+	start, end := s.Loc.Start, s.Loc.Start
+	start.Offset += err.Start
+	start.Col += err.Start
+	end.Offset += err.End
+	end.Col += err.End
+	msg := err.Msg
+	if err.Start > 0 {
+		msg += " (after " + strings.TrimSuffix(s.Text[:err.Start], ".") + ")"
+	}
+	return []lsp.Diagnostic{newDiagnostic(ruleScopeMismatch, Range(pdx.Range{Start: start, End: end}), msg)}
+}
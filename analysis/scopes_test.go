@@ -0,0 +1,47 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+func TestCheckScopes(t *testing.T) {
+	ix := index.New()
+	entry := ix.UpdateFile("/mod/events/my_events.txt", `my.0001 = {
+	immediate = {
+		liege = { add_gold = 1 }
+		holder = { add_gold = 1 }
+		every_held_title = { holder = { add_gold = 1 } }
+		save_scope_as = me
+		set_variable = { name = x value = root.primary_title.faith.dynasty }
+		scope:me.holder = { add_gold = 1 }
+		every_vassal = { every_claimant = { } }
+		my_link.holder = { }
+	}
+}
+`)
+	type report struct {
+		line, start, end int
+		msg              string
+	}
+	want := []report{
+		{3, 2, 8, "holder is not valid in character scope; it needs landed_title"},
+		{6, 61, 68, "dynasty is not valid in faith scope; it needs character or dynasty_house (after root.primary_title.faith)"},
+		{7, 11, 17, "holder is not valid in character scope; it needs landed_title (after scope:me)"},
+		{8, 19, 33, "every_claimant is not valid in character scope; it needs landed_title"},
+	}
+	got := checkScopes(entry)
+	if len(got) != len(want) {
+		t.Fatalf("got %d diagnostics, want %d: %+v", len(got), len(want), got)
+	}
+	for i, d := range got {
+		w := want[i]
+		if d.Range.Start.Line != w.line || d.Range.End.Line != w.line || d.Range.Start.Character != w.start || d.Range.End.Character != w.end || d.Message != w.msg {
+			t.Errorf("diagnostic %d = %d:%d-%d %q, want %d:%d-%d %q", i, d.Range.Start.Line, d.Range.Start.Character, d.Range.End.Character, d.Message, w.line, w.start, w.end, w.msg)
+		}
+		if d.Code != ruleScopeMismatch.ID {
+			t.Errorf("diagnostic %d has code %s", i, d.Code)
+		}
+	}
+}
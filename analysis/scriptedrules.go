@@ -0,0 +1,49 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+var ruleScriptedRule = register(Rule{ID: "scripted-rule", Description: "A scripted_rules entry that is not one of the hooks the game defines in its own scripted_rules, or that is not a trigger block.", Severity: lsp.Warning})
+
+// checkScriptedRules validates the hooks defined by common/scripted_rules
+// files. The game only evaluates the rules it defines itself, so the known
+// hooks are those of the vanilla files; without them only the shape of each
+// rule is checked.
+func checkScriptedRules(entry *index.FileEntry, ix *index.Index) []lsp.Diagnostic {
+	if !strings.HasPrefix(entry.VirtualPath, "common/scripted_rules/") {
+		return nil
+	}
+	var hooks []string
+	known := make(map[string]bool)
+	if base := ix.Base(); base != nil {
+		hooks = base.Names(index.KindScriptedRule)
+		for _, name := range hooks {
+			known[name] = true
+		}
+	}
+	var diagnostics []lsp.Diagnostic
+	for _, f := range entry.File.Root.Fields {
+		if f.Key == nil || !index.IsStaticName(f.Key.Text) {
+			continue
+		}
+		if f.Block() == nil {
+			diagnostics = append(diagnostics, newDiagnostic(ruleScriptedRule, Range(f.Range()),
+				fmt.Sprintf("scripted rule '%s' must be a trigger block", f.Key.Text)))
+		}
+		if len(known) == 0 || known[f.Key.Text] {
+			continue
+		}
+		msg := fmt.Sprintf("'%s' is not a scripted rule the game evaluates", f.Key.Text)
+		if s := suggest(f.Key.Text, hooks); s != "" {
+			msg += fmt.Sprintf("; did you mean '%s'?", s)
+		}
+		diagnostics = append(diagnostics, newDiagnostic(ruleScriptedRule, Range(f.Key.Loc), msg))
+	}
+	return diagnostics
+}
@@ -0,0 +1,63 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/spell"
+)
+
+var ruleSpelling = register(Rule{ID: "spelling", Description: "A misspelled word in the prose of a localization text, checked against the `spellcheck` dictionaries and the CK3 terms; keys, data functions, variables and formatting codes are skipped.", Severity: lsp.Information, Optional: true})
+
+// checkSpelling reports the unknown words of the localization texts of
+// the dictionary's language. Capitalized words inside a sentence are taken
+// for names and all-capital words for abbreviations.
+func checkSpelling(entry *index.FileEntry, env *Env) []lsp.Diagnostic {
+	d := env.Dictionary
+	if d == nil || entry.Loc == nil || entry.Loc.Language != d.Language {
+		return nil
+	}
+	var diagnostics []lsp.Diagnostic
+	for _, e := range entry.Loc.Entries {
+		for _, w := range spell.Words(e.Text) {
+			if skipSpelling(w) || d.Known(w.Text) || knownParts(d, w.Text) {
+				continue
+			}
+			msg := fmt.Sprintf("unknown word '%s'", w.Text)
+			if suggestions := d.Suggest(w.Text, 3); len(suggestions) > 0 {
+				msg += "; did you mean " + strings.Join(suggestions, ", ") + "?"
+			}
+			diagnostics = append(diagnostics, newDiagnostic(ruleSpelling, Range(e.TextSpan(w.Start, w.End)), msg))
+		}
+	}
+	return diagnostics
+}
+
+// skipSpelling reports whether w is a name or abbreviation rather than a
+// word to check.
+func skipSpelling(w spell.Word) bool {
+	first, _ := utf8.DecodeRuneInString(w.Text)
+	if !unicode.IsUpper(first) {
+		return false
+	}
+	return !w.SentenceStart || strings.ToUpper(w.Text) == w.Text
+}
+
+// knownParts reports whether every part of a hyphenated word is known.
+func knownParts(d *spell.Dictionary, word string) bool {
+	parts := strings.Split(word, "-")
+	if len(parts) < 2 {
+		return false
+	}
+	for _, part := range parts {
+		if !d.Known(part) {
+			return false
+		}
+	}
+	return true
+}
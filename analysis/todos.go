@@ -0,0 +1,58 @@
+package analysis
+
+import (
+	"regexp"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+var ruleTodo = register(Rule{ID: "todo-comment", Description: "A TODO, FIXME or HACK comment, listed so outstanding work shows up in the problems panel.", Severity: lsp.Hint, Optional: true})
+
+// todoPattern matches a work marker in a comment and the note after it.
+var todoPattern = regexp.MustCompile(`\b(TODO|FIXME|HACK)\b[:\s]*(.*)`)
+
+// Todo is a TODO, FIXME or HACK marker found in a comment.
+type Todo struct {
+	// Tag is the marker, such as "TODO".
+	Tag string
+	// Text is the rest of the comment after the marker.
+	Text string
+	// Range spans the whole comment.
+	Range pdx.Range
+}
+
+// Todos returns the work markers in the comments of a script, GUI or
+// localization file.
+func Todos(entry *index.FileEntry) []Todo {
+	var comments []pdx.Token
+	switch {
+	case entry.File != nil:
+		comments = entry.File.Comments
+	case entry.Loc != nil:
+		comments = entry.Loc.Comments
+	}
+	var todos []Todo
+	for _, c := range comments {
+		if m := todoPattern.FindStringSubmatch(c.Text); m != nil {
+			todos = append(todos, Todo{Tag: m[1], Text: strings.TrimSpace(m[2]), Range: c.Range})
+		}
+	}
+	return todos
+}
+
+// checkTodos reports every work marker as a hint.
+func checkTodos(entry *index.FileEntry) []lsp.Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	for _, todo := range Todos(entry) {
+		msg := todo.Tag
+		if todo.Text != "" {
+			msg += ": " + todo.Text
+		}
+		diagnostics = append(diagnostics, newDiagnostic(ruleTodo, Range(todo.Range), msg))
+	}
+	return diagnostics
+}
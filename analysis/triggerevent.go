@@ -0,0 +1,160 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+	"github.com/unLomTrois/gock3-lsp/scope"
+)
+
+var (
+	ruleEventDelay    = register(Rule{ID: "invalid-event-delay", Description: "A trigger_event delay the game cannot use: more than one of days, months and years, a negative or fractional number, or a range that is not `{ min max }` with min at most max.", Severity: lsp.Warning})
+	ruleEventScope    = register(Rule{ID: "event-scope-mismatch", Description: "An event fired by trigger_event, or by an on_action of the game, for a scope of another type than its `scope`, such as a character event fired from a title scope.", Severity: lsp.Warning})
+	onActionEventKeys = map[string]bool{"events": true, "random_events": true, "first_valid": true}
+)
+
+// checkEventDelays validates the delays of the trigger_event blocks of
+// entry.
+func checkEventDelays(entry *index.FileEntry) []lsp.Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	pdx.Walk(entry.File.Root, func(f *pdx.Field) bool {
+		if b := f.Block(); b != nil && f.KeyText() == "trigger_event" {
+			diagnostics = append(diagnostics, checkEventDelay(b)...)
+		}
+		return true
+	})
+	return diagnostics
+}
+
+// checkEventDelay validates the delay of the trigger_event block b.
+func checkEventDelay(b *pdx.Block) []lsp.Diagnostic {
+	var diagnostics []lsp.Diagnostic
+	var first *pdx.Field
+	for _, f := range b.Fields {
+		unit := f.KeyText()
+		if unit != "days" && unit != "months" && unit != "years" {
+			continue
+		}
+		if first != nil {
+			diagnostics = append(diagnostics, newDiagnostic(ruleEventDelay, Range(f.Key.Loc),
+				fmt.Sprintf("trigger_event has both %s and %s; only one delay is used", first.KeyText(), unit)))
+		} else {
+			first = f
+		}
+		if list := f.Block(); list != nil {
+			diagnostics = append(diagnostics, checkDelayRange(unit, list)...)
+			continue
+		}
+		if v, ok := literalNumber(f); ok {
+			diagnostics = append(diagnostics, checkDelayNumber(unit, f.Scalar(), v)...)
+		}
+	}
+	return diagnostics
+}
+
+// checkDelayRange validates a random delay like `days = { 5 10 }`.
+func checkDelayRange(unit string, list *pdx.Block) []lsp.Diagnostic {
+	var bounds []*pdx.Scalar
+	for _, f := range list.Fields {
+		if f.Key != nil || f.Scalar() == nil {
+			return []lsp.Diagnostic{newDiagnostic(ruleEventDelay, Range(list.Loc),
+				fmt.Sprintf("a range of %s is written { min max }", unit))}
+		}
+		bounds = append(bounds, f.Scalar())
+	}
+	if len(bounds) != 2 {
+		return []lsp.Diagnostic{newDiagnostic(ruleEventDelay, Range(list.Loc),
+			fmt.Sprintf("a range of %s has 2 values, { min max }, not %d", unit, len(bounds)))}
+	}
+	var diagnostics []lsp.Diagnostic
+	var values [2]float64
+	literal := true
+	for i, s := range bounds {
+		v, err := strconv.ParseFloat(s.Text, 64)
+		ok := err == nil && !s.Quoted
+		literal = literal && ok
+		if ok {
+			values[i] = v
+			diagnostics = append(diagnostics, checkDelayNumber(unit, s, v)...)
+		}
+	}
+	if literal && values[0] > values[1] {
+		diagnostics = append(diagnostics, newDiagnostic(ruleEventDelay, Range(list.Loc),
+			fmt.Sprintf("the range of %s starts at %s, after its end %s", unit, bounds[0].Text, bounds[1].Text)))
+	}
+	return diagnostics
+}
+
+// checkDelayNumber flags a literal delay of v that is negative or not a
+// whole number.
+func checkDelayNumber(unit string, s *pdx.Scalar, v float64) []lsp.Diagnostic {
+	switch {
+	case v < 0:
+		return []lsp.Diagnostic{newDiagnostic(ruleEventDelay, Range(s.Loc),
+			fmt.Sprintf("a delay of %s %s is negative", s.Text, unit))}
+	case v != math.Trunc(v):
+		return []lsp.Diagnostic{newDiagnostic(ruleEventDelay, Range(s.Loc),
+			fmt.Sprintf("a delay of %s %s is not a whole number", s.Text, unit))}
+	}
+	return nil
+}
+
+// checkEventScopes reports the events fired by trigger_event, or listed in
+// the on_actions of the game, for a scope whose type differs from their
+// root type.
+func checkEventScopes(entry *index.FileEntry, ix *index.Index) []Diagnostic {
+	tree := scope.Analyze(entry.File, entry.VirtualPath)
+	if tree == nil {
+		return nil
+	}
+	onAction := strings.HasPrefix(entry.VirtualPath, "common/on_action/")
+	var diagnostics []Diagnostic
+	check := func(frame *scope.Frame, id *pdx.Scalar) {
+		if frame == nil || id == nil || frame.This == scope.Unknown || frame.This == scope.None {
+			return
+		}
+		defs := ix.Definitions(index.KindEvent, id.Text)
+		if len(defs) == 0 {
+			return
+		}
+		def := ix.FieldAt(defs[0].Location)
+		if def == nil || def.Block() == nil {
+			return
+		}
+		root := scope.EventRoot(def.Block())
+		if root == scope.Unknown || root == scope.None || root == frame.This {
+			return
+		}
+		d := newDiagnostic(ruleEventScope, Range(id.Loc),
+			fmt.Sprintf("event %s expects a %s scope but is fired for a %s scope", id.Text, root, frame.This))
+		diagnostics = append(diagnostics, Diagnostic{Diagnostic: d, RelatedInformation: []RelatedInformation{{
+			Location: lsp.Location{URI: FileURI(defs[0].Path), Range: Range(defs[0].Range)},
+			Message:  "event definition",
+		}}})
+	}
+	pdx.Walk(entry.File.Root, func(f *pdx.Field) bool {
+		switch {
+		case f.KeyText() == "trigger_event" && f.Block() != nil:
+			if id := f.Block().Get("id"); id != nil {
+				check(tree.Parent(f), id.Scalar())
+			}
+		case f.KeyText() == "trigger_event":
+			check(tree.Parent(f), f.Scalar())
+		case onAction && onActionEventKeys[f.KeyText()] && f.Block() != nil && f.ParentField() != nil && f.ParentField().ParentField() == nil:
+			// The events of an on_action are fired for its root.
+			for _, item := range f.Block().Fields {
+				if s := item.Scalar(); s != nil && s.Text != "0" {
+					check(tree.Parent(item), s)
+				}
+			}
+		}
+		return true
+	})
+	return diagnostics
+}
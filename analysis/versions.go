@@ -0,0 +1,37 @@
+package analysis
+
+import (
+	"fmt"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+var ruleOutdatedTranslation = register(Rule{ID: "outdated-translation", Description: "A translation whose `:version` is lower than the English entry of the same key, meaning the English text changed since it was translated.", Severity: lsp.Information})
+
+// checkVersions flags the entries of a translation whose version lags
+// behind the English entry of the same key.
+func checkVersions(entry *index.FileEntry, ix *index.Index) []Diagnostic {
+	if entry.Loc == nil || entry.Loc.Language == referenceLanguage {
+		return nil
+	}
+	var diagnostics []Diagnostic
+	for _, e := range entry.Loc.Entries {
+		for _, ref := range ix.Localizations(e.Key) {
+			if ref.Language != referenceLanguage {
+				continue
+			}
+			if e.VersionNumber() < ref.VersionNumber() {
+				d := newDiagnostic(ruleOutdatedTranslation, Range(e.KeyRange),
+					fmt.Sprintf("translation of '%s' is at version %d but the English text is at version %d", e.Key, e.VersionNumber(), ref.VersionNumber()))
+				diagnostics = append(diagnostics, Diagnostic{Diagnostic: d, RelatedInformation: []RelatedInformation{{
+					Location: lsp.Location{URI: FileURI(ref.Path), Range: Range(ref.KeyRange)},
+					Message:  "English text",
+				}}})
+			}
+			break
+		}
+	}
+	return diagnostics
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// defaultAnalyzerTimeout bounds a run of an external analyzer without a
+// configured timeout.
+const defaultAnalyzerTimeout = 10 * time.Second
+
+// analyzerDelay is how long an external analyzer waits after a file
+// changed before it runs, so that typing runs it once per pause rather than
+// once per keystroke.
+const analyzerDelay = 500 * time.Millisecond
+
+// analyzerSlots bounds the external analyzer processes running at once,
+// such as when workspace/diagnostic checks the whole mod.
+var analyzerSlots = make(chan struct{}, runtime.NumCPU())
+
+// ExternalAnalyzerSettings configures an executable run over every file
+// that receives the parsed file as JSON on stdin and writes its
+// diagnostics as a JSON array on stdout.
+type ExternalAnalyzerSettings struct {
+	// Name is the source of the analyzer's diagnostics.
+	Name string `json:"name"`
+	// Command is the executable and its arguments, run in the workspace
+	// folder.
+	Command []string `json:"command"`
+	// Folders limits the analyzer to files under these game folders, such
+	// as "events/"; it sees every file when empty.
+	Folders []string `json:"folders"`
+	// Timeout is the time a run may take, in milliseconds.
+	Timeout int `json:"timeout"`
+}
+
+// AnalyzerInput is the JSON an external analyzer receives for a file.
+// Script and GUI files come with their fields, localization files with
+// their entries.
+type AnalyzerInput struct {
+	Path         string            `json:"path"`
+	VirtualPath  string            `json:"virtualPath"`
+	Text         string            `json:"text"`
+	Fields       []ASTNode         `json:"fields,omitempty"`
+	Localization *LocalizationJSON `json:"localization,omitempty"`
+}
+
+// ASTNode is the JSON form of a field: `key op value`, or a bare value in
+// a list. A block value is marked by Block and holds Fields.
+type ASTNode struct {
+	Key    string    `json:"key,omitempty"`
+	Op     string    `json:"op,omitempty"`
+	Value  string    `json:"value,omitempty"`
+	Quoted bool      `json:"quoted,omitempty"`
+	Block  bool      `json:"block,omitempty"`
+	Tag    string    `json:"tag,omitempty"`
+	Fields []ASTNode `json:"fields,omitempty"`
+	Range  lsp.Range `json:"range"`
+}
+
+// LocalizationJSON is the JSON form of a localization file.
+type LocalizationJSON struct {
+	Language string         `json:"language"`
+	Entries  []LocEntryJSON `json:"entries"`
+}
+
+// LocEntryJSON is the JSON form of a localization entry.
+type LocEntryJSON struct {
+	Key     string    `json:"key"`
+	Version string    `json:"version,omitempty"`
+	Text    string    `json:"text"`
+	Range   lsp.Range `json:"range"`
+}
+
+// analyzerCache keeps the diagnostics of external analyzers by analyzer
+// and file, with a hash of the input they were computed from, so that
+// only changed files are analyzed again. wanted holds the hash of the
+// input each one was last scheduled for, and timers the runs waiting for
+// the delay. It has its own lock because diagnostics are computed under a
+// read lock of the server.
+type analyzerCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedDiagnostics
+	wanted  map[string][sha256.Size]byte
+	timers  map[string]*time.Timer
+}
+
+type cachedDiagnostics struct {
+	hash        [sha256.Size]byte
+	diagnostics []analysis.Diagnostic
+}
+
+// reset drops every cached result, after the analyzers were reconfigured.
+func (c *analyzerCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, t := range c.timers {
+		t.Stop()
+	}
+	c.entries, c.wanted, c.timers = nil, nil, nil
+}
+
+// externalAnalyzer runs an ExternalAnalyzerSettings as an
+// analysis.Analyzer.
+type externalAnalyzer struct {
+	settings ExternalAnalyzerSettings
+	dir      string
+	text     func(entry *index.FileEntry) string
+	skip     func(path string) bool
+	cache    *analyzerCache
+	// done is called with the path of a file once new diagnostics of it
+	// are cached, outside any lock of the server.
+	done func(path string)
+}
+
+// externalAnalyzers returns the configured external analyzers. The
+// caller must hold s.mutex.
+func (s *Server) externalAnalyzers() []analysis.Analyzer {
+	var list []analysis.Analyzer
+	for _, settings := range s.Settings.ExternalAnalyzers {
+		if len(settings.Command) == 0 {
+			continue
+		}
+		if settings.Name == "" {
+			settings.Name = settings.Command[0]
+		}
+		list = append(list, &externalAnalyzer{
+			settings: settings,
+			dir:      s.RootPath,
+			text:     s.fileText,
+			skip:     s.readOnly,
+			cache:    &s.analyzerCache,
+			done:     s.analyzerDone,
+		})
+	}
+	return list
+}
+
+// analyzerDone publishes the diagnostics of filePath again once an
+// external analyzer reported new ones for it: those of an open document
+// with textDocument/publishDiagnostics, and those of the other files to
+// the pending workspace/diagnostic requests.
+func (s *Server) analyzerDone(filePath string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, open := s.Documents[filePath]; open {
+		diagnostics := s.diagnose(filePath, s.crossFile[filePath])
+		s.DiagFiles[filePath] = diagnostics
+		if err := s.publishDiagnostics(context.Background(), filePathToURI(filePath), diagnostics); err != nil {
+			log.Printf("Failed to publish external analyzer diagnostics for document: %s", filePath)
+		}
+	}
+	if s.pullingDiagnostics.Load() {
+		s.signalDiagnostics()
+	}
+}
+
+func (a *externalAnalyzer) Name() string { return a.settings.Name }
+
+// Rules is empty: the codes of external diagnostics are not known in
+// advance, but Options still applies to them.
+func (a *externalAnalyzer) Rules() []analysis.Rule { return nil }
+
+// Run returns the diagnostics the executable reported for entry. It never
+// runs the executable itself, since it is called under the lock of the
+// server: when the input changed since the last run, a new run is
+// scheduled after analyzerDelay, and the diagnostics of the last one are
+// kept until it is done.
+func (a *externalAnalyzer) Run(entry *index.FileEntry, env *analysis.Env) []analysis.Diagnostic {
+	if a.skip(entry.Path) || !a.wants(entry.VirtualPath) || entry.File == nil && entry.Loc == nil {
+		return nil
+	}
+	input, err := json.Marshal(analyzerInput(entry, a.text(entry)))
+	if err != nil {
+		return []analysis.Diagnostic{analysis.AnalyzerError(a.Name(), err)}
+	}
+	key := a.Name() + "\x00" + entry.Path
+	hash := sha256.Sum256(input)
+	a.cache.mu.Lock()
+	defer a.cache.mu.Unlock()
+	if a.cache.wanted[key] != hash {
+		a.schedule(key, entry.Path, input, hash)
+	}
+	return a.cache.entries[key].diagnostics
+}
+
+// schedule runs the executable on input after analyzerDelay, replacing the
+// run waiting for the same file. The caller must hold a.cache.mu.
+func (a *externalAnalyzer) schedule(key, path string, input []byte, hash [sha256.Size]byte) {
+	if a.cache.wanted == nil {
+		a.cache.wanted = make(map[string][sha256.Size]byte)
+		a.cache.timers = make(map[string]*time.Timer)
+	}
+	a.cache.wanted[key] = hash
+	if t := a.cache.timers[key]; t != nil {
+		t.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(analyzerDelay, func() {
+		a.cache.mu.Lock()
+		if a.cache.timers[key] == timer {
+			delete(a.cache.timers, key)
+		}
+		a.cache.mu.Unlock()
+		a.analyze(key, path, input, hash)
+	})
+	a.cache.timers[key] = timer
+}
+
+// analyze runs the executable on input and caches its diagnostics, unless
+// those of a later input are cached already.
+func (a *externalAnalyzer) analyze(key, path string, input []byte, hash [sha256.Size]byte) {
+	analyzerSlots <- struct{}{}
+	diagnostics, err := a.exec(input)
+	<-analyzerSlots
+	if err != nil {
+		log.Printf("External analyzer %s failed on %s: %v", a.Name(), path, err)
+		diagnostics = []analysis.Diagnostic{analysis.AnalyzerError(a.Name(), err)}
+	}
+
+	a.cache.mu.Lock()
+	wanted, ok := a.cache.wanted[key]
+	if !ok || hash != wanted && a.cache.entries[key].hash == wanted {
+		// The analyzers were reset, or a run on newer input finished first.
+		a.cache.mu.Unlock()
+		return
+	}
+	if a.cache.entries == nil {
+		a.cache.entries = make(map[string]cachedDiagnostics)
+	}
+	a.cache.entries[key] = cachedDiagnostics{hash: hash, diagnostics: diagnostics}
+	a.cache.mu.Unlock()
+	if a.done != nil {
+		a.done(path)
+	}
+}
+
+// wants reports whether the analyzer checks the file at vpath.
+func (a *externalAnalyzer) wants(vpath string) bool {
+	return inFolders(vpath, a.settings.Folders)
+}
+
+// exec runs the executable on input and decodes its diagnostics.
+func (a *externalAnalyzer) exec(input []byte) ([]analysis.Diagnostic, error) {
+	timeout := defaultAnalyzerTimeout
+	if a.settings.Timeout > 0 {
+		timeout = time.Duration(a.settings.Timeout) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, a.settings.Command[0], a.settings.Command[1:]...)
+	cmd.Dir = a.dir
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("timed out after %v", timeout)
+		}
+		if msg, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n"); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+	diagnostics := []analysis.Diagnostic{}
+	if err := json.Unmarshal(stdout.Bytes(), &diagnostics); err != nil {
+		return nil, fmt.Errorf("invalid output: %v", err)
+	}
+	return diagnostics, nil
+}
+
+// analyzerInput builds the JSON input of an external analyzer for entry.
+func analyzerInput(entry *index.FileEntry, text string) AnalyzerInput {
+	input := AnalyzerInput{Path: entry.Path, VirtualPath: entry.VirtualPath, Text: text}
+	if entry.File != nil {
+		input.Fields = astNodes(entry.File.Root)
+	}
+	if entry.Loc != nil {
+		l := &LocalizationJSON{Language: entry.Loc.Language, Entries: []LocEntryJSON{}}
+		for _, e := range entry.Loc.Entries {
+			rng := e.KeyRange
+			rng.End = e.ValueRange.End
+			l.Entries = append(l.Entries, LocEntryJSON{Key: e.Key, Version: e.Version, Text: e.Text, Range: analysis.Range(rng)})
+		}
+		input.Localization = l
+	}
+	return input
+}
+
+// astNodes converts the fields of a block to their JSON form, which leaves
+// out the parent links of the AST.
+func astNodes(b *pdx.Block) []ASTNode {
+	nodes := []ASTNode{}
+	for _, f := range b.Fields {
+		node := ASTNode{Key: f.KeyText(), Op: string(f.Op), Range: analysis.Range(f.Range())}
+		if s := f.Scalar(); s != nil {
+			node.Value, node.Quoted = s.Text, s.Quoted
+		}
+		if sub := f.Block(); sub != nil {
+			node.Block = true
+			node.Fields = astNodes(sub)
+			if sub.Tag != nil {
+				node.Tag = sub.Tag.Text
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
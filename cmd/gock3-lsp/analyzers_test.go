@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeAnalyzer writes a shell script that counts its runs in a file next
+// to it and reports one diagnostic, and returns its path and that of the
+// count.
+func writeAnalyzer(t *testing.T) (script, runs string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the analyzer of the test is a shell script")
+	}
+	dir := t.TempDir()
+	script, runs = filepath.Join(dir, "analyzer.sh"), filepath.Join(dir, "runs")
+	body := "#!/bin/sh\ncat >/dev/null\necho run >> '" + runs + "'\n" +
+		`echo '[{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":1}},"severity":2,"code":"my-rule","message":"from the analyzer"}]'` + "\n"
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return script, runs
+}
+
+// hasAnalyzerDiagnostic reports whether the diagnostics of filePath hold
+// the one of the test analyzer.
+func hasAnalyzerDiagnostic(s *Server, filePath string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, d := range s.GetDiagnostics(filePath) {
+		if d.Message == "from the analyzer" {
+			return true
+		}
+	}
+	return false
+}
+
+func TestExternalAnalyzerAsync(t *testing.T) {
+	script, runs := writeAnalyzer(t)
+	s := NewServer()
+	s.Settings.ExternalAnalyzers = []ExternalAnalyzerSettings{{Name: "mine", Command: []string{script}}}
+	t.Cleanup(s.analyzerCache.reset)
+	filePath := "/mods/my_mod/events/my_events.txt"
+
+	// Edits in a burst: none of them waits for the analyzer, which runs
+	// once for the last one.
+	for _, text := range []string{"a = b\n", "a = bc\n", "a = bcd\n"} {
+		s.mutex.Lock()
+		openDocument(s, filePath, text)
+		s.mutex.Unlock()
+		if hasAnalyzerDiagnostic(s, filePath) {
+			t.Fatal("the analyzer ran under the lock of the server")
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !hasAnalyzerDiagnostic(s, filePath) {
+		if time.Now().After(deadline) {
+			t.Fatal("the diagnostics of the analyzer never arrived")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	data, err := os.ReadFile(runs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "run"); n != 1 {
+		t.Errorf("the analyzer ran %d times, want once", n)
+	}
+}
+
+func TestExternalAnalyzerKeepsLastResult(t *testing.T) {
+	script, _ := writeAnalyzer(t)
+	s := NewServer()
+	s.Settings.ExternalAnalyzers = []ExternalAnalyzerSettings{{Name: "mine", Command: []string{script}}}
+	t.Cleanup(s.analyzerCache.reset)
+	filePath := "/mods/my_mod/events/my_events.txt"
+	openDocument(s, filePath, "a = b\n")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !hasAnalyzerDiagnostic(s, filePath) {
+		if time.Now().After(deadline) {
+			t.Fatal("the diagnostics of the analyzer never arrived")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	s.mutex.Lock()
+	openDocument(s, filePath, "a = c\n")
+	s.mutex.Unlock()
+	if !hasAnalyzerDiagnostic(s, filePath) {
+		t.Error("the diagnostics of the last run are dropped while the next one waits")
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// BlockPathEntry is one block enclosing a position.
+type BlockPathEntry struct {
+	Key   string    `json:"key"`
+	Range lsp.Range `json:"range"`
+}
+
+// BlockPath is the result of gock3/blockPath.
+type BlockPath struct {
+	// Path joins the keys with " > ", as in "my_event.1 > option > if".
+	Path   string           `json:"path"`
+	Blocks []BlockPathEntry `json:"blocks"`
+}
+
+// bareBlockKey stands for a block without a key, such as the items of a
+// list of blocks.
+const bareBlockKey = "{ }"
+
+// BlockPath handles the gock3/blockPath request, which returns the keys of
+// the blocks enclosing a position, outermost first, for display in a status
+// bar.
+func (s *Server) BlockPath(ctx context.Context, params lsp.TextDocumentPositionParams) (BlockPath, error) {
+	log.Printf("Block path request for document: %s at Line %d, Character %d",
+		params.TextDocument.URI, params.Position.Line, params.Position.Character)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := BlockPath{Blocks: []BlockPathEntry{}}
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		log.Printf("Error converting URI to file path: %v", err)
+		return result, err
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil {
+		return result, nil
+	}
+	pos := pdx.Pos{Line: params.Position.Line, Col: params.Position.Character}
+	var keys []string
+	for _, f := range entry.File.PathAt(pos) {
+		b := f.Block()
+		if b == nil || !b.Loc.Contains(pos) {
+			continue
+		}
+		key := f.KeyText()
+		if key == "" {
+			key = bareBlockKey
+		}
+		keys = append(keys, key)
+		result.Blocks = append(result.Blocks, BlockPathEntry{Key: key, Range: analysis.Range(f.Range())})
+	}
+	result.Path = strings.Join(keys, " > ")
+	return result, nil
+}
+
+// matchingBrace returns the location of the brace matching the one at (or
+// right before) the cursor, so that go to definition jumps between the two
+// ends of a long block.
+func (s *Server) matchingBrace(params lsp.TextDocumentPositionParams) (lsp.Location, bool) {
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return lsp.Location{}, false
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil {
+		return lsp.Location{}, false
+	}
+	line, col := params.Position.Line, params.Position.Character
+	var match *pdx.Pos
+	var visit func(b *pdx.Block)
+	visit = func(b *pdx.Block) {
+		for _, f := range b.Fields {
+			fb := f.Block()
+			if fb == nil || match != nil || !containsCursor(fb.Loc, line, col) {
+				continue
+			}
+			if fb.Closed {
+				open, close := openBrace(entry.File.Text, fb), closeBrace(fb)
+				switch {
+				case atBrace(open, line, col):
+					match = &close
+				case atBrace(close, line, col):
+					match = &open
+				}
+			}
+			if match == nil {
+				visit(fb)
+			}
+		}
+	}
+	visit(entry.File.Root)
+	if match == nil {
+		return lsp.Location{}, false
+	}
+	r := analysis.Range(pdx.Range{Start: *match, End: pdx.Pos{Line: match.Line, Col: match.Col + 1}})
+	return lsp.Location{URI: params.TextDocument.URI, Range: r}, true
+}
+
+// openBrace returns the position of a block's '{', which follows its tag if
+// it has one.
+func openBrace(text string, b *pdx.Block) pdx.Pos {
+	if b.Tag == nil {
+		return b.Loc.Start
+	}
+	pos := b.Tag.Loc.End
+	for pos.Offset < len(text) && text[pos.Offset] != '{' {
+		if text[pos.Offset] == '\n' {
+			pos.Line++
+			pos.Col = -1
+		}
+		pos.Offset++
+		pos.Col++
+	}
+	return pos
+}
+
+// closeBrace returns the position of a closed block's '}'.
+func closeBrace(b *pdx.Block) pdx.Pos {
+	pos := b.Loc.End
+	pos.Offset--
+	pos.Col--
+	return pos
+}
+
+// atBrace reports whether the cursor is on the brace at pos or right after
+// it, where editors place it when a brace is typed or selected.
+func atBrace(pos pdx.Pos, line, col int) bool {
+	return pos.Line == line && (pos.Col == col || pos.Col+1 == col)
+}
+
+// containsCursor reports whether r holds the cursor, including its end.
+func containsCursor(r pdx.Range, line, col int) bool {
+	start, end := r.Start, r.End
+	if line < start.Line || line > end.Line {
+		return false
+	}
+	return (line > start.Line || col >= start.Col) && (line < end.Line || col <= end.Col)
+}
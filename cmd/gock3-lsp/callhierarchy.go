@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+// CallHierarchyItem is a definition in an event chain, which go-lsp lacks.
+// Data holds the kind and name of the definition, if it is indexed.
+type CallHierarchyItem struct {
+	Name           string             `json:"name"`
+	Kind           lsp.SymbolKind     `json:"kind"`
+	Detail         string             `json:"detail,omitempty"`
+	URI            lsp.DocumentURI    `json:"uri"`
+	Range          lsp.Range          `json:"range"`
+	SelectionRange lsp.Range          `json:"selectionRange"`
+	Data           *callHierarchyData `json:"data,omitempty"`
+}
+
+// callHierarchyData identifies the definition of a CallHierarchyItem.
+type callHierarchyData struct {
+	Kind index.Kind `json:"kind"`
+	Name string     `json:"name"`
+}
+
+// CallHierarchyCallsParams asks for the incoming or outgoing calls of an
+// item.
+type CallHierarchyCallsParams struct {
+	Item CallHierarchyItem `json:"item"`
+}
+
+// CallHierarchyIncomingCall is a definition firing the item, and where.
+type CallHierarchyIncomingCall struct {
+	From       CallHierarchyItem `json:"from"`
+	FromRanges []lsp.Range       `json:"fromRanges"`
+}
+
+// CallHierarchyOutgoingCall is a definition the item fires, and where.
+type CallHierarchyOutgoingCall struct {
+	To         CallHierarchyItem `json:"to"`
+	FromRanges []lsp.Range       `json:"fromRanges"`
+}
+
+// chainKinds are the kinds of definitions that pass an event chain on:
+// events, on_actions and the scripted effects they call.
+var chainKinds = map[index.Kind]bool{
+	index.KindEvent:          true,
+	index.KindOnAction:       true,
+	index.KindScriptedEffect: true,
+}
+
+// TextDocumentPrepareCallHierarchy returns the definitions of the event,
+// on_action or scripted effect at the cursor, whose callers and callees
+// make up an event chain.
+func (s *Server) TextDocumentPrepareCallHierarchy(ctx context.Context, params lsp.TextDocumentPositionParams) ([]CallHierarchyItem, error) {
+	log.Printf("Prepare call hierarchy request received for URI: %s at position Line %d, Character %d",
+		params.TextDocument.URI, params.Position.Line, params.Position.Character)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	items := []CallHierarchyItem{}
+	kind, name, ok := s.symbolAt(params)
+	if !ok || !chainKinds[kind] {
+		return items, nil
+	}
+	for _, sym := range s.Index.Definitions(kind, name) {
+		items = append(items, s.hierarchyItem(sym))
+	}
+	return items, nil
+}
+
+// CallHierarchyIncomingCalls lists the definitions firing or calling the
+// item, such as the events with a trigger_event of it and the on_actions
+// listing it, with the places they do.
+func (s *Server) CallHierarchyIncomingCalls(ctx context.Context, params CallHierarchyCallsParams) ([]CallHierarchyIncomingCall, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	calls := []CallHierarchyIncomingCall{}
+	data := params.Item.Data
+	if data == nil {
+		return calls, nil
+	}
+	byCaller := map[string]int{}
+	for _, ref := range s.Index.Uses(data.Kind, data.Name) {
+		from, ok := s.callerItem(ref.Location)
+		if !ok {
+			continue
+		}
+		key := string(from.URI) + "#" + from.Name
+		i, seen := byCaller[key]
+		if !seen {
+			i = len(calls)
+			byCaller[key] = i
+			calls = append(calls, CallHierarchyIncomingCall{From: from})
+		}
+		calls[i].FromRanges = append(calls[i].FromRanges, analysis.Range(ref.Range))
+	}
+	log.Printf("Returning %d incoming calls of %s '%s'.", len(calls), data.Kind, data.Name)
+	return calls, nil
+}
+
+// CallHierarchyOutgoingCalls lists the events, on_actions and scripted
+// effects the item fires or calls anywhere in its definition, including
+// every option, with the places it does.
+func (s *Server) CallHierarchyOutgoingCalls(ctx context.Context, params CallHierarchyCallsParams) ([]CallHierarchyOutgoingCall, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	calls := []CallHierarchyOutgoingCall{}
+	filePath, err := uriToFilePath(params.Item.URI)
+	if err != nil {
+		return calls, err
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil {
+		return calls, nil
+	}
+	byCallee := map[callHierarchyData]int{}
+	refs := append(append([]index.Reference{}, entry.Refs...), s.Index.ScriptCalls(entry)...)
+	for _, ref := range refs {
+		r := analysis.Range(ref.Range)
+		if !chainKinds[ref.Kind] || !rangeContains(params.Item.Range, r) {
+			continue
+		}
+		callee := callHierarchyData{ref.Kind, ref.Name}
+		i, seen := byCallee[callee]
+		if !seen {
+			defs := s.Index.Definitions(ref.Kind, ref.Name)
+			if len(defs) == 0 {
+				continue
+			}
+			i = len(calls)
+			byCallee[callee] = i
+			calls = append(calls, CallHierarchyOutgoingCall{To: s.hierarchyItem(defs[0])})
+		}
+		calls[i].FromRanges = append(calls[i].FromRanges, r)
+	}
+	log.Printf("Returning %d outgoing calls of '%s'.", len(calls), params.Item.Name)
+	return calls, nil
+}
+
+// hierarchyItem describes a definition, covering its whole block.
+func (s *Server) hierarchyItem(sym index.Symbol) CallHierarchyItem {
+	item := CallHierarchyItem{
+		Name:           sym.Name,
+		Kind:           lsp.SKObject,
+		Detail:         string(sym.Kind),
+		URI:            filePathToURI(sym.Path),
+		Range:          analysis.Range(sym.Range),
+		SelectionRange: analysis.Range(sym.Range),
+		Data:           &callHierarchyData{sym.Kind, sym.Name},
+	}
+	if k, ok := definitionSymbolKinds[sym.Kind]; ok {
+		item.Kind = k
+	}
+	if entry := s.Index.File(sym.Path); entry != nil && entry.File != nil {
+		if path := entry.File.PathAt(sym.Range.Start); len(path) > 0 {
+			item.Range = analysis.Range(path[0].Range())
+		}
+	}
+	return item
+}
+
+// callerItem describes the top-level definition loc is written in. One that
+// is not indexed, such as a decision, is named by its key and has no
+// callers of its own.
+func (s *Server) callerItem(loc index.Location) (CallHierarchyItem, bool) {
+	if container, ok := s.Index.Container(loc); ok {
+		return s.hierarchyItem(container), true
+	}
+	entry := s.Index.File(loc.Path)
+	if entry == nil || entry.File == nil {
+		return CallHierarchyItem{}, false
+	}
+	path := entry.File.PathAt(loc.Range.Start)
+	if len(path) == 0 || path[0].Key == nil {
+		return CallHierarchyItem{}, false
+	}
+	return CallHierarchyItem{
+		Name:           path[0].Key.Text,
+		Kind:           lsp.SKObject,
+		Detail:         folderOf(s.Index.VirtualPath(loc.Path)),
+		URI:            filePathToURI(loc.Path),
+		Range:          analysis.Range(path[0].Range()),
+		SelectionRange: analysis.Range(path[0].Key.Loc),
+	}, true
+}
+
+// rangeContains reports whether inner lies within outer.
+func rangeContains(outer, inner lsp.Range) bool {
+	before := func(a, b lsp.Position) bool {
+		return a.Line < b.Line || a.Line == b.Line && a.Character <= b.Character
+	}
+	return before(outer.Start, inner.Start) && before(inner.End, outer.End)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	lsp "github.com/sourcegraph/go-lsp"
+)
+
+// InitializeResult is the answer to the initialize request.
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+}
+
+// ServerCapabilities adds the capabilities of newer LSP versions to those
+// go-lsp knows. Its own fields replace the embedded ones with the same JSON
+// name.
+type ServerCapabilities struct {
+	lsp.ServerCapabilities
+	RenameProvider             *RenameOptions         `json:"renameProvider,omitempty"`
+	SemanticTokensProvider     *SemanticTokensOptions `json:"semanticTokensProvider,omitempty"`
+	FoldingRangeProvider       bool                   `json:"foldingRangeProvider,omitempty"`
+	InlayHintProvider          bool                   `json:"inlayHintProvider,omitempty"`
+	SelectionRangeProvider     bool                   `json:"selectionRangeProvider,omitempty"`
+	CallHierarchyProvider      bool                   `json:"callHierarchyProvider,omitempty"`
+	ColorProvider              bool                   `json:"colorProvider,omitempty"`
+	DocumentLinkProvider       *DocumentLinkOptions   `json:"documentLinkProvider,omitempty"`
+	LinkedEditingRangeProvider bool                   `json:"linkedEditingRangeProvider,omitempty"`
+	DiagnosticProvider         *DiagnosticOptions     `json:"diagnosticProvider,omitempty"`
+	DeclarationProvider        bool                   `json:"declarationProvider,omitempty"`
+}
+
+// RenameOptions advertise textDocument/rename and, with PrepareProvider,
+// textDocument/prepareRename.
+type RenameOptions struct {
+	PrepareProvider bool `json:"prepareProvider"`
+}
+
+// SemanticTokensOptions advertise textDocument/semanticTokens/full and
+// textDocument/semanticTokens/range.
+type SemanticTokensOptions struct {
+	Legend SemanticTokensLegend `json:"legend"`
+	Range  bool                 `json:"range"`
+	Full   bool                 `json:"full"`
+}
+
+// SemanticTokensLegend names the token types and modifiers that the
+// integers of SemanticTokens stand for.
+type SemanticTokensLegend struct {
+	TokenTypes     []string `json:"tokenTypes"`
+	TokenModifiers []string `json:"tokenModifiers"`
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+// ChecksumImpactParams are the parameters of gock3/checksumImpact. Without
+// a text document the whole workspace is classified.
+type ChecksumImpactParams struct {
+	TextDocument *lsp.TextDocumentIdentifier `json:"textDocument,omitempty"`
+}
+
+// ChecksumImpactResult tells whether the given document, or any mod file of
+// the workspace, changes the game checksum, and lists the files that do.
+type ChecksumImpactResult struct {
+	AffectsChecksum bool              `json:"affectsChecksum"`
+	Files           []lsp.DocumentURI `json:"files"`
+}
+
+// ChecksumImpact handles the gock3/checksumImpact request, which lets
+// authors of achievement-compatible mods see which files break
+// compatibility.
+func (s *Server) ChecksumImpact(ctx context.Context, params ChecksumImpactParams) (ChecksumImpactResult, error) {
+	log.Println("Checksum impact request received.")
+
+	var paths []string
+	if params.TextDocument != nil {
+		filePath, err := uriToFilePath(params.TextDocument.URI)
+		if err != nil {
+			log.Printf("Error converting URI to file path: %v", err)
+			return ChecksumImpactResult{}, err
+		}
+		paths = []string{filePath}
+	} else {
+		paths = s.Index.Paths()
+	}
+	result := ChecksumImpactResult{Files: []lsp.DocumentURI{}}
+	for _, path := range paths {
+		if index.AffectsChecksum(s.Index.VirtualPath(path)) {
+			result.Files = append(result.Files, filePathToURI(path))
+		}
+	}
+	result.AffectsChecksum = len(result.Files) > 0
+	log.Printf("Returning %d checksum-affecting files.", len(result.Files))
+	return result, nil
+}
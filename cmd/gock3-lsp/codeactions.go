@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+)
+
+// CodeAction is the LSP 3.8 code action literal, which go-lsp lacks.
+type CodeAction struct {
+	Title       string             `json:"title"`
+	Kind        lsp.CodeActionKind `json:"kind,omitempty"`
+	Diagnostics []lsp.Diagnostic   `json:"diagnostics,omitempty"`
+	Edit        *WorkspaceEdit     `json:"edit,omitempty"`
+	Command     *lsp.Command       `json:"command,omitempty"`
+}
+
+// WorkspaceEdit adds the document changes of LSP 3.13, which can create
+// and delete files, to the edit go-lsp knows. Clients apply either Changes
+// or DocumentChanges.
+type WorkspaceEdit struct {
+	Changes         map[string][]lsp.TextEdit `json:"changes,omitempty"`
+	DocumentChanges []interface{}             `json:"documentChanges,omitempty"`
+}
+
+// FileOperation is a document change creating ("create") or deleting
+// ("delete") the file at URI.
+type FileOperation struct {
+	Kind string          `json:"kind"`
+	URI  lsp.DocumentURI `json:"uri"`
+}
+
+// TextDocumentEdit is a document change editing the file at URI. Without a
+// version the edits apply to whatever the document holds.
+type TextDocumentEdit struct {
+	TextDocument struct {
+		URI     lsp.DocumentURI `json:"uri"`
+		Version *int            `json:"version"`
+	} `json:"textDocument"`
+	Edits []lsp.TextEdit `json:"edits"`
+}
+
+// CodeActionProvider offers the quick fixes of the diagnostics of some
+// rules. Each feature that can fix its diagnostics adds a provider to
+// codeActionProviders.
+type CodeActionProvider interface {
+	// Rules lists the IDs of the rules whose diagnostics the provider
+	// fixes, or nil for the diagnostics of every rule.
+	Rules() []string
+	// QuickFixes returns the fixes of diagnostic d of the document at
+	// filePath. It is called with s.mutex held for reading.
+	QuickFixes(s *Server, filePath string, d lsp.Diagnostic) []CodeAction
+}
+
+// ruleFix is a CodeActionProvider fixing the diagnostics of one rule with
+// a function.
+type ruleFix struct {
+	rule string
+	fix  func(s *Server, filePath string, d lsp.Diagnostic) []CodeAction
+}
+
+func (f ruleFix) Rules() []string { return []string{f.rule} }
+
+func (f ruleFix) QuickFixes(s *Server, filePath string, d lsp.Diagnostic) []CodeAction {
+	return f.fix(s, filePath, d)
+}
+
+// codeActionProviders are asked for fixes in order, so the fixes specific
+// to a rule come before the generic ones.
+var codeActionProviders = []CodeActionProvider{
+	ruleFix{"outdated-supported-version", (*Server).bumpVersionFix},
+	ruleFix{"identical-override", (*Server).minimizeOverrideFix},
+	suppressionFix{},
+}
+
+// TextDocumentCodeAction offers the quick fixes of the providers for the
+// diagnostics in the requested range.
+func (s *Server) TextDocumentCodeAction(ctx context.Context, params lsp.CodeActionParams) ([]CodeAction, error) {
+	log.Printf("Code action request received for URI: %s", params.TextDocument.URI)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		log.Printf("Error converting URI to file path: %v", err)
+		return nil, err
+	}
+	actions := []CodeAction{}
+	if s.readOnlyMode || s.readOnly(filePath) {
+		log.Printf("No code actions for read-only document: %s", filePath)
+		return actions, nil
+	}
+	// A fix of several diagnostics in the range is offered once.
+	titles := make(map[string]bool)
+	for _, d := range params.Context.Diagnostics {
+		for _, p := range codeActionProviders {
+			if !fixesRule(p, d.Code) {
+				continue
+			}
+			for _, action := range p.QuickFixes(s, filePath, d) {
+				if !titles[action.Title] {
+					titles[action.Title] = true
+					actions = append(actions, action)
+				}
+			}
+		}
+	}
+	log.Printf("Returning %d code actions.", len(actions))
+	return actions, nil
+}
+
+// fixesRule reports whether p fixes the diagnostics of the rule id.
+func fixesRule(p CodeActionProvider, id string) bool {
+	rules := p.Rules()
+	if rules == nil {
+		return true
+	}
+	for _, rule := range rules {
+		if rule == id {
+			return true
+		}
+	}
+	return false
+}
+
+// suppressionFix silences a diagnostic with a `# gock3-ignore` comment on
+// the line above it, indented like that line. Syntax errors cannot be
+// silenced this way, as the game would still fail to parse the file.
+type suppressionFix struct{}
+
+func (suppressionFix) Rules() []string { return nil }
+
+func (suppressionFix) QuickFixes(s *Server, filePath string, d lsp.Diagnostic) []CodeAction {
+	if d.Code == "" || d.Code == "syntax-error" {
+		return nil
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil {
+		return nil
+	}
+	lines := strings.Split(s.fileText(entry), "\n")
+	line := d.Range.Start.Line
+	if line >= len(lines) {
+		return nil
+	}
+	indent := lines[line][:len(lines[line])-len(strings.TrimLeft(lines[line], " \t"))]
+	pos := lsp.Position{Line: line}
+	return []CodeAction{{
+		Title:       "Ignore " + d.Code + " on this line",
+		Kind:        lsp.CAKQuickFix,
+		Diagnostics: []lsp.Diagnostic{d},
+		Edit: &WorkspaceEdit{Changes: map[string][]lsp.TextEdit{
+			string(filePathToURI(filePath)): {{Range: lsp.Range{Start: pos, End: pos}, NewText: indent + "# " + analysis.DirectiveIgnore + " " + d.Code + "\n"}},
+		}},
+	}}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	lsp "github.com/sourcegraph/go-lsp"
+)
+
+// openDocument stores text as the open document at filePath, indexed, as
+// didOpen does without publishing diagnostics to a client.
+func openDocument(s *Server, filePath, text string) {
+	s.Documents[filePath] = text
+	s.Index.UpdateFile(filePath, text)
+}
+
+// codeActions opens text at filePath and returns the code actions for
+// diagnostics.
+func codeActions(t *testing.T, s *Server, filePath, text string, diagnostics ...lsp.Diagnostic) []CodeAction {
+	t.Helper()
+	uri := filePathToURI(filePath)
+	openDocument(s, filePath, text)
+	actions, err := s.TextDocumentCodeAction(context.Background(), lsp.CodeActionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: uri},
+		Context:      lsp.CodeActionContext{Diagnostics: diagnostics},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return actions
+}
+
+func TestCodeActionRuleFix(t *testing.T) {
+	s := NewServer()
+	s.GameVersion = "1.13.2"
+	filePath := "/mods/my_mod/descriptor.mod"
+	d := lsp.Diagnostic{
+		Range: lsp.Range{Start: lsp.Position{Line: 1, Character: 20}, End: lsp.Position{Line: 1, Character: 28}},
+		Code:  "outdated-supported-version",
+	}
+	actions := codeActions(t, s, filePath, "name = \"My Mod\"\nsupported_version = \"1.12.*\"\n", d)
+
+	if len(actions) == 0 || actions[0].Title != "Bump supported_version to 1.13.*" {
+		t.Fatalf("actions = %+v, want the bump of supported_version first", actions)
+	}
+	want := &WorkspaceEdit{Changes: map[string][]lsp.TextEdit{
+		string(filePathToURI(filePath)): {{
+			Range:   lsp.Range{Start: lsp.Position{Line: 1, Character: 20}, End: lsp.Position{Line: 1, Character: 28}},
+			NewText: `"1.13.*"`,
+		}},
+	}}
+	if !reflect.DeepEqual(actions[0].Edit, want) {
+		t.Errorf("edit = %+v, want %+v", actions[0].Edit, want)
+	}
+	if actions[0].Kind != lsp.CAKQuickFix || len(actions[0].Diagnostics) != 1 {
+		t.Errorf("action = %+v, want a quick fix of the diagnostic", actions[0])
+	}
+}
+
+func TestCodeActionSuppression(t *testing.T) {
+	s := NewServer()
+	filePath := "/mods/my_mod/events/my_events.txt"
+	text := "namespace = my\nmy.0001 = {\n\timmediate = {\n\t\thas_character_flag = my_flag\n\t}\n}\n"
+	d := lsp.Diagnostic{
+		Range: lsp.Range{Start: lsp.Position{Line: 3, Character: 23}, End: lsp.Position{Line: 3, Character: 30}},
+		Code:  "unset-flag",
+	}
+	actions := codeActions(t, s, filePath, text, d)
+
+	if len(actions) != 1 {
+		t.Fatalf("actions = %+v, want the suppression only", actions)
+	}
+	pos := lsp.Position{Line: 3}
+	want := &WorkspaceEdit{Changes: map[string][]lsp.TextEdit{
+		string(filePathToURI(filePath)): {{Range: lsp.Range{Start: pos, End: pos}, NewText: "\t\t# gock3-ignore unset-flag\n"}},
+	}}
+	if actions[0].Title != "Ignore unset-flag on this line" || !reflect.DeepEqual(actions[0].Edit, want) {
+		t.Errorf("action = %q %+v, want the comment above line 3, indented like it: %+v", actions[0].Title, actions[0].Edit, want)
+	}
+}
+
+func TestCodeActionWithoutFix(t *testing.T) {
+	s := NewServer()
+	filePath := "/mods/my_mod/events/my_events.txt"
+	text := "namespace = my\nmy.0001 = {\n"
+	actions := codeActions(t, s, filePath, text,
+		lsp.Diagnostic{Range: lsp.Range{Start: lsp.Position{Line: 1}, End: lsp.Position{Line: 1, Character: 9}}, Code: "syntax-error"},
+		lsp.Diagnostic{Range: lsp.Range{Start: lsp.Position{Line: 1}, End: lsp.Position{Line: 1, Character: 9}}},
+	)
+	if len(actions) != 0 {
+		t.Errorf("actions = %+v, want none", actions)
+	}
+}
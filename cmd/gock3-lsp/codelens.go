@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+// codeLensKinds are the definitions annotated with their reference count.
+var codeLensKinds = map[index.Kind]bool{
+	index.KindEvent:           true,
+	index.KindScriptedEffect:  true,
+	index.KindScriptedTrigger: true,
+}
+
+// showReferencesCommand is the client command a reference count lens
+// runs, with the document URI, the position and the locations to list.
+// VS Code and clients mimicking it open the references view.
+const showReferencesCommand = "editor.action.showReferences"
+
+// TextDocumentCodeLens annotates every event, scripted effect and scripted
+// trigger defined in a document with the number of its uses in the mod and
+// vanilla, so that dead events stand out. Clicking the lens lists them.
+func (s *Server) TextDocumentCodeLens(ctx context.Context, params lsp.CodeLensParams) ([]lsp.CodeLens, error) {
+	log.Printf("Code lens request received for URI: %s", params.TextDocument.URI)
+
+	lenses := []lsp.CodeLens{}
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return lenses, err
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil {
+		return lenses, nil
+	}
+	for _, sym := range entry.Symbols {
+		if !codeLensKinds[sym.Kind] {
+			continue
+		}
+		locations := []lsp.Location{}
+		for _, ref := range s.Index.Uses(sym.Kind, sym.Name) {
+			locations = append(locations, toLocation(ref.Location))
+		}
+		title := fmt.Sprintf("%d references", len(locations))
+		if len(locations) == 1 {
+			title = "1 reference"
+		}
+		rng := analysis.Range(sym.Range)
+		lenses = append(lenses, lsp.CodeLens{
+			Range: rng,
+			Command: lsp.Command{
+				Title:     title,
+				Command:   showReferencesCommand,
+				Arguments: []interface{}{params.TextDocument.URI, rng.Start, locations},
+			},
+		})
+	}
+	log.Printf("Returning %d code lenses.", len(lenses))
+	return lenses, nil
+}
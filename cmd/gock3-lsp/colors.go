@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// Color is a color with components from 0 to 1, which go-lsp lacks.
+type Color struct {
+	Red   float64 `json:"red"`
+	Green float64 `json:"green"`
+	Blue  float64 `json:"blue"`
+	Alpha float64 `json:"alpha"`
+}
+
+// ColorInformation is a color written in a document.
+type ColorInformation struct {
+	Range lsp.Range `json:"range"`
+	Color Color     `json:"color"`
+}
+
+// DocumentColorParams asks for the colors of a document.
+type DocumentColorParams struct {
+	TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+}
+
+// ColorPresentationParams asks how to write a color picked for a range.
+type ColorPresentationParams struct {
+	TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+	Color        Color                      `json:"color"`
+	Range        lsp.Range                  `json:"range"`
+}
+
+// ColorPresentation is a way of writing a color.
+type ColorPresentation struct {
+	Label    string        `json:"label"`
+	TextEdit *lsp.TextEdit `json:"textEdit,omitempty"`
+}
+
+// colorFormat is the notation of a color block.
+type colorFormat int
+
+const (
+	colorUnit   colorFormat = iota // { 0.8 0.2 0.1 }, from 0 to 1
+	colorBytes                     // { 204 51 25 }, from 0 to 255
+	colorRGB                       // rgb { 204 51 25 }
+	colorHSV                       // hsv { 0.02 0.88 0.8 }, from 0 to 1
+	colorHSV360                    // hsv360 { 7 88 80 }, degrees and percents
+)
+
+// colorFormats are the notations offered by the color picker, after the
+// one the color is written in.
+var colorFormats = []colorFormat{colorRGB, colorUnit, colorHSV, colorHSV360}
+
+// colorBlock is a color written in a document.
+type colorBlock struct {
+	rng    pdx.Range
+	format colorFormat
+	color  Color
+	// alpha is set if the color has an alpha component.
+	alpha bool
+}
+
+// TextDocumentDocumentColor returns the colors of a script or GUI file: the
+// blocks of three or four numbers that are tagged rgb, hsv or hsv360, such
+// as `color = hsv { 0.5 0.6 0.7 }`, or are the value of a key naming a
+// color, such as `color = { 0.8 0.2 0.1 }` in cultures, titles and GUI
+// files. Editors show them as swatches.
+func (s *Server) TextDocumentDocumentColor(ctx context.Context, params DocumentColorParams) ([]ColorInformation, error) {
+	log.Printf("Document color request received for URI: %s", params.TextDocument.URI)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	colors := []ColorInformation{}
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return colors, err
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil {
+		return colors, nil
+	}
+	for _, c := range colorBlocks(entry.File) {
+		colors = append(colors, ColorInformation{Range: analysis.Range(c.rng), Color: c.color})
+	}
+	return colors, nil
+}
+
+// TextDocumentColorPresentation writes a color picked for a color block,
+// first in the notation of the block, then in the others.
+func (s *Server) TextDocumentColorPresentation(ctx context.Context, params ColorPresentationParams) ([]ColorPresentation, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	presentations := []ColorPresentation{}
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return presentations, err
+	}
+	if s.readOnlyMode || s.readOnly(filePath) {
+		return presentations, nil
+	}
+	format, alpha := colorRGB, false
+	if entry := s.Index.File(filePath); entry != nil && entry.File != nil {
+		for _, c := range colorBlocks(entry.File) {
+			if analysis.Range(c.rng) == params.Range {
+				format, alpha = c.format, c.alpha
+			}
+		}
+	}
+	alpha = alpha || params.Color.Alpha < 1
+	formats := []colorFormat{format}
+	for _, f := range colorFormats {
+		if f != format {
+			formats = append(formats, f)
+		}
+	}
+	for _, f := range formats {
+		text := formatColor(params.Color, f, alpha)
+		presentations = append(presentations, ColorPresentation{
+			Label:    text,
+			TextEdit: &lsp.TextEdit{Range: params.Range, NewText: text},
+		})
+	}
+	return presentations, nil
+}
+
+// colorBlocks returns the color blocks of file.
+func colorBlocks(file *pdx.File) []colorBlock {
+	var blocks []colorBlock
+	pdx.Walk(file.Root, func(f *pdx.Field) bool {
+		if c, ok := parseColor(f); ok {
+			blocks = append(blocks, c)
+		}
+		return true
+	})
+	return blocks
+}
+
+// parseColor reads the color block that is the value of f, if it is one.
+func parseColor(f *pdx.Field) (colorBlock, bool) {
+	b := f.Block()
+	if b == nil || len(b.Fields) < 3 || len(b.Fields) > 4 {
+		return colorBlock{}, false
+	}
+	format := colorUnit
+	switch {
+	case b.Tag != nil && strings.EqualFold(b.Tag.Text, "rgb"):
+		format = colorRGB
+	case b.Tag != nil && strings.EqualFold(b.Tag.Text, "hsv"):
+		format = colorHSV
+	case b.Tag != nil && strings.EqualFold(b.Tag.Text, "hsv360"):
+		format = colorHSV360
+	case b.Tag != nil || !strings.Contains(strings.ToLower(f.KeyText()), "color"):
+		return colorBlock{}, false
+	}
+	values := make([]float64, len(b.Fields))
+	for i, item := range b.Fields {
+		v := item.Scalar()
+		if item.Key != nil || v == nil {
+			return colorBlock{}, false
+		}
+		n, err := strconv.ParseFloat(v.Text, 64)
+		if err != nil || n < 0 {
+			return colorBlock{}, false
+		}
+		values[i] = n
+		// Untagged components above 1 count from 0 to 255.
+		if format == colorUnit && n > 1 {
+			format = colorBytes
+		}
+	}
+
+	c := colorBlock{rng: b.Loc, format: format, alpha: len(values) == 4}
+	if b.Tag != nil {
+		c.rng.Start = b.Tag.Loc.Start
+	}
+	c.color.Alpha = 1
+	scale := 1.0
+	switch format {
+	case colorHSV:
+		c.color.Red, c.color.Green, c.color.Blue = hsvToRGB(values[0], values[1], values[2])
+	case colorHSV360:
+		c.color.Red, c.color.Green, c.color.Blue = hsvToRGB(values[0]/360, values[1]/100, values[2]/100)
+		scale = 100
+	case colorBytes, colorRGB:
+		scale = 255
+		c.color.Red, c.color.Green, c.color.Blue = values[0]/scale, values[1]/scale, values[2]/scale
+	default:
+		c.color.Red, c.color.Green, c.color.Blue = values[0], values[1], values[2]
+	}
+	if c.alpha {
+		c.color.Alpha = values[3] / scale
+	}
+	clamp := func(v *float64) { *v = math.Min(math.Max(*v, 0), 1) }
+	clamp(&c.color.Red)
+	clamp(&c.color.Green)
+	clamp(&c.color.Blue)
+	clamp(&c.color.Alpha)
+	return c, true
+}
+
+// formatColor writes c in format, with its alpha component if alpha is set.
+func formatColor(c Color, format colorFormat, alpha bool) string {
+	var values []float64
+	var tag string
+	a := c.Alpha
+	switch format {
+	case colorHSV, colorHSV360:
+		h, s, v := rgbToHSV(c.Red, c.Green, c.Blue)
+		values = []float64{h, s, v}
+		tag = "hsv "
+		if format == colorHSV360 {
+			values = []float64{math.Round(h * 360), math.Round(s * 100), math.Round(v * 100)}
+			a = math.Round(a * 100)
+			tag = "hsv360 "
+		}
+	case colorBytes, colorRGB:
+		values = []float64{math.Round(c.Red * 255), math.Round(c.Green * 255), math.Round(c.Blue * 255)}
+		a = math.Round(a * 255)
+		if format == colorRGB {
+			tag = "rgb "
+		}
+	default:
+		values = []float64{c.Red, c.Green, c.Blue}
+	}
+	if alpha {
+		values = append(values, a)
+	}
+	parts := make([]string, len(values))
+	for i, v := range values {
+		// Fractions keep two decimals, as the game's files do.
+		parts[i] = analysis.FormatNumber(math.Round(v*100) / 100)
+	}
+	return fmt.Sprintf("%s{ %s }", tag, strings.Join(parts, " "))
+}
+
+// hsvToRGB converts a color from hue, saturation and value to red, green
+// and blue, all from 0 to 1.
+func hsvToRGB(h, s, v float64) (float64, float64, float64) {
+	h = math.Mod(h, 1) * 6
+	i := math.Floor(h)
+	f := h - i
+	p, q, t := v*(1-s), v*(1-s*f), v*(1-s*(1-f))
+	switch int(i) {
+	case 0:
+		return v, t, p
+	case 1:
+		return q, v, p
+	case 2:
+		return p, v, t
+	case 3:
+		return p, q, v
+	case 4:
+		return t, p, v
+	}
+	return v, p, q
+}
+
+// rgbToHSV converts a color from red, green and blue to hue, saturation
+// and value, all from 0 to 1.
+func rgbToHSV(r, g, b float64) (float64, float64, float64) {
+	max := math.Max(r, math.Max(g, b))
+	min := math.Min(r, math.Min(g, b))
+	d := max - min
+	var h, s float64
+	if max > 0 {
+		s = d / max
+	}
+	switch {
+	case d == 0:
+		h = 0
+	case max == r:
+		h = math.Mod((g-b)/d, 6)
+	case max == g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	h /= 6
+	if h < 0 {
+		h++
+	}
+	return h, s, max
+}
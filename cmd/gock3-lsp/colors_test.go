@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+
+	lsp "github.com/sourcegraph/go-lsp"
+)
+
+const colorsPath = "/mod/common/culture/cultures/my.txt"
+
+// colorsText has a color in each notation, and blocks that are not colors.
+const colorsText = `my_culture = {
+	color = { 0.8 0.2 0.1 }
+	map_color = { 204 51 25 }
+	text_color = rgb { 255 0 0 128 }
+	tint = hsv { 0.5 1 1 }
+	background = hsv360 { 120 100 50 }
+	colour_blind = { 0.1 0.2 }
+	position = { 1 2 3 }
+	color = { red green blue }
+	color = { -1 0 0 }
+}
+`
+
+// closeColors reports whether the components of a and b differ by at most
+// tolerance.
+func closeColors(a, b Color, tolerance float64) bool {
+	for _, d := range []float64{a.Red - b.Red, a.Green - b.Green, a.Blue - b.Blue, a.Alpha - b.Alpha} {
+		if math.Abs(d) > tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDocumentColor(t *testing.T) {
+	s := NewServer()
+	openDocument(s, colorsPath, colorsText)
+	got, err := s.TextDocumentDocumentColor(context.Background(), DocumentColorParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: filePathToURI(colorsPath)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ColorInformation{
+		{Range: lsp.Range{Start: lsp.Position{Line: 1, Character: 9}, End: lsp.Position{Line: 1, Character: 24}}, Color: Color{0.8, 0.2, 0.1, 1}},
+		{Range: lsp.Range{Start: lsp.Position{Line: 2, Character: 13}, End: lsp.Position{Line: 2, Character: 26}}, Color: Color{0.8, 0.2, 0.1, 1}},
+		{Range: lsp.Range{Start: lsp.Position{Line: 3, Character: 14}, End: lsp.Position{Line: 3, Character: 33}}, Color: Color{1, 0, 0, 0.5}},
+		{Range: lsp.Range{Start: lsp.Position{Line: 4, Character: 8}, End: lsp.Position{Line: 4, Character: 23}}, Color: Color{0, 1, 1, 1}},
+		{Range: lsp.Range{Start: lsp.Position{Line: 5, Character: 14}, End: lsp.Position{Line: 5, Character: 35}}, Color: Color{0, 0.5, 0, 1}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("colors = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].Range != want[i].Range || !closeColors(got[i].Color, want[i].Color, 0.005) {
+			t.Errorf("color %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestColorPresentation(t *testing.T) {
+	s := NewServer()
+	openDocument(s, colorsPath, colorsText)
+	present := func(color Color, rng lsp.Range) []string {
+		t.Helper()
+		got, err := s.TextDocumentColorPresentation(context.Background(), ColorPresentationParams{
+			TextDocument: lsp.TextDocumentIdentifier{URI: filePathToURI(colorsPath)},
+			Color:        color,
+			Range:        rng,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var labels []string
+		for _, p := range got {
+			if p.TextEdit == nil || p.TextEdit.Range != rng || p.TextEdit.NewText != p.Label {
+				t.Errorf("presentation %s edits %+v", p.Label, p.TextEdit)
+			}
+			labels = append(labels, p.Label)
+		}
+		return labels
+	}
+	red := Color{1, 0, 0, 1}
+
+	// The notation of the block comes first.
+	mapColor := lsp.Range{Start: lsp.Position{Line: 2, Character: 13}, End: lsp.Position{Line: 2, Character: 26}}
+	want := []string{"{ 255 0 0 }", "rgb { 255 0 0 }", "{ 1 0 0 }", "hsv { 0 1 1 }", "hsv360 { 0 100 100 }"}
+	if got := present(red, mapColor); !reflect.DeepEqual(got, want) {
+		t.Errorf("presentations of map_color = %q, want %q", got, want)
+	}
+	tint := lsp.Range{Start: lsp.Position{Line: 4, Character: 8}, End: lsp.Position{Line: 4, Character: 23}}
+	want = []string{"hsv { 0.25 0.5 0.8 }", "rgb { 153 204 102 }", "{ 0.6 0.8 0.4 }", "hsv360 { 90 50 80 }"}
+	if got := present(Color{0.6, 0.8, 0.4, 1}, tint); !reflect.DeepEqual(got, want) {
+		t.Errorf("presentations of tint = %q, want %q", got, want)
+	}
+	// Outside a block the color is written as rgb, with the alpha picked.
+	want = []string{"rgb { 255 0 0 128 }", "{ 1 0 0 0.5 }", "hsv { 0 1 1 0.5 }", "hsv360 { 0 100 100 50 }"}
+	if got := present(Color{1, 0, 0, 0.5}, lsp.Range{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("presentations with alpha = %q, want %q", got, want)
+	}
+
+	s.readOnlyMode = true
+	if got := present(red, mapColor); len(got) != 0 {
+		t.Errorf("presentations in read-only mode = %q, want none", got)
+	}
+}
+
+// TestColorRoundTrip checks that a color written in each notation reads
+// back as the same color.
+func TestColorRoundTrip(t *testing.T) {
+	for _, c := range []Color{{0.8, 0.2, 0.1, 1}, {0, 0.5, 1, 0.25}, {1, 1, 1, 1}, {0, 0, 0, 1}, {0.3, 0.9, 0.3, 1}} {
+		for _, format := range []colorFormat{colorUnit, colorBytes, colorRGB, colorHSV, colorHSV360} {
+			text := "color = " + formatColor(c, format, c.Alpha < 1) + "\n"
+			s := NewServer()
+			openDocument(s, colorsPath, text)
+			blocks := colorBlocks(s.Index.File(colorsPath).File)
+			if len(blocks) != 1 {
+				t.Errorf("%q has %d color blocks, want 1", text, len(blocks))
+				continue
+			}
+			// A hue with two decimals is only good to 3.6 degrees.
+			tolerance := 0.005
+			if format == colorHSV {
+				tolerance = 0.025
+			}
+			if got := blocks[0].color; !closeColors(got, c, tolerance) {
+				t.Errorf("%q reads as %+v, want %+v", text, got, c)
+			}
+		}
+	}
+}
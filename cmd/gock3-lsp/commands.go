@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	lsp "github.com/sourcegraph/go-lsp"
+)
+
+// commands are the workspace/executeCommand handlers by command name. Each
+// receives the command's arguments and returns its result.
+var commands = map[string]func(s *Server, ctx context.Context, args []interface{}) (interface{}, error){
+	"gock3.copyVanillaDefinition": (*Server).copyVanillaCommand,
+	"gock3.eventRoots":            (*Server).eventRootsCommand,
+	"gock3.exportMetrics":         (*Server).exportMetricsCommand,
+	"gock3.exportTranslations":    (*Server).exportTranslationsCommand,
+	"gock3.importTranslations":    (*Server).importTranslationsCommand,
+	"gock3.openRelated":           (*Server).openRelatedCommand,
+	"gock3.package":               (*Server).packageCommand,
+	"gock3.patchAudit":            (*Server).patchAuditCommand,
+	"gock3.pseudoLocalize":        (*Server).pseudoLocalizeCommand,
+	"gock3.runScriptChecks":       (*Server).runScriptChecksCommand,
+	"gock3.structuralSearch":      (*Server).structuralSearchCommand,
+	"gock3.syncDescriptor":        (*Server).syncDescriptorCommand,
+}
+
+// writingCommands are the commands that write files or return edits, which
+// read-only mode disables. gock3.structuralSearch is not one: it searches
+// in read-only mode too and rejects only its replace mode there.
+var writingCommands = map[string]bool{
+	"gock3.copyVanillaDefinition": true,
+	"gock3.exportMetrics":         true,
+	"gock3.exportTranslations":    true,
+	"gock3.importTranslations":    true,
+	"gock3.package":               true,
+	"gock3.pseudoLocalize":        true,
+	"gock3.syncDescriptor":        true,
+}
+
+// errReadOnlyMode rejects the features that change files when the server
+// runs with --read-only.
+var errReadOnlyMode = errors.New("the server runs in read-only mode")
+
+// commandNames returns the sorted names of the commands, for the server
+// capabilities, leaving out the writing ones in read-only mode.
+func commandNames(readOnlyMode bool) []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		if readOnlyMode && writingCommands[name] {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WorkspaceExecuteCommand runs one of the server's commands.
+func (s *Server) WorkspaceExecuteCommand(ctx context.Context, params lsp.ExecuteCommandParams) (interface{}, error) {
+	log.Printf("ExecuteCommand request received: %s", params.Command)
+
+	command, ok := commands[params.Command]
+	if !ok {
+		log.Printf("Unknown command: %s", params.Command)
+		return nil, fmt.Errorf("unknown command %q", params.Command)
+	}
+	if s.readOnlyMode && writingCommands[params.Command] {
+		log.Printf("Rejecting command %s in read-only mode.", params.Command)
+		return nil, errReadOnlyMode
+	}
+	result, err := command(s, ctx, params.Arguments)
+	if err != nil {
+		log.Printf("Command %s failed: %v", params.Command, err)
+		return nil, err
+	}
+	log.Printf("Command %s completed.", params.Command)
+	return result, nil
+}
+
+// stringArg returns the i-th command argument if it is a string, or "".
+func stringArg(args []interface{}, i int) string {
+	if i < len(args) {
+		if s, ok := args[i].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
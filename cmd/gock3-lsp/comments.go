@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// commentDirective is a word with a meaning at the start of a comment.
+type commentDirective struct {
+	name string
+	doc  string
+}
+
+// commentDirectives are the directives completed at the start of a
+// comment: suppressions, region markers and the doc tags of a comment
+// documenting the definition below it.
+var commentDirectives = []commentDirective{
+	{analysis.DirectiveIgnore, "Silences the listed rules, or every rule, on this line, or on the next line when written on a line of its own: `# gock3-ignore unset-flag`."},
+	{analysis.DirectiveIgnoreFile, "Silences the listed rules, or every rule, in the whole file: `# gock3-ignore-file naming-convention`."},
+	{"region", "Starts a foldable region, ended by `# endregion`: `# region Decisions`."},
+	{"endregion", "Ends the region started by the last `# region`."},
+	{"@param", "Documents a parameter of the scripted effect or trigger below: `# @param TARGET the character to reward`."},
+	{"@scope", "Documents the scope the definition below runs in: `# @scope character`."},
+	{"@deprecated", "Marks the definition below as deprecated, with what to use instead."},
+}
+
+// commentContextPattern matches the text of a comment up to the cursor
+// while the first word is typed.
+var commentContextPattern = regexp.MustCompile(`^\s*[\w@-]*$`)
+
+// commentCompletions offers the directives at the start of a comment and
+// the rule IDs after a suppression directive. It returns nil outside
+// comments. The caller must hold s.mutex.
+func (s *Server) commentCompletions(params lsp.TextDocumentPositionParams) []lsp.CompletionItem {
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return nil
+	}
+	comment, ok := commentText(linePrefix(s.Documents[filePath], params.Position))
+	if !ok {
+		return nil
+	}
+	items := []lsp.CompletionItem{}
+	if commentContextPattern.MatchString(comment) {
+		for _, d := range commentDirectives {
+			items = append(items, lsp.CompletionItem{Label: d.name, Kind: lsp.CIKKeyword, Documentation: d.doc})
+		}
+		return items
+	}
+	if directive := strings.Fields(comment)[0]; directive == analysis.DirectiveIgnore || directive == analysis.DirectiveIgnoreFile {
+		for _, r := range analysis.Rules() {
+			items = append(items, lsp.CompletionItem{Label: r.ID, Kind: lsp.CIKValue, Detail: severityName(r), Documentation: s.translate(r.Description)})
+		}
+	}
+	return items
+}
+
+// commentText returns the text after the # of the comment the line prefix
+// ends in, skipping # inside quoted strings.
+func commentText(prefix string) (string, bool) {
+	quoted := false
+	for i := 0; i < len(prefix); i++ {
+		switch prefix[i] {
+		case '"':
+			quoted = !quoted
+		case '#':
+			if !quoted {
+				return prefix[i+1:], true
+			}
+		}
+	}
+	return "", false
+}
+
+// severityName describes the default severity of a rule.
+func severityName(r analysis.Rule) string {
+	if r.Optional {
+		return "off by default"
+	}
+	switch r.Severity {
+	case lsp.Error:
+		return "error"
+	case lsp.Warning:
+		return "warning"
+	case lsp.Information:
+		return "information"
+	}
+	return "hint"
+}
+
+// docComment returns the lines of the comments written directly above a
+// definition key, without the #, or nil. Suppression directives are left
+// out.
+func docComment(entry *index.FileEntry, key pdx.Range) []string {
+	if entry.File == nil {
+		return nil
+	}
+	byLine := map[int]string{}
+	for _, c := range entry.File.Comments {
+		if c.Range.Start.Col == 0 || strings.TrimSpace(lineBefore(entry.File.Text, c.Range.Start.Offset)) == "" {
+			byLine[c.Range.Start.Line] = c.Text
+		}
+	}
+	var lines []string
+	for line := key.Start.Line - 1; line >= 0; line-- {
+		text, ok := byLine[line]
+		if !ok {
+			break
+		}
+		lines = append([]string{strings.TrimSpace(text)}, lines...)
+	}
+	doc := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(line, analysis.DirectiveIgnore) {
+			doc = append(doc, line)
+		}
+	}
+	if len(doc) == 0 {
+		return nil
+	}
+	return doc
+}
+
+// lineBefore returns the text of the line holding offset up to it.
+func lineBefore(text string, offset int) string {
+	start := strings.LastIndexByte(text[:offset], '\n') + 1
+	return text[start:offset]
+}
+
+// renderDoc turns the lines of a doc comment into Markdown, listing the
+// @param tags and showing @scope and @deprecated.
+func renderDoc(lines []string) string {
+	var prose, params, notes []string
+	for _, line := range lines {
+		tag, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+		switch tag {
+		case "@param":
+			name, text, _ := strings.Cut(rest, " ")
+			params = append(params, fmt.Sprintf("- `$%s$` %s", strings.Trim(name, "$"), strings.TrimSpace(text)))
+		case "@scope":
+			notes = append(notes, fmt.Sprintf("Scope: `%s`", rest))
+		case "@deprecated":
+			notes = append(notes, strings.TrimSpace("**Deprecated.** "+rest))
+		default:
+			prose = append(prose, line)
+		}
+	}
+	var parts []string
+	if len(prose) > 0 {
+		parts = append(parts, strings.Join(prose, "\n"))
+	}
+	parts = append(parts, notes...)
+	if len(params) > 0 {
+		parts = append(parts, "Parameters:\n"+strings.Join(params, "\n"))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// docHover shows the doc comment of the definition of the symbol, or the
+// scripted effect, trigger or script value call, under the cursor. It
+// returns nil where there is no documented definition.
+func (s *Server) docHover(filePath string, pos lsp.Position) *lsp.Hover {
+	params := lsp.TextDocumentPositionParams{TextDocument: lsp.TextDocumentIdentifier{URI: filePathToURI(filePath)}, Position: pos}
+	kind, name, ok := s.symbolAt(params)
+	if !ok || kind == index.KindLocalization {
+		return nil
+	}
+	for _, def := range s.Index.Definitions(kind, name) {
+		entry := s.Index.File(def.Path)
+		if entry == nil {
+			continue
+		}
+		doc := docComment(entry, def.Range)
+		if doc == nil {
+			continue
+		}
+		contents := fmt.Sprintf("**%s** `%s`\n\n%s", strings.ReplaceAll(string(kind), "_", " "), name, renderDoc(doc))
+		return &lsp.Hover{Contents: []lsp.MarkedString{lsp.RawMarkedString(contents)}}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+// snippetLines is the number of lines of a definition shown in the
+// documentation of a completion item.
+const snippetLines = 12
+
+// MarkupContent is documentation in Markdown, which go-lsp lacks.
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// ResolvedCompletionItem is a completion item with the Markdown
+// documentation and additional edits that completionItem/resolve fills
+// in. Its own fields replace the embedded ones with the same JSON name.
+type ResolvedCompletionItem struct {
+	lsp.CompletionItem
+	Documentation       *MarkupContent `json:"documentation,omitempty"`
+	AdditionalTextEdits []lsp.TextEdit `json:"additionalTextEdits,omitempty"`
+}
+
+// completionData is the data of the completion items of named objects,
+// which completionItem/resolve documents.
+type completionData struct {
+	Kind index.Kind `json:"kind"`
+	Name string     `json:"name"`
+	// URI and Namespace are set on the next event ID of an event file
+	// without a namespace, which is declared along with it.
+	URI       lsp.DocumentURI `json:"uri,omitempty"`
+	Namespace string          `json:"namespace,omitempty"`
+}
+
+// CompletionItemResolve handles completionItem/resolve: the documentation
+// of the item the user selected is only computed now, keeping the
+// completion lists cheap. Named objects get the localized text of their
+// name and the start of their definition, and the next ID of an event file
+// without a namespace gets the edit declaring it. Other items are returned
+// as they are.
+func (s *Server) CompletionItemResolve(ctx context.Context, item lsp.CompletionItem) (ResolvedCompletionItem, error) {
+	resolved := ResolvedCompletionItem{CompletionItem: item}
+	if item.Documentation != "" {
+		// The documentation of the list is kept, as the field replacing it.
+		resolved.Documentation = &MarkupContent{Kind: "plaintext", Value: item.Documentation}
+	}
+	if item.Data == nil {
+		return resolved, nil
+	}
+	var data completionData
+	raw, _ := json.Marshal(item.Data)
+	if err := json.Unmarshal(raw, &data); err != nil || data.Kind == "" {
+		return resolved, nil
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if doc := s.completionDoc(data); doc != "" {
+		resolved.Documentation = &MarkupContent{Kind: "markdown", Value: doc}
+	}
+	if data.Namespace != "" {
+		resolved.AdditionalTextEdits = s.namespaceEdits(data)
+	}
+	log.Printf("Resolved completion item '%s'.", item.Label)
+	return resolved, nil
+}
+
+// completionDoc documents the object of a completion item.
+func (s *Server) completionDoc(data completionData) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s** `%s`", strings.ReplaceAll(string(data.Kind), "_", " "), data.Name)
+	key := data.Name
+	if data.Kind == index.KindGameConcept {
+		key = "game_concept_" + data.Name
+	}
+	if len(s.Index.Localizations(key)) > 0 {
+		fmt.Fprintf(&b, "\n\n%s", s.localizedText(key))
+	}
+	if data.Namespace != "" {
+		fmt.Fprintf(&b, "\n\nAlso declares `namespace = %s` at the top of the file.", data.Namespace)
+	}
+	if text, path, ok := s.definitionSnippet(data.Kind, data.Name); ok {
+		fmt.Fprintf(&b, "\n\n```pdx\n%s\n```\n\n_%s_", text, path)
+	}
+	return b.String()
+}
+
+// definitionSnippet returns the first lines of the first definition of a
+// named object, and the virtual path of its file.
+func (s *Server) definitionSnippet(kind index.Kind, name string) (string, string, bool) {
+	defs := s.Index.Definitions(kind, name)
+	if len(defs) == 0 {
+		return "", "", false
+	}
+	f := s.Index.FieldAt(defs[0].Location)
+	def := s.Index.File(defs[0].Path)
+	if f == nil || def == nil {
+		return "", "", false
+	}
+	r := f.Range()
+	lines := strings.Split(def.File.Text[r.Start.Offset:r.End.Offset], "\n")
+	if len(lines) > snippetLines {
+		lines = append(lines[:snippetLines], "\t…")
+	}
+	return strings.Join(lines, "\n"), s.Index.VirtualPath(defs[0].Path), true
+}
+
+// namespaceEdits declares the namespace of an event file that still lacks
+// one, at its top.
+func (s *Server) namespaceEdits(data completionData) []lsp.TextEdit {
+	filePath, err := uriToFilePath(data.URI)
+	if err != nil {
+		return nil
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil || entry.File.Root.Get("namespace") != nil {
+		return nil
+	}
+	return []lsp.TextEdit{{NewText: fmt.Sprintf("namespace = %s\n", data.Namespace)}}
+}
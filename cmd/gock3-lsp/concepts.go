@@ -0,0 +1,36 @@
+package main
+
+import (
+	"regexp"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+// conceptContextPattern matches a partially typed concept key after `[` or
+// `Concept('` in a localization text.
+var conceptContextPattern = regexp.MustCompile(`(?:Concept\(\s*'|\[)([a-z0-9_]*)$`)
+
+// conceptCompletions offers game concept keys inside localization texts,
+// whose localized text completionItem/resolve adds.
+// The caller must hold s.mutex.
+func (s *Server) conceptCompletions(params lsp.TextDocumentPositionParams) []lsp.CompletionItem {
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil || !index.IsLocalizationFile(filePath) {
+		return nil
+	}
+	if !conceptContextPattern.MatchString(linePrefix(s.Documents[filePath], params.Position)) {
+		return nil
+	}
+	items := []lsp.CompletionItem{}
+	for _, name := range s.Index.Names(index.KindGameConcept) {
+		items = append(items, lsp.CompletionItem{
+			Label:  name,
+			Kind:   lsp.CIKConstant,
+			Detail: s.vanillaDetail("game concept"),
+			Data:   completionData{Kind: index.KindGameConcept, Name: name},
+		})
+	}
+	return items
+}
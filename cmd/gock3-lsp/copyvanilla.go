@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// wholeFileFolders are the folders whose objects can only be overridden by
+// replacing the vanilla file they are in: events are loaded once per ID,
+// so a second definition elsewhere is an error rather than an override.
+var wholeFileFolders = []string{"events/"}
+
+// copyVanillaCommand runs gock3.copyVanillaDefinition. The first argument
+// is the key of a top-level vanilla definition, such as an event, a
+// decision or a scripted effect; with the second argument "file" the whole
+// vanilla file is copied to the same path in the mod, replacing it.
+// Otherwise the definition alone is added to `zz_<file>` in the same
+// folder, which the game loads after the vanilla file so the later
+// definition wins. Events are always copied with their whole file. It
+// returns the location of the copy.
+func (s *Server) copyVanillaCommand(ctx context.Context, args []interface{}) (interface{}, error) {
+	key := stringArg(args, 0)
+	if key == "" {
+		return nil, errors.New("expected the key of a vanilla definition")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	base := s.Index.Base()
+	if s.RootPath == "" {
+		return nil, errors.New("no workspace root to copy into")
+	}
+	if base == nil {
+		return nil, errors.New("gamePath is not set or the game files are still being indexed")
+	}
+	entry, f := vanillaDefinition(base, key)
+	if f == nil {
+		return nil, fmt.Errorf("no top-level vanilla definition of '%s'", key)
+	}
+	wholeFile := stringArg(args, 1) == "file"
+	for _, folder := range wholeFileFolders {
+		wholeFile = wholeFile || strings.HasPrefix(entry.VirtualPath, folder)
+	}
+
+	vpath := entry.VirtualPath
+	text := entry.File.Text
+	if !wholeFile {
+		vpath = path.Join(path.Dir(vpath), "zz_"+path.Base(vpath))
+	}
+	target := filepath.Join(s.RootPath, filepath.FromSlash(vpath))
+	if _, open := s.Documents[target]; open {
+		return nil, fmt.Errorf("%s is open in the editor; close it first", vpath)
+	}
+	data, err := os.ReadFile(target)
+	existing := string(data)
+	switch {
+	case err == nil && wholeFile:
+		return nil, fmt.Errorf("the mod already overrides %s", vpath)
+	case err == nil:
+		if other := s.Index.File(target); other != nil && other.File != nil && other.File.Root.Get(key) != nil {
+			return nil, fmt.Errorf("%s already defines '%s'", vpath, key)
+		}
+		text = strings.TrimRight(existing, "\r\n") + "\n\n" + definitionText(entry.File, f) + "\n"
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	case !wholeFile:
+		text = definitionText(entry.File, f) + "\n"
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(target, []byte(text), 0o644); err != nil {
+		return nil, err
+	}
+	log.Printf("Copied vanilla definition '%s' from %s to %s", key, entry.Path, target)
+	copied := s.Index.UpdateFile(target, text)
+	s.refreshDiagnostics(ctx, "")
+
+	loc := lsp.Location{URI: filePathToURI(target)}
+	if copied != nil && copied.File != nil {
+		if g := copied.File.Root.All(key); len(g) > 0 {
+			loc.Range = analysis.Range(g[len(g)-1].Range())
+		}
+	}
+	return loc, nil
+}
+
+// vanillaDefinition finds the top-level field defining key in a vanilla
+// script file, the first by virtual path if there are several.
+func vanillaDefinition(base *index.Index, key string) (*index.FileEntry, *pdx.Field) {
+	var found *index.FileEntry
+	var field *pdx.Field
+	for _, p := range base.Paths() {
+		entry := base.File(p)
+		if entry == nil || entry.File == nil || !index.IsScriptFile(p) {
+			continue
+		}
+		if f := entry.File.Root.Get(key); f != nil && f.Block() != nil {
+			if found == nil || entry.VirtualPath < found.VirtualPath {
+				found, field = entry, f
+			}
+		}
+	}
+	return found, field
+}
+
+// definitionText returns the source of a top-level field together with the
+// comment lines right above it.
+func definitionText(file *pdx.File, f *pdx.Field) string {
+	r := f.Range()
+	start := r.Start.Offset
+	for start > 0 && file.Text[start-1] != '\n' {
+		start--
+	}
+	for start > 0 {
+		prev := strings.LastIndex(file.Text[:start-1], "\n") + 1
+		if !strings.HasPrefix(strings.TrimSpace(file.Text[prev:start-1]), "#") {
+			break
+		}
+		start = prev
+	}
+	return strings.ReplaceAll(file.Text[start:r.End.Offset], "\r\n", "\n")
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// defineHover shows the vanilla default and the effective value of the
+// define under the cursor, either an override key in common/defines or a
+// `define:NGame|KEY` reference. It returns nil elsewhere.
+func (s *Server) defineHover(filePath string, pos lsp.Position) *lsp.Hover {
+	entry := s.Index.File(filePath)
+	if entry == nil {
+		return nil
+	}
+	kind, name, rng, ok := entry.SymbolAt(pdx.Pos{Line: pos.Line, Col: pos.Character})
+	if !ok || kind != index.KindDefine {
+		return nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "**define** `%s`\n\n", name)
+	if base := s.Index.Base(); base != nil {
+		if defs := base.Definitions(kind, name); len(defs) > 0 {
+			if f := base.FieldAt(defs[0].Location); f != nil {
+				fmt.Fprintf(&b, "Vanilla default: `%s` (%s)\n\n", s.valueSource(base, defs[0].Path, f), analysis.DefineType(f))
+			}
+		} else {
+			b.WriteString("Not a vanilla define.\n\n")
+		}
+	}
+	for _, def := range s.Index.LocalDefinitions(kind, name) {
+		if f := s.Index.FieldAt(def.Location); f != nil {
+			fmt.Fprintf(&b, "Set to `%s` in _%s_\n\n", s.valueSource(s.Index, def.Path, f), s.Index.VirtualPath(def.Path))
+		}
+	}
+	hoverRange := analysis.Range(rng)
+	return &lsp.Hover{
+		Contents: []lsp.MarkedString{lsp.RawMarkedString(strings.TrimSpace(b.String()))},
+		Range:    &hoverRange,
+	}
+}
+
+// valueSource returns the script text of a field's value, on one line.
+func (s *Server) valueSource(ix *index.Index, path string, f *pdx.Field) string {
+	entry := ix.File(path)
+	if entry == nil || entry.File == nil || f.Value == nil {
+		return ""
+	}
+	r := f.Value.Range()
+	return strings.Join(strings.Fields(entry.File.Text[r.Start.Offset:r.End.Offset]), " ")
+}
+
+// defineLevels are the triggers comparing against a level whose thresholds
+// are a list define, such as `prestige_level >= 3`.
+var defineLevels = map[string]string{
+	"prestige_level": "NCharacter.PRESTIGE_LEVELS",
+	"piety_level":    "NCharacter.PIETY_LEVELS",
+}
+
+// effectiveDefine returns the field setting a define that the game uses:
+// files load by virtual path, so the last one wins.
+func (s *Server) effectiveDefine(name string) (*pdx.Field, index.Symbol, bool) {
+	defs := s.Index.Definitions(index.KindDefine, name)
+	if len(defs) == 0 {
+		return nil, index.Symbol{}, false
+	}
+	sort.SliceStable(defs, func(i, j int) bool { return s.Index.VirtualPath(defs[i].Path) < s.Index.VirtualPath(defs[j].Path) })
+	def := defs[len(defs)-1]
+	f := s.Index.FieldAt(def.Location)
+	return f, def, f != nil
+}
+
+// defineValueHover shows the effective value of the defines a number
+// depends on: the threshold of the level a defineLevels trigger compares
+// against, or the `define:` references of the script value under the
+// cursor. It returns nil elsewhere.
+func (s *Server) defineValueHover(filePath string, pos lsp.Position) *lsp.Hover {
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil {
+		return nil
+	}
+	p := pdx.Pos{Line: pos.Line, Col: pos.Character}
+	path := entry.File.PathAt(p)
+	if len(path) == 0 {
+		return nil
+	}
+	f := path[len(path)-1]
+	var b strings.Builder
+	var rng pdx.Range
+	if name, ok := defineLevels[f.KeyText()]; ok {
+		def, sym, ok := s.effectiveDefine(name)
+		if !ok || def.Block() == nil {
+			return nil
+		}
+		rng = f.Range()
+		fmt.Fprintf(&b, "**%s** thresholds from define `%s` (_%s_)\n\n", f.KeyText(), name, s.Index.VirtualPath(sym.Path))
+		level, err := strconv.Atoi(f.ValueText())
+		for i, v := range def.Block().Fields {
+			marker := ""
+			if err == nil && i == level {
+				marker = " ←"
+			}
+			fmt.Fprintf(&b, "- level %d: `%s`%s\n", i, v.ValueText(), marker)
+		}
+	} else if kind, name, r, ok := s.Index.CallAt(f); ok && kind == index.KindScriptValue && r.Contains(p) {
+		rng = r
+		var lines []string
+		for _, ref := range s.scriptValueDefines(name) {
+			if def, sym, ok := s.effectiveDefine(ref); ok {
+				lines = append(lines, fmt.Sprintf("- `%s` = `%s` (_%s_)", ref, s.valueSource(s.Index, sym.Path, def), s.Index.VirtualPath(sym.Path)))
+			}
+		}
+		if len(lines) == 0 {
+			return nil
+		}
+		fmt.Fprintf(&b, "**script value** `%s` depends on defines:\n\n%s", name, strings.Join(lines, "\n"))
+	} else {
+		return nil
+	}
+	hoverRange := analysis.Range(rng)
+	return &lsp.Hover{
+		Contents: []lsp.MarkedString{lsp.RawMarkedString(strings.TrimSpace(b.String()))},
+		Range:    &hoverRange,
+	}
+}
+
+// scriptValueDefines returns the names of the defines that the definition
+// of a script value refers to with `define:NGame|KEY`, in source order.
+func (s *Server) scriptValueDefines(name string) []string {
+	defs := s.Index.Definitions(index.KindScriptValue, name)
+	if len(defs) == 0 {
+		return nil
+	}
+	entry := s.Index.File(defs[0].Path)
+	f := s.Index.FieldAt(defs[0].Location)
+	if entry == nil || f == nil {
+		return nil
+	}
+	r := f.Range()
+	var names []string
+	seen := make(map[string]bool)
+	for _, ref := range entry.Refs {
+		if ref.Kind == index.KindDefine && !seen[ref.Name] && r.Contains(ref.Range.Start) {
+			seen[ref.Name] = true
+			names = append(names, ref.Name)
+		}
+	}
+	return names
+}
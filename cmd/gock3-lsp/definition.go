@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sort"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// TextDocumentDefinition jumps from a symbol or reference to every place the
+// symbol is defined in the workspace, where the mod overrides it, or else
+// in the vanilla game files, and from a brace to the matching one.
+func (s *Server) TextDocumentDefinition(ctx context.Context, params lsp.TextDocumentPositionParams) ([]lsp.Location, error) {
+	log.Printf("Definition request received for URI: %s at position Line %d, Character %d",
+		params.TextDocument.URI, params.Position.Line, params.Position.Character)
+
+	if brace, ok := s.matchingBrace(params); ok {
+		log.Printf("Returning the matching brace at Line %d, Character %d.", brace.Range.Start.Line, brace.Range.Start.Character)
+		return []lsp.Location{brace}, nil
+	}
+	kind, name, ok := s.symbolAt(params)
+	if !ok {
+		return []lsp.Location{}, nil
+	}
+	syms := s.Index.LocalDefinitions(kind, name)
+	if len(syms) == 0 {
+		syms = s.Index.Definitions(kind, name)
+	}
+	locations := []lsp.Location{}
+	for _, sym := range syms {
+		locations = append(locations, toLocation(sym.Location))
+	}
+	log.Printf("Returning %d definitions for %s '%s'.", len(locations), kind, name)
+	return locations, nil
+}
+
+// TextDocumentDeclaration jumps from a symbol or reference to its original
+// definition in the vanilla game files, even in a file the mod replaces,
+// so that an override can be compared with what it overrides. Symbols the
+// game does not define go to their definitions in the workspace.
+func (s *Server) TextDocumentDeclaration(ctx context.Context, params lsp.TextDocumentPositionParams) ([]lsp.Location, error) {
+	log.Printf("Declaration request received for URI: %s at position Line %d, Character %d",
+		params.TextDocument.URI, params.Position.Line, params.Position.Character)
+
+	kind, name, ok := s.symbolAt(params)
+	if !ok {
+		return []lsp.Location{}, nil
+	}
+	var syms []index.Symbol
+	if base := s.Index.Base(); base != nil {
+		syms = base.Definitions(kind, name)
+	}
+	if len(syms) == 0 {
+		syms = s.Index.Definitions(kind, name)
+	}
+	locations := []lsp.Location{}
+	for _, sym := range syms {
+		locations = append(locations, toLocation(sym.Location))
+	}
+	log.Printf("Returning %d declarations for %s '%s'.", len(locations), kind, name)
+	return locations, nil
+}
+
+// TextDocumentReferences lists every use of the symbol at the cursor,
+// including the calls of scripted effects, triggers and script values.
+func (s *Server) TextDocumentReferences(ctx context.Context, params lsp.ReferenceParams) ([]lsp.Location, error) {
+	log.Printf("References request received for URI: %s at position Line %d, Character %d",
+		params.TextDocument.URI, params.Position.Line, params.Position.Character)
+
+	kind, name, ok := s.symbolAt(params.TextDocumentPositionParams)
+	if !ok {
+		return []lsp.Location{}, nil
+	}
+	locations := []lsp.Location{}
+	if params.Context.IncludeDeclaration {
+		for _, sym := range s.Index.Definitions(kind, name) {
+			locations = append(locations, toLocation(sym.Location))
+		}
+	}
+	refs := s.Index.Uses(kind, name)
+	sort.SliceStable(refs, func(i, j int) bool { return refs[i].Less(refs[j].Location) })
+	for _, ref := range refs {
+		locations = append(locations, toLocation(ref.Location))
+	}
+	log.Printf("Returning %d references for %s '%s'.", len(locations), kind, name)
+	return locations, nil
+}
+
+// symbolAt finds the indexed symbol or reference under the cursor, or the
+// scripted effect, scripted trigger or script value it calls.
+func (s *Server) symbolAt(params lsp.TextDocumentPositionParams) (index.Kind, string, bool) {
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return "", "", false
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil {
+		return "", "", false
+	}
+	pos := pdx.Pos{Line: params.Position.Line, Col: params.Position.Character}
+	if kind, name, _, ok := entry.SymbolAt(pos); ok {
+		return kind, name, true
+	}
+	if entry.File == nil {
+		return "", "", false
+	}
+	path := entry.File.PathAt(pos)
+	if len(path) == 0 {
+		return "", "", false
+	}
+	kind, name, rng, ok := s.Index.CallAt(path[len(path)-1])
+	return kind, name, ok && rng.Contains(pos)
+}
+
+func toLocation(loc index.Location) lsp.Location {
+	return lsp.Location{URI: filePathToURI(loc.Path), Range: analysis.Range(loc.Range)}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	lsp "github.com/sourcegraph/go-lsp"
+)
+
+// configurationURL documents the gamePath setting.
+const configurationURL = "https://github.com/unLomTrois/gock3-lsp#configuration"
+
+// promptTimeout bounds the wait for the user to answer the gamePath prompt.
+const promptTimeout = 10 * time.Minute
+
+// Actions of the gamePath prompt.
+const (
+	actionOpenDocs = "How to configure"
+	actionDismiss  = "Not now"
+)
+
+// ShowMessageRequestParams are the parameters of window/showMessageRequest.
+type ShowMessageRequestParams struct {
+	Type    lsp.MessageType     `json:"type"`
+	Message string              `json:"message"`
+	Actions []MessageActionItem `json:"actions"`
+}
+
+// MessageActionItem is an action of window/showMessageRequest.
+type MessageActionItem struct {
+	Title string `json:"title"`
+}
+
+// ShowDocumentParams are the parameters of window/showDocument.
+type ShowDocumentParams struct {
+	URI       string     `json:"uri"`
+	External  bool       `json:"external"`
+	TakeFocus bool       `json:"takeFocus,omitempty"`
+	Selection *lsp.Range `json:"selection,omitempty"`
+}
+
+// Initialized handles the initialized notification. It runs the self-check
+// of the environment, and without a gamePath the server runs in a degraded
+// mode, so the user is told once how to configure it.
+func (s *Server) Initialized(ctx context.Context, params lsp.None) error {
+	s.selfCheck()
+	s.mutex.RLock()
+	degraded := s.Settings.GamePath == ""
+	s.mutex.RUnlock()
+	if degraded {
+		log.Println("No gamePath configured; vanilla symbols are unavailable.")
+		go s.promptGamePath()
+	}
+	return nil
+}
+
+// promptGamePath asks the user, once per session, to configure gamePath,
+// and opens the configuration docs if they want.
+func (s *Server) promptGamePath() {
+	if s.gamePathPrompted.Swap(true) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), promptTimeout)
+	defer cancel()
+	rsp, err := s.jrpcServer.Callback(ctx, "window/showMessageRequest", ShowMessageRequestParams{
+		Type:    lsp.MTWarning,
+		Message: "gock3: no gamePath is configured, so vanilla templates, localization and other symbols are unknown. Checks that need them are skipped until gamePath points at the 'game' folder of your CK3 installation.",
+		Actions: []MessageActionItem{{Title: actionOpenDocs}, {Title: actionDismiss}},
+	})
+	if err != nil {
+		log.Printf("Failed to prompt for gamePath: %v", err)
+		return
+	}
+	var action *MessageActionItem
+	if err := rsp.UnmarshalResult(&action); err != nil || action == nil || action.Title != actionOpenDocs {
+		return
+	}
+	if _, err := s.jrpcServer.Callback(ctx, "window/showDocument", ShowDocumentParams{URI: configurationURL, External: true}); err != nil {
+		log.Printf("Failed to open the configuration docs: %v", err)
+	}
+}
+
+// vanillaDetail marks the detail of a completion item listing names that
+// the vanilla files would add to while no gamePath is configured. The
+// caller must hold s.mutex.
+func (s *Server) vanillaDetail(detail string) string {
+	if s.Index.Base() != nil {
+		return detail
+	}
+	return detail + " (workspace only; vanilla names need gamePath)"
+}
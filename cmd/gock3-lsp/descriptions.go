@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// descHover lists every description a title, desc or option name can resolve
+// to when the cursor is inside one, or the text of the key under the
+// cursor. Inside the trigger of a triggered_desc it returns nil so triggers
+// get the same hovers as anywhere else; it returns nil outside descriptions
+// too.
+func (s *Server) descHover(filePath string, pos lsp.Position) *lsp.Hover {
+	entry := s.Index.File(filePath)
+	if entry == nil {
+		return nil
+	}
+	p := pdx.Pos{Line: pos.Line, Col: pos.Character}
+	for _, root := range analysis.DescRoots(entry) {
+		if !root.Range().Contains(p) {
+			continue
+		}
+		if inDescTrigger(entry.File.PathAt(p), root, p) {
+			return nil
+		}
+		for _, leaf := range analysis.DescLeaves(root) {
+			if leaf.Key.Loc.Contains(p) {
+				r := analysis.Range(leaf.Key.Loc)
+				return &lsp.Hover{
+					Contents: []lsp.MarkedString{lsp.RawMarkedString(fmt.Sprintf("`%s` (%s): %s", leaf.Key.Text, leaf.Condition, s.localizedText(leaf.Key.Text)))},
+					Range:    &r,
+				}
+			}
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "**Possible %s texts**\n\n", root.KeyText())
+		for _, leaf := range analysis.DescLeaves(root) {
+			fmt.Fprintf(&b, "- `%s` (%s): %s\n", leaf.Key.Text, leaf.Condition, s.localizedText(leaf.Key.Text))
+		}
+		r := analysis.Range(root.Range())
+		return &lsp.Hover{
+			Contents: []lsp.MarkedString{lsp.RawMarkedString(b.String())},
+			Range:    &r,
+		}
+	}
+	return nil
+}
+
+// inDescTrigger reports whether the fields enclosing p, outermost first,
+// reach into a trigger block below the description root.
+func inDescTrigger(path []*pdx.Field, root *pdx.Field, p pdx.Pos) bool {
+	below := false
+	for _, f := range path {
+		if f == root {
+			below = true
+			continue
+		}
+		if below && f.KeyText() == "trigger" && f.Block() != nil && f.Block().Loc.Contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// localizedText returns the English text of a localization key, falling back
+// to any other language, or a marker when it is not localized at all.
+func (s *Server) localizedText(key string) string {
+	entries := s.Index.Localizations(key)
+	if len(entries) == 0 {
+		return "_no localization_"
+	}
+	best := entries[0]
+	for _, e := range entries {
+		if e.Language == "l_english" {
+			best = e
+			break
+		}
+	}
+	if best.Language != "l_english" {
+		return fmt.Sprintf("%q (%s)", best.Text, strings.TrimPrefix(best.Language, "l_"))
+	}
+	return fmt.Sprintf("%q", best.Text)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	lsp "github.com/sourcegraph/go-lsp"
+)
+
+func TestDescHover(t *testing.T) {
+	s := NewServer()
+	filePath := "/mods/my_mod/events/my_events.txt"
+	openDocument(s, filePath, "namespace = my\nmy.0001 = {\n\tdesc = {\n\t\ttriggered_desc = {\n\t\t\ttrigger = { is_adult = yes }\n\t\t\tdesc = my.0001.adult\n\t\t}\n\t\tdesc = my.0001.desc\n\t}\n}\n")
+
+	hover := s.descHover(filePath, lsp.Position{Line: 5, Character: 12})
+	if hover == nil {
+		t.Fatal("no hover on the key of the triggered_desc")
+	}
+	if text := hover.Contents[0].Value; !strings.HasPrefix(text, "`my.0001.adult` (if trigger)") {
+		t.Errorf("hover = %q, want the key and its condition", text)
+	}
+	if hover := s.descHover(filePath, lsp.Position{Line: 4, Character: 18}); hover != nil {
+		t.Errorf("hover in the trigger = %+v, want nil to leave it to the trigger hovers", hover)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/mod"
+)
+
+// syncDescriptorCommand runs gock3.syncDescriptor, which updates
+// descriptor.mod or .metadata/metadata.json from the other. The optional
+// first argument names the source, "descriptor" or "metadata".
+func (s *Server) syncDescriptorCommand(ctx context.Context, args []interface{}) (interface{}, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.RootPath == "" {
+		return nil, errors.New("no workspace root with a descriptor")
+	}
+	written, err := mod.Sync(s.RootPath, stringArg(args, 0))
+	if err != nil {
+		return nil, err
+	}
+	descriptor := filepath.Join(s.RootPath, mod.DescriptorFile)
+	if _, open := s.Documents[descriptor]; !open {
+		if data, err := os.ReadFile(descriptor); err == nil {
+			s.Index.UpdateFile(descriptor, string(data))
+		}
+	}
+	s.refreshDiagnostics(ctx, "")
+	uris := make([]lsp.DocumentURI, 0, len(written))
+	for _, path := range written {
+		uris = append(uris, filePathToURI(path))
+	}
+	return uris, nil
+}
+
+// bumpVersionFix sets the descriptor's supported_version to cover the
+// installed game and then runs gock3.patchAudit to list what may break.
+func (s *Server) bumpVersionFix(filePath string, d lsp.Diagnostic) []CodeAction {
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil {
+		return nil
+	}
+	f := entry.File.Root.Get("supported_version")
+	if f == nil || f.Value == nil || s.GameVersion == "" {
+		return nil
+	}
+	version := mod.BumpVersion(f.ValueText(), s.GameVersion)
+	return []CodeAction{{
+		Title:       "Bump supported_version to " + version,
+		Kind:        lsp.CAKQuickFix,
+		Diagnostics: []lsp.Diagnostic{d},
+		Edit: &WorkspaceEdit{Changes: map[string][]lsp.TextEdit{
+			string(filePathToURI(filePath)): {{Range: analysis.Range(f.Value.Range()), NewText: strconv.Quote(version)}},
+		}},
+		Command: &lsp.Command{Title: "Audit overridden vanilla files", Command: "gock3.patchAudit", Arguments: []interface{}{}},
+	}}
+}
+
+// AuditItem is a mod file that may break with a game update.
+type AuditItem struct {
+	URI    lsp.DocumentURI `json:"uri"`
+	Reason string          `json:"reason"`
+}
+
+// patchAuditCommand runs gock3.patchAudit, which lists the mod files that
+// replace whole vanilla files: these silently drop every change a game
+// update makes to the original, so they need reviewing after a version
+// bump.
+func (s *Server) patchAuditCommand(ctx context.Context, args []interface{}) (interface{}, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.Index.Base() == nil {
+		return nil, errors.New("gamePath is not set or the game files are still being indexed")
+	}
+	items := []AuditItem{}
+	for _, path := range s.Index.Overrides() {
+		if s.RootPath != "" && !strings.HasPrefix(path, s.RootPath) {
+			continue
+		}
+		items = append(items, AuditItem{
+			URI:    filePathToURI(path),
+			Reason: "replaces vanilla " + s.Index.VirtualPath(path) + "; compare it with the updated game file",
+		})
+	}
+	log.Printf("Patch audit found %d overridden vanilla files.", len(items))
+	return items, nil
+}
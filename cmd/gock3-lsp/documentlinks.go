@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/url"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// wikiURL is the CK3 wiki, whose Effects and Triggers pages list the
+// built-in effects and triggers.
+const wikiURL = "https://ck3.paradoxwikis.com/"
+
+// DocumentLinkParams asks for the links of a document.
+type DocumentLinkParams struct {
+	TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+}
+
+// DocumentLink is a clickable range, which go-lsp lacks. Target is left
+// out until the link is resolved; Data tells what it documents.
+type DocumentLink struct {
+	Range   lsp.Range         `json:"range"`
+	Target  string            `json:"target,omitempty"`
+	Tooltip string            `json:"tooltip,omitempty"`
+	Data    *documentLinkData `json:"data,omitempty"`
+}
+
+// documentLinkData identifies the built-in effect or trigger of a link.
+type documentLinkData struct {
+	Page string `json:"page"`
+	Name string `json:"name"`
+}
+
+// DocumentLinkOptions advertise textDocument/documentLink and, with
+// ResolveProvider, documentLink/resolve.
+type DocumentLinkOptions struct {
+	ResolveProvider bool `json:"resolveProvider"`
+}
+
+// TextDocumentDocumentLink links the built-in effects and triggers of a
+// script file, such as `add_gold` or `death`, to their documentation on the
+// wiki. The keys are classified as for semantic tokens, so scripted effects
+// and triggers, which go to definition instead, are left out.
+func (s *Server) TextDocumentDocumentLink(ctx context.Context, params DocumentLinkParams) ([]DocumentLink, error) {
+	log.Printf("Document link request received for URI: %s", params.TextDocument.URI)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	links := []DocumentLink{}
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return links, err
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil {
+		return links, nil
+	}
+	pdx.Walk(entry.File.Root, func(f *pdx.Field) bool {
+		if f.Key == nil || f.Key.Quoted {
+			return true
+		}
+		typ, mods := s.keyToken(entry, f)
+		if mods != modDefaultLibrary {
+			return true
+		}
+		page := "Effects"
+		if typ == tokenTrigger {
+			page = "Triggers"
+		}
+		links = append(links, DocumentLink{
+			Range:   analysis.Range(f.Key.Loc),
+			Tooltip: "Open the documentation of " + f.Key.Text + " on the CK3 wiki",
+			Data:    &documentLinkData{Page: page, Name: f.Key.Text},
+		})
+		return true
+	})
+	log.Printf("Returning %d document links.", len(links))
+	return links, nil
+}
+
+// DocumentLinkResolve fills in the target of a link: the anchor of its
+// effect or trigger on the wiki page listing it.
+func (s *Server) DocumentLinkResolve(ctx context.Context, link DocumentLink) (DocumentLink, error) {
+	if link.Data != nil && link.Target == "" {
+		link.Target = wikiURL + link.Data.Page + "#" + url.PathEscape(link.Data.Name)
+	}
+	return link, nil
+}
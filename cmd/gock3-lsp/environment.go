@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/mod"
+)
+
+// States of an EnvironmentCheck.
+const (
+	checkOK      = "ok"
+	checkWarning = "warning"
+	checkSkipped = "skipped"
+)
+
+// launcherDatabase is the file the launcher keeps its playsets and mod
+// registrations in, in the folder holding the mods folder.
+const launcherDatabase = "launcher-v2.sqlite"
+
+// EnvironmentCheck is the outcome of one check of the self-check.
+type EnvironmentCheck struct {
+	Name string `json:"name"`
+	// State is "ok", "warning" or "skipped", the latter for a check of
+	// something that is not configured.
+	State   string `json:"state"`
+	Message string `json:"message"`
+}
+
+// EnvironmentReport answers gock3/environment with the configuration the
+// server runs with and what is wrong with it.
+type EnvironmentReport struct {
+	RootPath    string             `json:"rootPath"`
+	GamePath    string             `json:"gamePath"`
+	ModsPath    string             `json:"modsPath"`
+	GameVersion string             `json:"gameVersion,omitempty"`
+	Index       IndexStatus        `json:"index"`
+	Checks      []EnvironmentCheck `json:"checks"`
+}
+
+// Environment answers gock3/environment by running the self-check anew.
+func (s *Server) Environment(ctx context.Context, params lsp.None) (EnvironmentReport, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.environmentReport(), nil
+}
+
+// selfCheck logs a warning for every failed check of the environment, so
+// that a misconfiguration shows in the log right after startup rather than
+// as features that are silently missing.
+func (s *Server) selfCheck() {
+	s.mutex.RLock()
+	report := s.environmentReport()
+	s.mutex.RUnlock()
+	for _, check := range report.Checks {
+		if check.State == checkWarning {
+			log.Printf("Environment check '%s' failed: %s", check.Name, check.Message)
+		}
+	}
+}
+
+// environmentReport checks the folders and resources the settings point
+// to. The caller must hold s.mutex.
+func (s *Server) environmentReport() EnvironmentReport {
+	settings := s.Settings
+	s.health.mu.Lock()
+	status := s.health.status()
+	s.health.mu.Unlock()
+	report := EnvironmentReport{RootPath: s.RootPath, GamePath: settings.GamePath, ModsPath: settings.ModsPath, Index: status}
+	add := func(name, state, format string, args ...interface{}) {
+		report.Checks = append(report.Checks, EnvironmentCheck{Name: name, State: state, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.RootPath == "" {
+		add("workspace", checkWarning, "No workspace root; only open documents are indexed")
+	} else if _, err := os.ReadDir(s.RootPath); err != nil {
+		add("workspace", checkWarning, "The workspace cannot be read: %v", err)
+	} else {
+		add("workspace", checkOK, "%s", s.RootPath)
+	}
+
+	switch {
+	case settings.GamePath == "":
+		add("gamePath", checkWarning, "gamePath is not set and no installation was detected; vanilla symbols are unavailable")
+	case checkGamePath(settings.GamePath) != nil:
+		add("gamePath", checkWarning, "%v", checkGamePath(settings.GamePath))
+	default:
+		report.GameVersion = mod.GameVersion(settings.GamePath)
+		if report.GameVersion == "" {
+			add("gamePath", checkOK, "%s (version unknown: no launcher-settings.json)", settings.GamePath)
+		} else {
+			add("gamePath", checkOK, "%s (version %s)", settings.GamePath, report.GameVersion)
+		}
+	}
+
+	if settings.ModsPath == "" {
+		add("launcherDatabase", checkSkipped, "modsPath is not set and no mods folder was detected")
+	} else {
+		db := filepath.Join(filepath.Dir(settings.ModsPath), launcherDatabase)
+		if _, err := os.Stat(db); err != nil {
+			add("launcherDatabase", checkWarning, "No launcher database at '%s'; has the launcher been run?", db)
+		} else {
+			add("launcherDatabase", checkOK, "%s", db)
+		}
+	}
+
+	// The index lives in memory only, so there is no cache to go stale;
+	// what can fail is the scan that builds it.
+	if status.State == statusError {
+		add("index", checkWarning, "%s", status.Message)
+	} else {
+		add("index", checkOK, "%s: %d workspace files, %d vanilla files (rebuilt on every start, not cached)", status.State, status.WorkspaceFiles, status.VanillaFiles)
+	}
+
+	switch {
+	case settings.DataTypesPath == "":
+		add("dataTypes", checkSkipped, "dataTypesPath is not set; data functions in GUI files are not checked")
+	case s.DataTypes == nil:
+		add("dataTypes", checkWarning, "No data_types*.txt dumps could be loaded from '%s'", settings.DataTypesPath)
+	default:
+		add("dataTypes", checkOK, "%d global data functions from '%s'", len(s.DataTypes.Globals), settings.DataTypesPath)
+	}
+
+	for _, analyzer := range settings.ExternalAnalyzers {
+		if len(analyzer.Command) == 0 {
+			continue
+		}
+		if _, err := exec.LookPath(analyzer.Command[0]); err != nil {
+			add("externalAnalyzers", checkWarning, "'%s' cannot be run: %v", analyzer.Command[0], err)
+		}
+	}
+	return report
+}
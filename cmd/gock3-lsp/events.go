@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+// EventRoot is a place an event is ultimately fired from, such as an
+// on_action, a decision or a character interaction.
+type EventRoot struct {
+	// Kind is the kind of the root definition, or the folder it is in if it
+	// is not an indexed symbol.
+	Kind  string          `json:"kind"`
+	Name  string          `json:"name"`
+	URI   lsp.DocumentURI `json:"uri"`
+	Range lsp.Range       `json:"range"`
+	// Chain lists the events and scripted effects leading from the root to
+	// the event, the event itself last.
+	Chain []string `json:"chain"`
+}
+
+// eventRootsCommand runs gock3.eventRoots. The argument is an event ID or
+// text document position params pointing at an event.
+func (s *Server) eventRootsCommand(ctx context.Context, args []interface{}) (interface{}, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	event := stringArg(args, 0)
+	if event == "" && len(args) > 0 {
+		data, _ := json.Marshal(args[0])
+		var params lsp.TextDocumentPositionParams
+		if err := json.Unmarshal(data, &params); err != nil {
+			return nil, err
+		}
+		kind, name, ok := s.symbolAt(params)
+		if !ok || kind != index.KindEvent {
+			return nil, errors.New("no event at the given position")
+		}
+		event = name
+	}
+	if event == "" {
+		return nil, errors.New("expected an event ID or a position")
+	}
+	roots := s.eventRoots(event)
+	log.Printf("Found %d reachability roots for event %s.", len(roots), event)
+	return roots, nil
+}
+
+// eventRoots walks the uses of an event backwards through the events and
+// scripted effects that fire it, collecting the definitions of any other
+// kind where the chains start.
+func (s *Server) eventRoots(event string) []EventRoot {
+	type node struct {
+		kind index.Kind
+		name string
+	}
+	type item struct {
+		node
+		chain []string
+	}
+	roots := []EventRoot{}
+	seen := map[node]bool{{index.KindEvent, event}: true}
+	queue := []item{{node{index.KindEvent, event}, []string{event}}}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, ref := range s.Index.Uses(cur.kind, cur.name) {
+			container, ok := s.Index.Container(ref.Location)
+			switch {
+			case ok && (container.Kind == index.KindEvent || container.Kind == index.KindScriptedEffect):
+				next := node{container.Kind, container.Name}
+				if !seen[next] {
+					seen[next] = true
+					queue = append(queue, item{next, append([]string{container.Name}, cur.chain...)})
+				}
+			case ok:
+				roots = append(roots, EventRoot{Kind: string(container.Kind), Name: container.Name,
+					URI: filePathToURI(container.Path), Range: analysis.Range(container.Range), Chain: cur.chain})
+			default:
+				root := EventRoot{Kind: folderOf(s.Index.VirtualPath(ref.Path)), URI: filePathToURI(ref.Path), Range: analysis.Range(ref.Range), Chain: cur.chain}
+				if entry := s.Index.File(ref.Path); entry != nil && entry.File != nil {
+					if path := entry.File.PathAt(ref.Range.Start); len(path) > 0 && path[0].Key != nil {
+						root.Name = path[0].Key.Text
+						root.Range = analysis.Range(path[0].Key.Loc)
+					}
+				}
+				roots = append(roots, root)
+			}
+		}
+	}
+	return roots
+}
+
+// folderOf returns the folder part of a virtual path, "common/decisions"
+// for "common/decisions/x.txt".
+func folderOf(vpath string) string {
+	if i := strings.LastIndex(vpath, "/"); i >= 0 {
+		return vpath[:i]
+	}
+	return vpath
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+)
+
+// Values of Settings.ExternalFiles besides the default "suppress".
+const (
+	externalDowngrade = "downgrade"
+	externalShow      = "show"
+)
+
+// readOnly reports whether filePath lies outside the workspace, like the
+// vanilla files at gamePath or another mod opened for reference. The
+// server does not offer edits for such files.
+func (s *Server) readOnly(filePath string) bool {
+	if s.RootPath == "" {
+		return false
+	}
+	rel, err := filepath.Rel(s.RootPath, filePath)
+	return err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// externalDiagnostics applies Settings.ExternalFiles to the diagnostics of
+// a read-only file: by default they are dropped, "downgrade" turns them
+// into hints and "show" keeps them.
+func (s *Server) externalDiagnostics(diagnostics []analysis.Diagnostic) []analysis.Diagnostic {
+	switch s.Settings.ExternalFiles {
+	case externalShow:
+		return diagnostics
+	case externalDowngrade:
+		for i := range diagnostics {
+			diagnostics[i].Severity = lsp.Hint
+		}
+		return diagnostics
+	}
+	return []analysis.Diagnostic{}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// valueContextPattern matches `key = partial` at the end of a line prefix.
+var valueContextPattern = regexp.MustCompile(`([A-Za-z0-9_]+)\s*=\s*([A-Za-z0-9_.:]*)$`)
+
+// flagCompletions offers known flag names when the cursor is in the value of
+// a flag effect or trigger, either `has_character_flag = |` or the `flag = |`
+// entry of the block form. It returns nil outside such a context. The caller
+// must hold s.mutex.
+func (s *Server) flagCompletions(params lsp.TextDocumentPositionParams) []lsp.CompletionItem {
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return nil
+	}
+	m := valueContextPattern.FindStringSubmatch(linePrefix(s.Documents[filePath], params.Position))
+	if m == nil {
+		return nil
+	}
+
+	key := m[1]
+	if key == "flag" {
+		key = s.enclosingBlockKey(filePath, params.Position)
+	}
+	_, kind, ok := index.ParseFlagKey(key)
+	if !ok {
+		return nil
+	}
+
+	detail := strings.ReplaceAll(string(kind), "_", " ")
+	items := []lsp.CompletionItem{}
+	for _, name := range s.Index.Names(kind) {
+		items = append(items, lsp.CompletionItem{
+			Label:  name,
+			Kind:   lsp.CIKValue,
+			Detail: detail,
+		})
+	}
+	return items
+}
+
+// enclosingBlockKey returns the key of the innermost block containing pos.
+func (s *Server) enclosingBlockKey(filePath string, pos lsp.Position) string {
+	keys := s.enclosingKeys(filePath, pos)
+	if len(keys) == 0 {
+		return ""
+	}
+	return keys[len(keys)-1]
+}
+
+// enclosingKeys returns the keys of the blocks containing pos, outermost
+// first.
+func (s *Server) enclosingKeys(filePath string, pos lsp.Position) []string {
+	var keys []string
+	for _, f := range s.enclosingFields(filePath, pos) {
+		keys = append(keys, f.KeyText())
+	}
+	return keys
+}
+
+// enclosingFields returns the fields with a block value containing pos,
+// outermost first.
+func (s *Server) enclosingFields(filePath string, pos lsp.Position) []*pdx.Field {
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil {
+		return nil
+	}
+	var fields []*pdx.Field
+	for _, f := range entry.File.PathAt(pdx.Pos{Line: pos.Line, Col: pos.Character}) {
+		if f.Block() != nil {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// FoldingRangeParams asks for the folding ranges of a document.
+type FoldingRangeParams struct {
+	TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+}
+
+// FoldingRange is a range of lines the client can collapse, which go-lsp
+// lacks. The characters are left out for clients folding whole lines.
+type FoldingRange struct {
+	StartLine      int    `json:"startLine"`
+	StartCharacter *int   `json:"startCharacter,omitempty"`
+	EndLine        int    `json:"endLine"`
+	EndCharacter   *int   `json:"endCharacter,omitempty"`
+	Kind           string `json:"kind,omitempty"`
+}
+
+// The kinds of folding ranges other than blocks.
+const (
+	foldingComment = "comment"
+	foldingRegion  = "region"
+)
+
+// regionPattern matches the text of a `# region` or `# endregion` comment.
+var regionPattern = regexp.MustCompile(`^\s*(end)?region\b`)
+
+// TextDocumentFoldingRange returns the multi-line blocks of a file, such as
+// event definitions, options and effect blocks, along with runs of comment
+// lines and the regions between `# region` and `# endregion`.
+func (s *Server) TextDocumentFoldingRange(ctx context.Context, params FoldingRangeParams) ([]FoldingRange, error) {
+	log.Printf("Folding range request received for URI: %s", params.TextDocument.URI)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	ranges := []FoldingRange{}
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return ranges, err
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil {
+		return ranges, nil
+	}
+	if entry.File != nil {
+		pdx.Walk(entry.File.Root, func(f *pdx.Field) bool {
+			if b := f.Block(); b != nil && b.Closed {
+				ranges = s.appendBlockFold(ranges, b.Loc)
+			}
+			return true
+		})
+	}
+	ranges = append(ranges, commentFolds(entry, strings.Split(s.fileText(entry), "\n"))...)
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].StartLine < ranges[j].StartLine })
+	log.Printf("Returning %d folding ranges.", len(ranges))
+	return ranges, nil
+}
+
+// appendBlockFold adds the fold of a block spanning r if it is on more
+// than one line. Clients folding whole lines keep the line of the closing
+// brace visible.
+func (s *Server) appendBlockFold(ranges []FoldingRange, r pdx.Range) []FoldingRange {
+	if s.lineFoldingOnly {
+		if r.End.Line-1 > r.Start.Line {
+			ranges = append(ranges, FoldingRange{StartLine: r.Start.Line, EndLine: r.End.Line - 1})
+		}
+		return ranges
+	}
+	if r.End.Line > r.Start.Line {
+		// Keep the braces visible around the folded contents.
+		start, end := r.Start.Col+1, r.End.Col-1
+		ranges = append(ranges, FoldingRange{StartLine: r.Start.Line, StartCharacter: &start, EndLine: r.End.Line, EndCharacter: &end})
+	}
+	return ranges
+}
+
+// commentFolds returns the runs of two or more lines holding only a
+// comment, and the regions between `# region` and `# endregion` comments,
+// which nest. The lines of text tell comments on their own line from those
+// ending a line of script.
+func commentFolds(entry *index.FileEntry, lines []string) []FoldingRange {
+	var comments []pdx.Token
+	switch {
+	case entry.File != nil:
+		comments = entry.File.Comments
+	case entry.Loc != nil:
+		comments = entry.Loc.Comments
+	}
+	var ranges []FoldingRange
+	var regions []int
+	runStart, runEnd := -1, -1
+	endRun := func() {
+		if runEnd > runStart {
+			ranges = append(ranges, FoldingRange{StartLine: runStart, EndLine: runEnd, Kind: foldingComment})
+		}
+		runStart, runEnd = -1, -1
+	}
+	for _, c := range comments {
+		line := c.Range.Start.Line
+		if line >= len(lines) || !strings.HasPrefix(strings.TrimSpace(lines[line]), "#") {
+			continue
+		}
+		if m := regionPattern.FindStringSubmatch(c.Text); m != nil {
+			endRun()
+			if m[1] == "" {
+				regions = append(regions, line)
+			} else if n := len(regions); n > 0 {
+				if line > regions[n-1] {
+					ranges = append(ranges, FoldingRange{StartLine: regions[n-1], EndLine: line, Kind: foldingRegion})
+				}
+				regions = regions[:n-1]
+			}
+			continue
+		}
+		if line != runEnd+1 || runStart < 0 {
+			endRun()
+			runStart = line
+		}
+		runEnd = line
+	}
+	endRun()
+	return ranges
+}
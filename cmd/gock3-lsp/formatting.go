@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// TextDocumentFormatting pretty-prints an open script or GUI file with
+// pdx.Format: tab indentation by block depth and single spaces around
+// operators and braces. Only the lines that change are edited. Files with
+// syntax errors are refused, and read-only files are left alone.
+func (s *Server) TextDocumentFormatting(ctx context.Context, params lsp.DocumentFormattingParams) ([]lsp.TextEdit, error) {
+	log.Printf("Formatting request received for URI: %s", params.TextDocument.URI)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	edits, err := s.formattingEdits(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Returning %d formatting edits.", len(edits))
+	return edits, nil
+}
+
+// TextDocumentRangeFormatting formats like TextDocumentFormatting but only
+// edits the lines of the range, so the rest of the file stays as it is.
+// Since pdx.Format keeps line breaks, every line is formatted on its own
+// terms and the indentation still follows the whole file.
+func (s *Server) TextDocumentRangeFormatting(ctx context.Context, params lsp.DocumentRangeFormattingParams) ([]lsp.TextEdit, error) {
+	log.Printf("Range formatting request received for URI: %s, lines %d-%d", params.TextDocument.URI, params.Range.Start.Line, params.Range.End.Line)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	edits, err := s.formattingEdits(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	// A range ending at the start of a line does not include that line.
+	last := params.Range.End.Line
+	if params.Range.End.Character == 0 && last > params.Range.Start.Line {
+		last--
+	}
+	inRange := []lsp.TextEdit{}
+	for _, edit := range edits {
+		if edit.Range.Start.Line >= params.Range.Start.Line && edit.Range.Start.Line <= last {
+			inRange = append(inRange, edit)
+		}
+	}
+	log.Printf("Returning %d of %d formatting edits.", len(inRange), len(edits))
+	return inRange, nil
+}
+
+// formattingEdits returns the edits formatting the open document at uri.
+// The caller must hold s.mutex.
+func (s *Server) formattingEdits(uri lsp.DocumentURI) ([]lsp.TextEdit, error) {
+	filePath, err := uriToFilePath(uri)
+	if err != nil {
+		return nil, err
+	}
+	text, ok := s.Documents[filePath]
+	if !ok {
+		return nil, errors.New("Document does not exist for URI: " + string(uri))
+	}
+	if !index.IsScriptFile(filePath) && !index.IsGUIFile(filePath) || s.readOnlyMode || s.readOnly(filePath) || s.skipsFormat(filePath) {
+		return []lsp.TextEdit{}, nil
+	}
+	formatted, err := pdx.Format(text)
+	if err != nil {
+		log.Printf("Not formatting %s: %v", filePath, err)
+		return nil, err
+	}
+	return formatEdits(text, formatted), nil
+}
+
+// skipsFormat reports whether the file at filePath was generated by a tool
+// whose files the settings leave unformatted. The caller must hold
+// s.mutex.
+func (s *Server) skipsFormat(filePath string) bool {
+	entry := s.Index.File(filePath)
+	if entry == nil {
+		return false
+	}
+	tool, ok := analysis.GeneratedBy(entry)
+	if !ok {
+		return false
+	}
+	g, ok := s.Settings.generated(tool)
+	return ok && g.SkipFormat
+}
+
+// formatEdits returns the edits turning text into formatted, which has the
+// same lines apart from the blank ones at the end: one edit per changed
+// line, and one replacing the last common line and the rest of the file
+// if the line counts differ. Lines are compared and replaced without their
+// line endings, so the "\r" of CRLF files stays as it is.
+func formatEdits(text, formatted string) []lsp.TextEdit {
+	old, lines := splitLines(text), splitLines(formatted)
+	n := min(len(old), len(lines))
+	common := n
+	if len(old) != len(lines) {
+		common = n - 1
+	}
+	edits := []lsp.TextEdit{}
+	for i := 0; i < common; i++ {
+		if old[i] != lines[i] {
+			edits = append(edits, lsp.TextEdit{
+				Range:   lsp.Range{Start: lsp.Position{Line: i}, End: lsp.Position{Line: i, Character: utf16Len(old[i])}},
+				NewText: lines[i],
+			})
+		}
+	}
+	if common < n {
+		last := len(old) - 1
+		edits = append(edits, lsp.TextEdit{
+			Range:   lsp.Range{Start: lsp.Position{Line: common}, End: lsp.Position{Line: last, Character: utf16Len(old[last])}},
+			NewText: strings.Join(lines[common:], lineEnding(text)),
+		})
+	}
+	return edits
+}
+
+// splitLines splits text into its lines without their line endings.
+func splitLines(text string) []string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+// lineEnding returns the line ending text uses: "\r\n" if it has CRLF
+// lines, or "\n".
+func lineEnding(text string) string {
+	if strings.Contains(text, "\r\n") {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// onTypeTriggers are the characters after which the current line is
+// reindented as you type.
+var onTypeTriggers = []string{"}", "\n"}
+
+// TextDocumentOnTypeFormatting reindents the line of the cursor after a
+// closing brace or a newline is typed, so that `}` dedents to its block and
+// a new line inside a block starts at the block's depth. It only touches
+// the leading whitespace of that line, and works on files that do not
+// parse yet.
+func (s *Server) TextDocumentOnTypeFormatting(ctx context.Context, params lsp.DocumentOnTypeFormattingParams) ([]lsp.TextEdit, error) {
+	log.Printf("On-type formatting request received for URI: %s after %q at Line %d", params.TextDocument.URI, params.Ch, params.Position.Line)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	text, ok := s.Documents[filePath]
+	if !ok {
+		return nil, errors.New("Document does not exist for URI: " + string(params.TextDocument.URI))
+	}
+	lines := strings.Split(text, "\n")
+	n := params.Position.Line
+	if !index.IsScriptFile(filePath) && !index.IsGUIFile(filePath) || s.readOnlyMode || s.readOnly(filePath) || s.skipsFormat(filePath) || n >= len(lines) {
+		return []lsp.TextEdit{}, nil
+	}
+	line := strings.TrimSuffix(lines[n], "\r")
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	want := strings.Repeat("\t", pdx.LineDepth(text, n))
+	if indent == want {
+		return []lsp.TextEdit{}, nil
+	}
+	return []lsp.TextEdit{{
+		Range:   lsp.Range{Start: lsp.Position{Line: n}, End: lsp.Position{Line: n, Character: len(indent)}},
+		NewText: want,
+	}}, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// applyEdits applies non-overlapping edits to text as a client does,
+// which clamps the end of a range to the end of its line before the line
+// ending.
+func applyEdits(t *testing.T, text string, edits []lsp.TextEdit) string {
+	t.Helper()
+	edits = append([]lsp.TextEdit(nil), edits...)
+	sort.Slice(edits, func(i, j int) bool {
+		a, b := edits[i].Range.Start, edits[j].Range.Start
+		return a.Line > b.Line || a.Line == b.Line && a.Character > b.Character
+	})
+	for _, edit := range edits {
+		r := edit.Range
+		if lines := splitLines(text); r.End.Line < len(lines) {
+			r.End.Character = min(r.End.Character, utf16Len(lines[r.End.Line]))
+		}
+		var err error
+		text, err = applyContentChanges(text, []lsp.TextDocumentContentChangeEvent{{Range: &r, Text: edit.NewText}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return text
+}
+
+func TestFormatEdits(t *testing.T) {
+	tests := []struct {
+		name, text string
+	}{
+		{"lf", "a=b\nc = {\nd=e\n}\n"},
+		{"crlf", "a=b\r\nc = {\r\nd=e\r\n}\r\n"},
+		{"crlf with blank lines at the end", "a=b\r\nc = {\r\nd=e\r\n}\r\n\r\n\r\n"},
+		{"crlf unchanged", "a = b\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatted, err := pdx.Format(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			edits := formatEdits(tt.text, formatted)
+			for _, edit := range edits {
+				if strings.HasSuffix(edit.NewText, "\r") {
+					t.Errorf("edit %+v ends in a CR", edit)
+				}
+			}
+			if got := applyEdits(t, tt.text, edits); got != formatted {
+				t.Errorf("edited into %q, want %q", got, formatted)
+			}
+		})
+	}
+}
+
+func TestFormatEditsCRLFRange(t *testing.T) {
+	edits := formatEdits("a=b\r\nc = d\r\n", "a = b\r\nc = d\r\n")
+	want := lsp.TextEdit{Range: lsp.Range{End: lsp.Position{Character: 3}}, NewText: "a = b"}
+	if len(edits) != 1 || edits[0] != want {
+		t.Errorf("edits = %+v, want only %+v, ending before the CR", edits, want)
+	}
+}
+
+func TestRangeFormattingCRLF(t *testing.T) {
+	s := NewServer()
+	filePath := "/mods/my_mod/events/my_events.txt"
+	text := "a=b\r\nc = {\r\nd=e\r\nf=g\r\n}\r\n"
+	openDocument(s, filePath, text)
+
+	edits, err := s.TextDocumentRangeFormatting(context.Background(), lsp.DocumentRangeFormattingParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: filePathToURI(filePath)},
+		Range:        lsp.Range{Start: lsp.Position{Line: 2, Character: 1}, End: lsp.Position{Line: 3}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "a=b\r\nc = {\r\n\td = e\r\nf=g\r\n}\r\n"
+	if got := applyEdits(t, text, edits); got != want {
+		t.Errorf("formatted into %q, want only line 2 formatted: %q", got, want)
+	}
+}
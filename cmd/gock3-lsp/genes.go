@@ -0,0 +1,68 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+// quotedContextPattern matches a partially typed quoted bare value.
+var quotedContextPattern = regexp.MustCompile(`"([A-Za-z0-9_]*)$`)
+
+// geneCompletions offers ethnicity names as `ethnicity =`, `template =` and
+// `ethnicities` values, gene names in key position of DNA `genes` blocks and
+// ethnicities, and the templates of the enclosing gene for DNA values and
+// ethnicity `name =` entries. The caller must hold s.mutex.
+func (s *Server) geneCompletions(params lsp.TextDocumentPositionParams) []lsp.CompletionItem {
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil || !index.IsScriptFile(filePath) {
+		return nil
+	}
+	prefix := linePrefix(s.Documents[filePath], params.Position)
+	keys := s.enclosingKeys(filePath, params.Position)
+	inEthnicities := strings.HasPrefix(s.Index.VirtualPath(filePath), "common/ethnicities/")
+	parent := func(up int) string {
+		if up < len(keys) {
+			return keys[len(keys)-1-up]
+		}
+		return ""
+	}
+
+	if m := valueContextPattern.FindStringSubmatch(prefix); m != nil {
+		switch {
+		case m[1] == "ethnicity" || parent(0) == "ethnicities" || (m[1] == "template" && inEthnicities && len(keys) == 1):
+			return s.nameItems(index.KindEthnicity, "", lsp.CIKValue, "ethnicity")
+		case m[1] == "name" && inEthnicities && len(keys) == 3:
+			return s.nameItems(index.KindGeneTemplate, parent(1)+"/", lsp.CIKValue, "template of "+parent(1))
+		}
+		return nil
+	}
+	if quotedContextPattern.MatchString(prefix) && parent(1) == "genes" {
+		return s.nameItems(index.KindGeneTemplate, parent(0)+"/", lsp.CIKValue, "template of "+parent(0))
+	}
+	if keyContextPattern.MatchString(prefix) && (parent(0) == "genes" || (inEthnicities && len(keys) == 1)) {
+		return s.nameItems(index.KindGene, "", lsp.CIKField, "gene")
+	}
+	return nil
+}
+
+// nameItems returns the names of a kind that start with prefix, without
+// the prefix, to be documented by completionItem/resolve. The caller must
+// hold s.mutex.
+func (s *Server) nameItems(kind index.Kind, prefix string, itemKind lsp.CompletionItemKind, detail string) []lsp.CompletionItem {
+	items := []lsp.CompletionItem{}
+	for _, name := range s.Index.Names(kind) {
+		if label, ok := strings.CutPrefix(name, prefix); ok {
+			items = append(items, lsp.CompletionItem{
+				Label:  label,
+				Kind:   itemKind,
+				Detail: s.vanillaDetail(detail),
+				Data:   completionData{Kind: kind, Name: name},
+			})
+		}
+	}
+	return items
+}
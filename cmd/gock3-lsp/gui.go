@@ -0,0 +1,43 @@
+package main
+
+import (
+	"regexp"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/gui"
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+// keyContextPattern matches a partially typed key at the start of a line.
+var keyContextPattern = regexp.MustCompile(`^\s*([A-Za-z0-9_]*)$`)
+
+// guiCompletions offers template names after `using =` and widget type
+// names (built-in and declared with `type`) in key position of .gui files.
+// The caller must hold s.mutex.
+func (s *Server) guiCompletions(params lsp.TextDocumentPositionParams) []lsp.CompletionItem {
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil || !index.IsGUIFile(filePath) {
+		return nil
+	}
+	prefix := linePrefix(s.Documents[filePath], params.Position)
+
+	if m := valueContextPattern.FindStringSubmatch(prefix); m != nil && m[1] == "using" {
+		items := []lsp.CompletionItem{}
+		for _, name := range s.Index.Names(index.KindGUITemplate) {
+			items = append(items, lsp.CompletionItem{Label: name, Kind: lsp.CIKSnippet, Detail: s.vanillaDetail("template")})
+		}
+		return items
+	}
+	if !keyContextPattern.MatchString(prefix) {
+		return nil
+	}
+	items := []lsp.CompletionItem{}
+	for _, name := range gui.Widgets {
+		items = append(items, lsp.CompletionItem{Label: name, Kind: lsp.CIKClass, Detail: "built-in widget"})
+	}
+	for _, name := range s.Index.Names(index.KindGUIType) {
+		items = append(items, lsp.CompletionItem{Label: name, Kind: lsp.CIKClass, Detail: s.vanillaDetail("widget type")})
+	}
+	return items
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// TextDocumentDocumentHighlight highlights the occurrences in the document
+// of the symbol or saved scope under the cursor. Definitions, flags being
+// set and `save_scope_as` are writes; uses, checks and calls are reads.
+func (s *Server) TextDocumentDocumentHighlight(ctx context.Context, params lsp.TextDocumentPositionParams) ([]lsp.DocumentHighlight, error) {
+	log.Printf("Document highlight request received for URI: %s at position Line %d, Character %d",
+		params.TextDocument.URI, params.Position.Line, params.Position.Character)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	highlights := []lsp.DocumentHighlight{}
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return highlights, err
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil {
+		return highlights, nil
+	}
+	add := func(r pdx.Range, kind lsp.DocumentHighlightKind) {
+		highlights = append(highlights, lsp.DocumentHighlight{Range: analysis.Range(r), Kind: int(kind)})
+	}
+
+	if entry.File != nil {
+		pos := pdx.Pos{Line: params.Position.Line, Col: params.Position.Character}
+		if name, _, ok := savedScopeAt(entry.File, pos); ok {
+			pdx.Walk(entry.File.Root, func(f *pdx.Field) bool {
+				if v := savedScopeName(f); v != nil && v.Text == name {
+					add(v.Loc, lsp.Write)
+				}
+				for _, word := range []*pdx.Scalar{f.Key, f.Scalar()} {
+					if word == nil || !strings.Contains(word.Text, "scope:"+name) {
+						continue
+					}
+					for _, ref := range scopeRefs(word) {
+						if ref.name == name {
+							add(ref.rng, lsp.Read)
+						}
+					}
+				}
+				return true
+			})
+			log.Printf("Returning %d highlights for saved scope '%s'.", len(highlights), name)
+			return highlights, nil
+		}
+	}
+
+	kind, name, ok := s.symbolAt(params)
+	if !ok {
+		return highlights, nil
+	}
+	for _, sym := range entry.Symbols {
+		if sym.Kind == kind && sym.Name == name {
+			add(sym.Range, lsp.Write)
+		}
+	}
+	for _, ref := range entry.Refs {
+		if ref.Kind == kind && ref.Name == name {
+			add(ref.Range, lsp.Read)
+		}
+	}
+	for _, ref := range s.Index.ScriptCalls(entry) {
+		if ref.Kind == kind && ref.Name == name {
+			add(ref.Range, lsp.Read)
+		}
+	}
+	log.Printf("Returning %d highlights for %s '%s'.", len(highlights), kind, name)
+	return highlights, nil
+}
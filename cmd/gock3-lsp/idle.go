@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"github.com/creachadair/jrpc2"
+)
+
+// defaultIdleTimeout is the idle time after which resources are released
+// when Settings.IdleTimeout is 0.
+const defaultIdleTimeout = 30 * time.Minute
+
+// heartbeatInterval is how often the server checks whether it is idle.
+const heartbeatInterval = time.Minute
+
+// idleTimeout returns how long the server waits without requests before
+// releasing resources, or 0 if it never does.
+func (s Settings) idleTimeout() time.Duration {
+	switch {
+	case s.IdleTimeout < 0:
+		return 0
+	case s.IdleTimeout == 0:
+		return defaultIdleTimeout
+	}
+	return time.Duration(s.IdleTimeout) * time.Minute
+}
+
+// trackActivity records when each request arrives, and restores what
+// idleness released before handling the first request after it.
+func (s *Server) trackActivity(method string, next jrpc2.Handler) jrpc2.Handler {
+	return func(ctx context.Context, req *jrpc2.Request) (any, error) {
+		s.lastRequest.Store(time.Now().UnixNano())
+		if s.released.Swap(false) {
+			s.restoreResources()
+		}
+		return next(ctx, req)
+	}
+}
+
+// watchIdle is the heartbeat of the server: it checks every
+// heartbeatInterval whether no request arrived for the idle timeout, as
+// when the editor is left open while playing, and then releases the
+// resources that can be rebuilt. It stops when done is closed.
+func (s *Server) watchIdle(done <-chan struct{}) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		last := s.lastRequest.Load()
+		if last == 0 || s.released.Load() {
+			continue
+		}
+		s.mutex.RLock()
+		timeout := s.Settings.idleTimeout()
+		s.mutex.RUnlock()
+		if timeout > 0 && time.Since(time.Unix(0, last)) >= timeout {
+			s.releaseResources(timeout)
+		}
+	}
+}
+
+// releaseResources drops the vanilla index, which holds the syntax trees
+// of every game file, and the caches built from it, then returns the
+// freed memory to the operating system. A vanilla index shared with other
+// sessions is only freed once they all released it. The workspace index and open
+// documents are kept, as they are small and cannot be rebuilt from disk
+// alone. Nothing is released while a scan is running.
+func (s *Server) releaseResources(timeout time.Duration) {
+	s.health.mu.Lock()
+	scanning := s.health.workspaceIndexing || s.health.vanillaIndexing
+	s.health.mu.Unlock()
+	if scanning {
+		return
+	}
+
+	s.mutex.Lock()
+	if time.Since(time.Unix(0, s.lastRequest.Load())) < timeout {
+		// A request arrived while waiting for the lock.
+		s.mutex.Unlock()
+		return
+	}
+	s.releaseVanillaLayer()
+	s.analyzerCache.reset()
+	s.released.Store(true)
+	s.mutex.Unlock()
+
+	debug.FreeOSMemory()
+	log.Println("Idle; released the vanilla index and caches until the next request.")
+	s.updateIndexStatus(func(h *indexHealth) { h.vanillaFiles = 0 })
+}
+
+// restoreResources rebuilds what releaseResources dropped. The vanilla
+// files are indexed in the background, as after changing gamePath, so the
+// request that woke the server is answered from the workspace alone.
+func (s *Server) restoreResources() {
+	s.mutex.RLock()
+	gamePath := s.Settings.GamePath
+	s.mutex.RUnlock()
+	log.Println("Request after idleness; rebuilding the vanilla index.")
+	if gamePath != "" {
+		go s.indexVanilla(gamePath)
+	}
+}
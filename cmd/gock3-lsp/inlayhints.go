@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+	"github.com/unLomTrois/gock3-lsp/scope"
+)
+
+// InlayHintParams asks for the inlay hints of part of a document.
+type InlayHintParams struct {
+	TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+	Range        lsp.Range                  `json:"range"`
+}
+
+// InlayHint is a label shown inline after Position, which go-lsp lacks.
+type InlayHint struct {
+	Position     lsp.Position `json:"position"`
+	Label        string       `json:"label"`
+	Tooltip      string       `json:"tooltip,omitempty"`
+	PaddingLeft  bool         `json:"paddingLeft,omitempty"`
+	PaddingRight bool         `json:"paddingRight,omitempty"`
+}
+
+// TextDocumentInlayHint shows, with inlayHints.scriptValues on, the value
+// of every script value defined or used in the range that works out
+// statically, taking what it reads of the game state from
+// inlayHints.assumptions. The tooltip names the assumptions the value
+// depends on. With inlayHints.scopes on, it shows the scope type inferred
+// at the start of each block that changes scope.
+func (s *Server) TextDocumentInlayHint(ctx context.Context, params InlayHintParams) ([]InlayHint, error) {
+	log.Printf("Inlay hint request received for URI: %s", params.TextDocument.URI)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	hints := []InlayHint{}
+	settings := s.Settings.InlayHints
+	if !settings.ScriptValues && !settings.Scopes {
+		return hints, nil
+	}
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return hints, err
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil {
+		return hints, nil
+	}
+	if settings.Scopes {
+		hints = scopeHints(hints, entry, params.Range)
+	}
+	if !settings.ScriptValues {
+		log.Printf("Returning %d inlay hints.", len(hints))
+		return hints, nil
+	}
+	definitions := make(map[pdx.Range]string)
+	for _, sym := range entry.Symbols {
+		if sym.Kind == index.KindScriptValue {
+			definitions[sym.Range] = sym.Name
+		}
+	}
+	pdx.Walk(entry.File.Root, func(f *pdx.Field) bool {
+		r := analysis.Range(f.Range())
+		if r.End.Line < params.Range.Start.Line || r.Start.Line > params.Range.End.Line {
+			return false
+		}
+		if f.Key != nil {
+			if name, ok := definitions[f.Key.Loc]; ok {
+				hints = s.appendValueHint(hints, name, r.End)
+				return false
+			}
+		}
+		if kind, name, rng, ok := s.Index.CallAt(f); ok && kind == index.KindScriptValue {
+			hints = s.appendValueHint(hints, name, analysis.Range(rng).End)
+		}
+		return true
+	})
+	log.Printf("Returning %d inlay hints.", len(hints))
+	return hints, nil
+}
+
+// appendValueHint adds a hint with the value of the script value name at
+// pos, if it evaluates.
+func (s *Server) appendValueHint(hints []InlayHint, name string, pos lsp.Position) []InlayHint {
+	n, used, ok := analysis.EvalAssuming(s.Index, name, s.Settings.InlayHints.Assumptions)
+	if !ok {
+		return hints
+	}
+	hint := InlayHint{Position: pos, Label: "= " + analysis.FormatNumber(n), PaddingLeft: true}
+	if len(used) > 0 {
+		assumed := make([]string, len(used))
+		for i, a := range used {
+			assumed[i] = fmt.Sprintf("%s = %s", a, analysis.FormatNumber(s.Settings.InlayHints.Assumptions[a]))
+		}
+		hint.Tooltip = "Assuming " + strings.Join(assumed, ", ")
+	}
+	return append(hints, hint)
+}
+
+// scopeHints adds a hint after the opening brace of every block in r that
+// changes scope, such as `every_vassal = {` or `scope:target = {`, with the
+// type `this` has inside it, and of every definition whose root is known.
+// An unknown type shows as "?", which is often the sign of a scope bug.
+func scopeHints(hints []InlayHint, entry *index.FileEntry, r lsp.Range) []InlayHint {
+	tree := scope.Analyze(entry.File, entry.VirtualPath)
+	if tree == nil {
+		return hints
+	}
+	pdx.Walk(entry.File.Root, func(f *pdx.Field) bool {
+		fr := analysis.Range(f.Range())
+		if fr.End.Line < r.Start.Line || fr.Start.Line > r.End.Line {
+			return false
+		}
+		b := f.Block()
+		if b == nil {
+			return false
+		}
+		frame, from := tree.FrameOf(b), tree.Parent(f)
+		if frame == nil || frame == from || from == nil && frame.This == scope.Unknown {
+			return true
+		}
+		start := analysis.Range(b.Loc).Start
+		hint := InlayHint{
+			Position:    lsp.Position{Line: start.Line, Character: start.Character + 1},
+			Label:       typeName(frame.This),
+			PaddingLeft: true,
+		}
+		if frame.Root != scope.Unknown {
+			hint.Tooltip = "root: " + string(frame.Root)
+		}
+		hints = append(hints, hint)
+		return true
+	})
+	return hints
+}
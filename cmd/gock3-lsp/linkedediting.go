@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// LinkedEditingRanges are the ranges edited together, which go-lsp lacks.
+type LinkedEditingRanges struct {
+	Ranges      []lsp.Range `json:"ranges"`
+	WordPattern string      `json:"wordPattern,omitempty"`
+}
+
+// eventIDPattern matches what an event ID may be edited into.
+const eventIDPattern = `[\w.]+`
+
+// TextDocumentLinkedEditingRange links the ID of an event defined in the
+// document with its other occurrences in the document: the localization
+// keys named after it, such as `my_events.0001.t`, `.desc` and the option
+// names, and the events firing it. Only the ID part of the keys is linked,
+// so renaming the event keeps their suffixes.
+func (s *Server) TextDocumentLinkedEditingRange(ctx context.Context, params lsp.TextDocumentPositionParams) (*LinkedEditingRanges, error) {
+	log.Printf("Linked editing range request received for URI: %s at position Line %d, Character %d",
+		params.TextDocument.URI, params.Position.Line, params.Position.Character)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil {
+		return nil, nil
+	}
+	pos := pdx.Pos{Line: params.Position.Line, Col: params.Position.Character}
+	id, ok := linkedEventAt(entry, pos)
+	if !ok {
+		return nil, nil
+	}
+	result := &LinkedEditingRanges{WordPattern: eventIDPattern}
+	for _, word := range scalars(entry.File) {
+		if r, ok := idRange(word, id); ok {
+			result.Ranges = append(result.Ranges, analysis.Range(r))
+		}
+	}
+	log.Printf("Returning %d linked ranges for event '%s'.", len(result.Ranges), id)
+	return result, nil
+}
+
+// linkedEventAt returns the ID of the event defined in the file whose ID
+// part of a word is under pos.
+func linkedEventAt(entry *index.FileEntry, pos pdx.Pos) (string, bool) {
+	for _, word := range scalars(entry.File) {
+		if !word.Loc.Contains(pos) {
+			continue
+		}
+		for _, sym := range entry.Symbols {
+			if sym.Kind != index.KindEvent {
+				continue
+			}
+			// The end of the ID counts, so that typing at it extends the ID.
+			if r, ok := idRange(word, sym.Name); ok && r.Contains(pos) {
+				return sym.Name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// idRange returns the range of id in word, if word is id or a key named
+// after it.
+func idRange(word *pdx.Scalar, id string) (pdx.Range, bool) {
+	if word.Text != id && !strings.HasPrefix(word.Text, id+".") {
+		return pdx.Range{}, false
+	}
+	start := word.Loc.Start
+	if word.Quoted {
+		start.Col++
+	}
+	end := start
+	end.Col += len(id)
+	return pdx.Range{Start: start, End: end}, true
+}
+
+// scalars returns the keys and values of the file.
+func scalars(file *pdx.File) []*pdx.Scalar {
+	var words []*pdx.Scalar
+	pdx.Walk(file.Root, func(f *pdx.Field) bool {
+		for _, word := range []*pdx.Scalar{f.Key, f.Scalar()} {
+			if word != nil {
+				words = append(words, word)
+			}
+		}
+		return true
+	})
+	return words
+}
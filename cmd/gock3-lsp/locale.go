@@ -0,0 +1,33 @@
+package main
+
+import (
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/i18n"
+)
+
+// InitializeParams adds the locale of the client, which go-lsp lacks.
+type InitializeParams struct {
+	lsp.InitializeParams
+	Locale string `json:"locale,omitempty"`
+}
+
+// translate returns msg in the language of the client.
+func (s *Server) translate(msg string) string {
+	return i18n.Translate(s.locale, msg)
+}
+
+// translateDiagnostics translates the messages of diagnostics in place.
+func (s *Server) translateDiagnostics(diagnostics []analysis.Diagnostic) {
+	if i18n.Language(s.locale) == "" {
+		return
+	}
+	for i := range diagnostics {
+		d := &diagnostics[i]
+		d.Message = s.translate(d.Message)
+		for j := range d.RelatedInformation {
+			d.RelatedInformation[j].Message = s.translate(d.RelatedInformation[j].Message)
+		}
+	}
+}
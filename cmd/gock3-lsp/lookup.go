@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// LookupParams are the parameters of gock3/lookup: a province ID, such as
+// "1234", or a landed title key, such as "b_paris" or "c_ile_de_france".
+type LookupParams struct {
+	Query string `json:"query"`
+}
+
+// LookupProvince is a province with the barony holding it, the titles
+// above that barony, and its line of map_data/definition.csv, if found.
+type LookupProvince struct {
+	ID       int           `json:"id"`
+	Name     string        `json:"name,omitempty"`
+	Color    *[3]int       `json:"color,omitempty"`
+	Barony   string        `json:"barony,omitempty"`
+	County   string        `json:"county,omitempty"`
+	Duchy    string        `json:"duchy,omitempty"`
+	Kingdom  string        `json:"kingdom,omitempty"`
+	Empire   string        `json:"empire,omitempty"`
+	Location *lsp.Location `json:"location,omitempty"`
+}
+
+// LookupResult lists the provinces a query resolves to.
+type LookupResult struct {
+	Provinces []LookupProvince `json:"provinces"`
+}
+
+// Lookup handles the gock3/lookup request, which resolves between province
+// IDs and landed titles: a province ID gives the province and its barony,
+// and a title gives every province of the baronies below it.
+func (s *Server) Lookup(ctx context.Context, params LookupParams) (LookupResult, error) {
+	log.Printf("Lookup request received for '%s'.", params.Query)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	query := strings.TrimSpace(params.Query)
+	var baronies []string
+	var ids []int
+	switch {
+	case index.IsProvinceID(query):
+		id, _ := strconv.Atoi(query)
+		ids = []int{id}
+		baronies = []string{s.Index.ProvinceBarony(query)}
+	case index.IsTitleKey(query):
+		fields := s.Index.TitleFields(query)
+		if fields == nil {
+			return LookupResult{}, fmt.Errorf("title '%s' is not defined in common/landed_titles", query)
+		}
+		title := fields[0]
+		if strings.HasPrefix(query, "b_") {
+			baronies = []string{query}
+		}
+		pdx.Walk(title.Block(), func(f *pdx.Field) bool {
+			if strings.HasPrefix(f.KeyText(), "b_") && f.Block() != nil {
+				baronies = append(baronies, f.KeyText())
+			}
+			return f.Block() != nil && index.IsTitleKey(f.KeyText())
+		})
+		for _, barony := range baronies {
+			id := 0
+			if f := s.Index.TitleFields(barony); f != nil {
+				if p := f[0].Block().Get("province"); p != nil {
+					id, _ = strconv.Atoi(p.ValueText())
+				}
+			}
+			ids = append(ids, id)
+		}
+	default:
+		return LookupResult{}, fmt.Errorf("'%s' is neither a province ID nor a landed title", query)
+	}
+
+	definitions := s.provinceDefinitions()
+	result := LookupResult{Provinces: []LookupProvince{}}
+	for i, barony := range baronies {
+		p := LookupProvince{ID: ids[i]}
+		if def, ok := definitions[p.ID]; ok {
+			p.Name, p.Color = def.Name, &def.Color
+		}
+		if barony != "" {
+			s.fillTitles(&p, barony)
+		}
+		result.Provinces = append(result.Provinces, p)
+	}
+	log.Printf("Returning %d provinces.", len(result.Provinces))
+	return result, nil
+}
+
+// fillTitles sets the barony of p, the titles above it and its location.
+func (s *Server) fillTitles(p *LookupProvince, barony string) {
+	for _, f := range s.Index.TitleFields(barony) {
+		switch key := f.KeyText(); key[0] {
+		case 'b':
+			p.Barony = key
+		case 'c':
+			p.County = key
+		case 'd':
+			p.Duchy = key
+		case 'k':
+			p.Kingdom = key
+		case 'e':
+			p.Empire = key
+		}
+	}
+	if defs := s.Index.Definitions(index.KindTitle, barony); len(defs) > 0 {
+		p.Location = &lsp.Location{URI: filePathToURI(defs[0].Path), Range: analysis.Range(defs[0].Range)}
+	}
+}
+
+// provinceDefinitions reads the map_data/definition.csv the game uses: the
+// mod's if it has one, else the vanilla one. It returns nil if neither can
+// be read.
+func (s *Server) provinceDefinitions() map[int]index.ProvinceDefinition {
+	for _, root := range []string{s.RootPath, s.Settings.GamePath} {
+		if root == "" {
+			continue
+		}
+		path := filepath.Join(root, "map_data", "definition.csv")
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if err := s.checkReadable(path, s.Settings); err != nil {
+			log.Printf("Skipping province definitions: %v", err)
+			continue
+		}
+		defs, err := index.ReadProvinceDefinitions(path)
+		if err != nil {
+			log.Printf("Failed to read province definitions from '%s': %v", path, err)
+			continue
+		}
+		return defs
+	}
+	return nil
+}
@@ -3,67 +3,264 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"log"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/creachadair/jrpc2"
 	"github.com/creachadair/jrpc2/channel"
 	"github.com/creachadair/jrpc2/handler"
 	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/gui"
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/loc"
+	"github.com/unLomTrois/gock3-lsp/spell"
 )
 
 // Server encapsulates the state and handlers for the language server.
 type Server struct {
 	jrpcServer *jrpc2.Server
 	mutex      sync.RWMutex
-	DiagFiles  map[string][]lsp.Diagnostic
+	DiagFiles  map[string][]analysis.Diagnostic
 	Documents  map[string]string
+	Index      *index.Index
+	Vanilla    *index.Index
+	RootPath   string
+	Settings   Settings
+	DataTypes  *gui.DataTypes
+	// GameVersion is the version of the installation at Settings.GamePath.
+	GameVersion string
+	// Dictionary is the spell checking dictionary, loaded while the
+	// spelling rule is on.
+	Dictionary *spell.Dictionary
+	// Baselines holds the open English localization files as they were
+	// opened, and Bumped the keys whose version was bumped since.
+	Baselines map[string]*loc.File
+	Bumped    map[string]map[string]bool
+	// health tracks the progress and errors of indexing.
+	health indexHealth
+	// gamePathPrompted is set once the user was asked to configure
+	// gamePath.
+	gamePathPrompted atomic.Bool
+	// detectedGamePath and detectedModsPath are the folders of the local
+	// installation, looked for once.
+	detectOnce       sync.Once
+	detectedGamePath string
+	detectedModsPath string
+	// hierarchicalSymbols is set if the client shows nested document
+	// symbols.
+	hierarchicalSymbols bool
+	// lineFoldingOnly is set if the client folds whole lines only.
+	lineFoldingOnly bool
+	// snippetSupport is set if the client expands snippet completions.
+	snippetSupport bool
+	// applyEdit is set if the client accepts workspace/applyEdit, and
+	// documentChanges if its workspace edits take versioned documentChanges.
+	applyEdit       bool
+	documentChanges bool
+	// locale is the language of the client, which diagnostics and hover
+	// texts are translated into.
+	locale string
+	// plugins holds the paths of the analyzer plugins opened so far, and
+	// analyzerCache the results of the external analyzers.
+	plugins       map[string]bool
+	analyzerCache analyzerCache
+	// initialized is set once the initialize request succeeded, and
+	// calls counts the calls of every method.
+	initialized atomic.Bool
+	calls       handlerMetrics
+	// lastRequest is when the last request arrived, in Unix nanoseconds,
+	// and released is set while idleness released the vanilla index.
+	lastRequest atomic.Int64
+	released    atomic.Bool
+	// vanillaPath is the game folder of the shared vanilla index the
+	// session holds, if any.
+	vanillaPath string
+	// readOnlyMode disables every feature that edits or writes files, for
+	// review tools and web viewers; it is set by --read-only.
+	readOnlyMode bool
+	// diagnosticsChanged is closed and replaced whenever diagnostics are
+	// refreshed, waking pending workspace/diagnostic requests, and
+	// pullingDiagnostics is set once the client pulled them.
+	diagnosticsChanged chan struct{}
+	pullingDiagnostics atomic.Bool
+	// crossFile holds the diagnostics of the cross-file checks of open
+	// documents as of their last save.
+	crossFile map[string][]analysis.Diagnostic
 }
 
 // NewServer initializes a new Server instance with handlers.
 func NewServer() *Server {
 	s := &Server{
-		DiagFiles: make(map[string][]lsp.Diagnostic),
+		DiagFiles: make(map[string][]analysis.Diagnostic),
+		crossFile: make(map[string][]analysis.Diagnostic),
 		Documents: make(map[string]string),
+		Index:     index.New(),
+		Baselines: make(map[string]*loc.File),
+		Bumped:    make(map[string]map[string]bool),
+		plugins:   make(map[string]bool),
+
+		diagnosticsChanged: make(chan struct{}),
 	}
 
 	handlers := handler.Map{
-		"initialize":              handler.New(s.Initialize),
-		"textDocument/completion": handler.New(s.TextDocumentCompletion),
-		"textDocument/didOpen":    handler.New(s.TextDocumentDidOpen),
-		"textDocument/didClose":   handler.New(s.TextDocumentDidClose),
-		"textDocument/didChange":  handler.New(s.TextDocumentDidChange),
-		"textDocument/hover":      handler.New(s.TextDocumentHover),
+		"initialize":                        handler.New(s.Initialize),
+		"initialized":                       handler.New(s.Initialized),
+		"$/cancelRequest":                   handler.New(s.CancelRequest),
+		"textDocument/completion":           handler.New(s.TextDocumentCompletion),
+		"textDocument/didOpen":              handler.New(s.TextDocumentDidOpen),
+		"textDocument/didClose":             handler.New(s.TextDocumentDidClose),
+		"textDocument/didChange":            handler.New(s.TextDocumentDidChange),
+		"textDocument/didSave":              handler.New(s.TextDocumentDidSave),
+		"textDocument/willSaveWaitUntil":    handler.New(s.TextDocumentWillSaveWaitUntil),
+		"textDocument/hover":                handler.New(s.TextDocumentHover),
+		"textDocument/signatureHelp":        handler.New(s.TextDocumentSignatureHelp),
+		"textDocument/definition":           handler.New(s.TextDocumentDefinition),
+		"textDocument/declaration":          handler.New(s.TextDocumentDeclaration),
+		"textDocument/diagnostic":           handler.New(s.TextDocumentDiagnostic),
+		"textDocument/documentHighlight":    handler.New(s.TextDocumentDocumentHighlight),
+		"textDocument/documentLink":         handler.New(s.TextDocumentDocumentLink),
+		"textDocument/references":           handler.New(s.TextDocumentReferences),
+		"textDocument/codeAction":           handler.New(s.TextDocumentCodeAction),
+		"textDocument/codeLens":             handler.New(s.TextDocumentCodeLens),
+		"textDocument/colorPresentation":    handler.New(s.TextDocumentColorPresentation),
+		"textDocument/documentColor":        handler.New(s.TextDocumentDocumentColor),
+		"textDocument/documentSymbol":       handler.New(s.TextDocumentDocumentSymbol),
+		"textDocument/formatting":           handler.New(s.TextDocumentFormatting),
+		"textDocument/rangeFormatting":      handler.New(s.TextDocumentRangeFormatting),
+		"textDocument/onTypeFormatting":     handler.New(s.TextDocumentOnTypeFormatting),
+		"textDocument/foldingRange":         handler.New(s.TextDocumentFoldingRange),
+		"textDocument/inlayHint":            handler.New(s.TextDocumentInlayHint),
+		"textDocument/linkedEditingRange":   handler.New(s.TextDocumentLinkedEditingRange),
+		"textDocument/selectionRange":       handler.New(s.TextDocumentSelectionRange),
+		"textDocument/semanticTokens/full":  handler.New(s.TextDocumentSemanticTokensFull),
+		"textDocument/semanticTokens/range": handler.New(s.TextDocumentSemanticTokensRange),
+		"textDocument/prepareCallHierarchy": handler.New(s.TextDocumentPrepareCallHierarchy),
+		"textDocument/prepareRename":        handler.New(s.TextDocumentPrepareRename),
+		"textDocument/rename":               handler.New(s.TextDocumentRename),
+
+		"callHierarchy/incomingCalls": handler.New(s.CallHierarchyIncomingCalls),
+		"completionItem/resolve":      handler.New(s.CompletionItemResolve),
+		"callHierarchy/outgoingCalls": handler.New(s.CallHierarchyOutgoingCalls),
+		"documentLink/resolve":        handler.New(s.DocumentLinkResolve),
+
+		"workspace/diagnostic":             handler.New(s.WorkspaceDiagnostic),
+		"workspace/didChangeConfiguration": handler.New(s.WorkspaceDidChangeConfiguration),
+		"workspace/executeCommand":         handler.New(s.WorkspaceExecuteCommand),
+		"workspace/symbol":                 handler.New(s.WorkspaceSymbol),
+
+		"gock3/blockPath":      handler.New(s.BlockPath),
+		"gock3/checksumImpact": handler.New(s.ChecksumImpact),
+		"gock3/environment":    handler.New(s.Environment),
+		"gock3/fileRole":       handler.New(s.FileRole),
+		"gock3/handlerMetrics": handler.New(s.HandlerMetrics),
+		"gock3/lookup":         handler.New(s.Lookup),
+		"gock3/simulate":       handler.New(s.Simulate),
+		"gock3/todos":          handler.New(s.Todos),
 	}
 
+	handlers = wrap(handlers, s.middlewares()...)
+
 	s.jrpcServer = jrpc2.NewServer(handlers, &jrpc2.ServerOptions{
-		AllowPush: true,
+		AllowPush:   true,
+		Concurrency: handlerConcurrency(),
 	})
 	return s
 }
 
 // Initialize handles the LSP initialize request.
-func (s *Server) Initialize(ctx context.Context, params lsp.InitializeParams) (lsp.InitializeResult, error) {
+func (s *Server) Initialize(ctx context.Context, params InitializeParams) (InitializeResult, error) {
 	log.Println("Initialize request received.")
 
-	capabilities := lsp.ServerCapabilities{
+	settings, err := parseSettings(params.InitializationOptions)
+	if err != nil {
+		log.Printf("Ignoring invalid initializationOptions: %v", err)
+	}
+	root, err := uriToFilePath(params.Root())
+	s.mutex.Lock()
+	if err == nil && root != "" {
+		s.RootPath = root
+	}
+	s.hierarchicalSymbols = params.Capabilities.TextDocument.DocumentSymbol.HierarchicalDocumentSymbolSupport
+	if folding := params.Capabilities.TextDocument.FoldingRange; folding != nil {
+		s.lineFoldingOnly = folding.LineFoldingOnly
+	}
+	s.snippetSupport = params.Capabilities.TextDocument.Completion.CompletionItem.SnippetSupport
+	s.applyEdit = params.Capabilities.Workspace.ApplyEdit
+	s.documentChanges = params.Capabilities.Workspace.WorkspaceEdit.DocumentChanges
+	s.locale = params.Locale
+	s.applySettings(settings)
+	s.mutex.Unlock()
+
+	if s.RootPath != "" {
+		go s.indexWorkspace(root)
+	} else {
+		log.Println("No workspace root provided; only open documents will be indexed.")
+	}
+
+	capabilities := ServerCapabilities{ServerCapabilities: lsp.ServerCapabilities{
 		TextDocumentSync: &lsp.TextDocumentSyncOptionsOrKind{
 			Options: &lsp.TextDocumentSyncOptions{
-				OpenClose: true,
-				Change:    lsp.TDSKIncremental,
+				OpenClose:         true,
+				Change:            lsp.TDSKIncremental,
+				Save:              &lsp.SaveOptions{},
+				WillSaveWaitUntil: true,
 			},
 		},
 		CompletionProvider: &lsp.CompletionOptions{
-			ResolveProvider:   false,
+			ResolveProvider:   true,
 			TriggerCharacters: []string{"."},
 		},
-		HoverProvider: true,
+		CodeActionProvider:              true,
+		CodeLensProvider:                &lsp.CodeLensOptions{},
+		SignatureHelpProvider:           &lsp.SignatureHelpOptions{TriggerCharacters: []string{"{", " "}},
+		HoverProvider:                   true,
+		DefinitionProvider:              true,
+		ReferencesProvider:              true,
+		DocumentHighlightProvider:       true,
+		DocumentSymbolProvider:          true,
+		WorkspaceSymbolProvider:         true,
+		DocumentFormattingProvider:      true,
+		DocumentRangeFormattingProvider: true,
+		DocumentOnTypeFormattingProvider: &lsp.DocumentOnTypeFormattingOptions{
+			FirstTriggerCharacter: onTypeTriggers[0],
+			MoreTriggerCharacter:  onTypeTriggers[1:],
+		},
+		ExecuteCommandProvider: &lsp.ExecuteCommandOptions{
+			Commands: commandNames(s.readOnlyMode),
+		},
+	}}
+	capabilities.RenameProvider = &RenameOptions{PrepareProvider: true}
+	capabilities.DeclarationProvider = true
+	capabilities.FoldingRangeProvider = true
+	capabilities.InlayHintProvider = true
+	capabilities.SelectionRangeProvider = true
+	capabilities.CallHierarchyProvider = true
+	capabilities.LinkedEditingRangeProvider = true
+	capabilities.DocumentLinkProvider = &DocumentLinkOptions{ResolveProvider: true}
+	capabilities.ColorProvider = true
+	capabilities.DiagnosticProvider = &DiagnosticOptions{InterFileDependencies: true, WorkspaceDiagnostics: true}
+	capabilities.SemanticTokensProvider = &SemanticTokensOptions{Legend: semanticTokensLegend, Range: true, Full: true}
+	if s.readOnlyMode {
+		// Nothing that edits documents is offered.
+		capabilities.CodeActionProvider = false
+		capabilities.DocumentFormattingProvider = false
+		capabilities.DocumentRangeFormattingProvider = false
+		capabilities.DocumentOnTypeFormattingProvider = nil
+		capabilities.TextDocumentSync.Options.WillSaveWaitUntil = false
+		capabilities.RenameProvider = nil
+		log.Println("Running in read-only mode; edits are disabled.")
 	}
 
 	log.Println("Initialization complete. Server capabilities set.")
-	return lsp.InitializeResult{
+	return InitializeResult{
 		Capabilities: capabilities,
 	}, nil
 }
@@ -73,14 +270,37 @@ func (s *Server) TextDocumentCompletion(ctx context.Context, params lsp.Completi
 	log.Printf("Completion request received for URI: %s at position Line %d, Character %d",
 		params.TextDocument.URI, params.Position.Line, params.Position.Character)
 
-	// Example completion item; extend as needed.
-	items := []lsp.CompletionItem{
-		{
-			Label:         "namespace",
-			Kind:          lsp.CIKText,
-			Detail:        "Namespace of events",
-			Documentation: "https://ck3.paradoxwikis.com/Event_modding",
-		},
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var items []lsp.CompletionItem
+	for _, provider := range []func(lsp.TextDocumentPositionParams) []lsp.CompletionItem{
+		s.commentCompletions,
+		s.topLevelCompletions,
+		s.flagCompletions,
+		s.guiCompletions,
+		s.conceptCompletions,
+		s.geneCompletions,
+		s.chainCompletions,
+		s.referenceCompletions,
+		s.targetCompletions,
+	} {
+		if items = provider(params.TextDocumentPositionParams); items != nil {
+			break
+		}
+	}
+	// Snippets complement the items of the other providers.
+	items = append(items, s.snippetCompletions(params.TextDocumentPositionParams)...)
+	if items == nil {
+		// Example completion item; extend as needed.
+		items = []lsp.CompletionItem{
+			{
+				Label:         "namespace",
+				Kind:          lsp.CIKText,
+				Detail:        "Namespace of events",
+				Documentation: "https://ck3.paradoxwikis.com/Event_modding",
+			},
+		}
 	}
 
 	log.Printf("Returning %d completion items.", len(items))
@@ -101,15 +321,25 @@ func (s *Server) TextDocumentDidOpen(ctx context.Context, params lsp.DidOpenText
 		log.Printf("Invalid URI '%s' in DidOpen: %v", uri, err)
 		return err
 	}
+	if err := s.checkReadable(filePath, s.Settings); err != nil {
+		log.Printf("Ignoring document in DidOpen: %v", err)
+		return nil
+	}
 
 	log.Printf("Opening document: %s", filePath)
 
 	// Store the document content in memory.
 	s.Documents[filePath] = params.TextDocument.Text
+	if !index.IsIndexable(filePath) {
+		log.Printf("Not indexing document of role '%s': %s", s.Index.RoleOf(filePath).Role, filePath)
+		return nil
+	}
+	s.Index.UpdateFile(filePath, params.TextDocument.Text)
+	s.versionBaseline(filePath)
 	log.Printf("Stored content for document: %s (Length: %d characters)", filePath, len(params.TextDocument.Text))
 
 	// Get diagnostics for the opened file.
-	diagnostics := s.GetDiagnostics(filePath)
+	diagnostics := s.validate(filePath)
 	s.DiagFiles[filePath] = diagnostics
 	log.Printf("Generated %d diagnostics for document: %s", len(diagnostics), filePath)
 
@@ -119,6 +349,8 @@ func (s *Server) TextDocumentDidOpen(ctx context.Context, params lsp.DidOpenText
 		return err
 	}
 	log.Printf("Published diagnostics for document: %s", filePath)
+
+	s.refreshDiagnostics(ctx, filePath)
 	return nil
 }
 
@@ -133,6 +365,10 @@ func (s *Server) TextDocumentDidChange(ctx context.Context, params lsp.DidChange
 		log.Printf("Invalid URI '%s' in DidChange: %v", uri, err)
 		return err
 	}
+	if err := s.checkReadable(filePath, s.Settings); err != nil {
+		log.Printf("Ignoring document in DidChange: %v", err)
+		return nil
+	}
 
 	log.Printf("Changing document: %s", filePath)
 
@@ -142,16 +378,32 @@ func (s *Server) TextDocumentDidChange(ctx context.Context, params lsp.DidChange
 		return nil // No changes to apply.
 	}
 
-	change := params.ContentChanges[0]
 	previousLength := len(s.Documents[filePath])
-	s.Documents[filePath] = change.Text
-	newLength := len(change.Text)
+	text, err := applyContentChanges(s.Documents[filePath], params.ContentChanges)
+	if err != nil {
+		log.Printf("Failed to apply changes to document: %s - Error: %v", filePath, err)
+		return err
+	}
+	s.Documents[filePath] = text
+	if !index.IsIndexable(filePath) {
+		return nil
+	}
+	s.Index.UpdateFile(filePath, text)
+	s.bumpVersions(filePath, params.TextDocument.Version)
+	newLength := len(text)
 	log.Printf("Applied change to document: %s (Previous Length: %d, New Length: %d)", filePath, previousLength, newLength)
 
-	// Get updated diagnostics.
-	diagnostics := s.GetDiagnostics(filePath)
+	// Get updated diagnostics. The cross-file checks are left for the
+	// next save, keeping those of the last one in the meantime.
+	s.shiftCrossFile(filePath, params.ContentChanges)
+	diagnostics := s.diagnose(filePath, s.crossFile[filePath])
 	s.DiagFiles[filePath] = diagnostics
 	log.Printf("Generated %d updated diagnostics for document: %s", len(diagnostics), filePath)
+	// The edit may change the diagnostics of the files that are not open,
+	// which workspace/diagnostic reports once the edits settle.
+	if s.pullingDiagnostics.Load() {
+		s.signalDiagnostics()
+	}
 
 	// Publish updated diagnostics.
 	if err := s.publishDiagnostics(ctx, uri, diagnostics); err != nil {
@@ -173,14 +425,38 @@ func (s *Server) TextDocumentDidClose(ctx context.Context, params lsp.DidCloseTe
 		log.Printf("Invalid URI '%s' in DidClose: %v", uri, err)
 		return err
 	}
+	if err := s.checkReadable(filePath, s.Settings); err != nil {
+		log.Printf("Ignoring document in DidClose: %v", err)
+		return nil
+	}
 
 	log.Printf("Closing document: %s", filePath)
 
 	// Remove diagnostics and document content.
 	delete(s.DiagFiles, filePath)
+	delete(s.crossFile, filePath)
 	delete(s.Documents, filePath)
+	delete(s.Baselines, filePath)
+	delete(s.Bumped, filePath)
 	log.Printf("Removed diagnostics and content for document: %s", filePath)
 
+	// Unsaved edits are discarded on close, so fall back to the file on disk.
+	// Files outside the workspace are not part of the mod and only stay in
+	// the vanilla layer, if at all.
+	if data, err := os.ReadFile(filePath); err == nil && index.IsIndexable(filePath) && !s.readOnly(filePath) {
+		s.Index.UpdateFile(filePath, string(data))
+	} else {
+		s.Index.RemoveFile(filePath)
+	}
+
+	// A closed file is left to workspace/diagnostic, when the client pulls
+	// them, so its pushed diagnostics are cleared.
+	if s.pullingDiagnostics.Load() {
+		if err := s.publishDiagnostics(ctx, uri, []analysis.Diagnostic{}); err != nil {
+			log.Printf("Failed to clear diagnostics for document: %s", filePath)
+		}
+		s.signalDiagnostics()
+	}
 	return nil
 }
 
@@ -205,6 +481,39 @@ func (s *Server) TextDocumentHover(ctx context.Context, params lsp.TextDocumentP
 		return lsp.Hover{}, errors.New(errMsg)
 	}
 
+	if hover := s.descHover(filePath, params.Position); hover != nil {
+		log.Printf("Providing description hover in document: %s", filePath)
+		return *hover, nil
+	}
+	if hover := s.defineHover(filePath, params.Position); hover != nil {
+		log.Printf("Providing define hover in document: %s", filePath)
+		return *hover, nil
+	}
+	if hover := s.defineValueHover(filePath, params.Position); hover != nil {
+		log.Printf("Providing define value hover in document: %s", filePath)
+		return *hover, nil
+	}
+	if hover := s.onActionHover(filePath, params.Position); hover != nil {
+		log.Printf("Providing on_action hover in document: %s", filePath)
+		return *hover, nil
+	}
+	if hover := s.sourceHover(filePath, params.Position); hover != nil {
+		log.Printf("Providing definition hover in document: %s", filePath)
+		return *hover, nil
+	}
+	if hover := s.docHover(filePath, params.Position); hover != nil {
+		log.Printf("Providing doc comment hover in document: %s", filePath)
+		return *hover, nil
+	}
+	if hover := s.targetHover(filePath, params.Position); hover != nil {
+		log.Printf("Providing event target hover in document: %s", filePath)
+		return *hover, nil
+	}
+	if hover := s.scopeHover(filePath, params.Position); hover != nil {
+		log.Printf("Providing scope hover in document: %s", filePath)
+		return *hover, nil
+	}
+
 	// Get the specific line.
 	lines := strings.Split(content, "\n")
 	if params.Position.Line >= len(lines) {
@@ -248,19 +557,33 @@ func (s *Server) TextDocumentHover(ctx context.Context, params lsp.TextDocumentP
 	}, nil
 }
 
-// Start runs the language server.
+// Start runs the language server over standard input and output.
 func (s *Server) Start() error {
+	return s.serve(channel.Header("")(os.Stdin, os.Stdout))
+}
+
+// serve runs the language server over ch until the client disconnects,
+// then releases the session's share of the vanilla index.
+func (s *Server) serve(ch channel.Channel) error {
 	log.Println("Starting Language Server...")
-	s.jrpcServer.Start(channel.Header("")(os.Stdin, os.Stdout))
+	s.jrpcServer.Start(ch)
+	done := make(chan struct{})
+	go s.watchIdle(done)
 	log.Println("Language Server started successfully.")
-	return s.jrpcServer.Wait()
+	err := s.jrpcServer.Wait()
+	close(done)
+
+	s.mutex.Lock()
+	s.releaseVanillaLayer()
+	s.mutex.Unlock()
+	return err
 }
 
 // publishDiagnostics sends diagnostics to the client.
-func (s *Server) publishDiagnostics(ctx context.Context, uri lsp.DocumentURI, diagnostics []lsp.Diagnostic) error {
+func (s *Server) publishDiagnostics(ctx context.Context, uri lsp.DocumentURI, diagnostics []analysis.Diagnostic) error {
 	// No shared resources are accessed here, so no mutex is needed.
 	log.Printf("Publishing %d diagnostics for URI: %s", len(diagnostics), uri)
-	params := lsp.PublishDiagnosticsParams{
+	params := PublishDiagnosticsParams{
 		URI:         uri,
 		Diagnostics: diagnostics,
 	}
@@ -272,12 +595,57 @@ func (s *Server) publishDiagnostics(ctx context.Context, uri lsp.DocumentURI, di
 	return nil
 }
 
+// PublishDiagnosticsParams are the parameters of
+// textDocument/publishDiagnostics, with diagnostics that may carry related
+// information.
+type PublishDiagnosticsParams struct {
+	URI         lsp.DocumentURI       `json:"uri"`
+	Diagnostics []analysis.Diagnostic `json:"diagnostics"`
+}
+
 // GetDiagnostics generates diagnostics for a given file.
-// TODO: Implement actual diagnostic logic.
-func (s *Server) GetDiagnostics(filePath string) []lsp.Diagnostic {
-	// Placeholder: Return no diagnostics.
-	log.Printf("Generating diagnostics for document: %s (Placeholder implementation)", filePath)
-	return []lsp.Diagnostic{}
+func (s *Server) GetDiagnostics(filePath string) []analysis.Diagnostic {
+	return s.diagnose(filePath, nil)
+}
+
+// diagnose generates diagnostics for a given file, reporting crossFile
+// instead of running the cross-file checks if it is not nil.
+func (s *Server) diagnose(filePath string, crossFile []analysis.Diagnostic) []analysis.Diagnostic {
+	log.Printf("Generating diagnostics for document: %s", filePath)
+	entry := s.Index.File(filePath)
+	if entry == nil {
+		return []analysis.Diagnostic{}
+	}
+	env := s.analysisEnv()
+	env.CrossFile = crossFile
+	diagnostics := analysis.Run(entry, env)
+	if diagnostics == nil {
+		return []analysis.Diagnostic{}
+	}
+	if s.readOnly(filePath) {
+		diagnostics = s.externalDiagnostics(diagnostics)
+	}
+	s.translateDiagnostics(diagnostics)
+	return diagnostics
+}
+
+// analysisEnv returns the environment the checks run in.
+func (s *Server) analysisEnv() *analysis.Env {
+	env := &analysis.Env{
+		Index:       s.Index,
+		Options:     &s.Settings.Diagnostics,
+		DataTypes:   s.DataTypes,
+		GameVersion: s.GameVersion,
+		Dictionary:  s.Dictionary,
+		Analyzers:   s.externalAnalyzers(),
+	}
+	if len(s.Settings.Generated) > 0 {
+		env.Generated = make(map[string][]string, len(s.Settings.Generated))
+		for tool, g := range s.Settings.Generated {
+			env.Generated[tool] = g.SkipRules
+		}
+	}
+	return env
 }
 
 // uriToFilePath converts a file URI to a local file path.
@@ -285,11 +653,24 @@ func uriToFilePath(uri lsp.DocumentURI) (string, error) {
 	if !strings.HasPrefix(string(uri), "file://") {
 		return "", errors.New("unsupported URI scheme")
 	}
-	filePath := strings.TrimPrefix(string(uri), "file://")
+	filePath, err := url.PathUnescape(strings.TrimPrefix(string(uri), "file://"))
+	if err != nil {
+		return "", err
+	}
+	// Windows URIs look like file:///c:/path; drop the slash before the drive.
+	if len(filePath) >= 3 && filePath[0] == '/' && filePath[2] == ':' {
+		filePath = filePath[1:]
+	}
+	filePath = filepath.FromSlash(filePath)
 	log.Printf("Converted URI '%s' to file path '%s'", uri, filePath)
 	return filePath, nil
 }
 
+// filePathToURI converts a local file path to a file URI.
+func filePathToURI(filePath string) lsp.DocumentURI {
+	return analysis.FileURI(filePath)
+}
+
 // extractWord extracts the word at the given character position.
 func extractWord(line string, character int) (string, error) {
 	if character > len(line) {
@@ -325,7 +706,18 @@ func main() {
 	// Set up logging to include date and time.
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
+	if len(os.Args) > 1 && os.Args[1] == "package" {
+		os.Exit(runPackage(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		os.Exit(runServe(os.Args[2:]))
+	}
+	fs := flag.NewFlagSet("gock3-lsp", flag.ExitOnError)
+	readOnly := fs.Bool("read-only", false, "disable every feature that edits or writes files")
+	fs.Parse(os.Args[1:])
+
 	server := NewServer()
+	server.readOnlyMode = *readOnly
 	log.Println("Initializing Language Server...")
 	if err := server.Start(); err != nil {
 		log.Fatalf("Server exited with error: %v", err)
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+// FileMetrics describes the size of a workspace file and how much it
+// defines and uses.
+type FileMetrics struct {
+	URI         lsp.DocumentURI `json:"uri"`
+	Path        string          `json:"path"`
+	Bytes       int             `json:"bytes"`
+	Lines       int             `json:"lines"`
+	Definitions int             `json:"definitions"`
+	References  int             `json:"references"`
+}
+
+// SymbolMetrics counts the uses of a symbol defined in the workspace.
+type SymbolMetrics struct {
+	Kind index.Kind      `json:"kind"`
+	Name string          `json:"name"`
+	URI  lsp.DocumentURI `json:"uri"`
+	// Path is the virtual path of the file defining the symbol.
+	Path string `json:"path"`
+	Uses int    `json:"uses"`
+}
+
+// Metrics is the result of gock3.exportMetrics: the workspace files from
+// largest to smallest and its symbols from most to least used.
+type Metrics struct {
+	Files   []FileMetrics   `json:"files"`
+	Symbols []SymbolMetrics `json:"symbols"`
+}
+
+// exportMetricsCommand runs gock3.exportMetrics. The optional arguments are
+// the format, "json" (default) or "csv", and a path to write the export to;
+// without a path the export is returned.
+func (s *Server) exportMetricsCommand(ctx context.Context, args []interface{}) (interface{}, error) {
+	format, output := stringArg(args, 0), stringArg(args, 1)
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		return nil, fmt.Errorf("unknown format %q; expected json or csv", format)
+	}
+
+	s.mutex.RLock()
+	metrics := s.metrics()
+	s.mutex.RUnlock()
+
+	if format == "json" && output == "" {
+		return metrics, nil
+	}
+	var data []byte
+	var err error
+	if format == "json" {
+		data, err = json.MarshalIndent(metrics, "", "  ")
+	} else {
+		data, err = metrics.CSV()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if output == "" {
+		return string(data), nil
+	}
+	if err := os.WriteFile(output, data, 0o644); err != nil {
+		return nil, err
+	}
+	return map[string]string{"output": output}, nil
+}
+
+// metrics gathers the usage metrics of the workspace files. The caller
+// must hold s.mutex.
+func (s *Server) metrics() *Metrics {
+	type key struct {
+		kind index.Kind
+		name string
+	}
+	uses := map[key]int{}
+	symbols := map[key]SymbolMetrics{}
+	metrics := &Metrics{Files: []FileMetrics{}, Symbols: []SymbolMetrics{}}
+	for _, path := range s.Index.Paths() {
+		entry := s.Index.File(path)
+		if entry == nil {
+			continue
+		}
+		refs := append(append([]index.Reference(nil), entry.Refs...), s.Index.ScriptCalls(entry)...)
+		for _, ref := range refs {
+			uses[key{ref.Kind, ref.Name}]++
+		}
+		for _, sym := range entry.Symbols {
+			if _, ok := symbols[key{sym.Kind, sym.Name}]; !ok {
+				symbols[key{sym.Kind, sym.Name}] = SymbolMetrics{Kind: sym.Kind, Name: sym.Name, URI: filePathToURI(path), Path: entry.VirtualPath}
+			}
+		}
+		text := s.fileText(entry)
+		metrics.Files = append(metrics.Files, FileMetrics{
+			URI:         filePathToURI(path),
+			Path:        entry.VirtualPath,
+			Bytes:       len(text),
+			Lines:       lineCount(text),
+			Definitions: len(entry.Symbols),
+			References:  len(refs),
+		})
+	}
+	for k, sym := range symbols {
+		sym.Uses = uses[k]
+		metrics.Symbols = append(metrics.Symbols, sym)
+	}
+	sort.Slice(metrics.Files, func(i, j int) bool {
+		a, b := metrics.Files[i], metrics.Files[j]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return a.Path < b.Path
+	})
+	sort.Slice(metrics.Symbols, func(i, j int) bool {
+		a, b := metrics.Symbols[i], metrics.Symbols[j]
+		if a.Uses != b.Uses {
+			return a.Uses > b.Uses
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return metrics
+}
+
+// fileText returns the current text of an indexed file.
+func (s *Server) fileText(entry *index.FileEntry) string {
+	if text, ok := s.Documents[entry.Path]; ok {
+		return text
+	}
+	if entry.File != nil {
+		return entry.File.Text
+	}
+	data, _ := os.ReadFile(entry.Path)
+	return string(data)
+}
+
+// lineCount returns the number of lines of text, not counting an empty
+// line after the final newline.
+func lineCount(text string) int {
+	n := strings.Count(text, "\n")
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		n++
+	}
+	return n
+}
+
+// CSV renders the metrics as one table, with a "file" row per file and a
+// "symbol" row per symbol.
+func (m *Metrics) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"type", "path", "kind", "name", "uses", "bytes", "lines", "definitions", "references"})
+	for _, f := range m.Files {
+		w.Write([]string{"file", f.Path, "", "", "", strconv.Itoa(f.Bytes), strconv.Itoa(f.Lines), strconv.Itoa(f.Definitions), strconv.Itoa(f.References)})
+	}
+	for _, sym := range m.Symbols {
+		w.Write([]string{"symbol", sym.Path, string(sym.Kind), sym.Name, strconv.Itoa(sym.Uses), "", "", "", ""})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
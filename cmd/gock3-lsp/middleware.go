@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/jrpc2/handler"
+	lsp "github.com/sourcegraph/go-lsp"
+)
+
+// LSP error codes that jrpc2 does not define.
+const (
+	codeServerNotInitialized jrpc2.Code = -32002
+	codeRequestCancelled     jrpc2.Code = -32800
+)
+
+// Middleware wraps the handler of a method with a concern shared by every
+// method, such as logging or panic recovery.
+type Middleware func(method string, next jrpc2.Handler) jrpc2.Handler
+
+// wrap applies the middlewares to every handler, the first one outermost.
+func wrap(handlers handler.Map, middlewares ...Middleware) handler.Map {
+	wrapped := make(handler.Map, len(handlers))
+	for method, h := range handlers {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			h = middlewares[i](method, h)
+		}
+		wrapped[method] = h
+	}
+	return wrapped
+}
+
+// middlewares returns the middlewares of every handler, the first one
+// outermost: each request is logged, timed, guarded against panics and
+// cancellation, held back until the server is initialized, and wakes the
+// server from idleness.
+func (s *Server) middlewares() []Middleware {
+	return []Middleware{logRequests, s.recordMetrics, recoverPanics, checkCancelled, s.requireInitialized, s.trackActivity}
+}
+
+// logRequests logs how long each request took and the error it failed
+// with.
+func logRequests(method string, next jrpc2.Handler) jrpc2.Handler {
+	return func(ctx context.Context, req *jrpc2.Request) (any, error) {
+		start := time.Now()
+		result, err := next(ctx, req)
+		if err != nil {
+			log.Printf("%s failed after %v: %v", method, time.Since(start).Round(time.Microsecond), err)
+		} else if !req.IsNotification() {
+			log.Printf("%s answered in %v.", method, time.Since(start).Round(time.Microsecond))
+		}
+		return result, err
+	}
+}
+
+// recoverPanics turns a panic in a handler into an internal error, so that
+// a bug in one feature does not bring the whole server down.
+func recoverPanics(method string, next jrpc2.Handler) jrpc2.Handler {
+	return func(ctx context.Context, req *jrpc2.Request) (result any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Recovered from a panic in %s: %v\n%s", method, r, debug.Stack())
+				result, err = nil, jrpc2.Errorf(jrpc2.InternalError, "%s failed: %v", method, r)
+			}
+		}()
+		return next(ctx, req)
+	}
+}
+
+// checkCancelled skips requests the client cancelled before they ran, and
+// reports requests cancelled while running with the LSP error code instead
+// of a partial result.
+func checkCancelled(method string, next jrpc2.Handler) jrpc2.Handler {
+	return func(ctx context.Context, req *jrpc2.Request) (any, error) {
+		if ctx.Err() != nil {
+			return nil, jrpc2.Errorf(codeRequestCancelled, "%s was cancelled", method)
+		}
+		result, err := next(ctx, req)
+		if !req.IsNotification() && (errors.Is(err, context.Canceled) || ctx.Err() != nil) {
+			return nil, jrpc2.Errorf(codeRequestCancelled, "%s was cancelled", method)
+		}
+		return result, err
+	}
+}
+
+// minConcurrency is the least number of handlers run at once. jrpc2 runs as
+// many as there are CPUs, so with a single CPU a $/cancelRequest would wait
+// for the request it cancels to finish.
+const minConcurrency = 8
+
+// handlerConcurrency returns the number of handlers the server runs at
+// once.
+func handlerConcurrency() int {
+	return max(runtime.NumCPU(), minConcurrency)
+}
+
+// requireInitialized rejects requests before the initialize request
+// succeeded, as the LSP specification asks, and drops notifications.
+func (s *Server) requireInitialized(method string, next jrpc2.Handler) jrpc2.Handler {
+	if method == "initialize" {
+		return func(ctx context.Context, req *jrpc2.Request) (any, error) {
+			result, err := next(ctx, req)
+			if err == nil {
+				s.initialized.Store(true)
+			}
+			return result, err
+		}
+	}
+	return func(ctx context.Context, req *jrpc2.Request) (any, error) {
+		if !s.initialized.Load() {
+			if req.IsNotification() {
+				log.Printf("Dropping %s received before initialize.", method)
+				return nil, nil
+			}
+			return nil, jrpc2.Errorf(codeServerNotInitialized, "%s received before initialize", method)
+		}
+		return next(ctx, req)
+	}
+}
+
+// CancelParams is the body of the $/cancelRequest notification.
+type CancelParams struct {
+	ID json.RawMessage `json:"id"`
+}
+
+// CancelRequest cancels the context of a running request, so that
+// handlers checking it and the checkCancelled middleware stop early.
+func (s *Server) CancelRequest(ctx context.Context, params CancelParams) error {
+	if len(params.ID) != 0 {
+		s.jrpcServer.CancelRequest(string(params.ID))
+	}
+	return nil
+}
+
+// MethodMetrics are the counters of one method kept by the recordMetrics
+// middleware.
+type MethodMetrics struct {
+	Method string `json:"method"`
+	Calls  int    `json:"calls"`
+	Errors int    `json:"errors"`
+	// TotalMillis and MaxMillis are the total and the longest time spent
+	// answering.
+	TotalMillis float64 `json:"totalMillis"`
+	MaxMillis   float64 `json:"maxMillis"`
+}
+
+// handlerMetrics collects the MethodMetrics of every method. It has its
+// own lock because handlers run concurrently.
+type handlerMetrics struct {
+	mu      sync.Mutex
+	methods map[string]*MethodMetrics
+}
+
+// recordMetrics counts the calls, errors and latency of each method.
+func (s *Server) recordMetrics(method string, next jrpc2.Handler) jrpc2.Handler {
+	return func(ctx context.Context, req *jrpc2.Request) (any, error) {
+		start := time.Now()
+		result, err := next(ctx, req)
+		elapsed := float64(time.Since(start).Microseconds()) / 1000
+
+		m := &s.calls
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.methods == nil {
+			m.methods = make(map[string]*MethodMetrics)
+		}
+		stats := m.methods[method]
+		if stats == nil {
+			stats = &MethodMetrics{Method: method}
+			m.methods[method] = stats
+		}
+		stats.Calls++
+		if err != nil {
+			stats.Errors++
+		}
+		stats.TotalMillis += elapsed
+		stats.MaxMillis = max(stats.MaxMillis, elapsed)
+		return result, err
+	}
+}
+
+// HandlerMetrics answers gock3/handlerMetrics with the counters of every
+// method called so far, sorted by method.
+func (s *Server) HandlerMetrics(ctx context.Context, params lsp.None) ([]MethodMetrics, error) {
+	m := &s.calls
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	metrics := []MethodMetrics{}
+	for _, stats := range m.methods {
+		metrics = append(metrics, *stats)
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Method < metrics[j].Method })
+	return metrics, nil
+}
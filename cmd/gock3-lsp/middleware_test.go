@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/creachadair/jrpc2"
+	"github.com/creachadair/jrpc2/channel"
+	"github.com/creachadair/jrpc2/handler"
+	lsp "github.com/sourcegraph/go-lsp"
+)
+
+// startMiddlewareServer serves handlers wrapped in the middlewares of s,
+// with the initialize, $/cancelRequest and gock3/handlerMetrics handlers of
+// s, and returns a client connected to it.
+func startMiddlewareServer(t *testing.T, s *Server, handlers handler.Map) *jrpc2.Client {
+	t.Helper()
+	handlers["initialize"] = handler.New(func(ctx context.Context, params lsp.InitializeParams) (lsp.InitializeResult, error) {
+		return lsp.InitializeResult{}, nil
+	})
+	handlers["$/cancelRequest"] = handler.New(s.CancelRequest)
+	handlers["gock3/handlerMetrics"] = handler.New(s.HandlerMetrics)
+	cch, sch := channel.Direct()
+	s.jrpcServer = jrpc2.NewServer(wrap(handlers, s.middlewares()...), &jrpc2.ServerOptions{
+		Concurrency: handlerConcurrency(),
+	}).Start(sch)
+	client := jrpc2.NewClient(cch, nil)
+	t.Cleanup(func() {
+		client.Close()
+		s.jrpcServer.Wait()
+	})
+	return client
+}
+
+// errorCode returns the JSON-RPC error code of err, or 0.
+func errorCode(err error) jrpc2.Code {
+	var e *jrpc2.Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return 0
+}
+
+func TestRequireInitialized(t *testing.T) {
+	s := NewServer()
+	notified := make(chan struct{}, 1)
+	client := startMiddlewareServer(t, s, handler.Map{
+		"gock3/echo": handler.New(func(ctx context.Context, params []string) ([]string, error) {
+			return params, nil
+		}),
+		"gock3/notify": handler.New(func(ctx context.Context) error {
+			notified <- struct{}{}
+			return nil
+		}),
+	})
+	ctx := context.Background()
+
+	_, err := client.Call(ctx, "gock3/echo", []string{"x"})
+	if errorCode(err) != codeServerNotInitialized {
+		t.Fatalf("request before initialize: error = %v, want code %d", err, codeServerNotInitialized)
+	}
+	if err := client.Notify(ctx, "gock3/notify", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Call(ctx, "initialize", lsp.InitializeParams{}); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	if err := client.CallResult(ctx, "gock3/echo", []string{"x"}, &got); err != nil || len(got) != 1 || got[0] != "x" {
+		t.Fatalf("request after initialize = %q, %v", got, err)
+	}
+	select {
+	case <-notified:
+		t.Error("the notification sent before initialize was handled")
+	default:
+	}
+	if err := client.Notify(ctx, "gock3/notify", nil); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-notified:
+	case <-time.After(5 * time.Second):
+		t.Error("the notification sent after initialize was not handled")
+	}
+}
+
+func TestRecoverPanics(t *testing.T) {
+	s := NewServer()
+	client := startMiddlewareServer(t, s, handler.Map{
+		"gock3/boom": handler.New(func(ctx context.Context) (string, error) {
+			var m map[string]int
+			m["x"]++
+			return "", nil
+		}),
+		"gock3/ok": handler.New(func(ctx context.Context) (string, error) {
+			return "ok", nil
+		}),
+	})
+	ctx := context.Background()
+	if _, err := client.Call(ctx, "initialize", lsp.InitializeParams{}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := client.Call(ctx, "gock3/boom", nil)
+	if errorCode(err) != jrpc2.InternalError || !strings.Contains(err.Error(), "gock3/boom failed") {
+		t.Fatalf("panicking request: error = %v, want an internal error", err)
+	}
+	// The server keeps running and counts the failure.
+	var got string
+	if err := client.CallResult(ctx, "gock3/ok", nil, &got); err != nil || got != "ok" {
+		t.Fatalf("request after the panic = %q, %v", got, err)
+	}
+	var metrics []MethodMetrics
+	if err := client.CallResult(ctx, "gock3/handlerMetrics", nil, &metrics); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range metrics {
+		if m.Method == "gock3/boom" && (m.Calls != 1 || m.Errors != 1) {
+			t.Errorf("metrics of gock3/boom = %+v, want 1 call and 1 error", m)
+		}
+	}
+}
+
+func TestCancelRequest(t *testing.T) {
+	s := NewServer()
+	started := make(chan string, 1)
+	client := startMiddlewareServer(t, s, handler.Map{
+		"gock3/slow": handler.New(func(ctx context.Context) (string, error) {
+			started <- jrpc2.InboundRequest(ctx).ID()
+			select {
+			case <-ctx.Done():
+				// A partial result, which the middleware replaces.
+				return "partial", nil
+			case <-time.After(5 * time.Second):
+				return "done", nil
+			}
+		}),
+	})
+	ctx := context.Background()
+	if _, err := client.Call(ctx, "initialize", lsp.InitializeParams{}); err != nil {
+		t.Fatal(err)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := client.Call(ctx, "gock3/slow", nil)
+		errc <- err
+	}()
+	var id string
+	select {
+	case id = <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request did not start")
+	}
+	if err := client.Notify(ctx, "$/cancelRequest", CancelParams{ID: json.RawMessage(id)}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errc:
+		if errorCode(err) != codeRequestCancelled {
+			t.Errorf("cancelled request: error = %v, want code %d", err, codeRequestCancelled)
+		}
+	case <-time.After(4 * time.Second):
+		t.Fatal("the cancelled request still runs")
+	}
+}
+
+// TestCheckCancelled checks that a request whose context is already done
+// does not run.
+func TestCheckCancelled(t *testing.T) {
+	reqs, err := jrpc2.ParseRequests([]byte(`{"jsonrpc":"2.0","id":1,"method":"gock3/slow"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ran := false
+	h := checkCancelled("gock3/slow", func(ctx context.Context, req *jrpc2.Request) (any, error) {
+		ran = true
+		return nil, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := h(ctx, reqs[0].ToRequest()); errorCode(err) != codeRequestCancelled {
+		t.Errorf("error = %v, want code %d", err, codeRequestCancelled)
+	}
+	if ran {
+		t.Error("the handler of a cancelled request ran")
+	}
+}
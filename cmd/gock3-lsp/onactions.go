@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+	"github.com/unLomTrois/gock3-lsp/scope"
+)
+
+// onActionHover documents the on_action of the game under the cursor,
+// where it is defined or appended to and where it is referred to: when the
+// game fires it, its root scope and the saved scopes it sets. It returns
+// nil elsewhere, and for the on_actions mods define themselves.
+func (s *Server) onActionHover(filePath string, pos lsp.Position) *lsp.Hover {
+	entry := s.Index.File(filePath)
+	if entry == nil {
+		return nil
+	}
+	kind, name, rng, ok := entry.SymbolAt(pdx.Pos{Line: pos.Line, Col: pos.Character})
+	if !ok || kind != index.KindOnAction {
+		return nil
+	}
+	oa, ok := scope.OnActionInfo(name)
+	if !ok {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**on_action** `%s`\n\n%s\n\n", name, oa.Fired)
+	if oa.Root == scope.None {
+		b.WriteString("Root: none; it is fired for no object.")
+	} else {
+		fmt.Fprintf(&b, "Root: `%s`, %s", oa.Root, oa.RootDesc)
+	}
+	if len(oa.Saved) > 0 {
+		names := make([]string, 0, len(oa.Saved))
+		for n := range oa.Saved {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		b.WriteString("\n\nSaved scopes:\n")
+		for _, n := range names {
+			fmt.Fprintf(&b, "\n- `scope:%s`: `%s`", n, oa.Saved[n])
+		}
+	}
+	hoverRange := analysis.Range(rng)
+	return &lsp.Hover{
+		Contents: []lsp.MarkedString{lsp.RawMarkedString(b.String())},
+		Range:    &hoverRange,
+	}
+}
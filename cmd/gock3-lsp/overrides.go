@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+)
+
+// minimizeOverrideFix turns a file replacing a whole vanilla file into a
+// partial override: the objects that differ from vanilla move to
+// `zz_<file>` in the same folder, which loads after the vanilla file so
+// its definitions win, and the override is deleted so vanilla loads again.
+// It is not offered if that file exists.
+func (s *Server) minimizeOverrideFix(filePath string, d lsp.Diagnostic) []CodeAction {
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil {
+		return nil
+	}
+	identical := analysis.IdenticalObjects(entry, s.Index)
+	if len(identical) == 0 {
+		return nil
+	}
+	target := filepath.Join(filepath.Dir(filePath), "zz_"+filepath.Base(filePath))
+	if _, err := os.Stat(target); err == nil || s.Index.File(target) != nil {
+		return nil
+	}
+	same := make(map[string]bool)
+	for _, f := range identical {
+		same[f.Key.Text] = true
+	}
+	var kept []string
+	for _, f := range entry.File.Root.Fields {
+		if f.Key != nil && !same[f.Key.Text] {
+			kept = append(kept, definitionText(entry.File, f))
+		}
+	}
+
+	uri := filePathToURI(filePath)
+	action := CodeAction{Kind: lsp.CAKQuickFix, Diagnostics: []lsp.Diagnostic{d}, Edit: &WorkspaceEdit{}}
+	if len(kept) == 0 {
+		action.Title = "Delete this override, which is identical to vanilla"
+	} else {
+		targetURI := filePathToURI(target)
+		edit := TextDocumentEdit{Edits: []lsp.TextEdit{{NewText: strings.Join(kept, "\n\n") + "\n"}}}
+		edit.TextDocument.URI = targetURI
+		objects := fmt.Sprintf("%d objects", len(kept))
+		if len(kept) == 1 {
+			objects = "object"
+		}
+		action.Title = fmt.Sprintf("Keep only the %s changed from vanilla, in %s", objects, filepath.Base(target))
+		action.Edit.DocumentChanges = append(action.Edit.DocumentChanges, FileOperation{Kind: "create", URI: targetURI}, edit)
+	}
+	action.Edit.DocumentChanges = append(action.Edit.DocumentChanges, FileOperation{Kind: "delete", URI: uri})
+	return []CodeAction{action}
+}
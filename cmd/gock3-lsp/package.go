@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/unLomTrois/gock3-lsp/mod"
+)
+
+// packageCommand runs gock3.package for the workspace root. The optional
+// first argument is the output path of the archive.
+func (s *Server) packageCommand(ctx context.Context, args []interface{}) (interface{}, error) {
+	s.mutex.RLock()
+	root, opts := s.RootPath, s.Settings.Package
+	s.mutex.RUnlock()
+	if root == "" {
+		return nil, errors.New("no workspace root to package")
+	}
+	output := stringArg(args, 0)
+	if output == "" {
+		output = mod.DefaultOutput(root)
+	}
+	return mod.Package(root, output, opts)
+}
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+// runPackage implements `gock3-lsp package [-o file.zip] [-ignore glob]...
+// [dir]` and returns the process exit code.
+func runPackage(args []string) int {
+	fs := flag.NewFlagSet("package", flag.ContinueOnError)
+	output := fs.String("o", "", "path of the zip archive (default: <dir>-<version>.zip next to the mod folder)")
+	var ignore stringList
+	fs.Var(&ignore, "ignore", "glob of files to leave out; may be repeated")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	root := "."
+	if fs.NArg() > 0 {
+		root = fs.Arg(0)
+	}
+	if *output == "" {
+		*output = mod.DefaultOutput(root)
+	}
+	result, err := mod.Package(root, *output, mod.PackageOptions{Ignore: ignore})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gock3-lsp package: %v\n", err)
+		return 1
+	}
+	for _, file := range result.Fixed {
+		fmt.Printf("normalized %s\n", file)
+	}
+	fmt.Printf("wrote %d files to %s\n", result.Files, result.Output)
+	return 0
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log"
+	"path/filepath"
+	"plugin"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+)
+
+// loadPlugins opens the Go plugins (built with -buildmode=plugin against
+// the same version of this module) that settings list and registers their
+// analyzers. A plugin either registers them itself from an init function
+// or exports `func Analyzers() []analysis.Analyzer`. Plugins cannot be
+// unloaded, so each path is opened once. The caller must hold s.mutex.
+func (s *Server) loadPlugins(paths []string) {
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		if s.plugins[path] {
+			continue
+		}
+		s.plugins[path] = true
+		p, err := plugin.Open(path)
+		if err != nil {
+			log.Printf("Failed to load analyzer plugin '%s': %v", path, err)
+			continue
+		}
+		sym, err := p.Lookup("Analyzers")
+		if err != nil {
+			log.Printf("Loaded analyzer plugin: %s", path)
+			continue
+		}
+		list, ok := sym.(func() []analysis.Analyzer)
+		if !ok {
+			log.Printf("Analyzer plugin '%s' exports Analyzers with the wrong type %T", path, sym)
+			continue
+		}
+		for _, a := range list() {
+			if err := analysis.RegisterAnalyzer(a); err != nil {
+				log.Printf("Skipping analyzer of plugin '%s': %v", path, err)
+				continue
+			}
+			log.Printf("Registered analyzer %s from plugin: %s", a.Name(), path)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/unLomTrois/gock3-lsp/loc"
+)
+
+// pseudoPadding is how much longer pseudo-localized texts are made, as a
+// fraction of their length, to show where translations would overflow.
+const pseudoPadding = 0.3
+
+// pseudoFilePrefix starts the names of the generated files, which are
+// never pseudo-localized again.
+const pseudoFilePrefix = "zz_gock3_pseudo_"
+
+// PseudoLocalizeResult describes the file written by gock3.pseudoLocalize.
+type PseudoLocalizeResult struct {
+	Output  string `json:"output"`
+	Entries int    `json:"entries"`
+}
+
+// pseudoLocalizeCommand runs gock3.pseudoLocalize: it copies the English
+// localization of the workspace, accented and padded, into a separate file
+// of the `replace` folder of a language, English by default, so that UI
+// overflow and untranslated hardcoded strings stand out in game. The
+// optional argument is the target language, such as "l_french".
+func (s *Server) pseudoLocalizeCommand(ctx context.Context, args []interface{}) (interface{}, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.RootPath == "" {
+		return nil, errors.New("no workspace root to write to")
+	}
+	language := stringArg(args, 0)
+	if language == "" {
+		language = "l_english"
+	}
+	if !strings.HasPrefix(language, "l_") {
+		return nil, fmt.Errorf("invalid language %q; expected a name such as l_english", language)
+	}
+	name := strings.TrimPrefix(language, "l_")
+	output := filepath.Join(s.RootPath, "localization", "replace", name, pseudoFilePrefix+language+".yml")
+
+	texts := map[string]string{}
+	for _, path := range s.Index.Paths() {
+		entry := s.Index.File(path)
+		if strings.HasPrefix(filepath.Base(path), pseudoFilePrefix) || entry == nil || entry.Loc == nil || entry.Loc.Language != "l_english" {
+			continue
+		}
+		for _, e := range entry.Loc.Entries {
+			texts[e.Key] = e.Text
+		}
+	}
+	if len(texts) == 0 {
+		return nil, errors.New("the workspace has no English localization")
+	}
+	keys := make([]string, 0, len(texts))
+	for key := range texts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("\uFEFF" + language + ":\n")
+	b.WriteString(" # Generated by gock3.pseudoLocalize; delete this file before release.\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, " %s:0 \"%s\"\n", key, loc.Pseudo(texts[key], pseudoPadding))
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(output, []byte(b.String()), 0o644); err != nil {
+		return nil, err
+	}
+	s.Index.UpdateFile(output, b.String())
+	log.Printf("Wrote %d pseudo-localized entries to: %s", len(keys), output)
+	return PseudoLocalizeResult{Output: output, Entries: len(keys)}, nil
+}
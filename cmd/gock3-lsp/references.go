@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+// referenceCompletions offers the defined names of an object kind as the
+// value of keys that refer to one, such as `has_domicile_building = |`, and
+// inside blocks that list them, such as the `regions` of a geographical
+// region. The caller must hold s.mutex.
+func (s *Server) referenceCompletions(params lsp.TextDocumentPositionParams) []lsp.CompletionItem {
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil || !index.IsScriptFile(filePath) {
+		return nil
+	}
+	vpath := s.Index.VirtualPath(filePath)
+	parent := s.enclosingBlockKey(filePath, params.Position)
+	var kind index.Kind
+	var ok bool
+	if m := valueContextPattern.FindStringSubmatch(linePrefix(s.Documents[filePath], params.Position)); m != nil {
+		kind, ok = index.RefKind(vpath, parent, m[1])
+	} else {
+		kind, ok = index.ListRefKind(vpath, parent)
+	}
+	if !ok {
+		return nil
+	}
+	return s.nameItems(kind, "", lsp.CIKValue, strings.ReplaceAll(string(kind), "_", " "))
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// Categories of related locations, in the order they are listed and cycled
+// through.
+const (
+	relatedDefinition   = "definition"
+	relatedLocalization = "localization"
+	relatedGUI          = "gui"
+	relatedGfx          = "gfx"
+)
+
+// gfxExtensions are the extensions of the image and model assets in gfx/.
+var gfxExtensions = map[string]bool{".dds": true, ".png": true, ".tga": true, ".asset": true, ".mesh": true}
+
+// RelatedLocation is a place an object is spread over: its script
+// definition, a localization entry, a GUI file using it or a gfx asset.
+type RelatedLocation struct {
+	Category string       `json:"category"`
+	Label    string       `json:"label"`
+	Location lsp.Location `json:"location"`
+}
+
+// relatedObject is the object whose related locations are listed.
+type relatedObject struct {
+	name string
+	// kind is the index kind of the object, or "" if it is not indexed,
+	// such as a trait or a decision.
+	kind index.Kind
+	// entry and field are the file and top-level field of its script
+	// definition, if it has one.
+	entry *index.FileEntry
+	field *pdx.Field
+}
+
+// openRelatedCommand runs gock3.openRelated. The argument is
+// `{ "textDocument", "position" }` of an object such as an event, a
+// decision, a trait or one of their localization keys. It returns the
+// locations of the object across the parallel folder trees of the game,
+// for a picker: its script definition, its localization entries, the GUI
+// files using it and its gfx assets. With a second argument "next" it
+// cycles instead, opening the first location after the current file with
+// window/showDocument and returning it.
+func (s *Server) openRelatedCommand(ctx context.Context, args []interface{}) (interface{}, error) {
+	var params lsp.TextDocumentPositionParams
+	if len(args) > 0 {
+		data, _ := json.Marshal(args[0])
+		if err := json.Unmarshal(data, &params); err != nil {
+			return nil, err
+		}
+	}
+	if params.TextDocument.URI == "" {
+		return nil, errors.New("expected a document and a position")
+	}
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.RLock()
+	related, err := s.relatedLocations(filePath, pdx.Pos{Line: params.Position.Line, Col: params.Position.Character})
+	s.mutex.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if stringArg(args, 1) != "next" {
+		return related, nil
+	}
+	next, ok := nextRelated(related, params.TextDocument.URI)
+	if !ok {
+		return nil, errors.New("the object has no related location in another file")
+	}
+	show := ShowDocumentParams{URI: string(next.Location.URI), TakeFocus: true, Selection: &next.Location.Range}
+	if _, err := s.jrpcServer.Callback(ctx, "window/showDocument", show); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// relatedLocations lists the locations of the object at pos in filePath.
+func (s *Server) relatedLocations(filePath string, pos pdx.Pos) ([]RelatedLocation, error) {
+	entry := s.Index.File(filePath)
+	if entry == nil {
+		return nil, fmt.Errorf("%s is not indexed", filePath)
+	}
+	obj, ok := s.relatedObjectAt(entry, pos)
+	if !ok {
+		return nil, errors.New("no object at the cursor")
+	}
+
+	var related []RelatedLocation
+	seen := map[lsp.Location]bool{}
+	add := func(category, label string, loc lsp.Location) {
+		if !seen[loc] {
+			seen[loc] = true
+			related = append(related, RelatedLocation{category, label, loc})
+		}
+	}
+
+	if obj.field != nil {
+		add(relatedDefinition, fmt.Sprintf("%s in %s", obj.name, obj.entry.VirtualPath),
+			lsp.Location{URI: filePathToURI(obj.entry.Path), Range: analysis.Range(obj.field.Key.Loc)})
+	}
+
+	keys := s.relatedKeys(obj)
+	for _, key := range keys {
+		entries := s.Index.Localizations(key)
+		// English first, as the language the others are translated from.
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Language == "l_english" && entries[j].Language != "l_english"
+		})
+		for _, e := range entries {
+			add(relatedLocalization, fmt.Sprintf("%s (%s)", key, strings.TrimPrefix(e.Language, "l_")),
+				lsp.Location{URI: filePathToURI(e.Path), Range: analysis.Range(e.KeyRange)})
+		}
+	}
+
+	var uses []index.Reference
+	if obj.kind != "" {
+		uses = s.Index.Uses(obj.kind, obj.name)
+	}
+	for _, key := range keys {
+		uses = append(uses, s.Index.References(index.KindLocalization, key)...)
+	}
+	for _, ref := range uses {
+		if index.IsGUIFile(ref.Path) {
+			add(relatedGUI, fmt.Sprintf("%s in %s", ref.Name, s.Index.VirtualPath(ref.Path)), toLocation(ref.Location))
+		}
+	}
+
+	for _, path := range s.relatedAssets(obj) {
+		add(relatedGfx, s.Index.VirtualPath(path), lsp.Location{URI: filePathToURI(path)})
+	}
+	log.Printf("Found %d locations related to '%s'.", len(related), obj.name)
+	return related, nil
+}
+
+// relatedObjectAt returns the object at pos: the indexed symbol there, the
+// object a localization key belongs to, or else the top-level definition
+// of a script file the cursor is in.
+func (s *Server) relatedObjectAt(entry *index.FileEntry, pos pdx.Pos) (relatedObject, bool) {
+	kind, name, ok := s.symbolAt(lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: filePathToURI(entry.Path)},
+		Position:     lsp.Position{Line: pos.Line, Character: pos.Col},
+	})
+	switch {
+	case ok && kind == index.KindLocalization:
+		return s.localizedObject(name)
+	case ok:
+		return s.definedObject(kind, name), true
+	case entry.File != nil:
+		path := entry.File.PathAt(pos)
+		if len(path) == 0 || path[0].Key == nil {
+			return relatedObject{}, false
+		}
+		return relatedObject{name: path[0].Key.Text, entry: entry, field: path[0]}, true
+	}
+	return relatedObject{}, false
+}
+
+// definedObject returns the object of an indexed name, with its first
+// top-level definition.
+func (s *Server) definedObject(kind index.Kind, name string) relatedObject {
+	obj := relatedObject{name: name, kind: kind}
+	for _, sym := range s.Index.Definitions(kind, name) {
+		if def := s.Index.File(sym.Path); def != nil && def.File != nil {
+			if path := def.File.PathAt(sym.Range.Start); len(path) > 0 && path[0].Key != nil {
+				obj.entry, obj.field = def, path[0]
+				break
+			}
+		}
+	}
+	return obj
+}
+
+// localizedObject returns the object a localization key belongs to: the
+// top-level definition of the first script using the key, or else the
+// indexed definition the key is named after, as `my_events.0001` is for
+// `my_events.0001.t`.
+func (s *Server) localizedObject(key string) (relatedObject, bool) {
+	for _, ref := range s.Index.References(index.KindLocalization, key) {
+		entry := s.Index.File(ref.Path)
+		if entry == nil || entry.File == nil || !index.IsScriptFile(ref.Path) {
+			continue
+		}
+		if path := entry.File.PathAt(ref.Range.Start); len(path) > 0 && path[0].Key != nil {
+			obj := relatedObject{name: path[0].Key.Text, entry: entry, field: path[0]}
+			if container, ok := s.Index.Container(ref.Location); ok {
+				obj.kind = container.Kind
+			}
+			return obj, true
+		}
+	}
+	kinds := make([]index.Kind, 0, len(definitionSymbolKinds))
+	for kind := range definitionSymbolKinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	for name := key; name != ""; {
+		for _, kind := range kinds {
+			if obj := s.definedObject(kind, name); obj.field != nil {
+				return obj, true
+			}
+		}
+		i := strings.LastIndexAny(name, "._")
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return relatedObject{name: key}, true
+}
+
+// relatedKeys returns the localization keys of obj: those its definition
+// refers to and those named after it, such as `name_desc` or `name.t`.
+func (s *Server) relatedKeys(obj relatedObject) []string {
+	seen := map[string]bool{}
+	var keys []string
+	add := func(key string) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	if obj.field != nil {
+		rng := obj.field.Range()
+		for _, ref := range obj.entry.Refs {
+			if ref.Kind == index.KindLocalization && !ref.Range.Start.Before(rng.Start) && !rng.End.Before(ref.Range.End) {
+				add(ref.Name)
+			}
+		}
+	}
+	var named []string
+	for _, key := range s.Index.Names(index.KindLocalization) {
+		if key == obj.name || strings.HasPrefix(key, obj.name+".") || strings.HasPrefix(key, obj.name+"_") {
+			named = append(named, key)
+		}
+	}
+	sort.Strings(named)
+	for _, key := range named {
+		add(key)
+	}
+	return keys
+}
+
+// relatedAssets returns the gfx files of obj: those its definition refers
+// to, such as the picture of a decision, and those named after it, such as
+// the icon of a trait, in the mod or else the game.
+func (s *Server) relatedAssets(obj relatedObject) []string {
+	var roots []string
+	for _, root := range []string{s.RootPath, s.vanillaPath} {
+		if root != "" {
+			roots = append(roots, root)
+		}
+	}
+	var assets []string
+	if obj.field != nil && obj.field.Block() != nil {
+		pdx.Walk(obj.field.Block(), func(f *pdx.Field) bool {
+			v := f.Scalar()
+			if v == nil || !strings.HasPrefix(strings.ToLower(v.Text), "gfx/") {
+				return true
+			}
+			for _, root := range roots {
+				path := filepath.Join(root, filepath.FromSlash(v.Text))
+				if _, err := os.Stat(path); err == nil {
+					assets = append(assets, path)
+					break
+				}
+			}
+			return true
+		})
+	}
+	for _, root := range roots {
+		filepath.WalkDir(filepath.Join(root, "gfx"), func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			ext := filepath.Ext(d.Name())
+			if gfxExtensions[strings.ToLower(ext)] && strings.TrimSuffix(d.Name(), ext) == obj.name {
+				assets = append(assets, path)
+			}
+			return nil
+		})
+	}
+	return assets
+}
+
+// nextRelated returns the first location after those in the current
+// document, wrapping around, that is in another file.
+func nextRelated(related []RelatedLocation, current lsp.DocumentURI) (RelatedLocation, bool) {
+	start := 0
+	for i, r := range related {
+		if r.Location.URI == current {
+			start = i + 1
+		}
+	}
+	for i := range related {
+		if r := related[(start+i)%len(related)]; r.Location.URI != current {
+			return r, true
+		}
+	}
+	return RelatedLocation{}, false
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// kindSavedScope marks a rename of a saved scope, which is not indexed but
+// found by its `scope:name` uses along the event chain it is saved in.
+const kindSavedScope index.Kind = "saved_scope"
+
+// renameKinds are the indexed kinds that can be renamed. Their uses are
+// all written by name, so renaming cannot miss a computed one.
+var renameKinds = map[index.Kind]bool{
+	index.KindScriptedEffect:  true,
+	index.KindScriptedTrigger: true,
+	index.KindScriptValue:     true,
+}
+
+// saveScopeKeys are the effects whose value names a saved scope.
+var saveScopeKeys = map[string]bool{
+	"save_scope_as":           true,
+	"save_temporary_scope_as": true,
+	"clear_saved_scope":       true,
+}
+
+// saveScopeValueKeys are the effects naming a saved scope in their `name`.
+var saveScopeValueKeys = map[string]bool{
+	"save_scope_value_as":           true,
+	"save_temporary_scope_value_as": true,
+}
+
+// renameNamePattern matches the names a definition or saved scope can be
+// renamed to.
+var renameNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// PrepareRenameResult is the range of the name to rename and its current
+// text.
+type PrepareRenameResult struct {
+	Range       lsp.Range `json:"range"`
+	Placeholder string    `json:"placeholder"`
+}
+
+// TextDocumentPrepareRename checks that the cursor is on a scripted effect,
+// scripted trigger, script value or saved scope defined in the workspace,
+// and rejects built-in keywords and game definitions with an explanation.
+func (s *Server) TextDocumentPrepareRename(ctx context.Context, params lsp.TextDocumentPositionParams) (*PrepareRenameResult, error) {
+	log.Printf("PrepareRename request received for URI: %s at position Line %d, Character %d",
+		params.TextDocument.URI, params.Position.Line, params.Position.Character)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	kind, name, rng, err := s.renameTarget(params)
+	if err != nil {
+		log.Printf("Rejecting rename: %v", err)
+		return nil, err
+	}
+	log.Printf("Renaming %s '%s' is possible.", kind, name)
+	return &PrepareRenameResult{Range: analysis.Range(rng), Placeholder: name}, nil
+}
+
+// TextDocumentRename renames the definition or saved scope at the cursor
+// and every use of it in the workspace.
+func (s *Server) TextDocumentRename(ctx context.Context, params lsp.RenameParams) (lsp.WorkspaceEdit, error) {
+	log.Printf("Rename request received for URI: %s at position Line %d, Character %d, new name '%s'",
+		params.TextDocument.URI, params.Position.Line, params.Position.Character, params.NewName)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	kind, name, _, err := s.renameTarget(lsp.TextDocumentPositionParams{TextDocument: params.TextDocument, Position: params.Position})
+	if err != nil {
+		return lsp.WorkspaceEdit{}, err
+	}
+	newName := params.NewName
+	if !renameNamePattern.MatchString(newName) {
+		return lsp.WorkspaceEdit{}, fmt.Errorf("'%s' is not a valid name; use letters, digits and underscores", newName)
+	}
+	if kind != kindSavedScope && len(s.Index.Definitions(kind, newName)) > 0 {
+		return lsp.WorkspaceEdit{}, fmt.Errorf("a %s named '%s' already exists", kind, newName)
+	}
+
+	var locations []index.Location
+	if kind == kindSavedScope {
+		filePath, _ := uriToFilePath(params.TextDocument.URI)
+		locations = s.savedScopeUses(name, s.scopeChain(filePath, pdx.Pos{Line: params.Position.Line, Col: params.Position.Character}))
+	} else {
+		for _, sym := range s.Index.LocalDefinitions(kind, name) {
+			locations = append(locations, sym.Location)
+		}
+		for _, ref := range s.Index.Uses(kind, name) {
+			locations = append(locations, ref.Location)
+		}
+	}
+
+	changes := map[string][]lsp.TextEdit{}
+	seen := map[index.Location]bool{}
+	edits := 0
+	for _, loc := range locations {
+		if seen[loc] || s.readOnly(loc.Path) {
+			continue
+		}
+		seen[loc] = true
+		uri := string(filePathToURI(loc.Path))
+		changes[uri] = append(changes[uri], lsp.TextEdit{Range: analysis.Range(loc.Range), NewText: newName})
+		edits++
+	}
+	for _, fileEdits := range changes {
+		sort.Slice(fileEdits, func(i, j int) bool {
+			a, b := fileEdits[i].Range.Start, fileEdits[j].Range.Start
+			return a.Line < b.Line || a.Line == b.Line && a.Character < b.Character
+		})
+	}
+	log.Printf("Renaming %s '%s' to '%s': %d edits in %d files.", kind, name, newName, edits, len(changes))
+	return lsp.WorkspaceEdit{Changes: changes}, nil
+}
+
+// renameTarget returns the kind, name and range of what a rename at the
+// cursor would change, or an error saying why nothing there can be renamed.
+func (s *Server) renameTarget(params lsp.TextDocumentPositionParams) (index.Kind, string, pdx.Range, error) {
+	if s.readOnlyMode {
+		return "", "", pdx.Range{}, errReadOnlyMode
+	}
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return "", "", pdx.Range{}, err
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil {
+		return "", "", pdx.Range{}, fmt.Errorf("only names in script files can be renamed")
+	}
+	if s.readOnly(filePath) {
+		return "", "", pdx.Range{}, fmt.Errorf("files outside the workspace cannot be changed")
+	}
+	pos := pdx.Pos{Line: params.Position.Line, Col: params.Position.Character}
+	if name, rng, ok := savedScopeAt(entry.File, pos); ok {
+		return kindSavedScope, name, rng, nil
+	}
+
+	kind, name, rng, ok := entry.SymbolAt(pos)
+	if !ok {
+		if path := entry.File.PathAt(pos); len(path) > 0 {
+			kind, name, rng, ok = s.Index.CallAt(path[len(path)-1])
+			ok = ok && rng.Contains(pos)
+		}
+	}
+	if !ok {
+		if word := wordAt(entry.File, pos); word != "" {
+			return "", "", pdx.Range{}, fmt.Errorf("'%s' is a built-in keyword and cannot be renamed", word)
+		}
+		return "", "", pdx.Range{}, fmt.Errorf("there is nothing to rename here")
+	}
+	if !renameKinds[kind] {
+		return "", "", pdx.Range{}, fmt.Errorf("renaming a %s is not supported", kind)
+	}
+	local := false
+	for _, sym := range s.Index.LocalDefinitions(kind, name) {
+		local = local || !s.readOnly(sym.Path)
+	}
+	if !local {
+		return "", "", pdx.Range{}, fmt.Errorf("%s '%s' is defined by the game files and cannot be renamed", kind, name)
+	}
+	return kind, name, rng, nil
+}
+
+// savedScopeAt returns the name of the saved scope under the cursor, in
+// `save_scope_as = name` or `scope:name`, and the range of the name alone.
+func savedScopeAt(file *pdx.File, pos pdx.Pos) (string, pdx.Range, bool) {
+	path := file.PathAt(pos)
+	if len(path) == 0 {
+		return "", pdx.Range{}, false
+	}
+	f := path[len(path)-1]
+	if v := savedScopeName(f); v != nil && v.Loc.Contains(pos) {
+		return v.Text, v.Loc, true
+	}
+	for _, word := range []*pdx.Scalar{f.Key, f.Scalar()} {
+		if word == nil || !word.Loc.Contains(pos) {
+			continue
+		}
+		for _, ref := range scopeRefs(word) {
+			if ref.rng.Start.Col-len("scope:") <= pos.Col && pos.Col <= ref.rng.End.Col {
+				return ref.name, ref.rng, true
+			}
+		}
+	}
+	return "", pdx.Range{}, false
+}
+
+// savedScopeName returns the value naming a saved scope in
+// `save_scope_as = name` or in the `name` of `save_scope_value_as`.
+func savedScopeName(f *pdx.Field) *pdx.Scalar {
+	key := f.KeyText()
+	if saveScopeKeys[key] || key == "name" && saveScopeValueKeys[f.ParentField().KeyText()] {
+		if v := f.Scalar(); v != nil && index.IsStaticName(v.Text) {
+			return v
+		}
+	}
+	return nil
+}
+
+// scopeRefPattern matches `scope:name` in a key or value such as
+// `scope:target.liege`.
+var scopeRefPattern = regexp.MustCompile(`scope:([A-Za-z0-9_]+)`)
+
+// scopeRef is a use of a saved scope and the range of its name.
+type scopeRef struct {
+	name string
+	rng  pdx.Range
+}
+
+// scopeRefs returns the saved scopes a key or value uses.
+func scopeRefs(word *pdx.Scalar) []scopeRef {
+	var refs []scopeRef
+	for _, m := range scopeRefPattern.FindAllStringSubmatchIndex(word.Text, -1) {
+		if m[0] > 0 && isWordChar(word.Text[m[0]-1]) {
+			continue
+		}
+		rng := word.Loc
+		if word.Quoted {
+			rng = index.StringSpan(word, m[2], m[3])
+		} else {
+			rng.Start.Col += utf16Len(word.Text[:m[2]])
+			rng.Start.Offset += m[2]
+			rng.End = rng.Start
+			rng.End.Col += utf16Len(word.Text[m[2]:m[3]])
+			rng.End.Offset += m[3] - m[2]
+		}
+		refs = append(refs, scopeRef{name: word.Text[m[2]:m[3]], rng: rng})
+	}
+	return refs
+}
+
+// locScopePattern matches the saved scopes used in localization, as in
+// [SCOPE.sC('target').GetName].
+var locScopePattern = regexp.MustCompile(`SCOPE\.[A-Za-z]+\('([A-Za-z0-9_]+)'\)`)
+
+// scopeChainKinds are the kinds of definitions a saved scope is passed on
+// to: those of an event chain and the scripted triggers and script values
+// they call.
+var scopeChainKinds = map[index.Kind]bool{
+	index.KindEvent:           true,
+	index.KindOnAction:        true,
+	index.KindScriptedEffect:  true,
+	index.KindScriptedTrigger: true,
+	index.KindScriptValue:     true,
+}
+
+// scopeDefinition is a top-level definition of a script file.
+type scopeDefinition struct {
+	path  string
+	field *pdx.Field
+}
+
+// scopeChain returns the definitions sharing the saved scopes of the one
+// at pos: those it fires or calls and those firing or calling it, and so
+// on, as saved scopes are passed on along an event chain. Outside any
+// definition, the chain is every definition of the file.
+func (s *Server) scopeChain(filePath string, pos pdx.Pos) []scopeDefinition {
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil {
+		return nil
+	}
+	path := entry.File.PathAt(pos)
+	if len(path) == 0 {
+		var chain []scopeDefinition
+		for _, f := range entry.File.Root.Fields {
+			chain = append(chain, scopeDefinition{filePath, f})
+		}
+		return chain
+	}
+	seen := map[*pdx.Field]bool{path[0]: true}
+	chain := []scopeDefinition{{filePath, path[0]}}
+	add := func(loc index.Location) {
+		e := s.Index.File(loc.Path)
+		if e == nil || e.File == nil {
+			return
+		}
+		if p := e.File.PathAt(loc.Range.Start); len(p) > 0 && !seen[p[0]] {
+			seen[p[0]] = true
+			chain = append(chain, scopeDefinition{loc.Path, p[0]})
+		}
+	}
+	for i := 0; i < len(chain); i++ {
+		def := chain[i]
+		e := s.Index.File(def.path)
+		rng := def.field.Range()
+		// The definitions it fires or calls.
+		for _, ref := range append(append([]index.Reference{}, e.Refs...), s.Index.ScriptCalls(e)...) {
+			if !scopeChainKinds[ref.Kind] || !rng.Contains(ref.Range.Start) {
+				continue
+			}
+			for _, sym := range s.Index.Definitions(ref.Kind, ref.Name) {
+				add(sym.Location)
+			}
+		}
+		// The definitions firing or calling it.
+		for _, sym := range e.Symbols {
+			if !scopeChainKinds[sym.Kind] || def.field.Key == nil || sym.Range != def.field.Key.Loc {
+				continue
+			}
+			for _, ref := range s.Index.Uses(sym.Kind, sym.Name) {
+				add(ref.Location)
+			}
+		}
+	}
+	return chain
+}
+
+// savedScopeUses finds where the definitions of chain save and use the
+// saved scope name, and where the localization they name uses it.
+func (s *Server) savedScopeUses(name string, chain []scopeDefinition) []index.Location {
+	var locations []index.Location
+	words := map[string]bool{}
+	for _, def := range chain {
+		if index.IsGUIFile(def.path) {
+			continue
+		}
+		pdx.Walk(&pdx.Block{Fields: []*pdx.Field{def.field}}, func(f *pdx.Field) bool {
+			if v := savedScopeName(f); v != nil && v.Text == name {
+				locations = append(locations, index.Location{Path: def.path, Range: v.Loc})
+			}
+			for _, word := range []*pdx.Scalar{f.Key, f.Scalar()} {
+				if word == nil {
+					continue
+				}
+				words[word.Text] = true
+				if !strings.Contains(word.Text, "scope:"+name) {
+					continue
+				}
+				for _, ref := range scopeRefs(word) {
+					if ref.name == name {
+						locations = append(locations, index.Location{Path: def.path, Range: ref.rng})
+					}
+				}
+			}
+			return true
+		})
+	}
+	for _, path := range s.Index.Paths() {
+		entry := s.Index.File(path)
+		if entry == nil || entry.Loc == nil {
+			continue
+		}
+		for _, e := range entry.Loc.Entries {
+			if !words[e.Key] {
+				continue
+			}
+			for _, m := range locScopePattern.FindAllStringSubmatchIndex(e.Text, -1) {
+				if e.Text[m[2]:m[3]] == name {
+					locations = append(locations, index.Location{Path: path, Range: e.TextSpan(m[2], m[3])})
+				}
+			}
+		}
+	}
+	return locations
+}
+
+// wordAt returns the key or value under the cursor, or "".
+func wordAt(file *pdx.File, pos pdx.Pos) string {
+	path := file.PathAt(pos)
+	if len(path) == 0 {
+		return ""
+	}
+	f := path[len(path)-1]
+	for _, word := range []*pdx.Scalar{f.Key, f.Scalar()} {
+		if word != nil && word.Loc.Contains(pos) {
+			return word.Text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	lsp "github.com/sourcegraph/go-lsp"
+)
+
+// renameFiles is a mod with two event chains saving a scope named target:
+// a.0001 firing a.0002 and calling my_effect, and the unrelated b.0001 and
+// c.0001.
+var renameFiles = map[string]string{
+	"/mod/events/a.txt": `namespace = a
+a.0001 = {
+	type = character_event
+	desc = a.0001.desc
+	immediate = {
+		random_vassal = { save_scope_as = target }
+		trigger_event = a.0002
+		my_effect = yes
+	}
+}
+a.0002 = {
+	type = character_event
+	immediate = {
+		scope:target = { add_gold = 1 }
+		set_variable = { name = x value = flag:dvůr_ærø.scope:target }
+	}
+}
+b.0001 = {
+	type = character_event
+	immediate = { liege = { save_scope_as = target } scope:target = { add_gold = 1 } }
+}
+`,
+	"/mod/common/scripted_effects/my_effects.txt": "my_effect = { scope:target = { add_prestige = 1 } }\n",
+	"/mod/events/c.txt": `namespace = c
+c.0001 = {
+	type = character_event
+	desc = c.0001.desc
+	immediate = { save_scope_as = target }
+}
+`,
+	"/mod/localization/english/my_l_english.yml": "\uFEFFl_english:\n a.0001.desc:0 \"[SCOPE.sC('target').GetName] is your vassal.\"\n c.0001.desc:0 \"[SCOPE.sC('target').GetName] is you.\"\n",
+}
+
+// renameSavedScope renames the saved scope at pos of filePath and returns
+// the renamed texts of the files changed.
+func renameSavedScope(t *testing.T, filePath string, pos lsp.Position) map[string]string {
+	t.Helper()
+	s := NewServer()
+	s.RootPath = "/mod"
+	for path, text := range renameFiles {
+		openDocument(s, path, text)
+	}
+	edit, err := s.TextDocumentRename(context.Background(), lsp.RenameParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: filePathToURI(filePath)},
+		Position:     pos,
+		NewName:      "victim",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	renamed := map[string]string{}
+	for uri, edits := range edit.Changes {
+		path, err := uriToFilePath(lsp.DocumentURI(uri))
+		if err != nil {
+			t.Fatal(err)
+		}
+		renamed[path] = applyEdits(t, renameFiles[path], edits)
+	}
+	return renamed
+}
+
+// TestRenameSavedScopeChain checks that a saved scope is renamed in the
+// definitions of its event chain and their localization only, at the
+// UTF-16 columns of lines with non-ASCII letters.
+func TestRenameSavedScopeChain(t *testing.T) {
+	// The cursor is on the target of save_scope_as in a.0001.
+	renamed := renameSavedScope(t, "/mod/events/a.txt", lsp.Position{Line: 5, Character: 37})
+
+	want := strings.NewReplacer(
+		"random_vassal = { save_scope_as = target }", "random_vassal = { save_scope_as = victim }",
+		"scope:target = { add_gold = 1 }\n\t\tset_variable", "scope:victim = { add_gold = 1 }\n\t\tset_variable",
+		"flag:dvůr_ærø.scope:target", "flag:dvůr_ærø.scope:victim",
+	).Replace(renameFiles["/mod/events/a.txt"])
+	if got := renamed["/mod/events/a.txt"]; got != want {
+		t.Errorf("events/a.txt =\n%s\nwant\n%s", got, want)
+	}
+	if got, want := renamed["/mod/common/scripted_effects/my_effects.txt"], "my_effect = { scope:victim = { add_prestige = 1 } }\n"; got != want {
+		t.Errorf("the scripted effect a.0001 calls = %q, want %q", got, want)
+	}
+	loc := renamed["/mod/localization/english/my_l_english.yml"]
+	if !strings.Contains(loc, "a.0001.desc:0 \"[SCOPE.sC('victim')") || !strings.Contains(loc, "c.0001.desc:0 \"[SCOPE.sC('target')") {
+		t.Errorf("only the localization of a.0001 should be renamed:\n%s", loc)
+	}
+	if _, ok := renamed["/mod/events/c.txt"]; ok {
+		t.Error("the unrelated event c.0001 was renamed")
+	}
+	if len(renamed) != 3 {
+		t.Errorf("renamed %d files, want 3", len(renamed))
+	}
+}
+
+// TestRenameSavedScopeOtherChain checks that renaming from an event that
+// fires nothing leaves the same name of other chains alone.
+func TestRenameSavedScopeOtherChain(t *testing.T) {
+	// The cursor is on scope:target in b.0001.
+	renamed := renameSavedScope(t, "/mod/events/a.txt", lsp.Position{Line: 19, Character: 56})
+
+	want := strings.Replace(renameFiles["/mod/events/a.txt"],
+		"liege = { save_scope_as = target } scope:target", "liege = { save_scope_as = victim } scope:victim", 1)
+	if got := renamed["/mod/events/a.txt"]; got != want {
+		t.Errorf("events/a.txt =\n%s\nwant\n%s", got, want)
+	}
+	if len(renamed) != 1 {
+		t.Errorf("renamed %d files, want only events/a.txt", len(renamed))
+	}
+}
+
+// TestRenameSavedScopeFromCallee checks that the chain is followed back
+// from a scripted effect to the events calling it.
+func TestRenameSavedScopeFromCallee(t *testing.T) {
+	renamed := renameSavedScope(t, "/mod/common/scripted_effects/my_effects.txt", lsp.Position{Line: 0, Character: 22})
+	if got := renamed["/mod/events/a.txt"]; !strings.Contains(got, "random_vassal = { save_scope_as = victim }") || !strings.Contains(got, "liege = { save_scope_as = target }") {
+		t.Errorf("events/a.txt =\n%s", got)
+	}
+	if _, ok := renamed["/mod/events/c.txt"]; ok {
+		t.Error("the unrelated event c.0001 was renamed")
+	}
+}
+
+func TestScopeRefsUTF16(t *testing.T) {
+	s := NewServer()
+	text := "a = { b = flag:ærø_😀.scope:target }\n"
+	openDocument(s, "/mod/events/x.txt", text)
+	f := s.Index.File("/mod/events/x.txt").File.Root.Fields[0].Block().Fields[0]
+	refs := scopeRefs(f.Scalar())
+	if len(refs) != 1 {
+		t.Fatalf("scopeRefs = %+v, want one", refs)
+	}
+	// "a = { b = flag:ærø_" is 19 UTF-16 units, the emoji 2 and ".scope:" 7.
+	if r := refs[0].rng; r.Start.Col != 28 || r.End.Col != 34 {
+		t.Errorf("range = %d-%d, want 28-34", r.Start.Col, r.End.Col)
+	}
+	if got := text[refs[0].rng.Start.Offset:refs[0].rng.End.Offset]; got != "target" {
+		t.Errorf("offsets cover %q, want target", got)
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+// FileRole handles the gock3/fileRole request, which tells what a document
+// holds by its folder: events, a database of common/ objects, history,
+// GUI, localization or plain text. Clients use its languageId to pick the
+// language mode, since a .txt file may be script or a changelog.
+func (s *Server) FileRole(ctx context.Context, params lsp.TextDocumentIdentifier) (index.FileRole, error) {
+	filePath, err := uriToFilePath(params.URI)
+	if err != nil {
+		return index.FileRole{}, err
+	}
+	role := s.Index.RoleOf(filePath)
+	log.Printf("File role of %s: %s (%s)", filePath, role.Role, role.Folder)
+	return role, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/unLomTrois/gock3-lsp/mod"
+)
+
+// readableRoots returns the folders the server may read files from under
+// settings: the workspace, the game folder and the launcher's folders next
+// to it, the Workshop mods of the game's Steam library that a mod may
+// depend on, the user's CK3 folder holding the mods folder, the logs and
+// the launcher database, and the folder of the system word list.
+func (s *Server) readableRoots(settings Settings) []string {
+	roots := []string{s.RootPath, filepath.Dir(systemWordList)}
+	if settings.GamePath != "" {
+		roots = append(roots, settings.GamePath, filepath.Join(filepath.Dir(settings.GamePath), "launcher"))
+		if workshop := mod.WorkshopPath(settings.GamePath); workshop != "" {
+			roots = append(roots, workshop)
+		}
+	}
+	if settings.ModsPath != "" {
+		roots = append(roots, filepath.Dir(settings.ModsPath))
+	}
+	return roots
+}
+
+// checkReadable returns an error unless path lies in one of the
+// readableRoots, after following symbolic links, so that the server can be
+// run on untrusted mod folders. Without a workspace root, only open
+// documents are handled and any path is accepted.
+func (s *Server) checkReadable(path string, settings Settings) error {
+	if s.RootPath == "" {
+		return nil
+	}
+	resolved := resolvePath(path)
+	for _, root := range s.readableRoots(settings) {
+		if within(resolvePath(root), resolved) {
+			return nil
+		}
+	}
+	return fmt.Errorf("'%s' is outside the workspace, game, Workshop and launcher folders", path)
+}
+
+// resolvePath makes path absolute and follows its symbolic links, as far
+// as it exists.
+func resolvePath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		return filepath.Join(dir, filepath.Base(abs))
+	}
+	return abs
+}
+
+// within reports whether path is root or below it.
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/unLomTrois/gock3-lsp/spell"
+)
+
+func TestCheckReadable(t *testing.T) {
+	dir := t.TempDir()
+	steamapps := filepath.Join(dir, "library", "steamapps")
+	game := filepath.Join(steamapps, "common", "Crusader Kings III", "game")
+	dependency := filepath.Join(steamapps, "workshop", "content", "1158310", "2217534250", "common", "traits", "00_traits.txt")
+	otherGame := filepath.Join(steamapps, "workshop", "content", "394360", "123", "secret.txt")
+	workspace := filepath.Join(dir, "my_mod")
+	outside := filepath.Join(dir, "secret.txt")
+	for _, path := range []string{
+		filepath.Join(game, "common", "traits", "00_traits.txt"),
+		filepath.Join(game, "..", "launcher", "launcher-settings.json"),
+		dependency,
+		otherGame,
+		filepath.Join(workspace, "descriptor.mod"),
+		outside,
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := filepath.Join(workspace, "link.txt")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Skip("symbolic links are not supported:", err)
+	}
+
+	s := NewServer()
+	s.RootPath = workspace
+	settings := Settings{GamePath: game, ModsPath: filepath.Join(dir, "user", "mod")}
+	tests := []struct {
+		name string
+		path string
+		ok   bool
+	}{
+		{"workspace", filepath.Join(workspace, "descriptor.mod"), true},
+		{"game", filepath.Join(game, "common", "traits", "00_traits.txt"), true},
+		{"launcher", filepath.Join(game, "..", "launcher", "launcher-settings.json"), true},
+		{"workshop dependency", dependency, true},
+		{"user folder", filepath.Join(dir, "user", "launcher-v2.sqlite"), true},
+		{"system word list", systemWordList, true},
+		{"workshop of another game", otherGame, false},
+		{"outside", outside, false},
+		{"escaping workspace", filepath.Join(workspace, "..", "secret.txt"), false},
+		{"symbolic link", link, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.checkReadable(tt.path, settings)
+			if (err == nil) != tt.ok {
+				t.Errorf("checkReadable(%s) = %v, want ok %v", tt.path, err, tt.ok)
+			}
+		})
+	}
+
+	s.RootPath = ""
+	if err := s.checkReadable(outside, settings); err != nil {
+		t.Errorf("without a workspace root, checkReadable(%s) = %v, want nil", outside, err)
+	}
+}
+
+// TestLoadDictionaryChecksSystemWordList checks that the default word list
+// is read through the check like configured dictionaries.
+func TestLoadDictionaryChecksSystemWordList(t *testing.T) {
+	builtin := spell.New("l_english").Len()
+	var checked []string
+	d := loadDictionary(SpellcheckSettings{Words: []string{"Rajput"}}, func(path string) error {
+		checked = append(checked, path)
+		return errors.New("rejected")
+	})
+	if len(checked) != 1 || checked[0] != systemWordList {
+		t.Errorf("checked %q, want the system word list", checked)
+	}
+	if d.Len() != builtin+1 {
+		t.Errorf("dictionary has %d words, want the %d built-in ones and the configured one", d.Len(), builtin)
+	}
+
+	dict := filepath.Join(t.TempDir(), "words.dic")
+	if err := os.WriteFile(dict, []byte("3\nzamindar\nsubahdar\njagirdar\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	checked = nil
+	d = loadDictionary(SpellcheckSettings{Dictionaries: []string{dict}}, func(path string) error {
+		checked = append(checked, path)
+		return nil
+	})
+	if len(checked) != 1 || checked[0] != dict {
+		t.Errorf("checked %q, want %q", checked, dict)
+	}
+	if d.Len() != builtin+3 {
+		t.Errorf("dictionary has %d words, want the %d built-in ones and 3", d.Len(), builtin)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// saveAfterDelay is the reason of the saves that editors make by
+// themselves after a delay.
+const saveAfterDelay = 2
+
+// WillSaveTextDocumentParams are the parameters of
+// textDocument/willSaveWaitUntil, which go-lsp lacks.
+type WillSaveTextDocumentParams struct {
+	TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+	// Reason is 1 for a manual save, 2 for an automatic one after a delay
+	// and 3 when the editor loses focus.
+	Reason int `json:"reason"`
+}
+
+// TextDocumentWillSaveWaitUntil formats a document as it is saved, with
+// formatOnSave: script and GUI files are formatted like with
+// textDocument/formatting, and the trailing whitespace of the lines of
+// other files, and of files with syntax errors, is trimmed. The saves an
+// editor makes by itself while you type are left alone.
+func (s *Server) TextDocumentWillSaveWaitUntil(ctx context.Context, params WillSaveTextDocumentParams) ([]lsp.TextEdit, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if !s.Settings.FormatOnSave || params.Reason == saveAfterDelay {
+		return []lsp.TextEdit{}, nil
+	}
+	uri := params.TextDocument.URI
+	filePath, err := uriToFilePath(uri)
+	if err != nil {
+		return nil, err
+	}
+	text, ok := s.Documents[filePath]
+	if !ok {
+		return nil, errors.New("Document does not exist for URI: " + string(uri))
+	}
+	if s.readOnlyMode || s.readOnly(filePath) || s.skipsFormat(filePath) {
+		return []lsp.TextEdit{}, nil
+	}
+	if index.IsScriptFile(filePath) || index.IsGUIFile(filePath) {
+		if formatted, err := pdx.Format(text); err == nil {
+			edits := formatEdits(text, formatted)
+			log.Printf("Formatting %s on save with %d edits.", filePath, len(edits))
+			return edits, nil
+		}
+	}
+	edits := formatEdits(text, trimTrailingSpace(text))
+	log.Printf("Trimming trailing whitespace of %s on save with %d edits.", filePath, len(edits))
+	return edits, nil
+}
+
+// trimTrailingSpace removes the spaces and tabs at the end of every line of
+// text, keeping its line endings.
+func trimTrailingSpace(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		cr := strings.HasSuffix(line, "\r")
+		line = strings.TrimRight(strings.TrimSuffix(line, "\r"), " \t")
+		if cr {
+			line += "\r"
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TextDocumentDidSave handles the event when a text document is saved. The
+// cross-file checks, such as whether references resolve and translations
+// keep up with English, are run again on the document, and the other open
+// documents are checked against it, since edits only run the light checks.
+func (s *Server) TextDocumentDidSave(ctx context.Context, params lsp.DidSaveTextDocumentParams) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	uri := params.TextDocument.URI
+	filePath, err := uriToFilePath(uri)
+	if err != nil {
+		log.Printf("Invalid URI '%s' in DidSave: %v", uri, err)
+		return err
+	}
+	if _, open := s.Documents[filePath]; !open || !index.IsIndexable(filePath) {
+		return nil
+	}
+
+	log.Printf("Validating saved document: %s", filePath)
+	diagnostics := s.validate(filePath)
+	s.DiagFiles[filePath] = diagnostics
+	if err := s.publishDiagnostics(ctx, uri, diagnostics); err != nil {
+		log.Printf("Failed to publish diagnostics for saved document: %s", filePath)
+		return err
+	}
+
+	s.refreshDiagnostics(ctx, filePath)
+	return nil
+}
+
+// validate runs every check on an open document, keeping the diagnostics
+// of the cross-file checks for its edits until the next save. The caller
+// must hold s.mutex.
+func (s *Server) validate(filePath string) []analysis.Diagnostic {
+	if entry := s.Index.File(filePath); entry != nil {
+		crossFile := analysis.CrossFile(entry, s.analysisEnv())
+		if crossFile == nil {
+			crossFile = []analysis.Diagnostic{}
+		}
+		s.crossFile[filePath] = crossFile
+	}
+	return s.diagnose(filePath, s.crossFile[filePath])
+}
+
+// shiftCrossFile moves the cross-file diagnostics of the last save of a
+// document along with the edits of changes, so that they stay on the text
+// they were reported for. Those on edited text are dropped, as are all of
+// them when the whole text is replaced. The caller must hold s.mutex.
+func (s *Server) shiftCrossFile(filePath string, changes []lsp.TextDocumentContentChangeEvent) {
+	diagnostics, ok := s.crossFile[filePath]
+	if !ok {
+		return
+	}
+	for _, change := range changes {
+		if change.Range == nil {
+			diagnostics = []analysis.Diagnostic{}
+			continue
+		}
+		kept := diagnostics[:0]
+		for _, d := range diagnostics {
+			if shifted, ok := shiftRange(d.Range, *change.Range, change.Text); ok {
+				d.Range = shifted
+				kept = append(kept, d)
+			}
+		}
+		diagnostics = kept
+	}
+	s.crossFile[filePath] = diagnostics
+}
+
+// shiftRange returns where r is after edited is replaced by text, or false
+// if they overlap.
+func shiftRange(r, edited lsp.Range, text string) (lsp.Range, bool) {
+	if !after(r.End, edited.Start) {
+		return r, true
+	}
+	if after(edited.End, r.Start) {
+		return lsp.Range{}, false
+	}
+	// The end of the inserted text.
+	lines := strings.Count(text, "\n")
+	end := lsp.Position{Line: edited.Start.Line + lines, Character: edited.Start.Character + utf16Len(text)}
+	if lines > 0 {
+		end.Character = utf16Len(text[strings.LastIndexByte(text, '\n')+1:])
+	}
+	shift := func(p lsp.Position) lsp.Position {
+		if p.Line == edited.End.Line {
+			p.Character += end.Character - edited.End.Character
+		}
+		p.Line += end.Line - edited.End.Line
+		return p
+	}
+	return lsp.Range{Start: shift(r.Start), End: shift(r.End)}, true
+}
+
+// after reports whether a is after b.
+func after(a, b lsp.Position) bool {
+	return a.Line > b.Line || a.Line == b.Line && a.Character > b.Character
+}
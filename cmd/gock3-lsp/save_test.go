@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	lsp "github.com/sourcegraph/go-lsp"
+)
+
+func TestWillSaveWaitUntilCRLF(t *testing.T) {
+	tests := []struct {
+		name, filePath, text, want string
+	}{
+		{"script", "/mods/my_mod/events/my_events.txt", "a=b  \r\nc = {\r\nd=e\r\n}\r\n", "a = b\r\nc = {\r\n\td = e\r\n}\r\n"},
+		{"syntax error", "/mods/my_mod/events/my_events.txt", "a = b \t\r\nc = {\r\n", "a = b\r\nc = {\r\n"},
+		{"localization", "/mods/my_mod/localization/english/my_l_english.yml", "l_english:  \r\n my_key:0 \"Text\" \r\n", "l_english:\r\n my_key:0 \"Text\"\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer()
+			s.Settings.FormatOnSave = true
+			openDocument(s, tt.filePath, tt.text)
+			edits, err := s.TextDocumentWillSaveWaitUntil(context.Background(), WillSaveTextDocumentParams{
+				TextDocument: lsp.TextDocumentIdentifier{URI: filePathToURI(tt.filePath)},
+				Reason:       1,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := applyEdits(t, tt.text, edits); got != tt.want {
+				t.Errorf("saved as %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+	"github.com/unLomTrois/gock3-lsp/scope"
+)
+
+// scopeHover shows the scope change of the iterator or event target key
+// under the cursor, as inferred by the scope engine. It returns nil
+// elsewhere.
+func (s *Server) scopeHover(filePath string, pos lsp.Position) *lsp.Hover {
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil {
+		return nil
+	}
+	p := pdx.Pos{Line: pos.Line, Col: pos.Character}
+	path := entry.File.PathAt(p)
+	if len(path) == 0 {
+		return nil
+	}
+	f := path[len(path)-1]
+	if f.Key == nil || !f.Key.Loc.Contains(p) || f.Block() == nil {
+		return nil
+	}
+	tree := scope.Analyze(entry.File, entry.VirtualPath)
+	from, to := tree.Parent(f), tree.FrameOf(f.Block())
+	if from == nil || to == nil || to == from {
+		return nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "**scope** `%s` → `%s`", typeName(from.This), typeName(to.This))
+	if to.Root != scope.Unknown {
+		fmt.Fprintf(&b, "\n\nroot: `%s`", to.Root)
+	}
+	hoverRange := analysis.Range(f.Key.Loc)
+	return &lsp.Hover{
+		Contents: []lsp.MarkedString{lsp.RawMarkedString(b.String())},
+		Range:    &hoverRange,
+	}
+}
+
+// typeName returns the name of a scope type, or "?" when it is unknown.
+func typeName(t scope.Type) string {
+	if t == scope.Unknown {
+		return "?"
+	}
+	return string(t)
+}
+
+// chainContextPattern matches a partially typed event target chain ending
+// in a `.` and the start of the next link.
+var chainContextPattern = regexp.MustCompile(`([A-Za-z0-9_:]+(?:\.[A-Za-z0-9_:]+)*)\.([A-Za-z0-9_]*)$`)
+
+// chainCompletions offers the event targets valid for the scope a chain
+// like `root.liege.` leads to. It returns nil outside such a chain. The
+// caller must hold s.mutex.
+func (s *Server) chainCompletions(params lsp.TextDocumentPositionParams) []lsp.CompletionItem {
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return nil
+	}
+	m := chainContextPattern.FindStringSubmatch(linePrefix(s.Documents[filePath], params.Position))
+	if m == nil || !scope.IsChainHead(strings.SplitN(m[1], ".", 2)[0]) {
+		return nil
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil {
+		return nil
+	}
+	frame := scope.Analyze(entry.File, entry.VirtualPath).At(pdx.Pos{Line: params.Position.Line, Col: params.Position.Character})
+	if frame == nil {
+		return nil
+	}
+	typ, chainErr := frame.Resolve(m[1])
+	if chainErr != nil {
+		return []lsp.CompletionItem{}
+	}
+	items := []lsp.CompletionItem{}
+	for _, name := range scope.Links(typ) {
+		link, _ := scope.LookupLink(name)
+		items = append(items, lsp.CompletionItem{Label: name, Kind: lsp.CIKField, Detail: fmt.Sprintf("%s → %s", typeName(typ), typeName(link.To))})
+	}
+	return items
+}
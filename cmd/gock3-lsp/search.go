@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// StructuralQuery is the argument of gock3.structuralSearch. It matches the
+// fields with Key, and Value if set, that are nested in a block keyed
+// Within, if set, in the mod files under one of Folders, if any.
+type StructuralQuery struct {
+	Key     string   `json:"key"`
+	Value   string   `json:"value,omitempty"`
+	Within  string   `json:"within,omitempty"`
+	Folders []string `json:"folders,omitempty"`
+	// Replace, if set, is the new value of every match, returned as a
+	// workspace edit.
+	Replace *string `json:"replace,omitempty"`
+}
+
+// StructuralMatch is a field matching a structural query.
+type StructuralMatch struct {
+	URI   lsp.DocumentURI `json:"uri"`
+	Range lsp.Range       `json:"range"`
+	Value string          `json:"value"`
+	// Path is the keys of the blocks enclosing the field, outermost first,
+	// joined like gock3/blockPath.
+	Path string `json:"path"`
+}
+
+// StructuralResult lists the matches of a query, and the edit replacing
+// their values if the query asked for one.
+type StructuralResult struct {
+	Matches []StructuralMatch  `json:"matches"`
+	Edit    *lsp.WorkspaceEdit `json:"edit,omitempty"`
+}
+
+// structuralSearchCommand runs gock3.structuralSearch, which finds fields
+// by key, value and enclosing block rather than by text, so that changing
+// every `add_gold = 100` inside options does not touch comments, other
+// keys or other blocks. The edit leaves out block values, which have no
+// single value to replace, and read-only files.
+func (s *Server) structuralSearchCommand(ctx context.Context, args []interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, errors.New("expected a query")
+	}
+	data, _ := json.Marshal(args[0])
+	var query StructuralQuery
+	if err := json.Unmarshal(data, &query); err != nil {
+		return nil, err
+	}
+	if query.Key == "" {
+		return nil, errors.New("the query has no key")
+	}
+	if query.Replace != nil && s.readOnlyMode {
+		return nil, errReadOnlyMode
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := StructuralResult{Matches: []StructuralMatch{}}
+	changes := map[string][]lsp.TextEdit{}
+	for _, path := range s.Index.Paths() {
+		entry := s.Index.File(path)
+		if entry == nil || entry.File == nil || !inFolders(entry.VirtualPath, query.Folders) {
+			continue
+		}
+		uri := filePathToURI(path)
+		pdx.Walk(entry.File.Root, func(f *pdx.Field) bool {
+			if !query.matches(f) {
+				return true
+			}
+			sc := f.Scalar()
+			match := StructuralMatch{URI: uri, Range: analysis.Range(f.Range()), Path: blockPathText(f)}
+			if sc != nil {
+				match.Value = sc.Text
+			}
+			result.Matches = append(result.Matches, match)
+			if query.Replace != nil && sc != nil && !s.readOnly(path) {
+				changes[string(uri)] = append(changes[string(uri)], lsp.TextEdit{Range: analysis.Range(sc.Loc), NewText: *query.Replace})
+			}
+			return true
+		})
+	}
+	if query.Replace != nil {
+		result.Edit = &lsp.WorkspaceEdit{Changes: changes}
+	}
+	log.Printf("Structural search for '%s' found %d matches in %d files to edit.", query.Key, len(result.Matches), len(changes))
+	return result, nil
+}
+
+// matches reports whether f has the key and value of q and is nested in a
+// block keyed q.Within.
+func (q StructuralQuery) matches(f *pdx.Field) bool {
+	if f.KeyText() != q.Key || q.Value != "" && f.ValueText() != q.Value {
+		return false
+	}
+	if q.Within == "" {
+		return true
+	}
+	for p := f.ParentField(); p != nil; p = p.ParentField() {
+		if p.KeyText() == q.Within {
+			return true
+		}
+	}
+	return false
+}
+
+// inFolders reports whether vpath is under one of folders, or folders is
+// empty.
+func inFolders(vpath string, folders []string) bool {
+	for _, folder := range folders {
+		if strings.HasPrefix(vpath, strings.TrimSuffix(folder, "/")+"/") {
+			return true
+		}
+	}
+	return len(folders) == 0
+}
+
+// blockPathText joins the keys of the blocks enclosing f, outermost first.
+func blockPathText(f *pdx.Field) string {
+	var keys []string
+	for p := f.ParentField(); p != nil; p = p.ParentField() {
+		key := p.KeyText()
+		if key == "" {
+			key = bareBlockKey
+		}
+		keys = append([]string{key}, keys...)
+	}
+	return strings.Join(keys, " > ")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	lsp "github.com/sourcegraph/go-lsp"
+)
+
+// searchFiles are the files of a mod at /mod, and a vanilla file outside
+// it, which is read-only.
+var searchFiles = map[string]string{
+	"/mod/events/a.txt": `a.0001 = {
+	immediate = { add_gold = 100 }
+	option = { add_gold = 100 }
+	option = { add_gold = 50 }
+}
+`,
+	"/mod/common/scripted_effects/my_effects.txt": "my_effect = { add_gold = 100 }\n",
+	"/game/events/vanilla.txt":                    "v.0001 = { option = { add_gold = 100 } }\n",
+}
+
+// structuralSearch runs gock3.structuralSearch with query through
+// workspace/executeCommand.
+func structuralSearch(s *Server, query map[string]interface{}) (StructuralResult, error) {
+	result, err := s.WorkspaceExecuteCommand(context.Background(), lsp.ExecuteCommandParams{
+		Command:   "gock3.structuralSearch",
+		Arguments: []interface{}{query},
+	})
+	if err != nil {
+		return StructuralResult{}, err
+	}
+	return result.(StructuralResult), nil
+}
+
+func newSearchServer() *Server {
+	s := NewServer()
+	s.RootPath = "/mod"
+	for path, text := range searchFiles {
+		openDocument(s, path, text)
+	}
+	return s
+}
+
+func TestStructuralSearch(t *testing.T) {
+	s := newSearchServer()
+	result, err := structuralSearch(s, map[string]interface{}{"key": "add_gold", "value": "100", "within": "option", "replace": "200"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range result.Matches {
+		got = append(got, string(m.URI)+" "+m.Path)
+	}
+	want := []string{string(filePathToURI("/game/events/vanilla.txt")) + " v.0001 > option", string(filePathToURI("/mod/events/a.txt")) + " a.0001 > option"}
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("matches = %q, want %q", got, want)
+	}
+	// The read-only vanilla file is left out of the edit.
+	wantEdit := map[string][]lsp.TextEdit{string(filePathToURI("/mod/events/a.txt")): {{
+		Range:   lsp.Range{Start: lsp.Position{Line: 2, Character: 23}, End: lsp.Position{Line: 2, Character: 26}},
+		NewText: "200",
+	}}}
+	if result.Edit == nil || !reflect.DeepEqual(result.Edit.Changes, wantEdit) {
+		t.Errorf("edit = %+v, want %+v", result.Edit, wantEdit)
+	}
+
+	result, err = structuralSearch(s, map[string]interface{}{"key": "add_gold", "folders": []string{"common/"}})
+	if err != nil || len(result.Matches) != 1 || result.Matches[0].Value != "100" || result.Edit != nil {
+		t.Errorf("search in common/ = %+v, %v, want the scripted effect and no edit", result, err)
+	}
+	if _, err := structuralSearch(s, map[string]interface{}{"value": "100"}); err == nil || err.Error() != "the query has no key" {
+		t.Errorf("search without a key: error = %v", err)
+	}
+}
+
+// TestStructuralSearchReadOnlyMode checks that read-only mode keeps the
+// search and rejects the replace mode.
+func TestStructuralSearchReadOnlyMode(t *testing.T) {
+	s := newSearchServer()
+	s.readOnlyMode = true
+	result, err := structuralSearch(s, map[string]interface{}{"key": "add_gold"})
+	if err != nil || len(result.Matches) != 5 {
+		t.Errorf("search in read-only mode = %+v, %v, want 5 matches", result, err)
+	}
+	if _, err := structuralSearch(s, map[string]interface{}{"key": "add_gold", "replace": "200"}); err != errReadOnlyMode {
+		t.Errorf("replace in read-only mode: error = %v, want %v", err, errReadOnlyMode)
+	}
+
+	names := commandNames(true)
+	for _, name := range names {
+		if writingCommands[name] {
+			t.Errorf("read-only mode offers the writing command %s", name)
+		}
+	}
+	if !reflect.DeepEqual(names, []string{"gock3.eventRoots", "gock3.openRelated", "gock3.patchAudit", "gock3.runScriptChecks", "gock3.structuralSearch"}) {
+		t.Errorf("commands in read-only mode = %q", names)
+	}
+	if _, err := s.WorkspaceExecuteCommand(context.Background(), lsp.ExecuteCommandParams{Command: "gock3.package"}); err != errReadOnlyMode {
+		t.Errorf("gock3.package in read-only mode: error = %v, want %v", err, errReadOnlyMode)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// SelectionRangeParams asks for the selection ranges at some positions of a
+// document.
+type SelectionRangeParams struct {
+	TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+	Positions    []lsp.Position             `json:"positions"`
+}
+
+// SelectionRange is a range to select and the larger one containing it,
+// which go-lsp lacks.
+type SelectionRange struct {
+	Range  lsp.Range       `json:"range"`
+	Parent *SelectionRange `json:"parent,omitempty"`
+}
+
+// TextDocumentSelectionRange answers "expand selection" from the parse
+// tree: the key or value under the cursor, then its `key = value` field,
+// then the block around it and the field owning that block, and so on up
+// to the top-level definition and the whole file.
+func (s *Server) TextDocumentSelectionRange(ctx context.Context, params SelectionRangeParams) ([]SelectionRange, error) {
+	log.Printf("Selection range request received for URI: %s", params.TextDocument.URI)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	ranges := []SelectionRange{}
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return ranges, err
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil {
+		return ranges, nil
+	}
+	whole := fileRange(s.fileText(entry))
+	for _, pos := range params.Positions {
+		ranges = append(ranges, *selectionAt(entry.File, pos, whole))
+	}
+	return ranges, nil
+}
+
+// selectionAt builds the chain of ranges enclosing pos, innermost first,
+// leaving out a range equal to the one inside it.
+func selectionAt(file *pdx.File, pos lsp.Position, whole lsp.Range) *SelectionRange {
+	sel := &SelectionRange{Range: whole}
+	push := func(r pdx.Range) {
+		if rng := analysis.Range(r); rng != sel.Range {
+			sel = &SelectionRange{Range: rng, Parent: sel}
+		}
+	}
+	p := pdx.Pos{Line: pos.Line, Col: pos.Character}
+	path := file.PathAt(p)
+	for i, f := range path {
+		push(f.Range())
+		// A block only counts as a step when the cursor is inside its
+		// braces, not on its key.
+		if b := f.Block(); b != nil && (i < len(path)-1 || b.Loc.Contains(p)) {
+			push(b.Loc)
+		}
+	}
+	if len(path) > 0 {
+		f := path[len(path)-1]
+		for _, word := range []*pdx.Scalar{f.Key, f.Scalar()} {
+			if word != nil && word.Loc.Contains(p) {
+				push(word.Loc)
+			}
+		}
+	}
+	return sel
+}
+
+// fileRange returns the range of the whole text.
+func fileRange(text string) lsp.Range {
+	lines := strings.Split(text, "\n")
+	last := len(lines) - 1
+	return lsp.Range{End: lsp.Position{Line: last, Character: utf16Len(lines[last])}}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+	"github.com/unLomTrois/gock3-lsp/scope"
+)
+
+// The semantic token types of the legend, by their index in it. Effects
+// and triggers use standard types that themes color apart.
+const (
+	tokenKeyword  = iota // control blocks such as if, limit and trigger, and yes/no
+	tokenEffect          // effects, as "function"
+	tokenTrigger         // triggers, as "macro"
+	tokenScope           // event targets and saved scopes, as "namespace"
+	tokenEvent           // event IDs
+	tokenNumber          // numbers and dates
+	tokenLocKey          // localization keys, as "string"
+	tokenValue           // script values, as "variable"
+	tokenProperty        // other keys
+)
+
+// The semantic token modifiers of the legend, as bits.
+const (
+	modDeclaration    = 1 << iota // the definition of the name
+	modDefaultLibrary             // built into the game rather than scripted
+	modDate                       // a number that is a date
+)
+
+// semanticTokensLegend is the legend advertised in the capabilities; the
+// orders match the constants above.
+var semanticTokensLegend = SemanticTokensLegend{
+	TokenTypes:     []string{"keyword", "function", "macro", "namespace", "event", "number", "string", "variable", "property"},
+	TokenModifiers: []string{"declaration", "defaultLibrary", "date"},
+}
+
+// symbolTokenTypes are the token types of the index kinds that are
+// highlighted where they are defined or used.
+var symbolTokenTypes = map[index.Kind]int{
+	index.KindEvent:           tokenEvent,
+	index.KindLocalization:    tokenLocKey,
+	index.KindScriptedEffect:  tokenEffect,
+	index.KindScriptedTrigger: tokenTrigger,
+	index.KindScriptValue:     tokenValue,
+}
+
+// datePattern matches dates such as 1066.9.15.
+var datePattern = regexp.MustCompile(`^\d{1,4}\.\d{1,2}\.\d{1,2}$`)
+
+// SemanticTokensParams asks for the tokens of a whole document.
+type SemanticTokensParams struct {
+	TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+}
+
+// SemanticTokensRangeParams asks for the tokens of part of a document.
+type SemanticTokensRangeParams struct {
+	TextDocument lsp.TextDocumentIdentifier `json:"textDocument"`
+	Range        lsp.Range                  `json:"range"`
+}
+
+// SemanticTokens holds tokens as the LSP encodes them: five integers per
+// token, the line and start relative to the previous token, the length,
+// the type and the modifier bits.
+type SemanticTokens struct {
+	Data []uint32 `json:"data"`
+}
+
+// semanticToken is a classified range on a single line.
+type semanticToken struct {
+	line, col, length int
+	typ, mods         int
+}
+
+// TextDocumentSemanticTokensFull classifies the tokens of a script or
+// localization file, telling effects from triggers, scopes, event IDs and
+// localization keys as only the server knows.
+func (s *Server) TextDocumentSemanticTokensFull(ctx context.Context, params SemanticTokensParams) (SemanticTokens, error) {
+	log.Printf("Semantic tokens request received for URI: %s", params.TextDocument.URI)
+	tokens, err := s.semanticTokens(params.TextDocument.URI)
+	if err != nil {
+		return SemanticTokens{}, err
+	}
+	log.Printf("Returning %d semantic tokens.", len(tokens))
+	return encodeTokens(tokens), nil
+}
+
+// TextDocumentSemanticTokensRange classifies the tokens of the lines of a
+// range, for clients showing part of a large file.
+func (s *Server) TextDocumentSemanticTokensRange(ctx context.Context, params SemanticTokensRangeParams) (SemanticTokens, error) {
+	log.Printf("Semantic tokens range request received for URI: %s, lines %d-%d",
+		params.TextDocument.URI, params.Range.Start.Line, params.Range.End.Line)
+	tokens, err := s.semanticTokens(params.TextDocument.URI)
+	if err != nil {
+		return SemanticTokens{}, err
+	}
+	inRange := tokens[:0]
+	for _, t := range tokens {
+		if t.line >= params.Range.Start.Line && t.line <= params.Range.End.Line {
+			inRange = append(inRange, t)
+		}
+	}
+	log.Printf("Returning %d semantic tokens.", len(inRange))
+	return encodeTokens(inRange), nil
+}
+
+// semanticTokens classifies the tokens of the indexed file at uri, sorted
+// by position. GUI files have none.
+func (s *Server) semanticTokens(uri lsp.DocumentURI) ([]semanticToken, error) {
+	filePath, err := uriToFilePath(uri)
+	if err != nil {
+		return nil, err
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil || index.IsGUIFile(filePath) {
+		return nil, nil
+	}
+	var tokens []semanticToken
+	add := func(r pdx.Range, typ, mods int) {
+		if r.Start.Line == r.End.Line && r.End.Col > r.Start.Col {
+			tokens = append(tokens, semanticToken{r.Start.Line, r.Start.Col, r.End.Col - r.Start.Col, typ, mods})
+		}
+	}
+	if entry.Loc != nil {
+		for _, e := range entry.Loc.Entries {
+			add(e.KeyRange, tokenLocKey, modDeclaration)
+		}
+	} else if entry.File != nil {
+		pdx.Walk(entry.File.Root, func(f *pdx.Field) bool {
+			if f.Key != nil {
+				typ, mods := s.keyToken(entry, f)
+				add(f.Key.Loc, typ, mods)
+			}
+			if v := f.Scalar(); v != nil {
+				if typ, mods, ok := s.valueToken(entry, f, v); ok {
+					add(v.Loc, typ, mods)
+				}
+			}
+			return true
+		})
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if tokens[i].line != tokens[j].line {
+			return tokens[i].line < tokens[j].line
+		}
+		return tokens[i].col < tokens[j].col
+	})
+	return tokens, nil
+}
+
+// keyToken classifies the key of f: definitions and calls by their kind,
+// control blocks as keywords, event targets as scopes, and the other keys
+// of effect and trigger blocks as built-in effects and triggers.
+func (s *Server) keyToken(entry *index.FileEntry, f *pdx.Field) (int, int) {
+	key := f.Key.Text
+	if typ, mods, ok := symbolToken(entry, f.Key.Loc); ok {
+		return typ, mods
+	}
+	if kind, _, _, ok := s.Index.CallAt(f); ok && kind != index.KindScriptValue {
+		return symbolTokenTypes[kind], 0
+	}
+	if _, err := strconv.ParseFloat(key, 64); err == nil {
+		return tokenNumber, 0
+	}
+	if prefix, _, _, ok := scope.Iterator(key); ok || prefix != "" && !scope.IsNonIterator(key) {
+		if prefix == "any" {
+			return tokenTrigger, modDefaultLibrary
+		}
+		return tokenEffect, modDefaultLibrary
+	}
+	if scope.BlockContext(key) != scope.ContextUnknown && f.Block() != nil || key == "namespace" && f.ParentField() == nil {
+		return tokenKeyword, 0
+	}
+	if isScopeName(key) {
+		return tokenScope, 0
+	}
+	switch fieldContext(f, entry.VirtualPath) {
+	case scope.ContextEffect:
+		return tokenEffect, modDefaultLibrary
+	case scope.ContextTrigger:
+		return tokenTrigger, modDefaultLibrary
+	}
+	return tokenProperty, 0
+}
+
+// valueToken classifies the scalar value v of f: numbers, dates, yes and
+// no, event targets and saved scopes, localization keys, and the indexed
+// names it refers to.
+func (s *Server) valueToken(entry *index.FileEntry, f *pdx.Field, v *pdx.Scalar) (int, int, bool) {
+	if typ, mods, ok := symbolToken(entry, v.Loc); ok {
+		return typ, mods, true
+	}
+	if index.IsStaticName(v.Text) && len(s.Index.Definitions(index.KindLocalization, v.Text)) > 0 {
+		return tokenLocKey, 0, true
+	}
+	if v.Quoted {
+		return 0, 0, false
+	}
+	if kind, _, _, ok := s.Index.CallAt(f); ok && kind == index.KindScriptValue {
+		return tokenValue, 0, true
+	}
+	if savedScopeName(f) == v {
+		return tokenScope, modDeclaration, true
+	}
+	switch {
+	case datePattern.MatchString(v.Text):
+		return tokenNumber, modDate, true
+	case v.Text == "yes" || v.Text == "no":
+		return tokenKeyword, 0, true
+	case isScopeName(v.Text):
+		return tokenScope, 0, true
+	}
+	if _, err := strconv.ParseFloat(v.Text, 64); err == nil {
+		return tokenNumber, 0, true
+	}
+	return 0, 0, false
+}
+
+// symbolToken classifies a key or value that the index records as the
+// definition or a use of a name of a symbolTokenTypes kind.
+func symbolToken(entry *index.FileEntry, r pdx.Range) (int, int, bool) {
+	for _, sym := range entry.Symbols {
+		if typ, ok := symbolTokenTypes[sym.Kind]; ok && sym.Range == r {
+			return typ, modDeclaration, true
+		}
+	}
+	for _, ref := range entry.Refs {
+		if typ, ok := symbolTokenTypes[ref.Kind]; ok && ref.Range == r {
+			return typ, 0, true
+		}
+	}
+	return 0, 0, false
+}
+
+// isScopeName reports whether name is an event target, such as root,
+// liege, scope:target or root.primary_title.
+func isScopeName(name string) bool {
+	return scope.IsChain(name) || scope.IsChainHead(name)
+}
+
+// fieldContext returns whether f is itself an effect or a trigger: a field
+// directly in a block of effects or triggers, looking through blocks that
+// only change the scope. The arguments of an effect or trigger, written
+// in its own block, are neither.
+func fieldContext(f *pdx.Field, vpath string) scope.Context {
+	p := f.ParentField()
+	for p != nil && isScopeName(p.KeyText()) {
+		p = p.ParentField()
+	}
+	if p == nil || p.ParentField() != nil && scope.BlockContext(p.KeyText()) == scope.ContextUnknown {
+		return scope.ContextUnknown
+	}
+	return scope.ContextOf(f, vpath)
+}
+
+// encodeTokens encodes sorted tokens relative to each other.
+func encodeTokens(tokens []semanticToken) SemanticTokens {
+	data := make([]uint32, 0, 5*len(tokens))
+	line, col := 0, 0
+	for _, t := range tokens {
+		if t.line != line {
+			col = 0
+		}
+		data = append(data, uint32(t.line-line), uint32(t.col-col), uint32(t.length), uint32(t.typ), uint32(t.mods))
+		line, col = t.line, t.col
+	}
+	return SemanticTokens{Data: data}
+}
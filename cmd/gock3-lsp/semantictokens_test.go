@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	lsp "github.com/sourcegraph/go-lsp"
+)
+
+// semanticFiles is a mod with an event using scripted effects, triggers,
+// values and localization of its own.
+var semanticFiles = map[string]string{
+	"/mod/events/a.txt": `namespace = a
+a.0001 = {
+	type = character_event
+	title = a.0001.t
+	trigger = { is_ai = no }
+	immediate = {
+		if = {
+			limit = { my_trigger = yes }
+			add_gold = my_value
+		}
+		every_vassal = { save_scope_as = vassal }
+		scope:vassal = { my_effect = yes }
+		root.liege = { add_prestige = 10 }
+		trigger_event = { id = a.0002 days = 3 }
+		set_variable = { name = x value = 1066.9.15 }
+	}
+}
+`,
+	"/mod/common/scripted_effects/my_effects.txt":   "my_effect = { add_gold = 1 }\n",
+	"/mod/common/scripted_triggers/my_triggers.txt": "my_trigger = { gold > 1 }\n",
+	"/mod/common/script_values/my_values.txt":       "my_value = 5\n",
+	"/mod/localization/english/my_l_english.yml":    "\uFEFFl_english:\n a.0001.t:0 \"Title\"\n",
+}
+
+// decodeTokens returns the text, type and modifiers of encoded tokens of
+// an ASCII text.
+func decodeTokens(t *testing.T, text string, tokens SemanticTokens) []string {
+	t.Helper()
+	lines := strings.Split(text, "\n")
+	var got []string
+	line, col := 0, 0
+	for i := 0; i+5 <= len(tokens.Data); i += 5 {
+		d := tokens.Data[i : i+5]
+		if d[0] > 0 {
+			col = 0
+		}
+		line += int(d[0])
+		col += int(d[1])
+		tok := fmt.Sprintf("%s %s", lines[line][col:col+int(d[2])], semanticTokensLegend.TokenTypes[d[3]])
+		for bit, name := range semanticTokensLegend.TokenModifiers {
+			if d[4]&(1<<bit) != 0 {
+				tok += " " + name
+			}
+		}
+		got = append(got, tok)
+	}
+	return got
+}
+
+// semanticTokensOf returns the decoded tokens of a file of semanticFiles.
+func semanticTokensOf(t *testing.T, s *Server, path string) []string {
+	t.Helper()
+	tokens, err := s.TextDocumentSemanticTokensFull(context.Background(), SemanticTokensParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: filePathToURI(path)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decodeTokens(t, semanticFiles[path], tokens)
+}
+
+func newSemanticServer() *Server {
+	s := NewServer()
+	s.RootPath = "/mod"
+	for path, text := range semanticFiles {
+		openDocument(s, path, text)
+	}
+	return s
+}
+
+func TestSemanticTokens(t *testing.T) {
+	s := newSemanticServer()
+	want := []string{
+		"namespace keyword",
+		"a.0001 event declaration",
+		"type property",
+		"title property",
+		"a.0001.t string",
+		"trigger keyword",
+		"is_ai macro defaultLibrary",
+		"no keyword",
+		"immediate keyword",
+		"if keyword",
+		"limit keyword",
+		"my_trigger macro",
+		"yes keyword",
+		"add_gold function defaultLibrary",
+		"my_value variable",
+		"every_vassal function defaultLibrary",
+		"save_scope_as function defaultLibrary",
+		"vassal namespace declaration",
+		"scope:vassal namespace",
+		"my_effect function",
+		"yes keyword",
+		"root.liege namespace",
+		"add_prestige function defaultLibrary",
+		"10 number",
+		"trigger_event function defaultLibrary",
+		"id property",
+		"a.0002 event",
+		"days property",
+		"3 number",
+		"set_variable function defaultLibrary",
+		"name property",
+		"value property",
+		"1066.9.15 number date",
+	}
+	if got := semanticTokensOf(t, s, "/mod/events/a.txt"); !reflect.DeepEqual(got, want) {
+		t.Errorf("tokens of events/a.txt =\n%q\nwant\n%q", got, want)
+	}
+
+	definitions := map[string][]string{
+		"/mod/common/scripted_effects/my_effects.txt":   {"my_effect function declaration", "add_gold function defaultLibrary", "1 number"},
+		"/mod/common/scripted_triggers/my_triggers.txt": {"my_trigger macro declaration", "gold macro defaultLibrary", "1 number"},
+		"/mod/common/script_values/my_values.txt":       {"my_value variable declaration", "5 number"},
+		"/mod/localization/english/my_l_english.yml":    {"a.0001.t string declaration"},
+	}
+	for path, want := range definitions {
+		if got := semanticTokensOf(t, s, path); !reflect.DeepEqual(got, want) {
+			t.Errorf("tokens of %s = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestSemanticTokensRange(t *testing.T) {
+	s := newSemanticServer()
+	tokens, err := s.TextDocumentSemanticTokensRange(context.Background(), SemanticTokensRangeParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: filePathToURI("/mod/events/a.txt")},
+		Range:        lsp.Range{Start: lsp.Position{Line: 11, Character: 5}, End: lsp.Position{Line: 12, Character: 0}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Whole lines are returned, encoded relative to the start of the file.
+	got := decodeTokens(t, semanticFiles["/mod/events/a.txt"], tokens)
+	want := []string{"scope:vassal namespace", "my_effect function", "yes keyword", "root.liege namespace", "add_prestige function defaultLibrary", "10 number"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tokens of lines 11-12 = %q, want %q", got, want)
+	}
+}
+
+func TestEncodeTokens(t *testing.T) {
+	got := encodeTokens([]semanticToken{
+		{line: 0, col: 0, length: 9, typ: tokenKeyword},
+		{line: 1, col: 4, length: 6, typ: tokenEvent, mods: modDeclaration},
+		{line: 1, col: 13, length: 2, typ: tokenNumber},
+		{line: 4, col: 2, length: 3, typ: tokenEffect, mods: modDefaultLibrary},
+	})
+	want := []uint32{
+		0, 0, 9, tokenKeyword, 0,
+		1, 4, 6, tokenEvent, modDeclaration,
+		0, 9, 2, tokenNumber, 0,
+		3, 2, 3, tokenEffect, modDefaultLibrary,
+	}
+	if !reflect.DeepEqual(got.Data, want) {
+		t.Errorf("encodeTokens = %v, want %v", got.Data, want)
+	}
+	if got := encodeTokens(nil); got.Data == nil {
+		t.Error("encodeTokens(nil) has null data, which clients reject")
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/creachadair/jrpc2/channel"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+// vanillaLayer is the vanilla index of one game folder, shared by the
+// sessions using it. ready is closed once the scan ended.
+type vanillaLayer struct {
+	ready chan struct{}
+	index *index.Index
+	count int
+	err   error
+	users int
+}
+
+// vanillaLayers holds the vanilla indexes in use by game folder, so that
+// clients of the same server process scan the game files once. The shared
+// index is only read: each session layers its own workspace index and open
+// documents over it.
+var vanillaLayers = struct {
+	mu     sync.Mutex
+	layers map[string]*vanillaLayer
+}{layers: make(map[string]*vanillaLayer)}
+
+// acquireVanilla returns the vanilla index of gamePath and the number of
+// files in it, scanning the game folder unless another session already did
+// or is doing so. A successful call must be paired with releaseVanilla.
+func acquireVanilla(gamePath string) (*index.Index, int, error) {
+	vanillaLayers.mu.Lock()
+	layer, ok := vanillaLayers.layers[gamePath]
+	if !ok {
+		layer = &vanillaLayer{ready: make(chan struct{})}
+		vanillaLayers.layers[gamePath] = layer
+	}
+	layer.users++
+	vanillaLayers.mu.Unlock()
+
+	if ok {
+		<-layer.ready
+		log.Printf("Sharing the vanilla index of another session: %s", gamePath)
+	} else {
+		layer.index = index.New()
+		layer.count, layer.err = layer.index.ScanFolders(gamePath, vanillaFolders)
+		if layer.err == nil {
+			log.Printf("Indexed %d vanilla files from: %s", layer.count, gamePath)
+			if n := layer.index.ScanDLC(gamePath); n > 0 {
+				log.Printf("Indexed %d DLC descriptors from: %s", n, gamePath)
+			}
+		}
+		close(layer.ready)
+	}
+
+	if layer.err != nil {
+		// A failed scan is not kept, so that the next session retries.
+		vanillaLayers.mu.Lock()
+		if vanillaLayers.layers[gamePath] == layer {
+			delete(vanillaLayers.layers, gamePath)
+		}
+		vanillaLayers.mu.Unlock()
+		return nil, 0, layer.err
+	}
+	return layer.index, layer.count, nil
+}
+
+// releaseVanilla ends a session's use of the vanilla index of gamePath. The
+// index is dropped once no session uses it.
+func releaseVanilla(gamePath string) {
+	vanillaLayers.mu.Lock()
+	defer vanillaLayers.mu.Unlock()
+	layer, ok := vanillaLayers.layers[gamePath]
+	if !ok {
+		return
+	}
+	layer.users--
+	if layer.users <= 0 {
+		delete(vanillaLayers.layers, gamePath)
+	}
+}
+
+// releaseVanillaLayer drops the vanilla index the session holds, if any.
+// The caller must hold s.mutex.
+func (s *Server) releaseVanillaLayer() {
+	if s.vanillaPath != "" {
+		releaseVanilla(s.vanillaPath)
+		s.vanillaPath = ""
+	}
+	s.Vanilla = nil
+	s.Index.SetBase(nil)
+}
+
+// runServe implements `gock3-lsp serve [-addr host:port] [--read-only]`,
+// which serves every client connecting over TCP with a session of its own:
+// open documents, settings and the workspace index are per client, while
+// the vanilla index of a game folder is shared. Clients are not
+// authenticated, and a session runs the external analyzers and plugins of
+// its initializationOptions, so listening on an address other machines
+// reach is warned about. It returns the process exit code.
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", "127.0.0.1:7007", "address to listen on")
+	readOnly := fs.Bool("read-only", false, "disable every feature that edits or writes files")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gock3-lsp serve: %v\n", err)
+		return 1
+	}
+	log.Printf("Listening for clients on %s", ln.Addr())
+	if !isLoopback(ln.Addr()) {
+		log.Printf("Warning: %s is reachable from other machines and clients are not authenticated; anyone connecting can read and write files and run commands as this user.", ln.Addr())
+	}
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			log.Printf("Failed to accept a client: %v", err)
+			return 1
+		}
+		go func() {
+			defer conn.Close()
+			log.Printf("Client connected from %s", conn.RemoteAddr())
+			s := NewServer()
+			s.readOnlyMode = *readOnly
+			err := s.serve(channel.Header("")(conn, conn))
+			log.Printf("Client %s disconnected: %v", conn.RemoteAddr(), err)
+		}()
+	}
+}
+
+// isLoopback reports whether addr only accepts connections from this
+// machine.
+func isLoopback(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	return ok && tcp.IP.IsLoopback()
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIsLoopback(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"127.0.0.1:7007", true},
+		{"[::1]:7007", true},
+		{"0.0.0.0:7007", false},
+		{"192.168.1.10:7007", false},
+	}
+	for _, tt := range tests {
+		addr, err := net.ResolveTCPAddr("tcp", tt.addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := isLoopback(addr); got != tt.want {
+			t.Errorf("isLoopback(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
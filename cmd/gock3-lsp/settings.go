@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/gui"
+	"github.com/unLomTrois/gock3-lsp/mod"
+	"github.com/unLomTrois/gock3-lsp/spell"
+)
+
+// Settings is the user configuration, received as initializationOptions and
+// through workspace/didChangeConfiguration.
+type Settings struct {
+	Diagnostics analysis.Options `json:"diagnostics"`
+	// DataTypesPath is the folder holding the data_types*.txt dumps written
+	// by the game's script_docs console command.
+	DataTypesPath string `json:"dataTypesPath"`
+	// GamePath is the game folder of the CK3 installation, e.g.
+	// ".../Crusader Kings III/game", indexed as the vanilla base layer.
+	// When empty, the installation is looked for in the usual places.
+	GamePath string `json:"gamePath"`
+	// ModsPath is the folder the launcher reads local mods from, detected
+	// like GamePath when empty.
+	ModsPath string `json:"modsPath"`
+	// ExternalFiles controls the diagnostics of files outside the
+	// workspace: "suppress" (the default), "downgrade" to hints, or "show".
+	ExternalFiles string `json:"externalFiles"`
+	// Generated configures the files external tools generate, by tool, or
+	// by analysis.AnyTool for every tool, as a `# generated by <tool>`
+	// header marks them.
+	Generated map[string]GeneratedSettings `json:"generated"`
+	// Localization configures the editing of localization files.
+	Localization LocalizationSettings `json:"localization"`
+	// Package configures the gock3.package command.
+	Package mod.PackageOptions `json:"package"`
+	// Spellcheck configures the spelling rule.
+	Spellcheck SpellcheckSettings `json:"spellcheck"`
+	// IdleTimeout is the number of minutes without requests after which
+	// the vanilla index and caches are released, 30 by default; a negative
+	// value keeps them.
+	IdleTimeout int `json:"idleTimeout"`
+	// FormatOnSave formats documents as they are saved, through
+	// textDocument/willSaveWaitUntil.
+	FormatOnSave bool `json:"formatOnSave"`
+	// InlayHints configures textDocument/inlayHint.
+	InlayHints InlayHintSettings `json:"inlayHints"`
+	// Plugins are Go plugins adding analyzers with extra rules. Like
+	// ExternalAnalyzers, they are only read from initializationOptions.
+	Plugins []string `json:"plugins"`
+	// ExternalAnalyzers are executables checking each file.
+	ExternalAnalyzers []ExternalAnalyzerSettings `json:"externalAnalyzers"`
+}
+
+// SpellcheckSettings configures the spell checking of localization.
+type SpellcheckSettings struct {
+	// Dictionaries are word list files, one word per line; Hunspell .dic
+	// files work too. Without any, the system word list is used.
+	Dictionaries []string `json:"dictionaries"`
+	// Words are extra words of the workspace, such as character names.
+	Words []string `json:"words"`
+	// Language is the localization language checked, "l_english" by
+	// default.
+	Language string `json:"language"`
+}
+
+// GeneratedSettings configures the files a tool generates, which are
+// still indexed for navigation.
+type GeneratedSettings struct {
+	// SkipFormat leaves them unformatted, so that the next run of the tool
+	// does not undo the formatting.
+	SkipFormat bool `json:"skipFormat"`
+	// SkipRules are the rules not reported in them, analysis.AnyTool for
+	// every rule.
+	SkipRules []string `json:"skipRules"`
+}
+
+// generated returns the settings of the files tool generates.
+func (s Settings) generated(tool string) (GeneratedSettings, bool) {
+	if g, ok := s.Generated[tool]; ok {
+		return g, true
+	}
+	g, ok := s.Generated[analysis.AnyTool]
+	return g, ok
+}
+
+// InlayHintSettings configures the inlay hints.
+type InlayHintSettings struct {
+	// ScriptValues shows the value of script values that evaluate
+	// statically, under Assumptions.
+	ScriptValues bool `json:"scriptValues"`
+	// Assumptions are sample values of the game state that script values
+	// read, such as `{ "gold": 500, "age": 30 }`, by operand or by its
+	// last link.
+	Assumptions map[string]float64 `json:"assumptions"`
+	// Scopes shows the scope type inferred for definitions and for the
+	// blocks that change scope.
+	Scopes bool `json:"scopes"`
+}
+
+// systemWordList is the word list used when no dictionary is configured.
+const systemWordList = "/usr/share/dict/words"
+
+// loadDictionary builds the spell checking dictionary of settings, reading
+// only the dictionaries that check accepts.
+func loadDictionary(settings SpellcheckSettings, check func(path string) error) *spell.Dictionary {
+	language := settings.Language
+	if language == "" {
+		language = "l_english"
+	}
+	d := spell.New(language, settings.Words...)
+	paths := settings.Dictionaries
+	if len(paths) == 0 {
+		paths = []string{systemWordList}
+	}
+	for _, path := range paths {
+		if err := check(path); err != nil {
+			log.Printf("Skipping dictionary: %v", err)
+			continue
+		}
+		if err := d.Load(path); err != nil {
+			log.Printf("Failed to load dictionary '%s': %v", path, err)
+		}
+	}
+	log.Printf("Loaded spell checking dictionary of %d words for %s.", d.Len(), language)
+	return d
+}
+
+// parseSettings decodes raw client settings. Clients may send the settings
+// object directly or nested under a "gock3" section.
+func parseSettings(raw interface{}) (Settings, error) {
+	var settings Settings
+	if raw == nil {
+		return settings, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return settings, err
+	}
+	var section struct {
+		Gock3 *json.RawMessage `json:"gock3"`
+	}
+	if err := json.Unmarshal(data, &section); err == nil && section.Gock3 != nil {
+		data = *section.Gock3
+	}
+	err = json.Unmarshal(data, &settings)
+	return settings, err
+}
+
+// WorkspaceDidChangeConfiguration applies new settings and refreshes the
+// diagnostics of open documents.
+func (s *Server) WorkspaceDidChangeConfiguration(ctx context.Context, params lsp.DidChangeConfigurationParams) error {
+	settings, err := parseSettings(params.Settings)
+	if err != nil {
+		log.Printf("Invalid settings in DidChangeConfiguration: %v", err)
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.applySettings(s.keepTrusted(settings))
+	log.Println("Settings updated; refreshing diagnostics.")
+	s.refreshDiagnostics(ctx, "")
+	return nil
+}
+
+// keepTrusted returns settings with the plugins and external analyzers
+// the initializationOptions set. Those run code, and the configuration a
+// client sends later may include the settings of the workspace folder,
+// which an untrusted mod can ship. The caller must hold s.mutex.
+func (s *Server) keepTrusted(settings Settings) Settings {
+	if len(settings.Plugins) > 0 && !reflect.DeepEqual(settings.Plugins, s.Settings.Plugins) ||
+		len(settings.ExternalAnalyzers) > 0 && !reflect.DeepEqual(settings.ExternalAnalyzers, s.Settings.ExternalAnalyzers) {
+		log.Println("Ignoring plugins and externalAnalyzers changed by didChangeConfiguration; they are only read from initializationOptions.")
+	}
+	settings.Plugins, settings.ExternalAnalyzers = s.Settings.Plugins, s.Settings.ExternalAnalyzers
+	return settings
+}
+
+// applySettings stores settings and reloads the resources they point to.
+// The caller must hold s.mutex.
+func (s *Server) applySettings(settings Settings) {
+	settings = s.detectPaths(settings)
+	if settings.DataTypesPath != s.Settings.DataTypesPath {
+		s.DataTypes = nil
+		if settings.DataTypesPath != "" {
+			err := s.checkReadable(settings.DataTypesPath, settings)
+			var dt *gui.DataTypes
+			if err == nil {
+				dt, err = gui.LoadDataTypes(settings.DataTypesPath)
+			}
+			if err != nil {
+				log.Printf("Failed to load data types from '%s': %v", settings.DataTypesPath, err)
+			} else {
+				s.DataTypes = dt
+				log.Printf("Loaded %d global data functions from: %s", len(dt.Globals), settings.DataTypesPath)
+			}
+		}
+	}
+	if settings.GamePath != s.Settings.GamePath {
+		s.releaseVanillaLayer()
+		s.GameVersion = ""
+		if settings.GamePath != "" {
+			go s.indexVanilla(settings.GamePath)
+		} else {
+			go s.updateIndexStatus(func(h *indexHealth) {
+				h.vanillaScan++
+				h.vanillaIndexing, h.vanillaFiles, h.vanillaError = false, 0, ""
+			})
+		}
+	}
+	switch {
+	case !settings.Diagnostics.Enabled("spelling"):
+		s.Dictionary = nil
+	case s.Dictionary == nil || !reflect.DeepEqual(settings.Spellcheck, s.Settings.Spellcheck):
+		s.Dictionary = loadDictionary(settings.Spellcheck, func(path string) error { return s.checkReadable(path, settings) })
+	}
+	s.loadPlugins(settings.Plugins)
+	if !reflect.DeepEqual(settings.ExternalAnalyzers, s.Settings.ExternalAnalyzers) {
+		s.analyzerCache.reset()
+	}
+	s.Settings = settings
+}
+
+// detectPaths fills in the game and mods folders of settings that leave
+// them empty with those of the local installation, looked for once.
+func (s *Server) detectPaths(settings Settings) Settings {
+	s.detectOnce.Do(func() {
+		s.detectedGamePath = mod.DetectGamePath()
+		s.detectedModsPath = mod.DetectModsPath()
+		log.Printf("Detected game folder: '%s', mods folder: '%s'", s.detectedGamePath, s.detectedModsPath)
+	})
+	if settings.GamePath == "" {
+		settings.GamePath = s.detectedGamePath
+	}
+	if settings.ModsPath == "" {
+		settings.ModsPath = s.detectedModsPath
+	}
+	return settings
+}
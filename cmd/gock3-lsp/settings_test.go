@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	lsp "github.com/sourcegraph/go-lsp"
+)
+
+// TestUntrustedSettings checks that the settings that run code are taken
+// from initializationOptions only, and not from didChangeConfiguration,
+// which may carry the settings of the workspace folder.
+func TestUntrustedSettings(t *testing.T) {
+	s := NewServer()
+	trusted := []ExternalAnalyzerSettings{{Name: "mine", Command: []string{"my-checks"}}}
+	_, err := s.Initialize(context.Background(), InitializeParams{InitializeParams: lsp.InitializeParams{
+		InitializationOptions: map[string]interface{}{
+			"externalAnalyzers": []map[string]interface{}{{"name": "mine", "command": []string{"my-checks"}}},
+			"gamePath":          t.TempDir(),
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s.Settings.ExternalAnalyzers, trusted) {
+		t.Fatalf("externalAnalyzers = %+v, want those of initializationOptions", s.Settings.ExternalAnalyzers)
+	}
+
+	err = s.WorkspaceDidChangeConfiguration(context.Background(), lsp.DidChangeConfigurationParams{
+		Settings: map[string]interface{}{"gock3": map[string]interface{}{
+			"externalAnalyzers": []map[string]interface{}{{"name": "evil", "command": []string{"sh", "-c", "rm -rf ~"}}},
+			"plugins":           []string{"/tmp/evil.so"},
+			"formatOnSave":      true,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s.Settings.ExternalAnalyzers, trusted) || len(s.Settings.Plugins) != 0 || len(s.plugins) != 0 {
+		t.Errorf("externalAnalyzers = %+v, plugins = %v, want those of initializationOptions", s.Settings.ExternalAnalyzers, s.Settings.Plugins)
+	}
+	if !s.Settings.FormatOnSave {
+		t.Error("the other settings of didChangeConfiguration are not applied")
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// blockParameter is an entry of an effect or trigger taking a block.
+// Names lists the keys that fill it, which are alternatives such as days,
+// months and years.
+type blockParameter struct {
+	Names []string
+	Value string
+	Doc   string
+}
+
+// blockSignature is the documentation of an effect or trigger taking a
+// block of parameters.
+type blockSignature struct {
+	Doc    string
+	Params []blockParameter
+}
+
+// durationParameter is the duration most timed effects take.
+var durationParameter = blockParameter{[]string{"days", "months", "years"}, "n", "How long it lasts, or how long until it happens; one of the three."}
+
+// blockSignatures are the effects and triggers that take a block, by key.
+var blockSignatures = map[string]blockSignature{
+	"add_opinion": {"Adds an opinion modifier towards target to the scope character.", []blockParameter{
+		{[]string{"target"}, "character", "The character the opinion is of."},
+		{[]string{"modifier"}, "opinion_modifier", "The opinion modifier from common/opinion_modifiers."},
+		{[]string{"opinion"}, "n", "Overrides the opinion value, for modifiers without a fixed one."},
+		durationParameter,
+	}},
+	"reverse_add_opinion": {"Adds an opinion modifier of target towards the scope character.", []blockParameter{
+		{[]string{"target"}, "character", "The character holding the opinion."},
+		{[]string{"modifier"}, "opinion_modifier", "The opinion modifier from common/opinion_modifiers."},
+		{[]string{"opinion"}, "n", "Overrides the opinion value, for modifiers without a fixed one."},
+		durationParameter,
+	}},
+	"remove_opinion": {"Removes an opinion modifier towards target.", []blockParameter{
+		{[]string{"target"}, "character", "The character the opinion is of."},
+		{[]string{"modifier"}, "opinion_modifier", "The opinion modifier to remove."},
+	}},
+	"has_opinion_modifier": {"Checks for an opinion modifier towards target.", []blockParameter{
+		{[]string{"target"}, "character", "The character the opinion is of."},
+		{[]string{"modifier"}, "opinion_modifier", "The opinion modifier."},
+		{[]string{"value"}, "n", "Optionally compares the value of the modifier."},
+	}},
+	"opinion": {"Compares the opinion of the scope character of target.", []blockParameter{
+		{[]string{"target"}, "character", "The character the opinion is of."},
+		{[]string{"value"}, "n", "The value compared against, with an operator."},
+	}},
+	"trigger_event": {"Fires an event for the scope, now or after a delay.", []blockParameter{
+		{[]string{"id", "on_action"}, "event", "The event, or an on_action to fire instead."},
+		durationParameter,
+	}},
+	"add_character_modifier": {"Adds a static modifier to the scope character.", []blockParameter{
+		{[]string{"modifier"}, "modifier", "The modifier from common/modifiers."},
+		durationParameter,
+	}},
+	"add_county_modifier": {"Adds a static modifier to the scope county.", []blockParameter{
+		{[]string{"modifier"}, "modifier", "The modifier from common/modifiers."},
+		durationParameter,
+	}},
+	"set_variable": {"Sets a variable of the scope.", []blockParameter{
+		{[]string{"name"}, "variable", "The name of the variable."},
+		{[]string{"value"}, "value", "The value, a number, scope or flag; yes if left out."},
+		durationParameter,
+	}},
+	"change_variable": {"Changes a numeric variable of the scope.", []blockParameter{
+		{[]string{"name"}, "variable", "The name of the variable."},
+		{[]string{"add", "subtract", "multiply", "divide", "modulo", "min", "max"}, "n", "The operation and its operand."},
+	}},
+	"add_hook": {"Gives the scope character a hook on target.", []blockParameter{
+		{[]string{"type"}, "hook_type", "The hook type from common/hook_types."},
+		{[]string{"target"}, "character", "The character the hook is on."},
+		durationParameter,
+	}},
+	"send_interface_message": {"Sends a message to the scope character's player.", []blockParameter{
+		{[]string{"type"}, "message_type", "The message type from common/messages."},
+		{[]string{"title"}, "localization", "The title of the message."},
+		{[]string{"desc"}, "localization", "The description of the message."},
+		{[]string{"left_icon", "right_icon"}, "scope", "The scopes shown beside the message."},
+	}},
+	"save_scope_value_as": {"Saves a value as a named scope.", []blockParameter{
+		{[]string{"name"}, "scope_name", "The name, used as scope:name."},
+		{[]string{"value"}, "value", "The value to save."},
+	}},
+	"random": {"Runs the effects inside with a chance.", []blockParameter{
+		{[]string{"chance"}, "n", "The percent chance."},
+		{[]string{"modifier"}, "modifier", "Changes the chance when its trigger holds."},
+	}},
+	"duel": {"Runs a skill duel against a target or a value.", []blockParameter{
+		{[]string{"skill"}, "skill", "The skill used by the scope character."},
+		{[]string{"target", "value"}, "character", "The opponent, or a fixed skill value."},
+	}},
+}
+
+// signatureKeyPattern matches the key being written at the end of a line
+// prefix, if any.
+var signatureKeyPattern = regexp.MustCompile(`(?:^|[\s{])([A-Za-z_]+)\s*(?:[<>=!?]=?\s*\S*)?$`)
+
+// TextDocumentSignatureHelp shows the parameters of the effect or trigger
+// whose block the cursor is in, such as `add_opinion = { ... }`, with the
+// one being written active, or else the first one the block lacks.
+func (s *Server) TextDocumentSignatureHelp(ctx context.Context, params lsp.TextDocumentPositionParams) (*lsp.SignatureHelp, error) {
+	log.Printf("Signature help request received for URI: %s at position Line %d, Character %d",
+		params.TextDocument.URI, params.Position.Line, params.Position.Character)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	path := s.enclosingFields(filePath, params.Position)
+	if len(path) == 0 {
+		return nil, nil
+	}
+	block := path[len(path)-1]
+	sig, ok := blockSignatures[block.KeyText()]
+	if !ok || !block.Block().Loc.Contains(pdx.Pos{Line: params.Position.Line, Col: params.Position.Character}) {
+		return nil, nil
+	}
+
+	labels := make([]string, len(sig.Params))
+	info := lsp.SignatureInformation{Documentation: sig.Doc}
+	for i, p := range sig.Params {
+		labels[i] = strings.Join(p.Names, "|") + " = " + p.Value
+		info.Parameters = append(info.Parameters, lsp.ParameterInformation{Label: labels[i], Documentation: p.Doc})
+	}
+	info.Label = block.KeyText() + " = { " + strings.Join(labels, " ") + " }"
+
+	active := -1
+	if m := signatureKeyPattern.FindStringSubmatch(linePrefix(s.Documents[filePath], params.Position)); m != nil {
+		active = parameterIndex(sig, m[1])
+	}
+	for i, p := range sig.Params {
+		if active >= 0 {
+			break
+		}
+		present := false
+		for _, name := range p.Names {
+			present = present || block.Block().Get(name) != nil
+		}
+		if !present {
+			active = i
+		}
+	}
+	return &lsp.SignatureHelp{Signatures: []lsp.SignatureInformation{info}, ActiveParameter: max(active, 0)}, nil
+}
+
+// parameterIndex returns the index of the parameter of sig that key
+// fills, or -1.
+func parameterIndex(sig blockSignature, key string) int {
+	for i, p := range sig.Params {
+		for _, name := range p.Names {
+			if name == key {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+	"github.com/unLomTrois/gock3-lsp/scope"
+)
+
+// SimulateParams are the parameters of gock3/simulate: an event ID, or a
+// document position inside an event.
+type SimulateParams struct {
+	Event        string                      `json:"event,omitempty"`
+	TextDocument *lsp.TextDocumentIdentifier `json:"textDocument,omitempty"`
+	Position     *lsp.Position               `json:"position,omitempty"`
+}
+
+// SimulateSection lists the outcomes of one effect block of an event, such
+// as `immediate` or an option. Nested outcomes are indented by two spaces
+// per level under the condition or scope change they depend on.
+type SimulateSection struct {
+	Title    string   `json:"title"`
+	Outcomes []string `json:"outcomes"`
+}
+
+// SimulateResult is the outcome summary of an event.
+type SimulateResult struct {
+	Event    string            `json:"event"`
+	Sections []SimulateSection `json:"sections"`
+	// Text is the whole summary as Markdown, for display in a panel.
+	Text string `json:"text"`
+}
+
+// simulatedBlocks are the effect blocks of an event that are summarized,
+// in the order the game runs them.
+var simulatedBlocks = []string{"immediate", "option", "after"}
+
+// maxSimulateDepth bounds how deep scripted effects are expanded.
+const maxSimulateDepth = 4
+
+// Simulate handles the experimental gock3/simulate request. It walks the
+// effects of an event symbolically and describes what they do, without
+// evaluating any trigger.
+func (s *Server) Simulate(ctx context.Context, params SimulateParams) (SimulateResult, error) {
+	log.Printf("Simulate request received for event '%s'.", params.Event)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	event, err := s.simulatedEvent(params)
+	if err != nil {
+		log.Printf("Cannot simulate: %v", err)
+		return SimulateResult{}, err
+	}
+	defs := s.Index.Definitions(index.KindEvent, event)
+	if len(defs) == 0 {
+		return SimulateResult{}, fmt.Errorf("unknown event %s", event)
+	}
+	entry := s.Index.File(defs[0].Path)
+	f := s.Index.FieldAt(defs[0].Location)
+	if entry == nil || f == nil || f.Block() == nil {
+		return SimulateResult{}, fmt.Errorf("event %s has no body", event)
+	}
+
+	sim := &simulator{s: s, text: entry.File.Text}
+	result := SimulateResult{Event: event, Sections: []SimulateSection{}}
+	for _, key := range simulatedBlocks {
+		for _, block := range f.Block().All(key) {
+			if block.Block() == nil {
+				continue
+			}
+			title := key
+			if key == "option" {
+				title = "option"
+				if name := block.Block().Get("name"); name != nil && name.Scalar() != nil {
+					title = fmt.Sprintf("option %s %s", name.ValueText(), s.localizedText(name.ValueText()))
+				}
+			}
+			sim.outcomes = []string{}
+			sim.walk(block.Block(), 0, 0)
+			result.Sections = append(result.Sections, SimulateSection{Title: title, Outcomes: sim.outcomes})
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n", event)
+	for _, section := range result.Sections {
+		fmt.Fprintf(&b, "\n### %s\n\n", section.Title)
+		if len(section.Outcomes) == 0 {
+			b.WriteString("_nothing_\n")
+		}
+		for _, outcome := range section.Outcomes {
+			trimmed := strings.TrimLeft(outcome, " ")
+			fmt.Fprintf(&b, "%s- %s\n", outcome[:len(outcome)-len(trimmed)], trimmed)
+		}
+	}
+	result.Text = b.String()
+	log.Printf("Simulated %d sections of event %s.", len(result.Sections), event)
+	return result, nil
+}
+
+// simulatedEvent returns the event the parameters point at.
+func (s *Server) simulatedEvent(params SimulateParams) (string, error) {
+	if params.Event != "" {
+		return params.Event, nil
+	}
+	if params.TextDocument == nil || params.Position == nil {
+		return "", errors.New("expected an event ID or a position")
+	}
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return "", err
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil {
+		return "", errors.New("document is not indexed")
+	}
+	path := entry.File.PathAt(pdx.Pos{Line: params.Position.Line, Col: params.Position.Character})
+	if len(path) == 0 || !index.IsEventID(path[0].KeyText()) {
+		return "", errors.New("no event at the given position")
+	}
+	return path[0].KeyText(), nil
+}
+
+// simulator collects the outcome lines of an effect block.
+type simulator struct {
+	s        *Server
+	text     string
+	outcomes []string
+}
+
+var (
+	variableEffectPattern = regexp.MustCompile(`^(set|change|remove|clamp)_(global_|local_)?variable$`)
+	modifierEffectPattern = regexp.MustCompile(`^(add|remove)_([a-z_]+)_modifier$`)
+	currencyEffectPattern = regexp.MustCompile(`^(add|remove)_(gold|prestige|piety|dread|stress|tyranny|renown|influence|short_term_gold)$`)
+)
+
+// walk adds the outcomes of the effects of b at the given nesting level.
+// calls counts the scripted effects expanded on the way.
+func (sim *simulator) walk(b *pdx.Block, level, calls int) {
+	for i, f := range b.Fields {
+		key := f.KeyText()
+		switch key {
+		case "", "name", "limit", "trigger", "alternative_limit", "ai_chance", "weight", "chance", "modifier",
+			"show_chance", "desc", "custom_tooltip", "show_as_tooltip", "flavor", "order_by", "max", "min":
+			continue
+		}
+		sub := f.Block()
+		switch {
+		case key == "if" || key == "else_if":
+			sim.nested(f, level, calls, fmt.Sprintf("%s %s:", strings.ReplaceAll(key, "_", " "), sim.condition(sub)))
+		case key == "else":
+			sim.nested(f, level, calls, "otherwise:")
+		case key == "hidden_effect":
+			sim.nested(f, level, calls, "hidden:")
+		case key == "random" && sub != nil:
+			sim.nested(f, level, calls, fmt.Sprintf("with %s%% chance:", valueOf(sub, "chance")))
+		case key == "random_list" && sub != nil:
+			sim.randomList(sub, level, calls)
+		case sub != nil && isIterator(key):
+			prefix, _, name, _ := scope.Iterator(key)
+			sim.nested(f, level, calls, fmt.Sprintf("%s %s:", iteratorPhrase(prefix), strings.ReplaceAll(name, "_", " ")))
+		default:
+			sim.effect(b, i, level, calls)
+		}
+	}
+}
+
+// nested adds a heading line and the outcomes of f's block below it.
+func (sim *simulator) nested(f *pdx.Field, level, calls int, heading string) {
+	if f.Block() == nil {
+		return
+	}
+	sim.add(level, heading)
+	sim.walk(f.Block(), level+1, calls)
+}
+
+// randomList adds the outcomes of each entry of a random_list with its
+// base chance.
+func (sim *simulator) randomList(b *pdx.Block, level, calls int) {
+	total := 0.0
+	for _, f := range b.Fields {
+		if n, err := strconv.ParseFloat(f.KeyText(), 64); err == nil && f.Block() != nil {
+			total += n
+		}
+	}
+	sim.add(level, "one of, at random:")
+	for _, f := range b.Fields {
+		n, err := strconv.ParseFloat(f.KeyText(), 64)
+		if err != nil || f.Block() == nil || total == 0 {
+			continue
+		}
+		heading := fmt.Sprintf("%.0f%% base chance", 100*n/total)
+		if f.Block().Get("modifier") != nil {
+			heading += " (modified)"
+		}
+		sim.nested(f, level+1, calls, heading+":")
+	}
+}
+
+// effect adds the outcome of the i-th field of b.
+func (sim *simulator) effect(b *pdx.Block, i, level, calls int) {
+	f := b.Fields[i]
+	key := f.KeyText()
+	value := f.ValueText()
+	sub := f.Block()
+	switch {
+	case key == "add_trait" || key == "remove_trait":
+		sim.add(level, fmt.Sprintf("%ss trait %s", strings.TrimSuffix(key, "_trait"), value))
+	case key == "trigger_event":
+		sim.add(level, "fires "+sim.triggeredEvent(f))
+	case key == "save_scope_as" || key == "save_temporary_scope_as":
+		sim.add(level, "saves the scope as scope:"+value)
+	case key == "death":
+		sim.add(level, "dies")
+	case currencyEffectPattern.MatchString(key):
+		m := currencyEffectPattern.FindStringSubmatch(key)
+		sign := "+"
+		if m[1] == "remove" {
+			sign = "-"
+		}
+		sim.add(level, fmt.Sprintf("%s %s%s", strings.ReplaceAll(m[2], "_", " "), sign, sim.amount(f)))
+	case variableEffectPattern.MatchString(key):
+		m := variableEffectPattern.FindStringSubmatch(key)
+		name, val := value, ""
+		if sub != nil {
+			name = valueOf(sub, "name")
+			if v := sub.Get("value"); v != nil {
+				val = " to " + sim.source(v)
+			} else if v := sub.Get("add"); v != nil {
+				val = " by " + sim.source(v)
+			}
+		}
+		sim.add(level, fmt.Sprintf("%ss %svariable %s%s", m[1], m[2], name, val))
+	case modifierEffectPattern.MatchString(key):
+		m := modifierEffectPattern.FindStringSubmatch(key)
+		name := value
+		if sub != nil {
+			name = valueOf(sub, "modifier")
+		}
+		sim.add(level, fmt.Sprintf("%ss %s modifier %s", m[1], strings.ReplaceAll(m[2], "_", " "), name))
+	case flagOutcome(f) != "":
+		sim.add(level, flagOutcome(f))
+	case len(sim.s.Index.Definitions(index.KindScriptedEffect, key)) > 0:
+		sim.add(level, "runs scripted effect "+key+":")
+		defs := sim.s.Index.Definitions(index.KindScriptedEffect, key)
+		def := sim.s.Index.FieldAt(defs[0].Location)
+		entry := sim.s.Index.File(defs[0].Path)
+		if calls >= maxSimulateDepth || def == nil || def.Block() == nil || entry == nil {
+			sim.add(level+1, "…")
+			return
+		}
+		inner := &simulator{s: sim.s, text: entry.File.Text, outcomes: sim.outcomes}
+		inner.walk(def.Block(), level+1, calls+1)
+		sim.outcomes = inner.outcomes
+	case sub != nil && scope.IsChainHead(strings.SplitN(key, ".", 2)[0]):
+		sim.nested(f, level, calls, fmt.Sprintf("for %s:", key))
+	case sub != nil && hasBlocks(sub):
+		sim.nested(f, level, calls, key+":")
+	default:
+		r := f.Range()
+		sim.add(level, strings.Join(strings.Fields(sim.text[r.Start.Offset:r.End.Offset]), " "))
+	}
+}
+
+// flagOutcome describes a flag effect, or returns "" for other fields.
+func flagOutcome(f *pdx.Field) string {
+	op, kind, ok := index.ParseFlagKey(f.KeyText())
+	if !ok || op == index.FlagCheck {
+		return ""
+	}
+	name := f.ValueText()
+	if b := f.Block(); b != nil {
+		name = valueOf(b, "flag")
+	}
+	verb := "sets"
+	if op == index.FlagRemove {
+		verb = "removes"
+	}
+	return fmt.Sprintf("%s %s %s", verb, strings.ReplaceAll(string(kind), "_", " "), strings.TrimPrefix(name, "flag:"))
+}
+
+// triggeredEvent describes the event or on_action a trigger_event fires.
+func (sim *simulator) triggeredEvent(f *pdx.Field) string {
+	b := f.Block()
+	if b == nil {
+		return "event " + f.ValueText()
+	}
+	target := "event " + valueOf(b, "id")
+	if b.Get("id") == nil {
+		target = "on_action " + valueOf(b, "on_action")
+	}
+	for _, unit := range []string{"days", "weeks", "months", "years"} {
+		if d := b.Get(unit); d != nil {
+			target += fmt.Sprintf(" after %s %s", sim.source(d), unit)
+		}
+	}
+	return target
+}
+
+// amount returns the compact source of an effect's value, or of the
+// `value` of its block form.
+func (sim *simulator) amount(f *pdx.Field) string {
+	if b := f.Block(); b != nil && b.Get("value") != nil {
+		return sim.source(b.Get("value"))
+	}
+	if f.Block() != nil {
+		return "(computed)"
+	}
+	return f.ValueText()
+}
+
+// condition returns the compact source of the limit of an if block.
+func (sim *simulator) condition(b *pdx.Block) string {
+	if b == nil || b.Get("limit") == nil || b.Get("limit").Block() == nil {
+		return "(always)"
+	}
+	limit := b.Get("limit").Block()
+	r := limit.Loc
+	text := strings.Join(strings.Fields(sim.text[r.Start.Offset:r.End.Offset]), " ")
+	if len(text) > 80 {
+		text = text[:77] + "... }"
+	}
+	return text
+}
+
+// source returns the script text of a field's value, on one line.
+func (sim *simulator) source(f *pdx.Field) string {
+	if f.Value == nil {
+		return ""
+	}
+	r := f.Value.Range()
+	return strings.Join(strings.Fields(sim.text[r.Start.Offset:r.End.Offset]), " ")
+}
+
+func (sim *simulator) add(level int, line string) {
+	sim.outcomes = append(sim.outcomes, strings.Repeat("  ", level)+line)
+}
+
+// iteratorPhrase describes the scopes an iterator prefix runs an effect
+// on.
+func iteratorPhrase(prefix string) string {
+	switch prefix {
+	case "every":
+		return "for every"
+	case "random":
+		return "for a random"
+	case "ordered":
+		return "for the first ordered"
+	}
+	return "for any"
+}
+
+// isIterator reports whether key is an every_, random_, ordered_ or any_
+// iterator.
+func isIterator(key string) bool {
+	prefix, _, _, _ := scope.Iterator(key)
+	return prefix != "" && !scope.IsNonIterator(key)
+}
+
+// valueOf returns the scalar value of the first field of b with the given
+// key, or "".
+func valueOf(b *pdx.Block, key string) string {
+	if f := b.Get(key); f != nil {
+		return f.ValueText()
+	}
+	return ""
+}
+
+// hasBlocks reports whether any field of b has a block value, which makes
+// b a block of effects rather than the arguments of one.
+func hasBlocks(b *pdx.Block) bool {
+	for _, f := range b.Fields {
+		if f.Block() != nil {
+			return true
+		}
+	}
+	return false
+}
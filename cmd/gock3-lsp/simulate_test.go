@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	lsp "github.com/sourcegraph/go-lsp"
+)
+
+// simulateFiles is a mod with an event that uses every kind of outcome the
+// simulation describes, and a scripted effect calling itself.
+var simulateFiles = map[string]string{
+	"/mod/events/a.txt": `namespace = a
+a.0001 = {
+	type = character_event
+	immediate = {
+		add_gold = 50
+		if = {
+			limit = { is_ai = yes }
+			add_trait = brave
+		}
+		else = { remove_trait = craven }
+		every_vassal = { add_prestige = { value = 10 } }
+		my_effect = yes
+		trigger_event = { id = a.0002 days = 3 }
+	}
+	option = {
+		name = a.0001.a
+		random_list = {
+			30 = { death = yes }
+			10 = {
+				modifier = { add = 5 }
+				add_character_flag = brave_flag
+			}
+		}
+	}
+	option = { name = a.0001.b }
+}
+a.0002 = {
+	type = character_event
+	immediate = { my_recursion = yes }
+}
+`,
+	"/mod/common/scripted_effects/my_effects.txt": `my_effect = {
+	save_scope_as = target
+	set_variable = { name = x value = 5 }
+}
+my_recursion = { my_recursion = yes }
+`,
+	"/mod/localization/english/my_l_english.yml": "\uFEFFl_english:\n a.0001.a:0 \"Be brave\"\n",
+}
+
+func newSimulateServer() *Server {
+	s := NewServer()
+	s.RootPath = "/mod"
+	for path, text := range simulateFiles {
+		openDocument(s, path, text)
+	}
+	return s
+}
+
+func TestSimulate(t *testing.T) {
+	s := newSimulateServer()
+	got, err := s.Simulate(context.Background(), SimulateParams{Event: "a.0001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SimulateSection{
+		{Title: "immediate", Outcomes: []string{
+			"gold +50",
+			"if { is_ai = yes }:",
+			"  adds trait brave",
+			"otherwise:",
+			"  removes trait craven",
+			"for every vassal:",
+			"  prestige +10",
+			"runs scripted effect my_effect:",
+			"  saves the scope as scope:target",
+			"  sets variable x to 5",
+			"fires event a.0002 after 3 days",
+		}},
+		{Title: `option a.0001.a "Be brave"`, Outcomes: []string{
+			"one of, at random:",
+			"  75% base chance:",
+			"    dies",
+			"  25% base chance (modified):",
+			"    sets character flag brave_flag",
+		}},
+		{Title: "option a.0001.b _no localization_", Outcomes: []string{}},
+	}
+	if !reflect.DeepEqual(got.Sections, want) {
+		t.Errorf("sections = %q, want %q", got.Sections, want)
+	}
+	for _, line := range []string{"## a.0001\n", "- if { is_ai = yes }:\n  - adds trait brave\n", "### option a.0001.b _no localization_\n\n_nothing_\n"} {
+		if !strings.Contains(got.Text, line) {
+			t.Errorf("text lacks %q:\n%s", line, got.Text)
+		}
+	}
+}
+
+// TestSimulateRecursion checks that a scripted effect calling itself is
+// expanded a bounded number of times.
+func TestSimulateRecursion(t *testing.T) {
+	s := newSimulateServer()
+	got, err := s.Simulate(context.Background(), SimulateParams{Event: "a.0002"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []string
+	for level := 0; level <= maxSimulateDepth; level++ {
+		want = append(want, strings.Repeat("  ", level)+"runs scripted effect my_recursion:")
+	}
+	want = append(want, strings.Repeat("  ", maxSimulateDepth+1)+"…")
+	if len(got.Sections) != 1 || !reflect.DeepEqual(got.Sections[0].Outcomes, want) {
+		t.Errorf("sections = %q, want one with %q", got.Sections, want)
+	}
+}
+
+func TestSimulateAtPosition(t *testing.T) {
+	s := newSimulateServer()
+	uri := filePathToURI("/mod/events/a.txt")
+	// The cursor is on add_trait in a.0001.
+	got, err := s.Simulate(context.Background(), SimulateParams{
+		TextDocument: &lsp.TextDocumentIdentifier{URI: uri},
+		Position:     &lsp.Position{Line: 7, Character: 4},
+	})
+	if err != nil || got.Event != "a.0001" {
+		t.Errorf("Simulate at a position = %q, %v, want a.0001", got.Event, err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		params SimulateParams
+		err    string
+	}{
+		{"no parameters", SimulateParams{}, "expected an event ID or a position"},
+		{"unknown event", SimulateParams{Event: "a.9999"}, "unknown event a.9999"},
+		{"namespace", SimulateParams{TextDocument: &lsp.TextDocumentIdentifier{URI: uri}, Position: &lsp.Position{Line: 0, Character: 2}}, "no event at the given position"},
+		{"unknown document", SimulateParams{TextDocument: &lsp.TextDocumentIdentifier{URI: filePathToURI("/mod/events/b.txt")}, Position: &lsp.Position{}}, "document is not indexed"},
+	} {
+		if _, err := s.Simulate(context.Background(), tt.params); err == nil || err.Error() != tt.err {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/scope"
+)
+
+// snippet is the skeleton of a common construct, with the tab stops of
+// the snippet syntax of LSP.
+type snippet struct {
+	label, detail, body string
+}
+
+// effectSnippets are offered where effects are written.
+var effectSnippets = []snippet{
+	{"if", "if block", "if = {\n\tlimit = {\n\t\t$1\n\t}\n\t$0\n}"},
+	{"else_if", "else_if block", "else_if = {\n\tlimit = {\n\t\t$1\n\t}\n\t$0\n}"},
+	{"else", "else block", "else = {\n\t$0\n}"},
+	{"random_list", "random_list block", "random_list = {\n\t${1:50} = {\n\t\t$2\n\t}\n\t${3:50} = {\n\t\t$0\n\t}\n}"},
+}
+
+// triggerSnippets are offered where triggers are written.
+var triggerSnippets = []snippet{
+	{"trigger_if", "trigger_if block", "trigger_if = {\n\tlimit = {\n\t\t$1\n\t}\n\t$0\n}"},
+	{"trigger_else", "trigger_else block", "trigger_else = {\n\t$0\n}"},
+}
+
+// decisionSnippet is the skeleton of a decision.
+var decisionSnippet = snippet{"decision", "decision template", `${1:my_decision} = {
+	picture = {
+		reference = "${2:gfx/interface/illustrations/decisions/decision_misc.dds}"
+	}
+	desc = ${1}_desc
+	selection_tooltip = ${1}_tooltip
+
+	is_shown = {
+		$3
+	}
+	is_valid = {
+		$4
+	}
+	cost = {
+		gold = ${5:50}
+	}
+
+	effect = {
+		$0
+	}
+
+	ai_check_interval = ${6:120}
+	ai_will_do = {
+		base = ${7:0}
+	}
+}`}
+
+// snippetCompletions offers the skeletons of common constructs at the
+// start of a key, for clients that support snippets: a whole event at the
+// top level of an event file, numbered with its next ID, a decision at the
+// top level of common/decisions, and if, else and random_list blocks where
+// effects or triggers are written. They are added to the items of the
+// other providers rather than replacing them.
+func (s *Server) snippetCompletions(params lsp.TextDocumentPositionParams) []lsp.CompletionItem {
+	if !s.snippetSupport {
+		return nil
+	}
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil || !index.IsScriptFile(filePath) {
+		return nil
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil {
+		return nil
+	}
+	if !topLevelKeyPattern.MatchString(linePrefix(s.Documents[filePath], params.Position)) {
+		return nil
+	}
+
+	var snippets []snippet
+	fields := s.enclosingFields(filePath, params.Position)
+	if len(fields) == 0 {
+		switch {
+		case entry.Role.Role == index.RoleEvents:
+			snippets = append(snippets, eventSnippet(entry))
+		case strings.HasPrefix(entry.VirtualPath, "common/decisions/"):
+			snippets = append(snippets, decisionSnippet)
+		}
+	} else {
+		f := fields[len(fields)-1]
+		context := scope.BlockContext(f.KeyText())
+		if context == scope.ContextUnknown {
+			context = scope.ContextOf(f, entry.VirtualPath)
+		}
+		switch context {
+		case scope.ContextEffect:
+			snippets = effectSnippets
+		case scope.ContextTrigger:
+			snippets = triggerSnippets
+		}
+	}
+
+	items := make([]lsp.CompletionItem, 0, len(snippets))
+	for _, sn := range snippets {
+		items = append(items, lsp.CompletionItem{
+			Label:            sn.label,
+			Kind:             lsp.CIKSnippet,
+			Detail:           sn.detail,
+			InsertText:       sn.body,
+			InsertTextFormat: lsp.ITFSnippet,
+		})
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return items
+}
+
+// eventSnippet is the skeleton of an event with the next ID of the event
+// file of entry.
+func eventSnippet(entry *index.FileEntry) snippet {
+	ids := eventIDItems(entry)
+	id := ids[len(ids)-1].Label
+	return snippet{"event", "event template", fmt.Sprintf(`${1:%s} = {
+	type = ${2|character_event,letter_event,court_event,activity_event|}
+	title = ${1}.t
+	desc = ${1}.desc
+	theme = ${3:default}
+
+	left_portrait = {
+		character = root
+		animation = ${4:idle}
+	}
+
+	trigger = {
+		$5
+	}
+
+	immediate = {
+		$6
+	}
+
+	option = {
+		name = ${1}.a
+		$0
+	}
+}`, id)}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// sourceHoverKinds are the kinds whose hover shows the script of their
+// definition, such as the formula of a scripted modifier.
+var sourceHoverKinds = map[index.Kind]bool{
+	index.KindScriptedModifier: true,
+}
+
+// sourceHover shows the definition of a symbol of a sourceHoverKinds kind
+// under the cursor. It returns nil elsewhere.
+func (s *Server) sourceHover(filePath string, pos lsp.Position) *lsp.Hover {
+	entry := s.Index.File(filePath)
+	if entry == nil {
+		return nil
+	}
+	kind, name, rng, ok := entry.SymbolAt(pdx.Pos{Line: pos.Line, Col: pos.Character})
+	if !ok || !sourceHoverKinds[kind] {
+		return nil
+	}
+	defs := s.Index.Definitions(kind, name)
+	if len(defs) == 0 {
+		return nil
+	}
+	f := s.Index.FieldAt(defs[0].Location)
+	def := s.Index.File(defs[0].Path)
+	if f == nil || def == nil {
+		return nil
+	}
+	r := f.Range()
+	text := def.File.Text[r.Start.Offset:r.End.Offset]
+	contents := fmt.Sprintf("**%s** `%s`\n\n```pdx\n%s\n```\n\n_%s_", strings.ReplaceAll(string(kind), "_", " "), name, text, s.Index.VirtualPath(defs[0].Path))
+	hoverRange := analysis.Range(rng)
+	return &lsp.Hover{
+		Contents: []lsp.MarkedString{lsp.RawMarkedString(contents)},
+		Range:    &hoverRange,
+	}
+}
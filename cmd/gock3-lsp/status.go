@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// States of an IndexStatus.
+const (
+	statusIdle     = "idle"
+	statusIndexing = "indexing"
+	statusError    = "error"
+)
+
+// IndexStatus is the body of the gock3/indexStatus notification, sent
+// whenever indexing starts or ends so that clients can show the health of
+// the index in a status bar.
+type IndexStatus struct {
+	// State is "idle", "indexing" or "error".
+	State string `json:"state"`
+	// WorkspaceFiles and VanillaFiles count the indexed files.
+	WorkspaceFiles int `json:"workspaceFiles"`
+	VanillaFiles   int `json:"vanillaFiles"`
+	// Message explains an error, or says what is being indexed.
+	Message string `json:"message,omitempty"`
+}
+
+// indexHealth tracks the progress of the workspace and vanilla scans. It
+// has its own lock because scans run without holding the server's.
+type indexHealth struct {
+	mu                sync.Mutex
+	workspaceIndexing bool
+	vanillaIndexing   bool
+	workspaceFiles    int
+	vanillaFiles      int
+	workspaceError    string
+	vanillaError      string
+	// vanillaScan numbers the vanilla scans, so that one made stale by a
+	// change of the gamePath leaves the status of the next alone.
+	vanillaScan int
+}
+
+// status summarizes the health of the index.
+func (h *indexHealth) status() IndexStatus {
+	status := IndexStatus{State: statusIdle, WorkspaceFiles: h.workspaceFiles, VanillaFiles: h.vanillaFiles}
+	switch {
+	case h.workspaceError != "":
+		status.State, status.Message = statusError, h.workspaceError
+	case h.vanillaError != "":
+		status.State, status.Message = statusError, h.vanillaError
+	case h.workspaceIndexing:
+		status.State, status.Message = statusIndexing, "Indexing the workspace"
+	case h.vanillaIndexing:
+		status.State, status.Message = statusIndexing, "Indexing the vanilla game files"
+	}
+	return status
+}
+
+// updateIndexStatus changes the index health with update and notifies the
+// client of the result.
+func (s *Server) updateIndexStatus(update func(h *indexHealth)) {
+	s.health.mu.Lock()
+	update(&s.health)
+	status := s.health.status()
+	s.health.mu.Unlock()
+
+	log.Printf("Index status: %s (%d workspace files, %d vanilla files) %s", status.State, status.WorkspaceFiles, status.VanillaFiles, status.Message)
+	if err := s.jrpcServer.Notify(context.Background(), "gock3/indexStatus", status); err != nil {
+		log.Printf("Failed to send index status: %v", err)
+	}
+}
+
+// checkGamePath reports why gamePath is not a readable CK3 game folder, if
+// it is not one.
+func checkGamePath(gamePath string) error {
+	info, err := os.Stat(gamePath)
+	if err != nil {
+		return fmt.Errorf("gamePath '%s' cannot be read: %v", gamePath, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("gamePath '%s' is not a folder", gamePath)
+	}
+	if _, err := os.ReadDir(filepath.Join(gamePath, "common")); err != nil {
+		if _, nested := os.Stat(filepath.Join(gamePath, "game", "common")); nested == nil {
+			return fmt.Errorf("gamePath '%s' is the installation folder; use its 'game' subfolder", gamePath)
+		}
+		return fmt.Errorf("gamePath '%s' has no readable 'common' folder; expected the 'game' folder of a CK3 installation", gamePath)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/index"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+	"github.com/unLomTrois/gock3-lsp/scope"
+)
+
+// DocumentSymbol is an entry of the outline of a file, which go-lsp lacks.
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           lsp.SymbolKind   `json:"kind"`
+	Range          lsp.Range        `json:"range"`
+	SelectionRange lsp.Range        `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+// definitionSymbolKinds are the outline kinds of the top-level definitions
+// of the index kinds; other definitions show as classes.
+var definitionSymbolKinds = map[index.Kind]lsp.SymbolKind{
+	index.KindEvent:            lsp.SKEvent,
+	index.KindOnAction:         lsp.SKEvent,
+	index.KindScriptedEffect:   lsp.SKFunction,
+	index.KindScriptedTrigger:  lsp.SKFunction,
+	index.KindScriptedModifier: lsp.SKFunction,
+	index.KindScriptValue:      lsp.SKVariable,
+	index.KindScriptedGUI:      lsp.SKInterface,
+	index.KindGUITemplate:      lsp.SKInterface,
+	index.KindGUIType:          lsp.SKInterface,
+}
+
+// TextDocumentDocumentSymbol returns the outline of a file: its top-level
+// definitions, constants and namespace, with the blocks they contain
+// (options, triggers, effects...) nested below. Localization files list
+// their keys. Clients without hierarchical symbols get the same entries
+// flattened.
+func (s *Server) TextDocumentDocumentSymbol(ctx context.Context, params lsp.DocumentSymbolParams) (any, error) {
+	log.Printf("DocumentSymbol request received for URI: %s", params.TextDocument.URI)
+
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil {
+		return []DocumentSymbol{}, nil
+	}
+	symbols := documentSymbols(entry)
+	log.Printf("Returning %d top-level symbols.", len(symbols))
+
+	s.mutex.RLock()
+	hierarchical := s.hierarchicalSymbols
+	s.mutex.RUnlock()
+	if !hierarchical {
+		return flattenSymbols(params.TextDocument.URI, symbols, ""), nil
+	}
+	return symbols, nil
+}
+
+// documentSymbols builds the outline of an indexed file.
+func documentSymbols(entry *index.FileEntry) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	if entry.Loc != nil {
+		for _, e := range entry.Loc.Entries {
+			rng := e.KeyRange
+			rng.End = e.ValueRange.End
+			symbols = append(symbols, DocumentSymbol{
+				Name:           e.Key,
+				Detail:         e.Text,
+				Kind:           lsp.SKString,
+				Range:          analysis.Range(rng),
+				SelectionRange: analysis.Range(e.KeyRange),
+			})
+		}
+		return symbols
+	}
+	if entry.File == nil {
+		return symbols
+	}
+
+	kinds := map[pdx.Range]index.Kind{}
+	for _, sym := range entry.Symbols {
+		kinds[sym.Range] = sym.Kind
+	}
+	for _, f := range entry.File.Root.Fields {
+		if f.Key == nil {
+			continue
+		}
+		if f.Block() == nil {
+			if sym, ok := topLevelScalarSymbol(f, kinds[f.Key.Loc]); ok {
+				symbols = append(symbols, sym)
+			}
+			continue
+		}
+		sym := blockSymbol(f)
+		sym.Kind = lsp.SKClass
+		if kind, ok := kinds[f.Key.Loc]; ok {
+			if k, ok := definitionSymbolKinds[kind]; ok {
+				sym.Kind = k
+			}
+			if sym.Detail == "" {
+				sym.Detail = string(kind)
+			}
+		}
+		symbols = append(symbols, sym)
+	}
+	return symbols
+}
+
+// topLevelScalarSymbol returns the outline entry of a top-level
+// `key = value`: the namespace of an event file, a script constant or a
+// definition written as a single value, such as a script value.
+func topLevelScalarSymbol(f *pdx.Field, kind index.Kind) (DocumentSymbol, bool) {
+	sym := DocumentSymbol{
+		Name:           f.Key.Text,
+		Detail:         f.ValueText(),
+		Range:          analysis.Range(f.Range()),
+		SelectionRange: analysis.Range(f.Key.Loc),
+	}
+	switch {
+	case f.Key.Text == "namespace":
+		sym.Kind = lsp.SKNamespace
+	case strings.HasPrefix(f.Key.Text, "@"):
+		sym.Kind = lsp.SKConstant
+	case kind != "":
+		sym.Kind = lsp.SKVariable
+		if k, ok := definitionSymbolKinds[kind]; ok {
+			sym.Kind = k
+		}
+	default:
+		return DocumentSymbol{}, false
+	}
+	return sym, true
+}
+
+// blockSymbol returns the outline entry of a block-valued field with the
+// keyed blocks inside it as children. Its detail is the `name` of the
+// block, such as an option's localization key, or the type of an event.
+func blockSymbol(f *pdx.Field) DocumentSymbol {
+	b := f.Block()
+	name := f.Key.Text
+	if b.Tag != nil {
+		name += " " + b.Tag.Text
+	}
+	sym := DocumentSymbol{
+		Name:           name,
+		Detail:         valueOf(b, "name"),
+		Kind:           blockSymbolKind(f.Key.Text),
+		Range:          analysis.Range(f.Range()),
+		SelectionRange: analysis.Range(f.Key.Loc),
+	}
+	if sym.Detail == "" {
+		sym.Detail = valueOf(b, "type")
+	}
+	for _, child := range b.Fields {
+		if child.Key != nil && child.Block() != nil {
+			sym.Children = append(sym.Children, blockSymbol(child))
+		}
+	}
+	return sym
+}
+
+// blockSymbolKind picks the outline kind of a nested block by what it
+// holds, so that options, triggers and effects can be told apart.
+func blockSymbolKind(key string) lsp.SymbolKind {
+	if key == "option" {
+		return lsp.SKEnumMember
+	}
+	switch scope.BlockContext(key) {
+	case scope.ContextTrigger:
+		return lsp.SKBoolean
+	case scope.ContextEffect:
+		return lsp.SKMethod
+	}
+	return lsp.SKObject
+}
+
+// flattenSymbols lists an outline as SymbolInformation, each entry naming
+// the one containing it.
+func flattenSymbols(uri lsp.DocumentURI, symbols []DocumentSymbol, container string) []lsp.SymbolInformation {
+	flat := []lsp.SymbolInformation{}
+	for _, sym := range symbols {
+		flat = append(flat, lsp.SymbolInformation{
+			Name:          sym.Name,
+			Kind:          sym.Kind,
+			Location:      lsp.Location{URI: uri, Range: sym.Range},
+			ContainerName: container,
+		})
+		flat = append(flat, flattenSymbols(uri, sym.Children, sym.Name)...)
+	}
+	return flat
+}
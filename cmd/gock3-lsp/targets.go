@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+	"github.com/unLomTrois/gock3-lsp/scope"
+)
+
+// relativeTargets documents the event targets whose scope depends on where
+// they are written.
+var relativeTargets = map[string]string{
+	"root":     "The scope the event, decision or other definition was started for. It stays the same throughout the definition.",
+	"this":     "The current scope, as changed by the iterators and event targets enclosing the cursor.",
+	"prev":     "The scope before the last scope change.",
+	"prevprev": "CK3 has no `prevprev`. Save the scope with `save_scope_as` and refer to it as `scope:name`.",
+	"from":     "CK3 has no `from`. The scopes an event is fired with reach it as saved scopes, written `scope:name`.",
+	"fromfrom": "CK3 has no `fromfrom`. The scopes an event is fired with reach it as saved scopes, written `scope:name`.",
+}
+
+// targetType returns the type a relative target stands for in frame, and
+// whether CK3 has the target at all.
+func targetType(frame *scope.Frame, name string) (scope.Type, bool) {
+	switch name {
+	case "root":
+		return frame.Root, true
+	case "this":
+		return frame.This, true
+	case "prev":
+		return frame.PrevType(), true
+	}
+	return scope.Unknown, false
+}
+
+// targetHover documents the relative event target or saved scope under the
+// cursor, and the scope a link of a dotted chain leads to, with the types
+// inferred at that place. It returns nil elsewhere.
+func (s *Server) targetHover(filePath string, pos lsp.Position) *lsp.Hover {
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil {
+		return nil
+	}
+	p := pdx.Pos{Line: pos.Line, Col: pos.Character}
+	path := entry.File.PathAt(p)
+	if len(path) == 0 {
+		return nil
+	}
+	f := path[len(path)-1]
+	word := f.Key
+	if word == nil || !word.Loc.Contains(p) {
+		word = f.Scalar()
+	}
+	if word == nil || word.Quoted || !word.Loc.Contains(p) {
+		return nil
+	}
+	frame := scope.Analyze(entry.File, entry.VirtualPath).Parent(f)
+	if frame == nil {
+		return nil
+	}
+
+	lower := strings.ToLower(word.Text)
+	if !scope.IsChain(word.Text) && relativeTargets[lower] == "" && !strings.HasPrefix(lower, "scope:") {
+		return nil
+	}
+
+	// Find the link of the chain under the cursor.
+	at := min(max(p.Col-word.Loc.Start.Col, 0), len(word.Text))
+	start := strings.LastIndex(word.Text[:at], ".") + 1
+	end := len(word.Text)
+	if i := strings.Index(word.Text[start:], "."); i >= 0 {
+		end = start + i
+	}
+	link := lower[start:end]
+
+	var b strings.Builder
+	doc, relative := relativeTargets[link]
+	doc = s.translate(doc)
+	switch {
+	case relative && start == 0:
+		if typ, ok := targetType(frame, link); ok {
+			fmt.Fprintf(&b, "**%s** `%s`\n\n%s", link, typeName(typ), doc)
+		} else {
+			fmt.Fprintf(&b, "**%s**\n\n%s", link, doc)
+		}
+	case strings.HasPrefix(link, "scope:") && start == 0:
+		name := word.Text[start+len("scope:") : end]
+		if typ, ok := frame.Saved[name]; ok {
+			fmt.Fprintf(&b, "**scope:%s** `%s`\n\nA saved scope.", name, typeName(typ))
+		} else {
+			fmt.Fprintf(&b, "**scope:%s**\n\nNot saved in this definition; it must be passed in by whatever runs it.", name)
+		}
+	default:
+		typ, err := frame.Resolve(word.Text[:end])
+		if err != nil {
+			return nil
+		}
+		fmt.Fprintf(&b, "**%s** `%s`", word.Text[:end], typeName(typ))
+	}
+
+	rng := word.Loc
+	rng.Start.Col += start
+	rng.Start.Offset += start
+	rng.End.Col = word.Loc.Start.Col + end
+	rng.End.Offset = word.Loc.Start.Offset + end
+	hoverRange := analysis.Range(rng)
+	return &lsp.Hover{
+		Contents: []lsp.MarkedString{lsp.RawMarkedString(b.String())},
+		Range:    &hoverRange,
+	}
+}
+
+// targetContextPattern matches a partially typed event target at the start
+// of a key or a value.
+var targetContextPattern = regexp.MustCompile(`(?:^\s*|=\s*)([A-Za-z_:]*)$`)
+
+// targetCompletions offers root, this, prev and the saved scopes of the
+// definition, with the types they have at the cursor. It returns nil
+// outside script whose scopes are tracked. The caller must hold s.mutex.
+func (s *Server) targetCompletions(params lsp.TextDocumentPositionParams) []lsp.CompletionItem {
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return nil
+	}
+	if !targetContextPattern.MatchString(linePrefix(s.Documents[filePath], params.Position)) {
+		return nil
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil {
+		return nil
+	}
+	frame := scope.Analyze(entry.File, entry.VirtualPath).At(pdx.Pos{Line: params.Position.Line, Col: params.Position.Character})
+	if frame == nil {
+		return nil
+	}
+	items := []lsp.CompletionItem{}
+	for _, name := range []string{"root", "this", "prev"} {
+		typ, _ := targetType(frame, name)
+		items = append(items, lsp.CompletionItem{Label: name, Kind: lsp.CIKVariable, Detail: typeName(typ), Documentation: s.translate(relativeTargets[name])})
+	}
+	var saved []string
+	for name := range frame.Saved {
+		saved = append(saved, name)
+	}
+	sort.Strings(saved)
+	for _, name := range saved {
+		items = append(items, lsp.CompletionItem{Label: "scope:" + name, Kind: lsp.CIKVariable, Detail: typeName(frame.Saved[name]), Documentation: "A saved scope."})
+	}
+	return items
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+)
+
+// AssertionResult is the outcome of one assertion of a tests/ file.
+type AssertionResult struct {
+	Test      string          `json:"test"`
+	URI       lsp.DocumentURI `json:"uri"`
+	Range     lsp.Range       `json:"range"`
+	Evaluated bool            `json:"evaluated"`
+	Passed    bool            `json:"passed"`
+	Message   string          `json:"message"`
+}
+
+// ScriptChecks summarizes a gock3.runScriptChecks run.
+type ScriptChecks struct {
+	Passed  int               `json:"passed"`
+	Failed  int               `json:"failed"`
+	Skipped int               `json:"skipped"`
+	Results []AssertionResult `json:"results"`
+}
+
+// runScriptChecksCommand runs gock3.runScriptChecks: it evaluates the
+// assertions of every tests/ file of the workspace, publishes the failures
+// as diagnostics and returns a summary.
+func (s *Server) runScriptChecksCommand(ctx context.Context, args []interface{}) (interface{}, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	checks := ScriptChecks{Results: []AssertionResult{}}
+	for _, path := range s.Index.Paths() {
+		entry := s.Index.File(path)
+		if entry == nil || !analysis.IsTestFile(entry.VirtualPath) {
+			continue
+		}
+		for _, a := range analysis.EvaluateAssertions(entry, s.Index) {
+			switch {
+			case !a.Evaluated:
+				checks.Skipped++
+			case a.Passed:
+				checks.Passed++
+			default:
+				checks.Failed++
+			}
+			checks.Results = append(checks.Results, AssertionResult{
+				Test:      a.Test,
+				URI:       filePathToURI(path),
+				Range:     analysis.Range(a.Field.Range()),
+				Evaluated: a.Evaluated,
+				Passed:    a.Passed,
+				Message:   a.Message,
+			})
+		}
+		diagnostics := s.GetDiagnostics(path)
+		s.DiagFiles[path] = diagnostics
+		if err := s.publishDiagnostics(ctx, filePathToURI(path), diagnostics); err != nil {
+			log.Printf("Failed to publish script check diagnostics for: %s", path)
+		}
+	}
+	log.Printf("Script checks: %d passed, %d failed, %d skipped.", checks.Passed, checks.Failed, checks.Skipped)
+	return checks, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	lsp "github.com/sourcegraph/go-lsp"
+)
+
+// applyContentChanges applies incremental (or full, when Range is nil)
+// content changes to text in order.
+func applyContentChanges(text string, changes []lsp.TextDocumentContentChangeEvent) (string, error) {
+	for _, change := range changes {
+		if change.Range == nil {
+			text = change.Text
+			continue
+		}
+		start, err := positionToOffset(text, change.Range.Start)
+		if err != nil {
+			return "", err
+		}
+		end, err := positionToOffset(text, change.Range.End)
+		if err != nil {
+			return "", err
+		}
+		if end < start {
+			return "", errors.New("change range end precedes start")
+		}
+		text = text[:start] + change.Text + text[end:]
+	}
+	return text, nil
+}
+
+// positionToOffset converts an LSP position (UTF-16 based) to a byte offset.
+func positionToOffset(text string, pos lsp.Position) (int, error) {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		i := strings.IndexByte(text[offset:], '\n')
+		if i < 0 {
+			return 0, errors.New("position line out of range")
+		}
+		offset += i + 1
+	}
+	lineEnd := strings.IndexByte(text[offset:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(text) - offset
+	}
+	return offset + utf16ToByteOffset(text[offset:offset+lineEnd], pos.Character), nil
+}
+
+// utf16ToByteOffset converts a UTF-16 column within line to a byte offset,
+// clamping to the end of the line.
+func utf16ToByteOffset(line string, character int) int {
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		if r >= 0x10000 {
+			units += 2
+		} else {
+			units++
+		}
+	}
+	return len(line)
+}
+
+// linePrefix returns the text of the line at pos up to the cursor.
+func linePrefix(text string, pos lsp.Position) string {
+	lines := strings.Split(text, "\n")
+	if pos.Line >= len(lines) {
+		return ""
+	}
+	line := strings.TrimSuffix(lines[pos.Line], "\r")
+	prefix := line[:utf16ToByteOffset(line, pos.Character)]
+	if !utf8.ValidString(prefix) {
+		return ""
+	}
+	return prefix
+}
+
+// utf16Len returns the length of s in UTF-16 code units.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	lsp "github.com/sourcegraph/go-lsp"
+)
+
+func TestApplyContentChanges(t *testing.T) {
+	span := func(l1, c1, l2, c2 int) *lsp.Range {
+		return &lsp.Range{Start: lsp.Position{Line: l1, Character: c1}, End: lsp.Position{Line: l2, Character: c2}}
+	}
+	tests := []struct {
+		name, text string
+		changes    []lsp.TextDocumentContentChangeEvent
+		want       string
+	}{
+		{"full", "a = b\n", []lsp.TextDocumentContentChangeEvent{{Text: "c = d\n"}}, "c = d\n"},
+		{"insert", "a = b\n", []lsp.TextDocumentContentChangeEvent{{Range: span(0, 5, 0, 5), Text: "c"}}, "a = bc\n"},
+		{"replace across lines", "a = {\n\tb = c\n}\n", []lsp.TextDocumentContentChangeEvent{{Range: span(0, 4, 2, 1), Text: "d"}}, "a = d\n"},
+		{"in order", "a\n", []lsp.TextDocumentContentChangeEvent{{Range: span(0, 1, 0, 1), Text: "b"}, {Range: span(0, 0, 0, 1), Text: "c"}}, "cb\n"},
+		// The columns count UTF-16 code units: é is one, 😀 two.
+		{"utf-16 columns", "é😀x\n", []lsp.TextDocumentContentChangeEvent{{Range: span(0, 3, 0, 4), Text: "y"}}, "é😀y\n"},
+		{"crlf", "a = b\r\nc = d\r\n", []lsp.TextDocumentContentChangeEvent{{Range: span(1, 4, 1, 5), Text: "e"}}, "a = b\r\nc = e\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyContentChanges(tt.text, tt.changes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyContentChangesOutOfRange(t *testing.T) {
+	r := &lsp.Range{Start: lsp.Position{Line: 5}, End: lsp.Position{Line: 5}}
+	if _, err := applyContentChanges("a = b\n", []lsp.TextDocumentContentChangeEvent{{Range: r, Text: "c"}}); err == nil {
+		t.Error("no error for a change past the end of the document")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+)
+
+// TodosParams are the parameters of gock3/todos. Without a text document
+// the whole workspace is searched.
+type TodosParams struct {
+	TextDocument *lsp.TextDocumentIdentifier `json:"textDocument,omitempty"`
+}
+
+// TodoItem is a TODO, FIXME or HACK comment of the workspace.
+type TodoItem struct {
+	URI   lsp.DocumentURI `json:"uri"`
+	Range lsp.Range       `json:"range"`
+	Tag   string          `json:"tag"`
+	Text  string          `json:"text"`
+}
+
+// Todos handles the gock3/todos request, which lists the work markers left
+// in comments so that teams can track outstanding work from the editor.
+func (s *Server) Todos(ctx context.Context, params TodosParams) ([]TodoItem, error) {
+	log.Println("Todos request received.")
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var paths []string
+	if params.TextDocument != nil {
+		filePath, err := uriToFilePath(params.TextDocument.URI)
+		if err != nil {
+			log.Printf("Error converting URI to file path: %v", err)
+			return nil, err
+		}
+		paths = []string{filePath}
+	} else {
+		paths = s.Index.Paths()
+	}
+	items := []TodoItem{}
+	for _, path := range paths {
+		entry := s.Index.File(path)
+		if entry == nil {
+			continue
+		}
+		for _, todo := range analysis.Todos(entry) {
+			items = append(items, TodoItem{URI: filePathToURI(path), Range: analysis.Range(todo.Range), Tag: todo.Tag, Text: todo.Text})
+		}
+	}
+	log.Printf("Returning %d todos.", len(items))
+	return items, nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+// topLevelKeyPattern matches a line holding at most the start of a key.
+var topLevelKeyPattern = regexp.MustCompile(`^\s*[\w.:@-]*$`)
+
+// extendedKinds are the kinds of objects that mods extend by defining them
+// again with the same name, as on_actions are appended to, so that the
+// names vanilla defines are offered at the top level of their folder.
+var extendedKinds = map[index.Kind]bool{
+	index.KindOnAction: true,
+}
+
+// topLevelCompletions completes the keys at the top level of a script file
+// by its folder, so that a new file guides its author: `namespace` and the
+// next event ID in events/, the naming pattern of the definitions of
+// common/ folders such as scripted triggers, and the vanilla on_actions in
+// common/on_action.
+func (s *Server) topLevelCompletions(params lsp.TextDocumentPositionParams) []lsp.CompletionItem {
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil || !index.IsScriptFile(filePath) {
+		return nil
+	}
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.File == nil || len(s.enclosingFields(filePath, params.Position)) > 0 {
+		return nil
+	}
+	if !topLevelKeyPattern.MatchString(linePrefix(s.Documents[filePath], params.Position)) {
+		return nil
+	}
+
+	var items []lsp.CompletionItem
+	if entry.Role.Role == index.RoleEvents {
+		items = append(items, eventIDItems(entry)...)
+	}
+	kind, ok := index.DefinitionKind(entry.VirtualPath)
+	noun := strings.ReplaceAll(string(kind), "_", " ")
+	if ok {
+		for _, prefix := range s.namePrefixes(kind) {
+			items = append(items, lsp.CompletionItem{
+				Label:  prefix,
+				Kind:   lsp.CIKText,
+				Detail: fmt.Sprintf("%s name prefix", noun),
+			})
+		}
+	}
+	if ok && extendedKinds[kind] {
+		defined := map[string]bool{}
+		for _, sym := range entry.Symbols {
+			defined[sym.Name] = true
+		}
+		for _, name := range s.Index.Names(kind) {
+			if !defined[name] {
+				items = append(items, lsp.CompletionItem{Label: name, Kind: lsp.CIKEvent, Detail: s.vanillaDetail(noun)})
+			}
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return items
+}
+
+// eventIDItems offers `namespace` to an event file without one, and the
+// next free ID of its namespace, numbered like its other events.
+func eventIDItems(entry *index.FileEntry) []lsp.CompletionItem {
+	var items []lsp.CompletionItem
+	namespace, declare := "", false
+	if f := entry.File.Root.Get("namespace"); f != nil && f.Scalar() != nil {
+		namespace = f.Scalar().Text
+	} else {
+		items = append(items, lsp.CompletionItem{
+			Label:         "namespace",
+			Kind:          lsp.CIKKeyword,
+			Detail:        "Namespace of events",
+			Documentation: "The prefix of the IDs of the events of the file, such as `namespace = my_events` for `my_events.0001`.",
+		})
+		// The file name is the usual namespace.
+		base := path.Base(entry.VirtualPath)
+		namespace = strings.TrimSuffix(base, path.Ext(base))
+		declare = true
+	}
+	next, width := 1, 4
+	for _, sym := range entry.Symbols {
+		ns, id, ok := strings.Cut(sym.Name, ".")
+		if sym.Kind != index.KindEvent || !ok || ns != namespace {
+			continue
+		}
+		if n, err := strconv.Atoi(id); err == nil {
+			next, width = max(next, n+1), len(id)
+		}
+	}
+	id := lsp.CompletionItem{
+		Label:  fmt.Sprintf("%s.%0*d", namespace, width, next),
+		Kind:   lsp.CIKEvent,
+		Detail: "Next event ID",
+	}
+	if declare {
+		// completionItem/resolve declares the namespace along with it.
+		id.Data = completionData{Kind: index.KindEvent, Name: id.Label, URI: filePathToURI(entry.Path), Namespace: namespace}
+	}
+	return append(items, id)
+}
+
+// namePrefixes returns the prefixes names of kind start with: those of the
+// naming convention of the settings, or else the most common one of the
+// names the workspace defines.
+func (s *Server) namePrefixes(kind index.Kind) []string {
+	if convention, ok := s.Settings.Diagnostics.Naming[string(kind)]; ok && len(convention.Prefixes) > 0 {
+		return convention.Prefixes
+	}
+	counts := map[string]int{}
+	for _, p := range s.Index.Paths() {
+		entry := s.Index.File(p)
+		if entry == nil {
+			continue
+		}
+		for _, sym := range entry.Symbols {
+			if i := strings.Index(sym.Name, "_"); sym.Kind == kind && i > 0 {
+				counts[sym.Name[:i+1]]++
+			}
+		}
+	}
+	prefixes := make([]string, 0, len(counts))
+	for prefix := range counts {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		if counts[prefixes[i]] != counts[prefixes[j]] {
+			return counts[prefixes[i]] > counts[prefixes[j]]
+		}
+		return prefixes[i] < prefixes[j]
+	})
+	// Only a prefix most names share is a pattern.
+	if len(prefixes) == 0 || counts[prefixes[0]] < 2 {
+		return nil
+	}
+	return prefixes[:1]
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/unLomTrois/gock3-lsp/loc"
+)
+
+// translationSource is an English entry of the workspace.
+type translationSource struct {
+	entry loc.Entry
+	path  string
+}
+
+// ImportResult describes a gock3.importTranslations run.
+type ImportResult struct {
+	Files    []string `json:"files"`
+	Imported int      `json:"imported"`
+	Skipped  int      `json:"skipped"`
+}
+
+// exportTranslationsCommand runs gock3.exportTranslations. Arguments: the
+// target language, such as "l_french"; the format, "csv" (default) or
+// "xliff"; and an optional output path, without which the export is
+// returned. Keys are exported when they have no translation or the
+// translation's version is lower than the English one.
+func (s *Server) exportTranslationsCommand(ctx context.Context, args []interface{}) (interface{}, error) {
+	language, format, output := stringArg(args, 0), stringArg(args, 1), stringArg(args, 2)
+	if !strings.HasPrefix(language, "l_") || language == "l_english" {
+		return nil, fmt.Errorf("invalid target language %q; expected a name such as l_french", language)
+	}
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "xliff" {
+		return nil, fmt.Errorf("unknown format %q; expected csv or xliff", format)
+	}
+
+	s.mutex.RLock()
+	sources, order := s.translationSources()
+	targets := s.translations(language)
+	s.mutex.RUnlock()
+
+	var units []loc.Unit
+	for _, key := range order {
+		src := sources[key]
+		unit := loc.Unit{Key: key, Version: src.entry.Version, Source: src.entry.Text, File: s.Index.VirtualPath(src.path)}
+		if t, ok := targets[key]; ok {
+			if t.entry.VersionNumber() >= src.entry.VersionNumber() {
+				continue
+			}
+			unit.Target = t.entry.Text
+		}
+		units = append(units, unit)
+	}
+
+	var buf bytes.Buffer
+	var err error
+	if format == "csv" {
+		err = loc.WriteCSV(&buf, units)
+	} else {
+		err = loc.WriteXLIFF(&buf, language, units)
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Exported %d keys to translate into %s.", len(units), language)
+	if output == "" {
+		return buf.String(), nil
+	}
+	if err := os.WriteFile(output, buf.Bytes(), 0o644); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"output": output, "keys": len(units)}, nil
+}
+
+// importTranslationsCommand runs gock3.importTranslations. Arguments: the
+// path of a CSV or XLIFF file written by gock3.exportTranslations (or a
+// translator's tool) and the target language. Each translated key is
+// updated in place in the language's file that has it, or appended to the
+// counterpart of the English file it comes from, taking the English
+// version.
+func (s *Server) importTranslationsCommand(ctx context.Context, args []interface{}) (interface{}, error) {
+	input, language := stringArg(args, 0), stringArg(args, 1)
+	if input == "" {
+		return nil, errors.New("expected the path of a CSV or XLIFF file")
+	}
+	if !strings.HasPrefix(language, "l_") || language == "l_english" {
+		return nil, fmt.Errorf("invalid target language %q; expected a name such as l_french", language)
+	}
+	s.mutex.RLock()
+	err := s.checkReadable(input, s.Settings)
+	s.mutex.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(input)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var units []loc.Unit
+	switch strings.ToLower(filepath.Ext(input)) {
+	case ".csv":
+		units, err = loc.ReadCSV(f)
+	case ".xlf", ".xliff":
+		units, err = loc.ReadXLIFF(f)
+	default:
+		return nil, fmt.Errorf("unknown file type %q; expected .csv, .xlf or .xliff", filepath.Ext(input))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.RootPath == "" {
+		return nil, errors.New("no workspace root to write to")
+	}
+	sources, order := s.translationSources()
+	targets := s.translations(language)
+	result := ImportResult{Files: []string{}}
+	updates := map[string]map[string]loc.Unit{}
+	for _, u := range units {
+		if u.Key == "" || strings.TrimSpace(u.Target) == "" {
+			result.Skipped++
+			continue
+		}
+		dest := ""
+		if t, ok := targets[u.Key]; ok {
+			dest = t.path
+		} else if src, ok := sources[u.Key]; ok {
+			dest = counterpart(src.path, language)
+		} else {
+			name := strings.TrimPrefix(language, "l_")
+			dest = filepath.Join(s.RootPath, "localization", name, "gock3_imported_"+language+".yml")
+		}
+		if src, ok := sources[u.Key]; ok {
+			u.Version = src.entry.Version
+		}
+		if updates[dest] == nil {
+			updates[dest] = map[string]loc.Unit{}
+		}
+		updates[dest][u.Key] = u
+		result.Imported++
+	}
+
+	paths := make([]string, 0, len(updates))
+	for path := range updates {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		keys := append([]string{}, order...)
+		for key := range updates[path] {
+			if _, ok := sources[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		src, ok := s.Documents[path]
+		if !ok {
+			data, err := os.ReadFile(path)
+			if err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			src = string(data)
+		}
+		text := loc.SetEntries(src, language, updates[path], keys)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			return nil, err
+		}
+		if _, open := s.Documents[path]; !open {
+			s.Index.UpdateFile(path, text)
+		}
+		result.Files = append(result.Files, path)
+	}
+	log.Printf("Imported %d translations into %d %s files.", result.Imported, len(result.Files), language)
+	return result, nil
+}
+
+// translationSources returns the English entries of the workspace by key,
+// and the keys in file order. The caller must hold s.mutex.
+func (s *Server) translationSources() (map[string]translationSource, []string) {
+	sources := s.translations("l_english")
+	var order []string
+	for _, path := range s.Index.Paths() {
+		entry := s.Index.File(path)
+		if entry == nil || entry.Loc == nil || entry.Loc.Language != "l_english" || strings.HasPrefix(filepath.Base(path), pseudoFilePrefix) {
+			continue
+		}
+		for _, e := range entry.Loc.Entries {
+			if sources[e.Key].path == path {
+				order = append(order, e.Key)
+			}
+		}
+	}
+	return sources, order
+}
+
+// translations returns the workspace's entries of a language by key. The
+// caller must hold s.mutex.
+func (s *Server) translations(language string) map[string]translationSource {
+	entries := map[string]translationSource{}
+	for _, path := range s.Index.Paths() {
+		entry := s.Index.File(path)
+		if entry == nil || entry.Loc == nil || entry.Loc.Language != language || strings.HasPrefix(filepath.Base(path), pseudoFilePrefix) {
+			continue
+		}
+		for _, e := range entry.Loc.Entries {
+			if _, ok := entries[e.Key]; !ok {
+				entries[e.Key] = translationSource{entry: e, path: path}
+			}
+		}
+	}
+	return entries
+}
+
+// counterpart returns the path of the file of language matching an
+// English localization file, e.g. localization/french/x_l_french.yml for
+// localization/english/x_l_english.yml.
+func counterpart(path, language string) string {
+	name := strings.TrimPrefix(language, "l_")
+	dir, base := filepath.Split(path)
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(dir)), "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if parts[i] == "english" {
+			parts[i] = name
+			break
+		}
+	}
+	base = strings.Replace(base, "l_english", language, 1)
+	return filepath.Join(filepath.FromSlash(strings.Join(parts, "/")), base)
+}
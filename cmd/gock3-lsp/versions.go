@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+	"github.com/unLomTrois/gock3-lsp/loc"
+)
+
+// LocalizationSettings configures the editing of localization files.
+type LocalizationSettings struct {
+	// BumpVersions makes the server bump the :version of an English entry
+	// the first time its text is edited after the file was opened.
+	BumpVersions bool `json:"bumpVersions"`
+}
+
+// ApplyWorkspaceEditParams are the parameters of workspace/applyEdit.
+type ApplyWorkspaceEditParams struct {
+	Label string        `json:"label,omitempty"`
+	Edit  WorkspaceEdit `json:"edit"`
+}
+
+// ApplyWorkspaceEditResult is the answer of the client to
+// workspace/applyEdit.
+type ApplyWorkspaceEditResult struct {
+	Applied       bool   `json:"applied"`
+	FailureReason string `json:"failureReason,omitempty"`
+}
+
+// applyEditTimeout bounds the wait for the client to answer
+// workspace/applyEdit.
+const applyEditTimeout = 10 * time.Second
+
+// versionBaseline records an English localization file as it was opened,
+// so edits to its texts can be told from edits to its versions. The caller
+// must hold s.mutex.
+func (s *Server) versionBaseline(filePath string) {
+	delete(s.Bumped, filePath)
+	delete(s.Baselines, filePath)
+	entry := s.Index.File(filePath)
+	if entry == nil || entry.Loc == nil || entry.Loc.Language != "l_english" {
+		return
+	}
+	s.Baselines[filePath] = entry.Loc
+	s.Bumped[filePath] = map[string]bool{}
+}
+
+// bumpVersions asks the client to bump the version of every English entry
+// whose text changed since the file was opened without its version being
+// touched, once per key. The caller must hold s.mutex.
+func (s *Server) bumpVersions(filePath string, version int) {
+	params, keys := s.versionEdit(filePath, version)
+	if len(keys) == 0 {
+		return
+	}
+	log.Printf("Bumping the version of %d changed entries in: %s", len(keys), filePath)
+	// The client answers with didChange notifications that need the lock.
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), applyEditTimeout)
+		defer cancel()
+		var result ApplyWorkspaceEditResult
+		rsp, err := s.jrpcServer.Callback(ctx, "workspace/applyEdit", params)
+		if err == nil {
+			err = rsp.UnmarshalResult(&result)
+		}
+		if err != nil {
+			log.Printf("Failed to bump localization versions in %s: %v", filePath, err)
+			return
+		}
+		if !result.Applied {
+			// The document changed before the edit reached it; the keys
+			// are bumped again on a later change.
+			log.Printf("The client did not bump localization versions in %s: %s", filePath, result.FailureReason)
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			for _, key := range keys {
+				delete(s.Bumped[filePath], key)
+			}
+		}
+	}()
+}
+
+// versionEdit returns the edit bumping the versions of the changed entries
+// of filePath not bumped yet, and marks their keys bumped. The edit is for
+// the given version of the document, so that the client rejects it if the
+// document changed again before it arrived; clients without documentChanges
+// get plain changes, which they apply unchecked. Nothing is bumped for
+// clients without workspace/applyEdit. The caller must hold s.mutex.
+func (s *Server) versionEdit(filePath string, version int) (ApplyWorkspaceEditParams, []string) {
+	baseline := s.Baselines[filePath]
+	entry := s.Index.File(filePath)
+	if !s.Settings.Localization.BumpVersions || !s.applyEdit || s.readOnlyMode || s.readOnly(filePath) || baseline == nil || entry == nil || entry.Loc == nil {
+		return ApplyWorkspaceEditParams{}, nil
+	}
+	var edits []lsp.TextEdit
+	var keys []string
+	for _, e := range loc.Changed(baseline, entry.Loc) {
+		if s.Bumped[filePath][e.Key] {
+			continue
+		}
+		s.Bumped[filePath][e.Key] = true
+		keys = append(keys, e.Key)
+		edits = append(edits, lsp.TextEdit{
+			Range:   analysis.Range(e.VersionRange()),
+			NewText: strconv.Itoa(e.VersionNumber() + 1),
+		})
+	}
+	params := ApplyWorkspaceEditParams{Label: "Bump localization versions"}
+	if !s.documentChanges {
+		params.Edit.Changes = map[string][]lsp.TextEdit{string(filePathToURI(filePath)): edits}
+		return params, keys
+	}
+	edit := TextDocumentEdit{Edits: edits}
+	edit.TextDocument.URI = filePathToURI(filePath)
+	edit.TextDocument.Version = &version
+	params.Edit.DocumentChanges = []interface{}{edit}
+	return params, keys
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	lsp "github.com/sourcegraph/go-lsp"
+)
+
+const versionsPath = "/mods/my_mod/localization/english/my_l_english.yml"
+
+// newVersionsServer returns a server with an English localization file
+// whose my_key text changed since it was opened, for a client with the
+// given workspace edit capabilities.
+func newVersionsServer(applyEdit, documentChanges bool) *Server {
+	s := NewServer()
+	s.Settings.Localization.BumpVersions = true
+	s.applyEdit, s.documentChanges = applyEdit, documentChanges
+	openDocument(s, versionsPath, "l_english:\n my_key:0 \"Old text\"\n other_key:2 \"Kept\"\n")
+	s.versionBaseline(versionsPath)
+	s.Index.UpdateFile(versionsPath, "l_english:\n my_key:0 \"New text\"\n other_key:2 \"Kept\"\n")
+	return s
+}
+
+// versionBump is the edit bumping the version of my_key.
+var versionBump = []lsp.TextEdit{{
+	Range:   lsp.Range{Start: lsp.Position{Line: 1, Character: 8}, End: lsp.Position{Line: 1, Character: 9}},
+	NewText: "1",
+}}
+
+func TestVersionEdit(t *testing.T) {
+	s := newVersionsServer(true, true)
+	params, keys := s.versionEdit(versionsPath, 3)
+	if !reflect.DeepEqual(keys, []string{"my_key"}) {
+		t.Fatalf("keys = %v, want my_key", keys)
+	}
+	want := TextDocumentEdit{Edits: versionBump}
+	want.TextDocument.URI = filePathToURI(versionsPath)
+	version := 3
+	want.TextDocument.Version = &version
+	if params.Edit.Changes != nil || !reflect.DeepEqual(params.Edit.DocumentChanges, []interface{}{want}) {
+		t.Errorf("edit = %+v, want the bump for version 3 of the document: %+v", params.Edit, want)
+	}
+
+	if _, keys := s.versionEdit(versionsPath, 4); len(keys) != 0 {
+		t.Errorf("keys = %v, want none once bumped", keys)
+	}
+}
+
+// TestVersionEditCapabilities checks that clients without documentChanges
+// get plain changes, and that nothing is bumped for clients without
+// workspace/applyEdit.
+func TestVersionEditCapabilities(t *testing.T) {
+	s := newVersionsServer(true, false)
+	params, keys := s.versionEdit(versionsPath, 3)
+	want := map[string][]lsp.TextEdit{string(filePathToURI(versionsPath)): versionBump}
+	if len(keys) != 1 || params.Edit.DocumentChanges != nil || !reflect.DeepEqual(params.Edit.Changes, want) {
+		t.Errorf("edit without documentChanges = %+v, %v, want changes %+v", params.Edit, keys, want)
+	}
+
+	s = newVersionsServer(false, true)
+	if params, keys := s.versionEdit(versionsPath, 3); len(keys) != 0 || params.Edit.Changes != nil || params.Edit.DocumentChanges != nil {
+		t.Errorf("edit without workspace/applyEdit = %+v, %v, want none", params.Edit, keys)
+	}
+	// No key is marked bumped.
+	if s.Bumped[versionsPath]["my_key"] {
+		t.Error("my_key was marked bumped without an edit")
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/unLomTrois/gock3-lsp/mod"
+)
+
+// vanillaFolders are the folders of the game installation that are indexed.
+// Only English localization is read to keep memory use reasonable.
+var vanillaFolders = []string{"common", "events", "gui", "history", "map_data/geographical_regions", "localization/english"}
+
+// indexWorkspace scans the workspace root and refreshes the diagnostics of
+// open documents once the index is complete.
+func (s *Server) indexWorkspace(root string) {
+	log.Printf("Indexing workspace: %s", root)
+	s.updateIndexStatus(func(h *indexHealth) { h.workspaceIndexing, h.workspaceError = true, "" })
+	count, err := s.Index.ScanDir(root)
+	if err != nil {
+		log.Printf("Failed to index workspace: %s - Error: %v", root, err)
+		s.updateIndexStatus(func(h *indexHealth) {
+			h.workspaceIndexing, h.workspaceFiles = false, count
+			h.workspaceError = fmt.Sprintf("Failed to index the workspace: %v", err)
+		})
+		return
+	}
+	log.Printf("Indexed %d files in workspace: %s", count, root)
+	defer s.updateIndexStatus(func(h *indexHealth) { h.workspaceIndexing, h.workspaceFiles = false, count })
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Open documents may have unsaved edits that the scan overwrote.
+	for filePath, text := range s.Documents {
+		s.Index.UpdateFile(filePath, text)
+	}
+	s.refreshDiagnostics(context.Background(), "")
+}
+
+// refreshDiagnostics recomputes and publishes diagnostics for every open
+// document except skip, since cross-file checks may change when another
+// file is saved, and wakes the pending workspace/diagnostic requests.
+// Documents are refreshed in path order. The caller must
+// hold s.mutex.
+func (s *Server) refreshDiagnostics(ctx context.Context, skip string) {
+	defer s.signalDiagnostics()
+	paths := make([]string, 0, len(s.Documents))
+	for filePath := range s.Documents {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+	for _, filePath := range paths {
+		if filePath == skip {
+			continue
+		}
+		diagnostics := s.validate(filePath)
+		s.DiagFiles[filePath] = diagnostics
+		if err := s.publishDiagnostics(ctx, filePathToURI(filePath), diagnostics); err != nil {
+			log.Printf("Failed to refresh diagnostics for document: %s", filePath)
+		}
+	}
+}
+
+// indexVanilla scans the game installation into a new base layer, or
+// shares the one another session scanned, and installs it under the
+// workspace index once complete.
+func (s *Server) indexVanilla(gamePath string) {
+	log.Printf("Indexing vanilla game files: %s", gamePath)
+	if err := checkGamePath(gamePath); err != nil {
+		log.Printf("Invalid gamePath: %v", err)
+		s.updateIndexStatus(func(h *indexHealth) {
+			h.vanillaScan++
+			h.vanillaIndexing, h.vanillaFiles, h.vanillaError = false, 0, err.Error()
+		})
+		return
+	}
+	var scan int
+	s.updateIndexStatus(func(h *indexHealth) {
+		h.vanillaScan++
+		scan = h.vanillaScan
+		h.vanillaIndexing, h.vanillaError = true, ""
+	})
+	vanilla, count, err := acquireVanilla(gamePath)
+	if err != nil {
+		log.Printf("Failed to index vanilla game files: %s - Error: %v", gamePath, err)
+		s.updateIndexStatus(func(h *indexHealth) {
+			if h.vanillaScan != scan {
+				return
+			}
+			h.vanillaIndexing, h.vanillaFiles = false, 0
+			h.vanillaError = fmt.Sprintf("Failed to index the vanilla game files: %v", err)
+		})
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// The setting may have changed while scanning.
+	if s.Settings.GamePath != gamePath {
+		s.updateIndexStatus(func(h *indexHealth) {
+			if h.vanillaScan == scan {
+				h.vanillaIndexing = false
+			}
+		})
+		releaseVanilla(gamePath)
+		return
+	}
+	defer s.updateIndexStatus(func(h *indexHealth) { h.vanillaIndexing, h.vanillaFiles = false, count })
+	s.releaseVanillaLayer()
+	s.vanillaPath = gamePath
+	s.Vanilla = vanilla
+	s.GameVersion = mod.GameVersion(gamePath)
+	if s.GameVersion != "" {
+		log.Printf("Installed game version: %s", s.GameVersion)
+	}
+	s.Index.SetBase(vanilla)
+	s.refreshDiagnostics(context.Background(), "")
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestIndexVanillaStale checks that a vanilla scan made stale by a change
+// of the gamePath does not leave the index status indexing.
+func TestIndexVanillaStale(t *testing.T) {
+	gamePath := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(gamePath, "common"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer()
+	s.Settings.GamePath = filepath.Join(t.TempDir(), "other")
+
+	s.indexVanilla(gamePath)
+	if s.health.vanillaIndexing {
+		t.Error("the status is still indexing after the stale scan")
+	}
+	if s.Vanilla != nil {
+		t.Error("the stale scan installed its layer")
+	}
+}
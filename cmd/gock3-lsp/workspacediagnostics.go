@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"time"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/analysis"
+)
+
+// Kinds of pulled diagnostic reports.
+const (
+	reportFull      = "full"
+	reportUnchanged = "unchanged"
+)
+
+// workspaceDiagnosticsBatch is the number of files whose reports are
+// streamed in one $/progress notification.
+const workspaceDiagnosticsBatch = 100
+
+// workspaceDiagnosticsDelay is how long a pending workspace/diagnostic
+// request waits after a change for more changes, so that typing does not
+// re-check the whole mod on every keystroke.
+const workspaceDiagnosticsDelay = time.Second
+
+// DiagnosticOptions advertise textDocument/diagnostic and, with
+// WorkspaceDiagnostics, workspace/diagnostic.
+type DiagnosticOptions struct {
+	InterFileDependencies bool `json:"interFileDependencies"`
+	WorkspaceDiagnostics  bool `json:"workspaceDiagnostics"`
+}
+
+// DocumentDiagnosticParams are the parameters of textDocument/diagnostic.
+type DocumentDiagnosticParams struct {
+	TextDocument     lsp.TextDocumentIdentifier `json:"textDocument"`
+	PreviousResultID string                     `json:"previousResultId,omitempty"`
+}
+
+// DocumentDiagnosticReport is the answer to textDocument/diagnostic.
+type DocumentDiagnosticReport struct {
+	Kind     string                `json:"kind"`
+	ResultID string                `json:"resultId,omitempty"`
+	Items    []analysis.Diagnostic `json:"items"`
+}
+
+// PreviousResultID is the result ID of the last report the client got for
+// a file.
+type PreviousResultID struct {
+	URI   lsp.DocumentURI `json:"uri"`
+	Value string          `json:"value"`
+}
+
+// WorkspaceDiagnosticParams are the parameters of workspace/diagnostic.
+type WorkspaceDiagnosticParams struct {
+	PreviousResultIDs  []PreviousResultID `json:"previousResultIds"`
+	PartialResultToken interface{}        `json:"partialResultToken,omitempty"`
+}
+
+// WorkspaceDocumentDiagnosticReport is the report of one file. Items is
+// left out of unchanged reports.
+type WorkspaceDocumentDiagnosticReport struct {
+	Kind     string                `json:"kind"`
+	URI      lsp.DocumentURI       `json:"uri"`
+	Version  *int                  `json:"version"`
+	ResultID string                `json:"resultId"`
+	Items    []analysis.Diagnostic `json:"items,omitempty"`
+}
+
+// WorkspaceDiagnosticReport is the answer to workspace/diagnostic, and the
+// value of its partial results.
+type WorkspaceDiagnosticReport struct {
+	Items []WorkspaceDocumentDiagnosticReport `json:"items"`
+}
+
+// progressParams are the parameters of $/progress.
+type progressParams struct {
+	Token interface{} `json:"token"`
+	Value interface{} `json:"value"`
+}
+
+// TextDocumentDiagnostic handles textDocument/diagnostic. The diagnostics
+// of open documents are pushed with textDocument/publishDiagnostics as they
+// are edited, so the pulled report of a document is empty, keeping them
+// from being shown twice.
+func (s *Server) TextDocumentDiagnostic(ctx context.Context, params DocumentDiagnosticParams) (DocumentDiagnosticReport, error) {
+	return DocumentDiagnosticReport{Kind: reportFull, Items: []analysis.Diagnostic{}}, nil
+}
+
+// WorkspaceDiagnostic handles workspace/diagnostic: it reports the
+// diagnostics of every file of the mod that is not open, so that the
+// problems of files nobody opened yet are listed too. Files whose
+// diagnostics did not change since the result IDs the client sent are
+// reported unchanged, and if none changed the request waits for an edit
+// or a rescan, as clients send it again as soon as it is answered. With a
+// partial result token, the reports are streamed in batches with
+// $/progress while the mod is checked, from the first changed one on.
+func (s *Server) WorkspaceDiagnostic(ctx context.Context, params WorkspaceDiagnosticParams) (WorkspaceDiagnosticReport, error) {
+	s.pullingDiagnostics.Store(true)
+	previous := make(map[lsp.DocumentURI]string, len(params.PreviousResultIDs))
+	for _, id := range params.PreviousResultIDs {
+		previous[id.URI] = id.Value
+	}
+
+	for {
+		s.mutex.RLock()
+		changed := s.diagnosticsChanged
+		s.mutex.RUnlock()
+		reports, modified := s.workspaceReports(ctx, previous, params.PartialResultToken)
+		if err := ctx.Err(); err != nil {
+			return WorkspaceDiagnosticReport{}, err
+		}
+		if modified || len(previous) == 0 {
+			log.Printf("Reported the diagnostics of %d workspace files.", len(reports))
+			return WorkspaceDiagnosticReport{Items: reports}, nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return WorkspaceDiagnosticReport{}, ctx.Err()
+		}
+		// Let a burst of edits settle before checking the mod again.
+		select {
+		case <-time.After(workspaceDiagnosticsDelay):
+		case <-ctx.Done():
+			return WorkspaceDiagnosticReport{}, ctx.Err()
+		}
+	}
+}
+
+// workspaceReports returns the reports of the workspace files that are not
+// open, and whether any of them changed. Files previously reported that
+// are now open or gone are reported without diagnostics. With a partial
+// result token, the reports are sent with $/progress instead of returned
+// once one of them changed; until then they are held back, so that a check
+// finding nothing new streams nothing and the request keeps waiting. The
+// lock is taken file by file, so that edits are not held up while the whole
+// mod is checked.
+func (s *Server) workspaceReports(ctx context.Context, previous map[lsp.DocumentURI]string, token interface{}) ([]WorkspaceDocumentDiagnosticReport, bool) {
+	reports := []WorkspaceDocumentDiagnosticReport{}
+	modified := false
+	reported := map[lsp.DocumentURI]bool{}
+	add := func(uri lsp.DocumentURI, diagnostics []analysis.Diagnostic) {
+		reported[uri] = true
+		report := WorkspaceDocumentDiagnosticReport{Kind: reportFull, URI: uri, ResultID: resultID(diagnostics), Items: diagnostics}
+		if previous[uri] == report.ResultID {
+			report.Kind, report.Items = reportUnchanged, nil
+		} else {
+			modified = true
+		}
+		reports = append(reports, report)
+	}
+	flush := func() {
+		if token == nil || len(reports) == 0 || !modified {
+			return
+		}
+		value := WorkspaceDiagnosticReport{Items: reports}
+		if err := s.jrpcServer.Notify(ctx, "$/progress", progressParams{Token: token, Value: value}); err != nil {
+			log.Printf("Failed to stream workspace diagnostics: %v", err)
+		}
+		reports = []WorkspaceDocumentDiagnosticReport{}
+	}
+
+	for _, filePath := range s.Index.Paths() {
+		if ctx.Err() != nil {
+			return nil, false
+		}
+		s.mutex.RLock()
+		_, open := s.Documents[filePath]
+		var diagnostics []analysis.Diagnostic
+		if !open && !s.readOnly(filePath) && s.Index.File(filePath) != nil {
+			diagnostics = s.GetDiagnostics(filePath)
+		}
+		s.mutex.RUnlock()
+		if diagnostics != nil {
+			add(filePathToURI(filePath), diagnostics)
+		}
+		if len(reports) >= workspaceDiagnosticsBatch {
+			flush()
+		}
+	}
+	for uri := range previous {
+		if !reported[uri] {
+			add(uri, []analysis.Diagnostic{})
+		}
+	}
+	flush()
+	return reports, modified
+}
+
+// signalDiagnostics wakes the workspace/diagnostic requests waiting for a
+// change. The caller must hold s.mutex.
+func (s *Server) signalDiagnostics() {
+	close(s.diagnosticsChanged)
+	s.diagnosticsChanged = make(chan struct{})
+}
+
+// resultID identifies a list of diagnostics by its content.
+func resultID(diagnostics []analysis.Diagnostic) string {
+	data, _ := json.Marshal(diagnostics)
+	h := fnv.New64a()
+	h.Write(data)
+	return fmt.Sprintf("%016x", h.Sum64())
+}
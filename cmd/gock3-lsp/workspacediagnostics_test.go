@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	lsp "github.com/sourcegraph/go-lsp"
+)
+
+// TestWorkspaceDiagnosticEdit checks that a pending workspace/diagnostic
+// request returns once a document is edited.
+func TestWorkspaceDiagnosticEdit(t *testing.T) {
+	s := NewServer()
+	filePath := "/mods/my_mod/events/my_events.txt"
+	uri := filePathToURI(filePath)
+	s.Index.UpdateFile(filePath, "namespace = my\nmy.0001 = {\n")
+
+	first, err := s.WorkspaceDiagnostic(context.Background(), WorkspaceDiagnosticParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Items) != 1 || first.Items[0].Kind != reportFull || len(first.Items[0].Items) == 0 {
+		t.Fatalf("first report = %+v, want the diagnostics of the file", first)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	done := make(chan WorkspaceDiagnosticReport)
+	go func() {
+		report, err := s.WorkspaceDiagnostic(ctx, WorkspaceDiagnosticParams{
+			PreviousResultIDs: []PreviousResultID{{URI: uri, Value: first.Items[0].ResultID}},
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		done <- report
+	}()
+	select {
+	case report := <-done:
+		t.Fatalf("the request returned %+v before any edit", report)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	s.mutex.Lock()
+	s.Documents[filePath] = "namespace = my\nmy.0001 = {\n"
+	s.mutex.Unlock()
+	// The client is not connected, so publishing the diagnostics fails.
+	s.TextDocumentDidChange(context.Background(), lsp.DidChangeTextDocumentParams{
+		TextDocument:   lsp.VersionedTextDocumentIdentifier{TextDocumentIdentifier: lsp.TextDocumentIdentifier{URI: uri}, Version: 2},
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{{Text: "namespace = my\nmy.0001 = {\n}\n"}},
+	})
+
+	report := <-done
+	if len(report.Items) != 1 || report.Items[0].Kind != reportFull || len(report.Items[0].Items) != 0 {
+		t.Errorf("report = %+v, want the open file reported without diagnostics", report)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+
+	lsp "github.com/sourcegraph/go-lsp"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+// maxWorkspaceSymbols caps the answer to workspace/symbol; clients ask
+// again as the query gets longer.
+const maxWorkspaceSymbols = 500
+
+// WorkspaceSymbol finds the events, scripted effects, localization keys
+// and other definitions of the workspace whose names fuzzily match the
+// query, best matches first.
+func (s *Server) WorkspaceSymbol(ctx context.Context, params lsp.WorkspaceSymbolParams) ([]lsp.SymbolInformation, error) {
+	log.Printf("Workspace symbol request received for query: '%s'", params.Query)
+
+	type match struct {
+		sym   index.Symbol
+		score int
+	}
+	var matches []match
+	for _, path := range s.Index.Paths() {
+		entry := s.Index.File(path)
+		if entry == nil {
+			continue
+		}
+		for _, sym := range entry.Symbols {
+			if score, ok := fuzzyScore(params.Query, sym.Name); ok {
+				matches = append(matches, match{sym, score})
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.score != b.score {
+			return a.score < b.score
+		}
+		if a.sym.Name != b.sym.Name {
+			return a.sym.Name < b.sym.Name
+		}
+		return a.sym.Path < b.sym.Path
+	})
+
+	symbols := []lsp.SymbolInformation{}
+	for _, m := range matches {
+		if len(symbols) == maxWorkspaceSymbols {
+			break
+		}
+		symbols = append(symbols, lsp.SymbolInformation{
+			Name:          m.sym.Name,
+			Kind:          workspaceSymbolKind(m.sym.Kind),
+			Location:      toLocation(m.sym.Location),
+			ContainerName: string(m.sym.Kind),
+		})
+	}
+	log.Printf("Returning %d of %d matching symbols.", len(symbols), len(matches))
+	return symbols, nil
+}
+
+// workspaceSymbolKind is the symbol kind shown for an index kind.
+func workspaceSymbolKind(kind index.Kind) lsp.SymbolKind {
+	switch {
+	case kind == index.KindLocalization:
+		return lsp.SKString
+	case index.IsFlagKind(kind):
+		return lsp.SKKey
+	}
+	if k, ok := definitionSymbolKinds[kind]; ok {
+		return k
+	}
+	return lsp.SKClass
+}
+
+// fuzzyScore reports whether the letters of query appear in name in order,
+// ignoring case, and scores the match, lower being better: exact matches
+// first, then prefixes, substrings, and scattered letters by how far apart
+// they are.
+func fuzzyScore(query, name string) (int, bool) {
+	q, n := strings.ToLower(query), strings.ToLower(name)
+	switch {
+	case q == "" || q == n:
+		return 0, true
+	case strings.HasPrefix(n, q):
+		return 1, true
+	}
+	if i := strings.Index(n, q); i >= 0 {
+		return 2 + i, true
+	}
+	score, next, first := 1000, 0, -1
+	for i := 0; i < len(q); i++ {
+		j := strings.IndexByte(n[next:], q[i])
+		if j < 0 {
+			return 0, false
+		}
+		if first < 0 {
+			first = next + j
+		} else {
+			score += j
+		}
+		next += j + 1
+	}
+	return score + first, true
+}
@@ -3,9 +3,12 @@ module github.com/unLomTrois/gock3-lsp
 go 1.23
 
 require (
-	github.com/creachadair/jrpc2 v1.2.1 // indirect
+	github.com/creachadair/jrpc2 v1.2.1
+	github.com/sourcegraph/go-lsp v0.0.0-20240223163137-f80c5dd31dfd
+)
+
+require (
 	github.com/creachadair/mds v0.16.0 // indirect
-	github.com/sourcegraph/go-lsp v0.0.0-20240223163137-f80c5dd31dfd // indirect
 	github.com/unLomTrois/gock3 v0.0.0-20240920095049-bb6310905b28 // indirect
 	golang.org/x/sync v0.8.0 // indirect
 )
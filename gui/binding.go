@@ -0,0 +1,189 @@
+// Package gui understands the data-binding expressions used in .gui files,
+// such as "[GetScriptedGui('my_gui').Execute( GuiScope.End )]".
+package gui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Segment is one link of a call chain: a promote or function name with
+// optional arguments. Offset is the byte offset of Name in the property
+// string.
+type Segment struct {
+	Name   string
+	Args   []Arg
+	Offset int
+}
+
+// Chain is a dotted sequence of segments such as GetPlayer.GetPrimaryTitle.
+type Chain struct {
+	Segments []Segment
+}
+
+// Arg is a function argument: either a quoted or numeric literal, or a
+// nested chain.
+type Arg struct {
+	Literal string
+	Offset  int
+	Chain   *Chain
+}
+
+// SyntaxError is a malformed expression at a byte offset of the property
+// string.
+type SyntaxError struct {
+	Offset int
+	Msg    string
+}
+
+func (e SyntaxError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Offset, e.Msg)
+}
+
+// ParseBindings returns the chains of every [...] expression in a property
+// string. Text outside brackets, and format specifiers after '|', are
+// ignored.
+func ParseBindings(text string) ([]*Chain, []SyntaxError) {
+	var chains []*Chain
+	var errs []SyntaxError
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case ']':
+			errs = append(errs, SyntaxError{i, "unmatched ']'"})
+		case '[':
+			p := &parser{src: text, pos: i + 1}
+			chain := p.chain()
+			p.skipSpace()
+			if p.err == nil && p.pos < len(text) && text[p.pos] == '|' {
+				for p.pos < len(text) && text[p.pos] != ']' {
+					p.pos++
+				}
+			}
+			if p.err == nil && (p.pos >= len(text) || text[p.pos] != ']') {
+				p.fail("expected ']'")
+			}
+			if p.err != nil {
+				errs = append(errs, *p.err)
+				if end := strings.IndexByte(text[i:], ']'); end >= 0 {
+					i += end
+				} else {
+					i = len(text)
+				}
+				continue
+			}
+			chains = append(chains, chain)
+			i = p.pos
+		}
+	}
+	return chains, errs
+}
+
+type parser struct {
+	src string
+	pos int
+	err *SyntaxError
+}
+
+func (p *parser) fail(msg string) {
+	if p.err == nil {
+		p.err = &SyntaxError{p.pos, msg}
+	}
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *parser) chain() *Chain {
+	c := &Chain{}
+	for {
+		p.skipSpace()
+		seg, ok := p.segment()
+		if !ok {
+			return c
+		}
+		c.Segments = append(c.Segments, seg)
+		p.skipSpace()
+		if p.pos >= len(p.src) || p.src[p.pos] != '.' {
+			return c
+		}
+		p.pos++
+	}
+}
+
+func (p *parser) segment() (Segment, bool) {
+	start := p.pos
+	for p.pos < len(p.src) && isIdentChar(p.src[p.pos]) {
+		p.pos++
+	}
+	if start == p.pos {
+		p.fail("expected a promote or function name")
+		return Segment{}, false
+	}
+	seg := Segment{Name: p.src[start:p.pos], Offset: start}
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == '(' {
+		p.pos++
+		seg.Args = p.args()
+	}
+	return seg, p.err == nil
+}
+
+func (p *parser) args() []Arg {
+	var args []Arg
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == ')' {
+		p.pos++
+		return args
+	}
+	for p.err == nil {
+		p.skipSpace()
+		args = append(args, p.arg())
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			p.fail("expected ')'")
+			break
+		}
+		if p.src[p.pos] == ')' {
+			p.pos++
+			break
+		}
+		if p.src[p.pos] != ',' {
+			p.fail("expected ',' or ')'")
+			break
+		}
+		p.pos++
+	}
+	return args
+}
+
+func (p *parser) arg() Arg {
+	start := p.pos
+	if p.pos < len(p.src) && p.src[p.pos] == '\'' {
+		end := strings.IndexByte(p.src[p.pos+1:], '\'')
+		if end < 0 {
+			p.fail("unterminated string literal")
+			return Arg{}
+		}
+		p.pos += end + 2
+		return Arg{Literal: p.src[start+1 : p.pos-1], Offset: start}
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == '-' || isDigit(p.src[p.pos])) {
+		p.pos++
+		for p.pos < len(p.src) && (isDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		return Arg{Literal: p.src[start:p.pos], Offset: start}
+	}
+	return Arg{Chain: p.chain(), Offset: start}
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || isDigit(c) || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
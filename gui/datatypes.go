@@ -0,0 +1,96 @@
+package gui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DataTypes is the data system database dumped by the game's `script_docs`
+// console command (logs/data_types/*.txt). Entries look like:
+//
+//	Character.GetPrimaryTitle
+//	Definition type: Function
+//	Return type: Title
+//
+// Names without a dot are global promotes and functions.
+type DataTypes struct {
+	Globals map[string]string
+	Members map[string]map[string]string
+	names   map[string]bool
+}
+
+// LoadDataTypes reads every data types dump in dir.
+func LoadDataTypes(dir string) (*DataTypes, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "data_types*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, os.ErrNotExist
+	}
+	dt := &DataTypes{
+		Globals: make(map[string]string),
+		Members: make(map[string]map[string]string),
+		names:   make(map[string]bool),
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		dt.parse(string(data))
+	}
+	return dt, nil
+}
+
+func (dt *DataTypes) parse(text string) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r", ""), "\n")
+	for i := 0; i+1 < len(lines); i++ {
+		name := strings.TrimSpace(lines[i])
+		if name == "" || strings.Contains(name, " ") || !strings.HasPrefix(lines[i+1], "Definition type:") {
+			continue
+		}
+		ret := ""
+		if i+2 < len(lines) {
+			if r, ok := strings.CutPrefix(lines[i+2], "Return type:"); ok {
+				ret = strings.TrimSpace(r)
+			}
+		}
+		dt.add(name, ret)
+	}
+}
+
+func (dt *DataTypes) add(name, ret string) {
+	typ, member, ok := strings.Cut(name, ".")
+	if !ok {
+		dt.Globals[name] = ret
+		return
+	}
+	if dt.Members[typ] == nil {
+		dt.Members[typ] = make(map[string]string)
+	}
+	dt.Members[typ][member] = ret
+	dt.names[member] = true
+}
+
+// IsType reports whether name is a data type, which may start a chain to
+// refer to the object of that type in the current datacontext.
+func (dt *DataTypes) IsType(name string) bool {
+	_, ok := dt.Members[name]
+	return ok
+}
+
+// Lookup resolves a member of typ, returning its return type. When typ is
+// unknown (""), any type defining the member is accepted.
+func (dt *DataTypes) Lookup(typ, member string) (string, bool) {
+	if typ == "" {
+		return "", dt.names[member]
+	}
+	members, ok := dt.Members[typ]
+	if !ok {
+		return "", dt.names[member]
+	}
+	ret, ok := members[member]
+	return ret, ok
+}
@@ -0,0 +1,240 @@
+package gui
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ValueType is the expected shape of a property value.
+type ValueType int
+
+const (
+	Any ValueType = iota
+	Vec2
+	Anchor
+	LayoutPolicy
+	Bool
+	Number
+	Bezier
+)
+
+// propertyGroups are sets of properties shared by families of widgets.
+var propertyGroups = map[string][]string{
+	"common": {
+		"name", "size", "position", "parentanchor", "widgetanchor",
+		"layoutpolicy_horizontal", "layoutpolicy_vertical",
+		"layoutstretchfactor_horizontal", "layoutstretchfactor_vertical",
+		"minimumsize", "maximumsize", "margin", "margin_top", "margin_bottom",
+		"margin_left", "margin_right", "visible", "enabled", "alpha", "scale",
+		"tooltip", "raw_tooltip", "tooltipwidget", "tooltip_visible", "tooltip_enabled",
+		"tooltip_type", "tooltip_offset", "tooltip_parentanchor", "tooltip_widgetanchor",
+		"tooltip_horizontalbehavior", "tooltip_verticalbehavior", "tooltip_when_disabled",
+		"using", "datacontext", "datamodel", "datamodel_reuse_widgets", "datamodel_wrap",
+		"allow_outside", "ignoreinvisible", "resizeparent", "filter_mouse", "focuspolicy",
+		"state", "alwaystransparent", "onmousehierarchyenter", "onmousehierarchyleave",
+		"onmouseenter", "onmouseleave", "background", "modify_texture", "input_action",
+		"shortcut", "clicksound", "oversound", "onclick", "onrightclick", "ondoubleclick",
+		"block", "blockoverride", "layer", "movable", "ignore_in_debug_draw",
+		"distribute_visual_state", "oncreate", "alpha_mask", "dragdropid", "dragdropargs",
+		"ondragdrop", "ondragstart", "ondragend", "pop_out", "plotpoints",
+	},
+	"textured": {
+		"texture", "spritetype", "framesize", "frame", "mirror", "upframe", "downframe",
+		"overframe", "disableframe", "uphoverframe", "downhoverframe", "downpressedframe",
+		"spriteborder", "spriteborder_left", "spriteborder_right", "spriteborder_top",
+		"spriteborder_bottom", "texture_density", "color", "tintcolor", "effectname",
+		"shaderfile", "glow", "fittype", "rotate_uv", "translate_uv", "uv_scale",
+		"gfxtype", "loopanimation", "animation",
+	},
+	"textual": {
+		"text", "raw_text", "default_format", "font", "fontsize", "fontcolor",
+		"fontweight", "fontsize_min", "fonttintcolor", "align", "elide", "multiline",
+		"autoresize", "max_width", "min_width", "text_selectable", "line_gap",
+		"fontstyle", "fontcolors", "textcolor",
+	},
+	"layout": {
+		"spacing", "direction", "flipdirection", "item", "addcolumn", "addrow",
+		"maxhorizontalslots", "maxverticalslots", "setitemsizefromcell",
+		"datamodel_wrap", "place_items_in_reverse", "maxcolumns", "maxrows",
+	},
+	"scroll": {
+		"scrollbarpolicy_horizontal", "scrollbarpolicy_vertical",
+		"scrollbaralign_horizontal", "scrollbaralign_vertical",
+		"scrollbar_horizontal", "scrollbar_vertical", "scrollwidget",
+		"autoresizescrollarea", "scrollbarpolicy",
+	},
+	"interactive": {
+		"checked", "down", "button_ignore", "ontoggle", "onpressed", "onreleased",
+		"button_trigger", "oncheck",
+	},
+	"ranged": {
+		"value", "min", "max", "step", "onvaluechanged", "onvaluechangedrelease",
+		"progresstexture", "noprogresstexture", "bartexture", "animation_speed",
+		"track", "thumb", "invertprogress", "direction",
+	},
+	"editable": {
+		"ontextchanged", "onreturnpressed", "onfocusout", "onfocusin",
+		"maxcharacters", "ontextedited", "focus_on_visible",
+	},
+}
+
+// widgetGroups lists the property groups each built-in widget accepts on top
+// of "common".
+var widgetGroups = map[string][]string{
+	"window":             {"textured"},
+	"widget":             {"textured"},
+	"container":          {},
+	"margin_widget":      {"textured"},
+	"icon":               {"textured"},
+	"button":             {"textured", "textual", "interactive"},
+	"checkbutton":        {"textured", "textual", "interactive"},
+	"text_single":        {"textual"},
+	"text_multi":         {"textual"},
+	"textbox":            {"textual"},
+	"editbox":            {"textured", "textual", "editable"},
+	"hbox":               {"layout"},
+	"vbox":               {"layout"},
+	"flowcontainer":      {"layout"},
+	"dynamicgridbox":     {"layout"},
+	"fixedgridbox":       {"layout"},
+	"overlappingitembox": {"layout"},
+	"scrollarea":         {"scroll"},
+	"scrollbox":          {"scroll", "layout"},
+	"scrollbar":          {"ranged", "textured"},
+	"slider":             {"ranged", "textured"},
+	"progressbar":        {"ranged", "textured"},
+	"dropdown":           {"textured", "textual", "scroll"},
+	"combobox":           {"textured", "textual", "scroll"},
+	"expand":             {},
+	"spacer":             {},
+	"line":               {"textured"},
+}
+
+// propertyTypes are the properties whose values can be checked.
+var propertyTypes = map[string]ValueType{
+	"size": Vec2, "position": Vec2, "minimumsize": Vec2, "maximumsize": Vec2,
+	"margin": Vec2, "framesize": Vec2, "spriteborder": Vec2, "tooltip_offset": Vec2,
+	"spacing": Number, "alpha": Number, "scale": Number, "frame": Number,
+	"parentanchor": Anchor, "widgetanchor": Anchor,
+	"tooltip_parentanchor": Anchor, "tooltip_widgetanchor": Anchor,
+	"layoutpolicy_horizontal": LayoutPolicy, "layoutpolicy_vertical": LayoutPolicy,
+	"multiline": Bool, "autoresize": Bool, "resizeparent": Bool, "ignoreinvisible": Bool,
+	"allow_outside": Bool, "alwaystransparent": Bool, "movable": Bool,
+	"datamodel_reuse_widgets": Bool, "setitemsizefromcell": Bool,
+	"bezier": Bezier,
+}
+
+// StateProperties are the entries allowed in a `state = { ... }` block.
+var StateProperties = map[string]bool{
+	"name": true, "next": true, "duration": true, "delay": true, "alpha": true,
+	"position": true, "size": true, "scale": true, "bezier": true, "using": true,
+	"on_start": true, "on_finish": true, "trigger_on_create": true, "trigger_when": true,
+	"start_sound": true, "sound": true, "position_x": true, "position_y": true,
+	"size_x": true, "size_y": true, "frame": true, "color": true, "margin": true,
+	"rotation": true, "glow_alpha": true, "glow_radius": true, "animation": true,
+	"block": true, "blockoverride": true, "pos_offset": true,
+}
+
+// BuiltinStates are the state names the engine triggers itself. Other
+// names starting with "_" are never fired.
+var BuiltinStates = map[string]bool{
+	"_show": true, "_hide": true, "_mouse_enter": true, "_mouse_leave": true,
+	"_mouse_press": true, "_mouse_release": true, "_mouse_click": true,
+	"_mouse_hierarchy_enter": true, "_mouse_hierarchy_leave": true,
+}
+
+var (
+	anchorValues       = map[string]bool{"top": true, "bottom": true, "left": true, "right": true, "hcenter": true, "vcenter": true, "center": true}
+	layoutPolicyValues = map[string]bool{"fixed": true, "expanding": true, "preferred": true, "growing": true, "shrinking": true, "minimum": true, "maximum": true}
+)
+
+// IsWidget reports whether name is a built-in widget type.
+func IsWidget(name string) bool {
+	_, ok := widgetGroups[name]
+	return ok
+}
+
+// HasProperty reports whether widget accepts the property key. An unknown
+// widget (a template or declared type) accepts the properties of any
+// widget.
+func HasProperty(widget, key string) bool {
+	groups, ok := widgetGroups[widget]
+	if !ok {
+		for name := range propertyGroups {
+			if contains(propertyGroups[name], key) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, name := range append([]string{"common"}, groups...) {
+		if contains(propertyGroups[name], key) {
+			return true
+		}
+	}
+	return false
+}
+
+// PropertyType returns the expected value type of a property.
+func PropertyType(key string) ValueType {
+	return propertyTypes[key]
+}
+
+// CheckScalar validates a scalar value against a value type and returns a
+// problem description, or "" if the value is acceptable. Script constants
+// (@name) and data bindings ([...]) are always accepted.
+func CheckScalar(typ ValueType, value string) string {
+	if strings.HasPrefix(value, "@") || strings.Contains(value, "[") {
+		return ""
+	}
+	switch typ {
+	case Vec2, Bezier:
+		return "expected a { x y } block"
+	case Anchor:
+		for _, part := range strings.Split(value, "|") {
+			if !anchorValues[part] {
+				return "invalid anchor '" + part + "'; expected top, bottom, left, right, hcenter, vcenter or center"
+			}
+		}
+	case LayoutPolicy:
+		if !layoutPolicyValues[value] {
+			return "invalid layout policy '" + value + "'; expected fixed, expanding, preferred, growing, shrinking, minimum or maximum"
+		}
+	case Bool:
+		if value != "yes" && value != "no" {
+			return "expected yes or no"
+		}
+	case Number:
+		if !IsNumber(value) {
+			return "expected a number"
+		}
+	}
+	return ""
+}
+
+// Arity returns how many numbers a block value of typ must hold, or 0 if
+// typ is not a numeric tuple.
+func Arity(typ ValueType) int {
+	switch typ {
+	case Vec2:
+		return 2
+	case Bezier:
+		return 4
+	}
+	return 0
+}
+
+// IsNumber reports whether s is a number or a percentage such as "100%".
+func IsNumber(s string) bool {
+	_, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	return err == nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
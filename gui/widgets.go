@@ -0,0 +1,14 @@
+package gui
+
+import "sort"
+
+// Widgets are the built-in widget types every .gui file may instantiate,
+// sorted by name.
+var Widgets = func() []string {
+	names := make([]string, 0, len(widgetGroups))
+	for name := range widgetGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}()
@@ -0,0 +1,114 @@
+// Package i18n translates the messages of the server, such as diagnostics
+// and hover texts, into the language of the client.
+//
+// A catalog maps the English format strings of the messages, as passed to
+// fmt.Sprintf, to translated ones. Since messages are formatted where they
+// are produced, a message is translated by matching it against the English
+// formats and formatting the translation with the values it was made of.
+// Translations refer to the values by position, as in %[2]s, so they may
+// reorder them; every value is a string.
+package i18n
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// catalog maps English format strings to their translations.
+type catalog map[string]string
+
+// catalogs holds the catalog of each language by its base language tag.
+var catalogs = map[string]catalog{
+	"ru": russian,
+	"zh": chinese,
+}
+
+// template is a translation with the pattern of its English format, which
+// captures the values the message was formatted with.
+type template struct {
+	format      string
+	pattern     *regexp.Regexp
+	translation string
+}
+
+var (
+	compileOnce sync.Once
+	templates   map[string][]template
+)
+
+// verbPattern matches the formatting verbs of the English formats.
+var verbPattern = regexp.MustCompile(`%[-+# 0-9.]*[sdvqgf]`)
+
+// compile builds the patterns of the formats that take values. Formats
+// without values are looked up directly.
+func compile() {
+	templates = make(map[string][]template)
+	for lang, c := range catalogs {
+		for format, translation := range c {
+			if !verbPattern.MatchString(format) {
+				continue
+			}
+			var b strings.Builder
+			b.WriteString("^")
+			last := 0
+			for _, m := range verbPattern.FindAllStringIndex(format, -1) {
+				b.WriteString(regexp.QuoteMeta(format[last:m[0]]))
+				b.WriteString("(.*?)")
+				last = m[1]
+			}
+			b.WriteString(regexp.QuoteMeta(format[last:]))
+			b.WriteString("$")
+			templates[lang] = append(templates[lang], template{format, regexp.MustCompile(b.String()), translation})
+		}
+		// Longer formats are more specific, and are tried first so that a
+		// message matches the same template every time.
+		sort.Slice(templates[lang], func(i, j int) bool {
+			a, b := templates[lang][i].format, templates[lang][j].format
+			if len(a) != len(b) {
+				return len(a) > len(b)
+			}
+			return a < b
+		})
+	}
+}
+
+// Language returns the base language of a locale such as "ru" or "zh-CN",
+// or "" if there is no catalog for it.
+func Language(locale string) string {
+	lang := strings.ToLower(locale)
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	if _, ok := catalogs[lang]; !ok {
+		return ""
+	}
+	return lang
+}
+
+// Translate returns msg in the language of locale, or msg itself if the
+// language or the message has no translation.
+func Translate(locale, msg string) string {
+	lang := Language(locale)
+	if lang == "" {
+		return msg
+	}
+	if translation, ok := catalogs[lang][msg]; ok {
+		return translation
+	}
+	compileOnce.Do(compile)
+	for _, t := range templates[lang] {
+		m := t.pattern.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+		args := make([]interface{}, len(m)-1)
+		for i, v := range m[1:] {
+			args[i] = v
+		}
+		return fmt.Sprintf(t.translation, args...)
+	}
+	return msg
+}
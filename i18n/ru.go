@@ -0,0 +1,57 @@
+package i18n
+
+// russian is the Russian catalog.
+var russian = catalog{
+	// Syntax errors.
+	"unclosed '{'":                                 "незакрытая '{'",
+	"unexpected '}' without matching '{'":          "лишняя '}' без парной '{'",
+	"unexpected operator '%s' without a key":       "оператор '%[1]s' без ключа",
+	"missing value after '%s'":                     "нет значения после '%[1]s'",
+	"unterminated string":                          "незакрытая строка",
+	"unterminated inline math expression":          "незакрытое встроенное выражение",
+	"missing language header such as 'l_english:'": "нет заголовка языка, например 'l_english:'",
+
+	// Definitions and references.
+	"%s '%s' is not defined":               "%[1]s '%[2]s' не определён",
+	"%s '%s' is also defined in %s":        "%[1]s '%[2]s' также определён в %[3]s",
+	"%s '%s' is checked but never set":     "%[1]s '%[2]s' проверяется, но нигде не устанавливается",
+	"localization key '%s' is not defined": "ключ локализации '%[1]s' не определён",
+	"define '%s' does not exist":           "define '%[1]s' не существует",
+	"game concept '%s' is not defined":     "игровое понятие '%[1]s' не определено",
+	"scripted GUI '%s' is not defined":     "scripted GUI '%[1]s' не определён",
+	"'%s' is listed more than once in %s":  "'%[1]s' указан в %[2]s несколько раз",
+	"'%s' is identical to vanilla %s":      "'%[1]s' совпадает с ванильным %[2]s",
+	"event %s is never fired: no on_action, trigger_event, decision or interaction refers to it": "событие %[1]s нигде не вызывается: на него не ссылаются ни on_action, ни trigger_event, ни решения, ни взаимодействия",
+
+	// Scopes and performance.
+	"%s is not valid in %s scope; it needs %s":                                     "%[1]s недопустим в области %[2]s; нужна область %[3]s",
+	"%s is a trigger but is used among effects; use every_%s or random_%s":         "%[1]s — это условие, но используется среди эффектов; используйте every_%[2]s или random_%[3]s",
+	"%s is an effect but is used among triggers; use any_%s":                       "%[1]s — это эффект, но используется среди условий; используйте any_%[2]s",
+	"%s has no limit and %s every %s in the game":                                  "у %[1]s нет limit, и он %[2]s каждый %[3]s в игре",
+	"%s is nested in another every_ loop; its cost multiplies with the outer loop": "%[1]s вложен в другой цикл every_; его стоимость умножается на внешний цикл",
+	"random_list inside a loop evaluates all of its options on every iteration":    "random_list внутри цикла вычисляет все свои варианты на каждой итерации",
+
+	// Descriptions, DNA and versions.
+	"triggered_desc has no trigger":                                              "у triggered_desc нет trigger",
+	"triggered_desc has no desc":                                                 "у triggered_desc нет desc",
+	"trigger of triggered_desc must be a block":                                  "trigger в triggered_desc должен быть блоком",
+	"DNA string is not valid base64":                                             "строка DNA не является корректным base64",
+	"DNA string spans several lines; it must be pasted as a single line":         "строка DNA занимает несколько строк; её нужно вставить одной строкой",
+	"supported_version %s is older than the installed game %s":                   "supported_version %[1]s старше установленной игры %[2]s",
+	"translation of '%s' is at version %d but the English text is at version %d": "перевод '%[1]s' имеет версию %[2]s, а английский текст — версию %[3]s",
+	"assertion failed in %s: %s":                                                 "проверка не пройдена в %[1]s: %[2]s",
+
+	// Truncation.
+	"%d more problems suppressed; raise diagnostics.maxPerFile to see them": "ещё %[1]s проблем скрыто; увеличьте diagnostics.maxPerFile, чтобы увидеть их",
+
+	// Hover texts of event targets.
+	"The scope the event, decision or other definition was started for. It stays the same throughout the definition.": "Область, для которой запущено событие, решение или другое определение. Она не меняется во всём определении.",
+	"The current scope, as changed by the iterators and event targets enclosing the cursor.":                          "Текущая область с учётом итераторов и целей событий вокруг курсора.",
+	"The scope before the last scope change.":                                                                         "Область до последней смены области.",
+
+	// Rule descriptions.
+	"An any_ iterator among effects, or an every_, random_ or ordered_ iterator among triggers.": "Итератор any_ среди эффектов или итератор every_, random_ или ordered_ среди условий.",
+	"`dna = name` refers to a DNA that is not defined in common/dna_data.":                       "`dna = name` ссылается на DNA, не определённую в common/dna_data.",
+	"Localization refers to a game concept that is not defined in common/game_concepts.":         "Локализация ссылается на игровое понятие, не определённое в common/game_concepts.",
+	"A TODO, FIXME or HACK comment, listed so outstanding work shows up in the problems panel.":  "Комментарий TODO, FIXME или HACK, показанный, чтобы незавершённая работа была видна на панели проблем.",
+}
@@ -0,0 +1,57 @@
+package i18n
+
+// chinese is the Simplified Chinese catalog.
+var chinese = catalog{
+	// Syntax errors.
+	"unclosed '{'":                                 "未闭合的 '{'",
+	"unexpected '}' without matching '{'":          "多余的 '}'，没有与之匹配的 '{'",
+	"unexpected operator '%s' without a key":       "运算符 '%[1]s' 缺少键",
+	"missing value after '%s'":                     "'%[1]s' 之后缺少值",
+	"unterminated string":                          "未闭合的字符串",
+	"unterminated inline math expression":          "未闭合的内联数学表达式",
+	"missing language header such as 'l_english:'": "缺少语言标头，例如 'l_english:'",
+
+	// Definitions and references.
+	"%s '%s' is not defined":               "%[1]s '%[2]s' 未定义",
+	"%s '%s' is also defined in %s":        "%[1]s '%[2]s' 也在 %[3]s 中定义",
+	"%s '%s' is checked but never set":     "%[1]s '%[2]s' 被检查，但从未被设置",
+	"localization key '%s' is not defined": "本地化键 '%[1]s' 未定义",
+	"define '%s' does not exist":           "define '%[1]s' 不存在",
+	"game concept '%s' is not defined":     "游戏概念 '%[1]s' 未定义",
+	"scripted GUI '%s' is not defined":     "scripted GUI '%[1]s' 未定义",
+	"'%s' is listed more than once in %s":  "'%[1]s' 在 %[2]s 中出现了多次",
+	"'%s' is identical to vanilla %s":      "'%[1]s' 与原版 %[2]s 完全相同",
+	"event %s is never fired: no on_action, trigger_event, decision or interaction refers to it": "事件 %[1]s 从未被触发：没有任何 on_action、trigger_event、决议或互动引用它",
+
+	// Scopes and performance.
+	"%s is not valid in %s scope; it needs %s":                                     "%[1]s 在 %[2]s 作用域中无效；它需要 %[3]s",
+	"%s is a trigger but is used among effects; use every_%s or random_%s":         "%[1]s 是触发条件，却用在效果中；请使用 every_%[2]s 或 random_%[3]s",
+	"%s is an effect but is used among triggers; use any_%s":                       "%[1]s 是效果，却用在触发条件中；请使用 any_%[2]s",
+	"%s has no limit and %s every %s in the game":                                  "%[1]s 没有 limit，会%[2]s游戏中的每个 %[3]s",
+	"%s is nested in another every_ loop; its cost multiplies with the outer loop": "%[1]s 嵌套在另一个 every_ 循环中；其开销会随外层循环成倍增加",
+	"random_list inside a loop evaluates all of its options on every iteration":    "循环中的 random_list 每次迭代都会计算其全部选项",
+
+	// Descriptions, DNA and versions.
+	"triggered_desc has no trigger":                                              "triggered_desc 缺少 trigger",
+	"triggered_desc has no desc":                                                 "triggered_desc 缺少 desc",
+	"trigger of triggered_desc must be a block":                                  "triggered_desc 的 trigger 必须是一个块",
+	"DNA string is not valid base64":                                             "DNA 字符串不是有效的 base64",
+	"DNA string spans several lines; it must be pasted as a single line":         "DNA 字符串跨越多行；必须粘贴为单行",
+	"supported_version %s is older than the installed game %s":                   "supported_version %[1]s 早于已安装的游戏版本 %[2]s",
+	"translation of '%s' is at version %d but the English text is at version %d": "'%[1]s' 的翻译版本为 %[2]s，而英文文本版本为 %[3]s",
+	"assertion failed in %s: %s":                                                 "%[1]s 中的断言失败：%[2]s",
+
+	// Truncation.
+	"%d more problems suppressed; raise diagnostics.maxPerFile to see them": "另有 %[1]s 个问题未显示；增大 diagnostics.maxPerFile 以查看它们",
+
+	// Hover texts of event targets.
+	"The scope the event, decision or other definition was started for. It stays the same throughout the definition.": "事件、决议或其他定义启动时所针对的作用域，在整个定义中保持不变。",
+	"The current scope, as changed by the iterators and event targets enclosing the cursor.":                          "当前作用域，已考虑光标外层的迭代器和事件目标所做的更改。",
+	"The scope before the last scope change.":                                                                         "上一次作用域变更之前的作用域。",
+
+	// Rule descriptions.
+	"An any_ iterator among effects, or an every_, random_ or ordered_ iterator among triggers.": "效果中的 any_ 迭代器，或触发条件中的 every_、random_ 或 ordered_ 迭代器。",
+	"`dna = name` refers to a DNA that is not defined in common/dna_data.":                       "`dna = name` 引用了未在 common/dna_data 中定义的 DNA。",
+	"Localization refers to a game concept that is not defined in common/game_concepts.":         "本地化引用了未在 common/game_concepts 中定义的游戏概念。",
+	"A TODO, FIXME or HACK comment, listed so outstanding work shows up in the problems panel.":  "TODO、FIXME 或 HACK 注释，列出以便在问题面板中显示未完成的工作。",
+}
@@ -0,0 +1,62 @@
+package index
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// callCache holds the ScriptCalls of each file. Since whether a key is a
+// call depends on the definitions of every file, a change to the set of
+// scripted effects, scripted triggers or script values drops the whole
+// cache; any other change only drops the calls of the file changed.
+type callCache struct {
+	mu    sync.Mutex
+	files map[string][]Reference
+}
+
+// fileCalls returns the cached ScriptCalls of the file at path, computing
+// them if needed.
+func (ix *Index) fileCalls(path string) []Reference {
+	ix.calls.mu.Lock()
+	defer ix.calls.mu.Unlock()
+	if calls, ok := ix.calls.files[path]; ok {
+		return calls
+	}
+	if ix.calls.files == nil {
+		ix.calls.files = make(map[string][]Reference)
+	}
+	calls := ix.ScriptCalls(ix.File(path))
+	ix.calls.files[path] = calls
+	return calls
+}
+
+// invalidateCalls drops the cached calls of path, or all of them if the
+// change redefined what counts as a call. The caller must not hold ix.mu.
+func (ix *Index) invalidateCalls(path string, all bool) {
+	ix.calls.mu.Lock()
+	defer ix.calls.mu.Unlock()
+	if all {
+		ix.calls.files = nil
+		return
+	}
+	delete(ix.calls.files, path)
+}
+
+// callSignature lists the CallKinds definitions of entry, which decide
+// what other files' calls resolve to.
+func callSignature(entry *FileEntry) string {
+	if entry == nil {
+		return ""
+	}
+	var names []string
+	for _, sym := range entry.Symbols {
+		for _, kind := range CallKinds {
+			if sym.Kind == kind {
+				names = append(names, string(kind)+":"+sym.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, "\n")
+}
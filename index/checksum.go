@@ -0,0 +1,17 @@
+package index
+
+import "strings"
+
+// checksumFolders are the top-level folders whose files the game hashes
+// into the checksum that decides whether ironman achievements stay
+// available. Interface, graphics, sound and localization files do not count.
+var checksumFolders = map[string]bool{
+	"common": true, "events": true, "history": true, "map_data": true,
+}
+
+// AffectsChecksum reports whether a file at the virtual path vpath changes
+// the game checksum.
+func AffectsChecksum(vpath string) bool {
+	folder, _, ok := strings.Cut(vpath, "/")
+	return ok && checksumFolders[folder]
+}
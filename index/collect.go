@@ -0,0 +1,302 @@
+package index
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/unLomTrois/gock3-lsp/loc"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// collect fills in the symbols and references of a freshly parsed entry.
+func collect(entry *FileEntry) {
+	collectDefinitions(entry)
+	collectGenes(entry)
+	collectTitles(entry)
+	collectLaws(entry)
+	collectTitleHistoryRefs(entry)
+	collectFaiths(entry)
+	collectProvinceTerrain(entry)
+	collectLevels(entry)
+	collectRegions(entry)
+	collectDefines(entry)
+	collectDLC(entry)
+	collectEvents(entry)
+	gui := IsGUIFile(entry.Path)
+	pdx.Walk(entry.File.Root, func(f *pdx.Field) bool {
+		collectFlag(entry, f)
+		if !gui {
+			collectGeneRefs(entry, f)
+			collectDNARef(entry, f)
+			collectHistoryRefs(entry, f)
+			collectKeyRef(entry, f)
+			collectScriptedModifierRef(entry, f)
+			collectDefineRef(entry, f)
+			collectDLCRef(entry, f)
+			collectRandomEvents(entry, f)
+		} else {
+			collectScriptedGUIRefs(entry, f)
+			collectGUITypes(entry, f)
+		}
+		return true
+	})
+}
+
+// collectGUITypes records `template name { ... }` and `type name = base
+// { ... }` declarations, `using = name` references, and block-valued keys
+// as possible uses of a widget type. Only keys that resolve to a type
+// declaration are ever looked up, so ordinary properties are harmless.
+func collectGUITypes(entry *FileEntry, f *pdx.Field) {
+	b := f.Block()
+	switch {
+	case f.KeyText() == "template" && b != nil && b.Tag != nil:
+		entry.Symbols = append(entry.Symbols, Symbol{Kind: KindGUITemplate, Name: b.Tag.Text, Location: Location{Path: entry.Path, Range: b.Tag.Loc}})
+	case f.KeyText() == "using" && f.Scalar() != nil:
+		s := f.Scalar()
+		entry.Refs = append(entry.Refs, Reference{Kind: KindGUITemplate, Name: s.Text, Location: Location{Path: entry.Path, Range: s.Loc}})
+	case b != nil && f.Key != nil && IsGUITypeDeclaration(f):
+		entry.Symbols = append(entry.Symbols, Symbol{Kind: KindGUIType, Name: f.Key.Text, Location: Location{Path: entry.Path, Range: f.Key.Loc}})
+		if b.Tag != nil {
+			entry.Refs = append(entry.Refs, Reference{Kind: KindGUIType, Name: b.Tag.Text, Location: Location{Path: entry.Path, Range: b.Tag.Loc}})
+		}
+	case b != nil && f.Key != nil && (f.Op == pdx.OpAssign || f.Op == pdx.OpNone):
+		entry.Refs = append(entry.Refs, Reference{Kind: KindGUIType, Name: f.Key.Text, Location: Location{Path: entry.Path, Range: f.Key.Loc}})
+	}
+}
+
+// IsGUITypeDeclaration reports whether f is the `name = base { ... }` part of
+// `type name = base { ... }`, which parses as a bare `type` value followed
+// by the field.
+func IsGUITypeDeclaration(f *pdx.Field) bool {
+	fields := f.Parent.Fields
+	for i, sibling := range fields {
+		if sibling == f {
+			return i > 0 && fields[i-1].Key == nil && fields[i-1].ValueText() == "type"
+		}
+	}
+	return false
+}
+
+// definitionFolders maps folders whose top-level keys define named objects
+// to the kind of those objects.
+var definitionFolders = map[string]Kind{
+	"common/scripted_guis/":          KindScriptedGUI,
+	"common/game_concepts/":          KindGameConcept,
+	"common/ethnicities/":            KindEthnicity,
+	"common/dna_data/":               KindDNA,
+	"history/characters/":            KindCharacter,
+	"common/buildings/":              KindBuilding,
+	"common/holdings/":               KindHolding,
+	"common/culture/cultures/":       KindCulture,
+	"common/terrain_types/":          KindTerrain,
+	"common/domicile_types/":         KindDomicileType,
+	"common/domicile_buildings/":     KindDomicileBuilding,
+	"common/house_unities/":          KindHouseUnity,
+	"common/succession_appointment/": KindAppointment,
+	"common/tax_slots/":              KindTaxSlot,
+	"common/epidemics/":              KindEpidemic,
+	"common/diseases/":               KindDisease,
+	"common/dynasties/":              KindDynasty,
+	"common/dynasty_houses/":         KindHouse,
+	"common/accolade_types/":         KindAccoladeType,
+	"common/accolade_names/":         KindAccoladeName,
+	"common/scripted_modifiers/":     KindScriptedModifier,
+	"common/scripted_rules/":         KindScriptedRule,
+	"common/scripted_effects/":       KindScriptedEffect,
+	"common/scripted_triggers/":      KindScriptedTrigger,
+	"common/script_values/":          KindScriptValue,
+	"common/on_action/":              KindOnAction,
+}
+
+// DefinitionKind returns the kind of the objects the top-level keys of a
+// file at vpath define, if it is in a definition folder.
+func DefinitionKind(vpath string) (Kind, bool) {
+	for prefix, kind := range definitionFolders {
+		if strings.HasPrefix(vpath, prefix) {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+// collectDefinitions records the top-level keys of files in a definition
+// folder as symbols.
+func collectDefinitions(entry *FileEntry) {
+	for prefix, kind := range definitionFolders {
+		if !strings.HasPrefix(entry.VirtualPath, prefix) {
+			continue
+		}
+		for _, f := range entry.File.Root.Fields {
+			if f.Key == nil || f.Op != pdx.OpAssign || !IsStaticName(f.Key.Text) {
+				continue
+			}
+			entry.Symbols = append(entry.Symbols, Symbol{
+				Kind:     kind,
+				Name:     f.Key.Text,
+				Location: Location{Path: entry.Path, Range: f.Key.Loc},
+			})
+			if kind == KindGameConcept {
+				collectConceptAliases(entry, f)
+			}
+		}
+	}
+}
+
+// collectConceptAliases records the `alias = { ... }` names of a game
+// concept, which localization may use in place of its key.
+func collectConceptAliases(entry *FileEntry, f *pdx.Field) {
+	b := f.Block()
+	if b == nil {
+		return
+	}
+	for _, alias := range b.All("alias") {
+		ab := alias.Block()
+		if ab == nil {
+			continue
+		}
+		for _, item := range ab.Fields {
+			if s := item.Scalar(); s != nil && item.Key == nil {
+				entry.Symbols = append(entry.Symbols, Symbol{Kind: KindGameConcept, Name: s.Text, Location: Location{Path: entry.Path, Range: s.Loc}})
+			}
+		}
+	}
+}
+
+// scriptedGUIPattern matches GetScriptedGui('name') in data-binding strings.
+var scriptedGUIPattern = regexp.MustCompile(`GetScriptedGui\(\s*'([^']+)'`)
+
+// collectScriptedGUIRefs records the scripted GUIs a GUI property uses.
+func collectScriptedGUIRefs(entry *FileEntry, f *pdx.Field) {
+	s := f.Scalar()
+	if s == nil || !s.Quoted {
+		return
+	}
+	for _, m := range scriptedGUIPattern.FindAllStringSubmatchIndex(s.Text, -1) {
+		entry.Refs = append(entry.Refs, Reference{
+			Kind:     KindScriptedGUI,
+			Name:     s.Text[m[2]:m[3]],
+			Location: Location{Path: entry.Path, Range: StringSpan(s, m[2], m[3])},
+		})
+	}
+}
+
+// StringSpan returns the source range of the bytes [start, end) of a quoted
+// single-line scalar's text.
+func StringSpan(s *pdx.Scalar, start, end int) pdx.Range {
+	base := s.Loc.Start
+	base.Col++ // opening quote
+	base.Offset++
+	return pdx.Range{
+		Start: pdx.Pos{Offset: base.Offset + start, Line: base.Line, Col: base.Col + utf16Len(s.Text[:start])},
+		End:   pdx.Pos{Offset: base.Offset + end, Line: base.Line, Col: base.Col + utf16Len(s.Text[:end])},
+	}
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// collectLocalization records every key of a localization file and the
+// game concepts its texts use.
+func collectLocalization(entry *FileEntry) {
+	for _, e := range entry.Loc.Entries {
+		entry.Symbols = append(entry.Symbols, Symbol{
+			Kind:     KindLocalization,
+			Name:     e.Key,
+			Location: Location{Path: entry.Path, Range: e.KeyRange},
+		})
+		for _, ref := range loc.ConceptRefs(e.Text) {
+			entry.Refs = append(entry.Refs, Reference{
+				Kind:     KindGameConcept,
+				Name:     ref.Name,
+				Location: Location{Path: entry.Path, Range: e.TextSpan(ref.Start, ref.End)},
+			})
+		}
+	}
+}
+
+// flagKeyPattern matches the flag effects and triggers of every scope type:
+// add_character_flag, remove_title_flag, has_global_flag...
+var flagKeyPattern = regexp.MustCompile(`^(add|set|remove|clr|has)_([a-z_]+?)_flag$`)
+
+// runtimeFlagKinds are the scope types whose flags script sets with
+// add_<kind>_flag. Triggers like has_trait_flag or has_building_flag also
+// match flagKeyPattern but test the `flag = x` of database definitions,
+// which no effect sets.
+var runtimeFlagKinds = map[string]bool{
+	"character": true, "title": true, "dynasty": true, "house": true,
+	"global": true, "province": true, "faith": true, "culture": true,
+}
+
+// FlagOp classifies a flag effect or trigger key.
+type FlagOp int
+
+const (
+	FlagSet FlagOp = iota
+	FlagRemove
+	FlagCheck
+)
+
+// ParseFlagKey splits a key like "has_character_flag" into its operation and
+// flag kind ("character_flag"). ok is false for unrelated keys, and for
+// the triggers of database flags such as has_trait_flag.
+func ParseFlagKey(key string) (op FlagOp, kind Kind, ok bool) {
+	m := flagKeyPattern.FindStringSubmatch(key)
+	if m == nil || !runtimeFlagKinds[m[2]] {
+		return 0, "", false
+	}
+	switch m[1] {
+	case "add", "set":
+		op = FlagSet
+	case "remove", "clr":
+		op = FlagRemove
+	default:
+		op = FlagCheck
+	}
+	return op, Kind(m[2] + "_flag"), true
+}
+
+// IsFlagKind reports whether kind names a family of flags.
+func IsFlagKind(kind Kind) bool {
+	return strings.HasSuffix(string(kind), "_flag")
+}
+
+// collectFlag records `add_character_flag = name` (or the block form with
+// `flag = name`) as a symbol and the matching has_/remove_ keys as
+// references.
+func collectFlag(entry *FileEntry, f *pdx.Field) {
+	op, kind, ok := ParseFlagKey(f.KeyText())
+	if !ok {
+		return
+	}
+	name := f.Scalar()
+	if b := f.Block(); b != nil {
+		if flag := b.Get("flag"); flag != nil {
+			name = flag.Scalar()
+		}
+	}
+	if name == nil || !IsStaticName(name.Text) {
+		return
+	}
+	text := strings.TrimPrefix(name.Text, "flag:")
+	loc := Location{Path: entry.Path, Range: name.Loc}
+	if op == FlagSet {
+		entry.Symbols = append(entry.Symbols, Symbol{Kind: kind, Name: text, Location: loc})
+	} else {
+		entry.Refs = append(entry.Refs, Reference{Kind: kind, Name: text, Location: loc})
+	}
+}
+
+// IsStaticName reports whether a value is a literal name rather than a
+// script parameter, variable or inline expression resolved at runtime.
+func IsStaticName(s string) bool {
+	return s != "" && !strings.ContainsAny(s, "$@[]")
+}
@@ -0,0 +1,29 @@
+package index
+
+import "testing"
+
+func TestParseFlagKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		op   FlagOp
+		kind Kind
+		ok   bool
+	}{
+		{"add_character_flag", FlagSet, "character_flag", true},
+		{"set_global_flag", FlagSet, "global_flag", true},
+		{"remove_title_flag", FlagRemove, "title_flag", true},
+		{"clr_dynasty_flag", FlagRemove, "dynasty_flag", true},
+		{"has_house_flag", FlagCheck, "house_flag", true},
+		// Database flags, declared as `flag = x` in their definitions.
+		{"has_trait_flag", 0, "", false},
+		{"has_realm_law_flag", 0, "", false},
+		{"has_building_flag", 0, "", false},
+		{"add_gold", 0, "", false},
+	}
+	for _, tt := range tests {
+		op, kind, ok := ParseFlagKey(tt.key)
+		if op != tt.op || kind != tt.kind || ok != tt.ok {
+			t.Errorf("ParseFlagKey(%q) = %v, %q, %v, want %v, %q, %v", tt.key, op, kind, ok, tt.op, tt.kind, tt.ok)
+		}
+	}
+}
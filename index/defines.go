@@ -0,0 +1,53 @@
+package index
+
+import (
+	"strings"
+
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// KindDefine is the kind of engine defines, named "Namespace.KEY".
+const KindDefine Kind = "define"
+
+// DefineName returns the symbol name of a define.
+func DefineName(namespace, key string) string {
+	return namespace + "." + key
+}
+
+// IsDefinesFile reports whether vpath is a common/defines file.
+func IsDefinesFile(vpath string) bool {
+	return strings.HasPrefix(vpath, "common/defines/")
+}
+
+// collectDefines records the `NGame = { KEY = value }` entries of
+// common/defines files.
+func collectDefines(entry *FileEntry) {
+	if !IsDefinesFile(entry.VirtualPath) {
+		return
+	}
+	for _, ns := range entry.File.Root.Fields {
+		if ns.Key == nil || ns.Block() == nil {
+			continue
+		}
+		for _, f := range ns.Block().Fields {
+			if f.Key != nil {
+				entry.Symbols = append(entry.Symbols, Symbol{Kind: KindDefine, Name: DefineName(ns.Key.Text, f.Key.Text), Location: Location{Path: entry.Path, Range: f.Key.Loc}})
+			}
+		}
+	}
+}
+
+// collectDefineRef records `define:NGame|KEY` values as define references.
+func collectDefineRef(entry *FileEntry, f *pdx.Field) {
+	s := f.Scalar()
+	if s == nil || s.Quoted {
+		return
+	}
+	rest, ok := strings.CutPrefix(s.Text, "define:")
+	if !ok {
+		return
+	}
+	if ns, key, ok := strings.Cut(rest, "|"); ok {
+		entry.Refs = append(entry.Refs, Reference{Kind: KindDefine, Name: DefineName(ns, key), Location: Location{Path: entry.Path, Range: s.Loc}})
+	}
+}
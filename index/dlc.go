@@ -0,0 +1,66 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// Kinds of DLC names (matched by has_dlc) and DLC features (matched by
+// has_dlc_feature).
+const (
+	KindDLC        Kind = "dlc"
+	KindDLCFeature Kind = "dlc_feature"
+)
+
+// IsDLCFile reports whether path is a DLC descriptor of the game
+// installation (dlc/dlc001_name/dlc001.dlc).
+func IsDLCFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".dlc")
+}
+
+// ScanDLC indexes the DLC descriptors below the dlc folder of a game
+// installation, which may sit inside the game folder or next to it, and
+// returns how many were read.
+func (ix *Index) ScanDLC(gamePath string) int {
+	count := 0
+	for _, dir := range []string{filepath.Join(gamePath, "dlc"), filepath.Join(gamePath, "..", "dlc")} {
+		files, _ := filepath.Glob(filepath.Join(dir, "*", "*.dlc"))
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				continue
+			}
+			ix.UpdateFile(file, string(data))
+			count++
+		}
+	}
+	return count
+}
+
+// collectDLC records the `name` of a DLC descriptor.
+func collectDLC(entry *FileEntry) {
+	if !IsDLCFile(entry.Path) {
+		return
+	}
+	if name := entry.File.Root.Get("name"); name != nil && name.Scalar() != nil {
+		entry.Symbols = append(entry.Symbols, Symbol{Kind: KindDLC, Name: name.Scalar().Text, Location: Location{Path: entry.Path, Range: name.Scalar().Loc}})
+	}
+}
+
+// collectDLCRef records the arguments of has_dlc and has_dlc_feature.
+func collectDLCRef(entry *FileEntry, f *pdx.Field) {
+	s := f.Scalar()
+	if s == nil || !IsStaticName(s.Text) {
+		return
+	}
+	loc := Location{Path: entry.Path, Range: s.Loc}
+	switch f.KeyText() {
+	case "has_dlc":
+		entry.Refs = append(entry.Refs, Reference{Kind: KindDLC, Name: s.Text, Location: loc})
+	case "has_dlc_feature":
+		entry.Refs = append(entry.Refs, Reference{Kind: KindDLCFeature, Name: s.Text, Location: loc})
+	}
+}
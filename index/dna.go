@@ -0,0 +1,22 @@
+package index
+
+import "github.com/unLomTrois/gock3-lsp/pdx"
+
+// KindDNA is the kind of named DNA definitions in common/dna_data.
+const KindDNA Kind = "dna"
+
+// IsDNAString reports whether f is a `dna = "..."` portrait string rather
+// than a reference to a named DNA.
+func IsDNAString(f *pdx.Field) bool {
+	s := f.Scalar()
+	return f.KeyText() == "dna" && s != nil && s.Quoted
+}
+
+// collectDNARef records `dna = name` as a reference to a DNA definition.
+func collectDNARef(entry *FileEntry, f *pdx.Field) {
+	s := f.Scalar()
+	if f.KeyText() != "dna" || s == nil || s.Quoted || !IsStaticName(s.Text) {
+		return
+	}
+	entry.Refs = append(entry.Refs, Reference{Kind: KindDNA, Name: s.Text, Location: Location{Path: entry.Path, Range: s.Loc}})
+}
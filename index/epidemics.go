@@ -0,0 +1,24 @@
+package index
+
+import "strings"
+
+// Kinds of epidemics, legacy diseases and the regions they spread in.
+const (
+	KindEpidemic Kind = "epidemic"
+	KindDisease  Kind = "disease"
+	KindRegion   Kind = "geographical_region"
+)
+
+// collectRegions records the regions of map_data/geographical_regions. The
+// regions and titles they are composed of are references per
+// folderListKeys.
+func collectRegions(entry *FileEntry) {
+	if !strings.HasPrefix(entry.VirtualPath, "map_data/geographical_regions/") {
+		return
+	}
+	for _, region := range entry.File.Root.Fields {
+		if region.Key != nil && region.Block() != nil {
+			entry.Symbols = append(entry.Symbols, Symbol{Kind: KindRegion, Name: region.Key.Text, Location: Location{Path: entry.Path, Range: region.Key.Loc}})
+		}
+	}
+}
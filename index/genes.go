@@ -0,0 +1,118 @@
+package index
+
+import (
+	"strings"
+
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// Kinds of the portrait system.
+const (
+	KindEthnicity    Kind = "ethnicity"
+	KindGene         Kind = "gene"
+	KindGeneTemplate Kind = "gene_template"
+)
+
+// geneCategories are the top-level blocks of common/genes files that hold
+// gene definitions. special_genes nests the other categories.
+var geneCategories = map[string]bool{
+	"morph_genes": true, "accessory_genes": true, "color_genes": true, "special_genes": true,
+}
+
+// geneProperties are the block-valued entries of a gene that are not
+// templates.
+var geneProperties = map[string]bool{
+	"ugliness_feature_categories": true,
+}
+
+// GeneTemplateName returns the symbol name of a gene's template, which is
+// qualified by the gene since templates of different genes may share names.
+func GeneTemplateName(gene, template string) string {
+	return gene + "/" + template
+}
+
+// collectGenes records the genes of a common/genes file and their
+// templates.
+func collectGenes(entry *FileEntry) {
+	if !strings.HasPrefix(entry.VirtualPath, "common/genes/") {
+		return
+	}
+	var category func(b *pdx.Block)
+	category = func(b *pdx.Block) {
+		for _, f := range b.Fields {
+			gb := f.Block()
+			if f.Key == nil || gb == nil {
+				continue
+			}
+			if geneCategories[f.Key.Text] {
+				category(gb)
+				continue
+			}
+			gene := f.Key.Text
+			entry.Symbols = append(entry.Symbols, Symbol{Kind: KindGene, Name: gene, Location: Location{Path: entry.Path, Range: f.Key.Loc}})
+			for _, t := range gb.Fields {
+				if t.Key == nil || t.Block() == nil || geneProperties[t.Key.Text] {
+					continue
+				}
+				entry.Symbols = append(entry.Symbols, Symbol{Kind: KindGeneTemplate, Name: GeneTemplateName(gene, t.Key.Text), Location: Location{Path: entry.Path, Range: t.Key.Loc}})
+			}
+		}
+	}
+	for _, f := range entry.File.Root.Fields {
+		if b := f.Block(); b != nil && geneCategories[f.KeyText()] {
+			category(b)
+		}
+	}
+}
+
+// IsDNAGenes reports whether f is a gene setting inside the `genes = { ... }`
+// block of a DNA definition, e.g. `gene_chin_forward = { "chin_forward_pos"
+// 127 "chin_forward_pos" 127 }`.
+func IsDNAGenes(f *pdx.Field) bool {
+	return f.Key != nil && f.Block() != nil && f.ParentField().KeyText() == "genes"
+}
+
+// IsEthnicityGene reports whether f is a gene entry of an ethnicity in a
+// common/ethnicities file, e.g. `gene_chin_forward = { 10 = { name = ... } }`.
+func IsEthnicityGene(entry *FileEntry, f *pdx.Field) bool {
+	if !strings.HasPrefix(entry.VirtualPath, "common/ethnicities/") || f.Key == nil || f.Block() == nil {
+		return false
+	}
+	ethnicity := f.ParentField()
+	return ethnicity != nil && ethnicity.ParentField() == nil && f.Key.Text != "template"
+}
+
+// collectGeneRefs records the genes and templates used by DNA definitions
+// and ethnicities, and the ethnicities used by cultures, characters and
+// ethnicity templates.
+func collectGeneRefs(entry *FileEntry, f *pdx.Field) {
+	ref := func(kind Kind, name string, s *pdx.Scalar) {
+		entry.Refs = append(entry.Refs, Reference{Kind: kind, Name: name, Location: Location{Path: entry.Path, Range: s.Loc}})
+	}
+	switch {
+	case IsDNAGenes(f):
+		gene := f.Key.Text
+		ref(KindGene, gene, f.Key)
+		for _, item := range f.Block().Fields {
+			if s := item.Scalar(); item.Key == nil && s != nil && s.Quoted {
+				ref(KindGeneTemplate, GeneTemplateName(gene, s.Text), s)
+			}
+		}
+	case IsEthnicityGene(entry, f):
+		gene := f.Key.Text
+		ref(KindGene, gene, f.Key)
+		for _, weight := range f.Block().Fields {
+			if wb := weight.Block(); wb != nil {
+				if name := wb.Get("name"); name != nil && name.Scalar() != nil {
+					ref(KindGeneTemplate, GeneTemplateName(gene, name.Scalar().Text), name.Scalar())
+				}
+			}
+		}
+	case f.KeyText() == "template" && strings.HasPrefix(entry.VirtualPath, "common/ethnicities/") && f.Scalar() != nil:
+		ref(KindEthnicity, f.Scalar().Text, f.Scalar())
+	case (f.KeyText() == "ethnicity" || f.ParentField().KeyText() == "ethnicities") && f.Scalar() != nil:
+		if name := f.Scalar().Text; IsStaticName(name) && !strings.Contains(name, ":") {
+			ref(KindEthnicity, name, f.Scalar())
+		}
+	}
+}
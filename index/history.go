@@ -0,0 +1,149 @@
+package index
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// Kinds of history and landed title objects.
+const (
+	KindCharacter Kind = "character"
+	KindTitle     Kind = "title"
+	KindLaw       Kind = "law"
+	KindDynasty   Kind = "dynasty"
+	KindHouse     Kind = "dynasty_house"
+)
+
+// Date is a game date as written in history files, e.g. 1066.9.15.
+type Date struct {
+	Year, Month, Day int
+}
+
+// ParseDate parses a Y.M.D date key. Missing month and day default to 1.
+func ParseDate(s string) (Date, bool) {
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return Date{}, false
+	}
+	d := Date{Month: 1, Day: 1}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Date{}, false
+		}
+		switch i {
+		case 0:
+			d.Year = n
+		case 1:
+			d.Month = n
+		case 2:
+			d.Day = n
+		}
+	}
+	return d, len(parts) > 1
+}
+
+// Before reports whether d is earlier than e.
+func (d Date) Before(e Date) bool {
+	if d.Year != e.Year {
+		return d.Year < e.Year
+	}
+	if d.Month != e.Month {
+		return d.Month < e.Month
+	}
+	return d.Day < e.Day
+}
+
+func (d Date) String() string {
+	return fmt.Sprintf("%d.%d.%d", d.Year, d.Month, d.Day)
+}
+
+// titlePattern matches landed title keys of every tier.
+var titlePattern = regexp.MustCompile(`^[hekdcb]_[A-Za-z0-9_\-']+$`)
+
+// IsTitleKey reports whether s looks like a landed title key.
+func IsTitleKey(s string) bool {
+	return titlePattern.MatchString(s)
+}
+
+// collectTitles records the nested title definitions of common/landed_titles,
+// and the provinces of baronies.
+func collectTitles(entry *FileEntry) {
+	if !strings.HasPrefix(entry.VirtualPath, "common/landed_titles/") {
+		return
+	}
+	pdx.Walk(entry.File.Root, func(f *pdx.Field) bool {
+		if f.Key == nil || f.Block() == nil || !IsTitleKey(f.Key.Text) {
+			return false
+		}
+		entry.Symbols = append(entry.Symbols, Symbol{Kind: KindTitle, Name: f.Key.Text, Location: Location{Path: entry.Path, Range: f.Key.Loc}})
+		if p := f.Block().Get("province"); strings.HasPrefix(f.Key.Text, "b_") && p != nil && IsProvinceID(p.ValueText()) {
+			entry.Symbols = append(entry.Symbols, Symbol{Kind: KindBaronyProvince, Name: p.ValueText(), Location: Location{Path: entry.Path, Range: p.Scalar().Loc}})
+		}
+		return true
+	})
+}
+
+// lawGroupProperties are the entries of a law group that are not laws.
+var lawGroupProperties = map[string]bool{
+	"default": true, "cumulative": true, "flag": true,
+}
+
+// collectLaws records the laws of the law groups in common/laws.
+func collectLaws(entry *FileEntry) {
+	if !strings.HasPrefix(entry.VirtualPath, "common/laws/") {
+		return
+	}
+	for _, group := range entry.File.Root.Fields {
+		if group.Block() == nil {
+			continue
+		}
+		for _, law := range group.Block().Fields {
+			if law.Key == nil || law.Block() == nil || lawGroupProperties[law.Key.Text] {
+				continue
+			}
+			entry.Symbols = append(entry.Symbols, Symbol{Kind: KindLaw, Name: law.Key.Text, Location: Location{Path: entry.Path, Range: law.Key.Loc}})
+		}
+	}
+}
+
+// collectTitleHistoryRefs records the titles, holders and laws used by a
+// history/titles file.
+func collectTitleHistoryRefs(entry *FileEntry) {
+	if !strings.HasPrefix(entry.VirtualPath, "history/titles/") {
+		return
+	}
+	ref := func(kind Kind, s *pdx.Scalar) {
+		entry.Refs = append(entry.Refs, Reference{Kind: kind, Name: s.Text, Location: Location{Path: entry.Path, Range: s.Loc}})
+	}
+	for _, title := range entry.File.Root.Fields {
+		if title.Key == nil || title.Block() == nil {
+			continue
+		}
+		ref(KindTitle, title.Key)
+		for _, date := range title.Block().Fields {
+			if date.Block() == nil {
+				continue
+			}
+			for _, f := range date.Block().Fields {
+				s := f.Scalar()
+				switch key := f.KeyText(); {
+				case key == "holder" && s != nil && s.Text != "0":
+					ref(KindCharacter, s)
+				case (key == "liege" || key == "de_jure_liege") && s != nil && s.Text != "0":
+					ref(KindTitle, s)
+				case key == "succession_laws" && f.Block() != nil:
+					for _, law := range f.Block().Fields {
+						if s := law.Scalar(); law.Key == nil && s != nil {
+							ref(KindLaw, s)
+						}
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,604 @@
+// Package index maintains a workspace-wide table of parsed script files and
+// the symbols they define and reference.
+package index
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/unLomTrois/gock3-lsp/loc"
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// Kind identifies the namespace a symbol lives in, e.g. "character_flag".
+type Kind string
+
+const (
+	// KindLocalization is the kind of localization keys.
+	KindLocalization Kind = "localization"
+	KindScriptedGUI  Kind = "scripted_gui"
+	KindGUITemplate  Kind = "gui_template"
+	KindGUIType      Kind = "gui_type"
+	KindGameConcept  Kind = "game_concept"
+	KindAccoladeType Kind = "accolade_type"
+	KindAccoladeName Kind = "accolade_name"
+)
+
+// Location is a range inside an indexed file.
+type Location struct {
+	Path  string
+	Range pdx.Range
+}
+
+// Less orders locations by path and then position, so that lookups return
+// them in the same order however the files were indexed.
+func (l Location) Less(o Location) bool {
+	if l.Path != o.Path {
+		return l.Path < o.Path
+	}
+	return l.Range.Start.Before(o.Range.Start)
+}
+
+// Symbol is a place where a name of some kind is defined (or, for flags and
+// similar runtime names, set).
+type Symbol struct {
+	Kind Kind
+	Name string
+	Location
+}
+
+// Reference is a place where a name of some kind is used.
+type Reference struct {
+	Kind Kind
+	Name string
+	Location
+}
+
+// FileEntry is the indexed form of a single file. Exactly one of File (for
+// script) and Loc (for localization) is set.
+type FileEntry struct {
+	Path        string
+	VirtualPath string
+	// Role is what the file holds, going by its folder.
+	Role    FileRole
+	File    *pdx.File
+	Loc     *loc.File
+	Symbols []Symbol
+	Refs    []Reference
+}
+
+// Index is a concurrency-safe collection of indexed files with lookup
+// tables by kind and name.
+//
+// An index may have a base layer (the vanilla game files under a mod's
+// workspace). Lookups fall through to the base, except for base files whose
+// virtual path is overridden by a file in this index, mirroring how the
+// game replaces whole files.
+type Index struct {
+	mu     sync.RWMutex
+	files  map[string]*FileEntry
+	vpaths map[string]int
+	defs   map[Kind]map[string][]Symbol
+	refs   map[Kind]map[string][]Reference
+	base   *Index
+	calls  callCache
+	// roots are the folders of the mods and game files scanned into the
+	// index, slash separated, which VirtualPath makes paths relative to.
+	roots map[string]bool
+}
+
+// New returns an empty index.
+func New() *Index {
+	return &Index{
+		files:  make(map[string]*FileEntry),
+		vpaths: make(map[string]int),
+		defs:   make(map[Kind]map[string][]Symbol),
+		refs:   make(map[Kind]map[string][]Reference),
+		roots:  make(map[string]bool),
+	}
+}
+
+// SetBase makes base the layer below ix. A nil base removes the layer.
+func (ix *Index) SetBase(base *Index) {
+	defer ix.invalidateCalls("", true)
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.base = base
+}
+
+// Base returns the layer below ix, or nil.
+func (ix *Index) Base() *Index {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.base
+}
+
+// visibleLocked reports whether a base file at path is not overridden by a
+// file of ix. The caller must hold ix.mu.
+func (ix *Index) visibleLocked(path string) bool {
+	ix.base.mu.RLock()
+	defer ix.base.mu.RUnlock()
+	file := ix.base.files[path]
+	return file != nil && ix.vpaths[file.VirtualPath] == 0
+}
+
+// UpdateFile parses text as the contents of path and replaces any previous
+// entry for that file.
+func (ix *Index) UpdateFile(path, text string) *FileEntry {
+	vpath := ix.VirtualPath(path)
+	entry := &FileEntry{Path: path, VirtualPath: vpath, Role: roleOf(path, vpath)}
+	if entry.Role.Role == RoleLocalization {
+		entry.Loc = loc.Parse(path, text)
+		collectLocalization(entry)
+	} else {
+		entry.File = pdx.Parse(path, text)
+		collect(entry)
+	}
+
+	redefined := false
+	defer func() { ix.invalidateCalls(path, redefined) }()
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	redefined = callSignature(ix.files[path]) != callSignature(entry)
+	ix.removeLocked(path)
+	ix.files[path] = entry
+	ix.vpaths[entry.VirtualPath]++
+	for _, sym := range entry.Symbols {
+		if ix.defs[sym.Kind] == nil {
+			ix.defs[sym.Kind] = make(map[string][]Symbol)
+		}
+		ix.defs[sym.Kind][sym.Name] = append(ix.defs[sym.Kind][sym.Name], sym)
+	}
+	for _, ref := range entry.Refs {
+		if ix.refs[ref.Kind] == nil {
+			ix.refs[ref.Kind] = make(map[string][]Reference)
+		}
+		ix.refs[ref.Kind][ref.Name] = append(ix.refs[ref.Kind][ref.Name], ref)
+	}
+	return entry
+}
+
+// RemoveFile drops path from the index.
+func (ix *Index) RemoveFile(path string) {
+	redefined := false
+	defer func() { ix.invalidateCalls(path, redefined) }()
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	redefined = callSignature(ix.files[path]) != ""
+	ix.removeLocked(path)
+}
+
+func (ix *Index) removeLocked(path string) {
+	old, ok := ix.files[path]
+	if !ok {
+		return
+	}
+	delete(ix.files, path)
+	if ix.vpaths[old.VirtualPath]--; ix.vpaths[old.VirtualPath] <= 0 {
+		delete(ix.vpaths, old.VirtualPath)
+	}
+	for _, sym := range old.Symbols {
+		byName := ix.defs[sym.Kind]
+		byName[sym.Name] = removePath(byName[sym.Name], path)
+		if len(byName[sym.Name]) == 0 {
+			delete(byName, sym.Name)
+		}
+	}
+	for _, ref := range old.Refs {
+		byName := ix.refs[ref.Kind]
+		byName[ref.Name] = removePath(byName[ref.Name], path)
+		if len(byName[ref.Name]) == 0 {
+			delete(byName, ref.Name)
+		}
+	}
+}
+
+// File returns the entry for path, looking through to the base layer, or
+// nil if it is not indexed.
+func (ix *Index) File(path string) *FileEntry {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	if entry, ok := ix.files[path]; ok || ix.base == nil {
+		return entry
+	}
+	return ix.base.File(path)
+}
+
+// Paths returns the sorted paths of the files indexed in ix itself,
+// ignoring the base layer.
+func (ix *Index) Paths() []string {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	paths := make([]string, 0, len(ix.files))
+	for path := range ix.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// AllPaths returns the sorted paths of the files of ix and of the base
+// files they do not override.
+func (ix *Index) AllPaths() []string {
+	paths := ix.Paths()
+	if base := ix.Base(); base != nil {
+		ix.mu.RLock()
+		for _, path := range base.Paths() {
+			if ix.files[path] == nil && ix.visibleLocked(path) {
+				paths = append(paths, path)
+			}
+		}
+		ix.mu.RUnlock()
+		sort.Strings(paths)
+	}
+	return paths
+}
+
+// Overrides returns the sorted paths of the files of ix that replace a
+// base file with the same virtual path.
+func (ix *Index) Overrides() []string {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	if ix.base == nil {
+		return nil
+	}
+	ix.base.mu.RLock()
+	defer ix.base.mu.RUnlock()
+	var paths []string
+	for path, entry := range ix.files {
+		if ix.base.vpaths[entry.VirtualPath] > 0 {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// OverriddenFile returns the base file that the file of ix at path
+// replaces, having the same virtual path, or nil.
+func (ix *Index) OverriddenFile(path string) *FileEntry {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	entry := ix.files[path]
+	if ix.base == nil || entry == nil {
+		return nil
+	}
+	ix.base.mu.RLock()
+	defer ix.base.mu.RUnlock()
+	if ix.base.vpaths[entry.VirtualPath] == 0 {
+		return nil
+	}
+	for _, file := range ix.base.files {
+		if file.VirtualPath == entry.VirtualPath {
+			return file
+		}
+	}
+	return nil
+}
+
+// Definitions returns every symbol of the given kind and name, including
+// those of non-overridden base files after the ones of ix, each layer in
+// location order.
+func (ix *Index) Definitions(kind Kind, name string) []Symbol {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	syms := append([]Symbol(nil), ix.defs[kind][name]...)
+	sort.Slice(syms, func(i, j int) bool { return syms[i].Less(syms[j].Location) })
+	if ix.base != nil {
+		for _, sym := range ix.base.Definitions(kind, name) {
+			if ix.visibleLocked(sym.Path) {
+				syms = append(syms, sym)
+			}
+		}
+	}
+	return syms
+}
+
+// LocalDefinitions returns the symbols of the given kind and name defined in
+// ix itself, ignoring the base layer.
+func (ix *Index) LocalDefinitions(kind Kind, name string) []Symbol {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	syms := append([]Symbol(nil), ix.defs[kind][name]...)
+	sort.Slice(syms, func(i, j int) bool { return syms[i].Less(syms[j].Location) })
+	return syms
+}
+
+// References returns every reference to the given kind and name, including
+// those of non-overridden base files after the ones of ix, each layer in
+// location order.
+func (ix *Index) References(kind Kind, name string) []Reference {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	refs := append([]Reference(nil), ix.refs[kind][name]...)
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Less(refs[j].Location) })
+	if ix.base != nil {
+		for _, ref := range ix.base.References(kind, name) {
+			if ix.visibleLocked(ref.Path) {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// LocEntry is a localization entry together with its language.
+type LocEntry struct {
+	loc.Entry
+	Language string
+	Path     string
+}
+
+// Localizations returns every entry for a localization key, in all
+// languages.
+func (ix *Index) Localizations(key string) []LocEntry {
+	var entries []LocEntry
+	for _, sym := range ix.Definitions(KindLocalization, key) {
+		file := ix.File(sym.Path)
+		if file == nil || file.Loc == nil {
+			continue
+		}
+		if e, ok := file.Loc.Lookup(key); ok {
+			entries = append(entries, LocEntry{Entry: e, Language: file.Loc.Language, Path: sym.Path})
+		}
+	}
+	return entries
+}
+
+// Names returns the sorted names of all defined symbols of a kind.
+func (ix *Index) Names(kind Kind) []string {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	seen := make(map[string]bool, len(ix.defs[kind]))
+	for name := range ix.defs[kind] {
+		seen[name] = true
+	}
+	if ix.base != nil {
+		for _, name := range ix.base.Names(kind) {
+			if seen[name] {
+				continue
+			}
+			for _, sym := range ix.base.Definitions(kind, name) {
+				if ix.visibleLocked(sym.Path) {
+					seen[name] = true
+					break
+				}
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReferencedNames returns the sorted names referenced as a kind in ix
+// itself, ignoring the base layer.
+func (ix *Index) ReferencedNames(kind Kind) []string {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	names := make([]string, 0, len(ix.refs[kind]))
+	for name := range ix.refs[kind] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SymbolAt returns the kind and name of the symbol or reference of entry
+// whose range contains pos.
+func (entry *FileEntry) SymbolAt(pos pdx.Pos) (kind Kind, name string, rng pdx.Range, ok bool) {
+	for _, sym := range entry.Symbols {
+		if sym.Range.Contains(pos) {
+			return sym.Kind, sym.Name, sym.Range, true
+		}
+	}
+	for _, ref := range entry.Refs {
+		if ref.Range.Contains(pos) {
+			return ref.Kind, ref.Name, ref.Range, true
+		}
+	}
+	return "", "", pdx.Range{}, false
+}
+
+// ScanFolders indexes the given subfolders of root, skipping missing ones,
+// and returns how many files were read.
+func (ix *Index) ScanFolders(root string, folders []string) (int, error) {
+	ix.AddRoot(root)
+	total := 0
+	for _, folder := range folders {
+		dir := filepath.Join(root, filepath.FromSlash(folder))
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		n, err := ix.scanDir(dir)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// ScanDir indexes every script file below root, the root of a mod or of
+// a folder of mods, and returns how many files were read.
+func (ix *Index) ScanDir(root string) (int, error) {
+	ix.AddRoot(root)
+	return ix.scanDir(root)
+}
+
+// scanDir indexes every script file below dir and returns how many files
+// were read.
+func (ix *Index) scanDir(root string) (int, error) {
+	count := 0
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			log.Printf("Skipping unreadable path '%s': %v", path, err)
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !IsIndexable(path) {
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			// Links may lead out of the scanned folder.
+			log.Printf("Skipping symbolic link '%s'", path)
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("Failed to read '%s': %v", path, err)
+			return nil
+		}
+		ix.UpdateFile(path, string(data))
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// IsScriptFile reports whether path looks like a PDXScript file: a .txt
+// file other than the plain text ones RoleOf tells apart.
+func IsScriptFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".txt") && RoleOf(path).Role != RoleText
+}
+
+// IsGUIFile reports whether path is a .gui interface file.
+func IsGUIFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".gui")
+}
+
+// IsLocalizationFile reports whether path looks like a localization file:
+// a .yml file in localization/, or named like one outside the game folders.
+func IsLocalizationFile(path string) bool {
+	return RoleOf(path).Role == RoleLocalization
+}
+
+// IsDescriptorFile reports whether path is a mod descriptor (.mod), which
+// uses script syntax.
+func IsDescriptorFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".mod")
+}
+
+// IsIndexable reports whether UpdateFile understands the file at path.
+func IsIndexable(path string) bool {
+	return IsScriptFile(path) || IsGUIFile(path) || IsLocalizationFile(path) || IsDescriptorFile(path)
+}
+
+// topLevelFolders are the folders of the game's virtual filesystem that a
+// mod (or the game itself) is laid out in.
+var topLevelFolders = map[string]bool{
+	"common": true, "events": true, "gfx": true, "gui": true, "history": true,
+	"localization": true, "map_data": true, "music": true, "sound": true,
+	"content_source": true, "data_binding": true, "fonts": true, "notifications": true,
+	"tests": true, "tools": true, "dlc_metadata": true,
+}
+
+// IsGameFolder reports whether name is a top-level folder the game loads.
+func IsGameFolder(name string) bool {
+	return topLevelFolders[name]
+}
+
+// AddRoot records dir as the root of a mod or of the game files, the
+// folder holding their common, events and other top-level folders, for
+// the VirtualPath of ix and of the indexes it is the base of.
+func (ix *Index) AddRoot(dir string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.roots[strings.TrimSuffix(filepath.ToSlash(filepath.Clean(dir)), "/")] = true
+}
+
+// VirtualPath returns the virtual path of a file like the package-level
+// VirtualPath, except that under a root recorded with AddRoot in ix or its
+// base, the path is taken relative to the deepest one whose next folder is
+// a top-level game folder.
+func (ix *Index) VirtualPath(path string) string {
+	slashed := filepath.ToSlash(path)
+	best, found := "", false
+	for layer := ix; layer != nil; layer = layer.Base() {
+		if rel, ok := layer.rootRelative(slashed); ok && (!found || len(rel) < len(best)) {
+			best, found = rel, true
+		}
+	}
+	if found {
+		return best
+	}
+	return VirtualPath(path)
+}
+
+// RoleOf returns the role of the file at path, going by its VirtualPath in
+// ix.
+func (ix *Index) RoleOf(path string) FileRole {
+	return roleOf(path, ix.VirtualPath(path))
+}
+
+// VirtualPath returns the slash-separated path of a file relative to the
+// root of the mod or game it belongs to, e.g. "common/decisions/x.txt".
+// The root is found as the last top-level game folder in the path, so that
+// the folders of an installation such as steamapps/common are not taken
+// for it, except that the language subfolders of localization, such as
+// localization/english/events, are kept below it. Files outside one
+// return their base name.
+func VirtualPath(path string) string {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	root := -1
+	for i := len(parts) - 2; i >= 0; i-- {
+		if !topLevelFolders[parts[i]] {
+			continue
+		}
+		if root < 0 {
+			root = i
+		}
+		if parts[i] == "localization" {
+			root = i
+			break
+		}
+	}
+	if root < 0 {
+		return parts[len(parts)-1]
+	}
+	return strings.Join(parts[root:], "/")
+}
+
+// rootRelative returns the slash-separated path relative to the deepest
+// root of ix holding it in one of its top-level game folders.
+func (ix *Index) rootRelative(slashed string) (string, bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	best, found := "", false
+	for root := range ix.roots {
+		rel, ok := strings.CutPrefix(slashed, root+"/")
+		if !ok || found && len(rel) >= len(best) {
+			continue
+		}
+		if top, _, ok := strings.Cut(rel, "/"); ok && topLevelFolders[top] {
+			best, found = rel, true
+		}
+	}
+	return best, found
+}
+
+func removePath[T interface{ path() string }](items []T, path string) []T {
+	kept := items[:0]
+	for _, item := range items {
+		if item.path() != path {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+func (s Symbol) path() string    { return s.Path }
+func (r Reference) path() string { return r.Path }
@@ -0,0 +1,130 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdateFile(t *testing.T) {
+	ix := New()
+	entry := ix.UpdateFile("/mod/events/x.txt", "a = {\n")
+	if ix.File("/mod/events/x.txt") != entry || len(entry.File.Errors) != 1 {
+		t.Fatalf("entry = %+v, want the parsed file with its syntax error", entry)
+	}
+	fixed := ix.UpdateFile("/mod/events/x.txt", "a = { }\n")
+	if ix.File("/mod/events/x.txt") != fixed || len(fixed.File.Errors) != 0 {
+		t.Errorf("entry = %+v, want the new contents", ix.File("/mod/events/x.txt"))
+	}
+	ix.RemoveFile("/mod/events/x.txt")
+	if ix.File("/mod/events/x.txt") != nil {
+		t.Error("the removed file is still indexed")
+	}
+}
+
+func TestScanDir(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]bool{
+		"events/a.txt":         true,
+		"common/b/c.TXT":       true,
+		"readme.md":            false,
+		".git/hooks/d.txt":     false,
+		"events/.hidden/e.txt": false,
+	}
+	for name := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("a = b\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ix := New()
+	count, err := ix.ScanDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want 2", count)
+	}
+	for name, indexed := range files {
+		if got := ix.File(filepath.Join(root, filepath.FromSlash(name))) != nil; got != indexed {
+			t.Errorf("%s indexed = %v, want %v", name, got, indexed)
+		}
+	}
+}
+
+func TestVirtualPath(t *testing.T) {
+	tests := []struct {
+		name, path, want string
+	}{
+		{"mod", "/home/me/mods/my_mod/common/decisions/x.txt", "common/decisions/x.txt"},
+		{"steam game", "/home/me/.steam/steam/steamapps/common/Crusader Kings III/game/events/x.txt", "events/x.txt"},
+		{"steam game common", "/home/me/.steam/steam/steamapps/common/Crusader Kings III/game/common/scripted_effects/00_x.txt", "common/scripted_effects/00_x.txt"},
+		{"steam windows", "C:/Program Files (x86)/Steam/steamapps/common/Crusader Kings III/game/common/decisions/x.txt", "common/decisions/x.txt"},
+		{"steam localization", "/steamapps/common/Crusader Kings III/game/localization/english/events/x_l_english.yml", "localization/english/events/x_l_english.yml"},
+		{"localization subfolder", "/mods/my_mod/localization/english/events/x_l_english.yml", "localization/english/events/x_l_english.yml"},
+		{"mod folder named like a game folder", "/home/me/tools/my_mod/events/x.txt", "events/x.txt"},
+		{"outside a mod", "/home/me/notes/readme.txt", "readme.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VirtualPath(tt.path); got != tt.want {
+				t.Errorf("VirtualPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVirtualPathUnderRoot(t *testing.T) {
+	root := "/home/me/common/mods/my_mod"
+	ix := New()
+	ix.AddRoot(root)
+	tests := []struct {
+		path, want string
+	}{
+		{root + "/common/decisions/x.txt", "common/decisions/x.txt"},
+		{root + "/localization/english/events/x_l_english.yml", "localization/english/events/x_l_english.yml"},
+		// A file outside the top-level folders of the root falls back to
+		// the folders of its path.
+		{root + "/extra/events/x.txt", "events/x.txt"},
+	}
+	for _, tt := range tests {
+		if got := ix.VirtualPath(tt.path); got != tt.want {
+			t.Errorf("VirtualPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+	entry := ix.UpdateFile(root+"/common/decisions/x.txt", "x = { }\n")
+	if entry.VirtualPath != "common/decisions/x.txt" || entry.Role.Folder != "common/decisions" {
+		t.Errorf("indexed entry has virtual path %q and role %+v", entry.VirtualPath, entry.Role)
+	}
+}
+
+// TestVirtualPathRootsPerIndex checks that the roots of an index apply to
+// it and the indexes it is the base of, and not to other indexes, such as
+// the workspaces of other clients.
+func TestVirtualPathRootsPerIndex(t *testing.T) {
+	game := "/games/steamapps/common/Crusader Kings III/game"
+	vanilla := New()
+	vanilla.AddRoot(game)
+	mine, theirs := New(), New()
+	mine.SetBase(vanilla)
+	theirs.SetBase(vanilla)
+	mine.AddRoot("/home/me/my_mod")
+
+	// A subfolder named like a top-level folder needs the root.
+	path := "/home/me/my_mod/common/scripted_effects/events/x.txt"
+	if got := mine.VirtualPath(path); got != "common/scripted_effects/events/x.txt" {
+		t.Errorf("VirtualPath in the mod's index = %q, want common/scripted_effects/events/x.txt", got)
+	}
+	if got := theirs.VirtualPath(path); got != "events/x.txt" {
+		t.Errorf("VirtualPath in another index = %q, want the one found by its folders", got)
+	}
+	for _, ix := range []*Index{vanilla, mine, theirs} {
+		if got := ix.VirtualPath(game + "/common/traits/00_traits.txt"); got != "common/traits/00_traits.txt" {
+			t.Errorf("VirtualPath of a game file = %q, want common/traits/00_traits.txt", got)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package index
+
+import (
+	"strings"
+
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// Kinds of named levels of threshold tables.
+const (
+	KindLegitimacyLevel Kind = "legitimacy_level"
+	KindHouseUnityStage Kind = "house_unity_stage"
+)
+
+// levelFolders maps folders whose definitions contain threshold tables to
+// the kind of their levels. A level is a named block with a `threshold`
+// entry anywhere inside a top-level definition, e.g.
+//
+//	legitimacy = {
+//		levels = {
+//			illegitimate = { threshold = 0 }
+//			legitimate = { threshold = 50 }
+//		}
+//	}
+var levelFolders = map[string]Kind{
+	"common/legitimacy_types/": KindLegitimacyLevel,
+	"common/house_unities/":    KindHouseUnityStage,
+}
+
+// levelKinds are the kinds in levelFolders.
+var levelKinds = func() map[Kind]bool {
+	kinds := make(map[Kind]bool, len(levelFolders))
+	for _, kind := range levelFolders {
+		kinds[kind] = true
+	}
+	return kinds
+}()
+
+// LevelKind returns the kind of levels defined by files at vpath.
+func LevelKind(vpath string) (Kind, bool) {
+	for prefix, kind := range levelFolders {
+		if strings.HasPrefix(vpath, prefix) {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+// Levels returns the levels of a threshold table definition in source order.
+func Levels(def *pdx.Field) []*pdx.Field {
+	var levels []*pdx.Field
+	pdx.Walk(def.Block(), func(f *pdx.Field) bool {
+		if f.Key != nil && f.Block() != nil && f.Block().Get("threshold") != nil {
+			levels = append(levels, f)
+			return false
+		}
+		return true
+	})
+	return levels
+}
+
+// collectLevels records the levels of threshold table definitions.
+func collectLevels(entry *FileEntry) {
+	kind, ok := LevelKind(entry.VirtualPath)
+	if !ok {
+		return
+	}
+	for _, def := range entry.File.Root.Fields {
+		if def.Block() == nil {
+			continue
+		}
+		for _, level := range Levels(def) {
+			entry.Symbols = append(entry.Symbols, Symbol{Kind: kind, Name: level.Key.Text, Location: Location{Path: entry.Path, Range: level.Key.Loc}})
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package index
+
+import (
+	"strings"
+
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// Kinds of scripted modifiers and the game's scripted rule hooks.
+const (
+	KindScriptedModifier Kind = "scripted_modifier"
+	KindScriptedRule     Kind = "scripted_rule"
+)
+
+// weightBlocks are the keys of blocks evaluated as a weight or chance,
+// where scripted modifiers may be used.
+var weightBlocks = map[string]bool{
+	"ai_chance": true, "ai_will_do": true, "weight": true, "weight_multiplier": true,
+	"ai_accept": true, "chance": true,
+}
+
+// weightKeys are the built-in entries of a weight block. Any other key set
+// to yes or a block is a scripted modifier.
+var weightKeys = map[string]bool{
+	"base": true, "add": true, "subtract": true, "factor": true, "multiply": true,
+	"divide": true, "min": true, "max": true, "value": true, "modifier": true,
+	"opinion_modifier": true, "compare_modifier": true, "ai_value_modifier": true,
+	"compatibility_modifier": true, "if": true, "else_if": true, "else": true,
+	"desc": true, "format": true, "round": true, "floor": true, "ceiling": true,
+	"fixed_range": true, "integer_range": true, "limit": true,
+}
+
+// InWeightBlock reports whether f sits directly in a weight block, looking
+// through if/else_if/else wrappers. The top-level definitions of
+// common/scripted_modifiers are weight blocks too.
+func InWeightBlock(entry *FileEntry, f *pdx.Field) bool {
+	parent := f.ParentField()
+	for parent != nil && (parent.KeyText() == "if" || parent.KeyText() == "else_if" || parent.KeyText() == "else") {
+		parent = parent.ParentField()
+	}
+	if parent == nil {
+		return false
+	}
+	if parent.ParentField() == nil && strings.HasPrefix(entry.VirtualPath, "common/scripted_modifiers/") {
+		return true
+	}
+	return weightBlocks[parent.KeyText()]
+}
+
+// collectScriptedModifierRef records `name = yes` and `name = { PARAM =
+// value }` entries of weight blocks as scripted modifier references.
+func collectScriptedModifierRef(entry *FileEntry, f *pdx.Field) {
+	if f.Key == nil || weightKeys[f.Key.Text] || !IsStaticName(f.Key.Text) {
+		return
+	}
+	if f.ValueText() != "yes" && f.Block() == nil {
+		return
+	}
+	if InWeightBlock(entry, f) {
+		entry.Refs = append(entry.Refs, Reference{Kind: KindScriptedModifier, Name: f.Key.Text, Location: Location{Path: entry.Path, Range: f.Key.Loc}})
+	}
+}
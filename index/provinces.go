@@ -0,0 +1,203 @@
+package index
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// Kinds of province history objects.
+const (
+	KindBuilding Kind = "building"
+	KindHolding  Kind = "holding"
+	KindCulture  Kind = "culture"
+	KindFaith    Kind = "faith"
+	KindTerrain  Kind = "terrain"
+	// KindProvinceTerrain symbols are the `id = terrain` entries of
+	// common/province_terrain, named by province ID.
+	KindProvinceTerrain Kind = "province_terrain"
+	// KindBaronyProvince symbols are the `province = id` entries of the
+	// baronies of common/landed_titles, named by province ID.
+	KindBaronyProvince Kind = "barony_province"
+)
+
+// collectFaiths records the faiths nested in the religions of
+// common/religion/religions.
+func collectFaiths(entry *FileEntry) {
+	if !strings.HasPrefix(entry.VirtualPath, "common/religion/religions/") {
+		return
+	}
+	for _, religion := range entry.File.Root.Fields {
+		if religion.Block() == nil {
+			continue
+		}
+		faiths := religion.Block().Get("faiths")
+		if faiths == nil || faiths.Block() == nil {
+			continue
+		}
+		for _, faith := range faiths.Block().Fields {
+			if faith.Key != nil && faith.Block() != nil {
+				entry.Symbols = append(entry.Symbols, Symbol{Kind: KindFaith, Name: faith.Key.Text, Location: Location{Path: entry.Path, Range: faith.Key.Loc}})
+			}
+		}
+	}
+}
+
+// collectProvinceTerrain records the terrain of each province listed in
+// common/province_terrain, and the terrain types it assigns (including the
+// default_land and similar fallbacks) as references.
+func collectProvinceTerrain(entry *FileEntry) {
+	if !strings.HasPrefix(entry.VirtualPath, "common/province_terrain/") {
+		return
+	}
+	for _, f := range entry.File.Root.Fields {
+		s := f.Scalar()
+		if f.Key == nil || s == nil {
+			continue
+		}
+		if IsProvinceID(f.Key.Text) {
+			entry.Symbols = append(entry.Symbols, Symbol{Kind: KindProvinceTerrain, Name: f.Key.Text, Location: Location{Path: entry.Path, Range: f.Key.Loc}})
+		} else if !strings.HasPrefix(f.Key.Text, "default") {
+			continue
+		}
+		entry.Refs = append(entry.Refs, Reference{Kind: KindTerrain, Name: s.Text, Location: Location{Path: entry.Path, Range: s.Loc}})
+	}
+}
+
+// IsProvinceID reports whether s is a numeric province ID.
+func IsProvinceID(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ProvinceTerrain returns the terrain of a province as set in
+// common/province_terrain, or "" if it is not listed.
+func (ix *Index) ProvinceTerrain(id string) string {
+	for _, sym := range ix.Definitions(KindProvinceTerrain, id) {
+		if f := ix.FieldAt(sym.Location); f != nil {
+			return f.ValueText()
+		}
+	}
+	return ""
+}
+
+// ProvinceBarony returns the barony holding a province, or "" if no
+// barony of common/landed_titles lists it.
+func (ix *Index) ProvinceBarony(id string) string {
+	for _, sym := range ix.Definitions(KindBaronyProvince, id) {
+		if f := ix.FieldAt(sym.Location); f != nil {
+			if title := f.ParentField(); title != nil {
+				return title.KeyText()
+			}
+		}
+	}
+	return ""
+}
+
+// TitleFields returns the field defining a landed title followed by those
+// of the titles it is nested in, from the title up to its empire, or nil
+// if the title is not defined.
+func (ix *Index) TitleFields(title string) []*pdx.Field {
+	defs := ix.Definitions(KindTitle, title)
+	if len(defs) == 0 {
+		return nil
+	}
+	var fields []*pdx.Field
+	for f := ix.FieldAt(defs[0].Location); f != nil && IsTitleKey(f.KeyText()); f = f.ParentField() {
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// ProvinceDefinition is a line of map_data/definition.csv: a province ID,
+// the color it has on provinces.png and its internal name.
+type ProvinceDefinition struct {
+	ID    int
+	Color [3]int
+	Name  string
+}
+
+// ReadProvinceDefinitions reads a definition.csv file, whose lines are
+// `id;red;green;blue;name;x;`, by province ID. The first line, the ocean
+// placeholder 0, and malformed lines are skipped.
+func ReadProvinceDefinitions(path string) (map[int]ProvinceDefinition, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	defs := make(map[int]ProvinceDefinition)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.Split(strings.TrimPrefix(scanner.Text(), "\ufeff"), ";")
+		if len(parts) < 5 {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil || id <= 0 {
+			continue
+		}
+		def := ProvinceDefinition{ID: id, Name: strings.TrimSpace(parts[4])}
+		for i := range def.Color {
+			def.Color[i], _ = strconv.Atoi(strings.TrimSpace(parts[i+1]))
+		}
+		defs[id] = def
+	}
+	return defs, scanner.Err()
+}
+
+// FieldAt returns the innermost field of an indexed script file that
+// contains loc, typically the field a symbol was collected from.
+func (ix *Index) FieldAt(loc Location) *pdx.Field {
+	entry := ix.File(loc.Path)
+	if entry == nil || entry.File == nil {
+		return nil
+	}
+	path := entry.File.PathAt(loc.Range.Start)
+	if len(path) == 0 {
+		return nil
+	}
+	return path[len(path)-1]
+}
+
+// collectHistoryRefs records the cultures, faiths, holdings and buildings
+// used by province and character history.
+func collectHistoryRefs(entry *FileEntry, f *pdx.Field) {
+	provinces := strings.HasPrefix(entry.VirtualPath, "history/provinces/")
+	if !provinces && !strings.HasPrefix(entry.VirtualPath, "history/characters/") {
+		return
+	}
+	ref := func(kind Kind, s *pdx.Scalar) {
+		if IsStaticName(s.Text) {
+			entry.Refs = append(entry.Refs, Reference{Kind: kind, Name: s.Text, Location: Location{Path: entry.Path, Range: s.Loc}})
+		}
+	}
+	s := f.Scalar()
+	switch key := f.KeyText(); {
+	case key == "culture" && s != nil:
+		ref(KindCulture, s)
+	case (key == "religion" || key == "faith") && s != nil:
+		ref(KindFaith, s)
+	case !provinces:
+	case key == "holding" && s != nil && s.Text != "none" && s.Text != "auto":
+		ref(KindHolding, s)
+	case (key == "special_building" || key == "special_building_slot" || key == "duchy_capital_building") && s != nil:
+		ref(KindBuilding, s)
+	case key == "buildings" && f.Block() != nil:
+		for _, b := range f.Block().Fields {
+			if s := b.Scalar(); b.Key == nil && s != nil {
+				ref(KindBuilding, s)
+			}
+		}
+	}
+}
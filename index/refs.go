@@ -0,0 +1,115 @@
+package index
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// Kinds of the administrative government content added in Roads to Power.
+const (
+	KindDomicileType     Kind = "domicile_type"
+	KindDomicileBuilding Kind = "domicile_building"
+	KindHouseUnity       Kind = "house_unity"
+	KindAppointment      Kind = "succession_appointment"
+	KindTaxSlot          Kind = "tax_slot"
+)
+
+// refKeys maps effect, trigger and property keys whose value names an object
+// to the kind of that object, e.g. `has_domicile_building = x`.
+var refKeys = map[string]Kind{
+	"has_domicile_building":           KindDomicileBuilding,
+	"has_domicile_building_or_higher": KindDomicileBuilding,
+	"add_domicile_building":           KindDomicileBuilding,
+	"remove_domicile_building":        KindDomicileBuilding,
+	"start_domicile_building":         KindDomicileBuilding,
+	"is_domicile_type":                KindDomicileType,
+	"has_house_unity_stage":           KindHouseUnityStage,
+	"legitimacy_level":                KindLegitimacyLevel,
+	"geographical_region":             KindRegion,
+	"terrain":                         KindTerrain,
+	"has_building":                    KindBuilding,
+	"has_building_or_higher":          KindBuilding,
+	"add_building":                    KindBuilding,
+	"remove_building":                 KindBuilding,
+	"trigger_event":                   KindEvent,
+}
+
+// folderRefKeys are like refKeys for keys whose meaning depends on the
+// folder, such as the upgrade chain `next_building`.
+var folderRefKeys = map[string]map[string]Kind{
+	"common/buildings/":          {"next_building": KindBuilding},
+	"history/characters/":        {"dynasty": KindDynasty, "dynasty_house": KindHouse},
+	"common/domicile_buildings/": {"next_building": KindDomicileBuilding, "previous_building": KindDomicileBuilding, "domicile_type": KindDomicileType},
+	"common/on_action/":          {"fallback": KindOnAction},
+}
+
+// folderListKeys maps, per folder, keys of blocks that list object names
+// as bare values to the kind of those objects.
+var folderListKeys = map[string]map[string]Kind{
+	"map_data/geographical_regions/": {
+		"regions": KindRegion, "duchies": KindTitle, "counties": KindTitle,
+		"kingdoms": KindTitle, "empires": KindTitle,
+	},
+	"common/on_action/": {"events": KindEvent, "first_valid": KindEvent, "on_actions": KindOnAction},
+}
+
+// ListRefKind returns the kind of objects listed as bare values in a block
+// with key list, in a file with the given virtual path.
+func ListRefKind(vpath, list string) (Kind, bool) {
+	for prefix, keys := range folderListKeys {
+		if kind, ok := keys[list]; ok && strings.HasPrefix(vpath, prefix) {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+// blockRefKeys are like refKeys for keys whose meaning depends on the
+// enclosing block, such as the `type` of create_epidemic_outbreak.
+var blockRefKeys = map[string]map[string]Kind{
+	"create_epidemic_outbreak": {"type": KindEpidemic},
+	"create_accolade":          {"primary": KindAccoladeType, "secondary": KindAccoladeType, "name": KindAccoladeName},
+	"trigger_event":            {"id": KindEvent, "on_action": KindOnAction},
+}
+
+// RefKind returns the kind of object named by the value of key, directly
+// inside a block with key parent, in a file with the given virtual path.
+func RefKind(vpath, parent, key string) (Kind, bool) {
+	if kind, ok := blockRefKeys[parent][key]; ok {
+		return kind, true
+	}
+	for prefix, keys := range folderRefKeys {
+		if kind, ok := keys[key]; ok && strings.HasPrefix(vpath, prefix) {
+			return kind, true
+		}
+	}
+	kind, ok := refKeys[key]
+	return kind, ok
+}
+
+// collectKeyRef records the value of a key listed in refKeys, folderRefKeys
+// or blockRefKeys, and the bare values of a block in folderListKeys, as
+// references. Numbers are skipped for levels, since keys like
+// legitimacy_level also compare against a level's index.
+func collectKeyRef(entry *FileEntry, f *pdx.Field) {
+	s := f.Scalar()
+	if s == nil || !IsStaticName(s.Text) || strings.Contains(s.Text, ":") {
+		return
+	}
+	if f.Key == nil {
+		if kind, ok := ListRefKind(entry.VirtualPath, f.ParentField().KeyText()); ok {
+			entry.Refs = append(entry.Refs, Reference{Kind: kind, Name: s.Text, Location: Location{Path: entry.Path, Range: s.Loc}})
+		}
+		return
+	}
+	if kind, ok := RefKind(entry.VirtualPath, f.ParentField().KeyText(), f.KeyText()); ok && !(levelKinds[kind] && isNumber(s.Text)) {
+		entry.Refs = append(entry.Refs, Reference{Kind: kind, Name: s.Text, Location: Location{Path: entry.Path, Range: s.Loc}})
+	}
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
@@ -0,0 +1,118 @@
+package index
+
+import (
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Role is what a file holds. The extension alone does not decide it: a
+// .txt file holds events in events/, a database of objects in common/ and
+// history in history/, while a changelog.txt next to descriptor.mod is
+// plain text, and so is a .yml file outside localization/.
+type Role string
+
+// Roles of files.
+const (
+	RoleEvents       Role = "events"
+	RoleDatabase     Role = "database"
+	RoleHistory      Role = "history"
+	RoleMap          Role = "map"
+	RoleTests        Role = "tests"
+	RoleScript       Role = "script"
+	RoleGUI          Role = "gui"
+	RoleLocalization Role = "localization"
+	RoleDescriptor   Role = "descriptor"
+	RoleText         Role = "text"
+)
+
+// Language identifiers of the roles, for clients choosing the language
+// mode of a document.
+const (
+	LanguageScript       = "pdxscript"
+	LanguageGUI          = "pdxgui"
+	LanguageLocalization = "pdxlocalization"
+	LanguageText         = "plaintext"
+)
+
+// FileRole describes what a file holds, going by its folder and extension.
+type FileRole struct {
+	Role Role `json:"role"`
+	// Folder is the folder whose kind of content the file holds, such as
+	// "common/decisions", "history/titles" or "events", or "" outside the
+	// game folders.
+	Folder string `json:"folder,omitempty"`
+	// LanguageID is the language mode of the file.
+	LanguageID string `json:"languageId"`
+}
+
+// folderRoles are the roles of the script files of the top-level folders;
+// script in any other game folder, such as music/ or gfx/portraits/, is
+// RoleScript.
+var folderRoles = map[string]Role{
+	"events":   RoleEvents,
+	"common":   RoleDatabase,
+	"history":  RoleHistory,
+	"map_data": RoleMap,
+	"tests":    RoleTests,
+}
+
+// plainTextNames are the base names, without extension, of the text files
+// mods ship besides their script.
+var plainTextNames = map[string]bool{
+	"readme": true, "changelog": true, "changes": true, "license": true, "licence": true,
+	"credits": true, "notes": true, "todo": true, "copying": true, "authors": true,
+}
+
+// localizationName matches the names the game requires of localization
+// files, such as "my_events_l_english.yml".
+var localizationName = regexp.MustCompile(`(?i)_l_[a-z_]+\.yml$`)
+
+// RoleOf returns the role of the file at path. Files that are neither
+// script, GUI, localization nor plain text, such as images, have no role.
+func RoleOf(filePath string) FileRole {
+	return roleOf(filePath, VirtualPath(filePath))
+}
+
+// roleOf returns the role of the file at filePath with the virtual path
+// vpath.
+func roleOf(filePath, vpath string) FileRole {
+	top, _, inGame := strings.Cut(vpath, "/")
+	folder := ""
+	if inGame {
+		folder = path.Dir(vpath)
+	}
+	ext := strings.ToLower(filepath.Ext(vpath))
+	base := strings.ToLower(strings.TrimSuffix(path.Base(vpath), path.Ext(vpath)))
+	text := FileRole{RoleText, folder, LanguageText}
+	switch ext {
+	case ".gui":
+		return FileRole{RoleGUI, folder, LanguageGUI}
+	case ".mod":
+		return FileRole{RoleDescriptor, folder, LanguageScript}
+	case ".yml":
+		// Outside a mod's folders, such as when opened on their own,
+		// localization files are told by their names.
+		if top == "localization" || !inGame && localizationName.MatchString(vpath) {
+			return FileRole{RoleLocalization, folder, LanguageLocalization}
+		}
+		return text
+	case ".txt":
+		if inGame && top == "localization" || !inGame && plainTextNames[base] {
+			return text
+		}
+		role, ok := folderRoles[top]
+		switch {
+		case !inGame || !ok:
+			role = RoleScript
+		case role == RoleEvents:
+			// Events may be sorted into subfolders of events/.
+			folder = "events"
+		}
+		return FileRole{role, folder, LanguageScript}
+	case ".md":
+		return text
+	}
+	return FileRole{Folder: folder}
+}
@@ -0,0 +1,134 @@
+package index
+
+import (
+	"strings"
+
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// Kinds of events and of the reusable script blocks they call.
+const (
+	KindEvent           Kind = "event"
+	KindOnAction        Kind = "on_action"
+	KindScriptedEffect  Kind = "scripted_effect"
+	KindScriptedTrigger Kind = "scripted_trigger"
+	KindScriptValue     Kind = "script_value"
+)
+
+// CallKinds are the kinds of definitions used by writing their name as a
+// key (`my_effect = yes`) or, for script values, as a value.
+var CallKinds = []Kind{KindScriptedEffect, KindScriptedTrigger, KindScriptValue}
+
+// IsEventID reports whether name looks like an event ID, "namespace.0001".
+func IsEventID(name string) bool {
+	ns, id, ok := strings.Cut(name, ".")
+	return ok && ns != "" && id != "" && !strings.Contains(name, ":")
+}
+
+// collectEvents records the events defined in the events folder.
+func collectEvents(entry *FileEntry) {
+	if !strings.HasPrefix(entry.VirtualPath, "events/") {
+		return
+	}
+	for _, f := range entry.File.Root.Fields {
+		if f.Key != nil && f.Block() != nil && IsEventID(f.Key.Text) && IsStaticName(f.Key.Text) {
+			entry.Symbols = append(entry.Symbols, Symbol{Kind: KindEvent, Name: f.Key.Text, Location: Location{Path: entry.Path, Range: f.Key.Loc}})
+		}
+	}
+}
+
+// collectRandomEvents records the events of an on_action's
+// `random_events = { 100 = event.1 }`, whose keys are weights.
+func collectRandomEvents(entry *FileEntry, f *pdx.Field) {
+	if f.ParentField().KeyText() != "random_events" || !strings.HasPrefix(entry.VirtualPath, "common/on_action/") {
+		return
+	}
+	if s := f.Scalar(); s != nil && f.Key != nil && IsEventID(s.Text) {
+		entry.Refs = append(entry.Refs, Reference{Kind: KindEvent, Name: s.Text, Location: Location{Path: entry.Path, Range: s.Loc}})
+	}
+}
+
+// callDefinitionFolders are the folders defining the CallKinds, whose
+// top-level keys are definitions rather than calls.
+var callDefinitionFolders = []string{"common/scripted_effects/", "common/scripted_triggers/", "common/script_values/"}
+
+// ScriptCalls returns the uses of scripted effects, scripted triggers and
+// script values in entry. Since these are written like built-in effects,
+// triggers and numbers, they are not indexed as references but resolved
+// against the current definitions on demand.
+func (ix *Index) ScriptCalls(entry *FileEntry) []Reference {
+	if entry == nil || entry.File == nil || IsGUIFile(entry.Path) {
+		return nil
+	}
+	skipTop := false
+	for _, prefix := range callDefinitionFolders {
+		skipTop = skipTop || strings.HasPrefix(entry.VirtualPath, prefix)
+	}
+	var calls []Reference
+	pdx.Walk(entry.File.Root, func(f *pdx.Field) bool {
+		if skipTop && f.Parent == entry.File.Root {
+			return true
+		}
+		if kind, name, rng, ok := ix.CallAt(f); ok {
+			calls = append(calls, Reference{Kind: kind, Name: name, Location: Location{Path: entry.Path, Range: rng}})
+		}
+		return true
+	})
+	return calls
+}
+
+// CallAt resolves the field f as a use of a scripted effect or trigger (by
+// its key) or of a script value (by its value).
+func (ix *Index) CallAt(f *pdx.Field) (Kind, string, pdx.Range, bool) {
+	if f.Key != nil && IsStaticName(f.Key.Text) && !strings.ContainsAny(f.Key.Text, ":.") {
+		for _, kind := range []Kind{KindScriptedEffect, KindScriptedTrigger} {
+			if len(ix.Definitions(kind, f.Key.Text)) > 0 {
+				return kind, f.Key.Text, f.Key.Loc, true
+			}
+		}
+	}
+	if s := f.Scalar(); s != nil && !s.Quoted && IsStaticName(s.Text) && !isNumber(s.Text) && !strings.Contains(s.Text, ":") {
+		if len(ix.Definitions(KindScriptValue, s.Text)) > 0 {
+			return KindScriptValue, s.Text, s.Loc, true
+		}
+	}
+	return "", "", pdx.Range{}, false
+}
+
+// Container returns the top-level definition of the file at loc that
+// encloses it, such as the event a trigger_event is written in.
+func (ix *Index) Container(loc Location) (Symbol, bool) {
+	entry := ix.File(loc.Path)
+	if entry == nil || entry.File == nil {
+		return Symbol{}, false
+	}
+	path := entry.File.PathAt(loc.Range.Start)
+	if len(path) == 0 || path[0].Key == nil {
+		return Symbol{}, false
+	}
+	for _, sym := range entry.Symbols {
+		if sym.Range == path[0].Key.Loc {
+			return sym, true
+		}
+	}
+	return Symbol{}, false
+}
+
+// Uses returns the references to a symbol, including, for the CallKinds,
+// the calls of every file, kept in a cache that edits invalidate.
+func (ix *Index) Uses(kind Kind, name string) []Reference {
+	refs := ix.References(kind, name)
+	for _, k := range CallKinds {
+		if k != kind {
+			continue
+		}
+		for _, path := range ix.AllPaths() {
+			for _, call := range ix.fileCalls(path) {
+				if call.Kind == kind && call.Name == name {
+					refs = append(refs, call)
+				}
+			}
+		}
+	}
+	return refs
+}
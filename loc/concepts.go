@@ -0,0 +1,41 @@
+package loc
+
+import (
+	"regexp"
+
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// ConceptRef is a game concept used by a localization text, either as
+// [Concept('key','Text')] or as a bare [key] / [key|E]. Start and End are
+// the byte offsets of the key within the text.
+type ConceptRef struct {
+	Name       string
+	Start, End int
+}
+
+var (
+	conceptCallPattern = regexp.MustCompile(`Concept\(\s*'([^']+)'`)
+	conceptBarePattern = regexp.MustCompile(`\[\s*([a-z][a-z0-9_]*)\s*(?:\|[^\]]*)?\]`)
+)
+
+// ConceptRefs returns the game concepts referenced by a localization text.
+func ConceptRefs(text string) []ConceptRef {
+	var refs []ConceptRef
+	for _, pattern := range []*regexp.Regexp{conceptCallPattern, conceptBarePattern} {
+		for _, m := range pattern.FindAllStringSubmatchIndex(text, -1) {
+			refs = append(refs, ConceptRef{Name: text[m[2]:m[3]], Start: m[2], End: m[3]})
+		}
+	}
+	return refs
+}
+
+// TextSpan returns the source range of the bytes [start, end) of e.Text.
+func (e Entry) TextSpan(start, end int) pdx.Range {
+	base := e.ValueRange.Start
+	base.Col++ // opening quote
+	return pdx.Range{
+		Start: pdx.Pos{Line: base.Line, Col: base.Col + utf16Len(e.Text[:start])},
+		End:   pdx.Pos{Line: base.Line, Col: base.Col + utf16Len(e.Text[:end])},
+	}
+}
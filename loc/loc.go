@@ -0,0 +1,125 @@
+// Package loc parses Paradox localization files (`localization/**/*.yml`).
+//
+// A localization file starts with a language header such as `l_english:`
+// followed by one entry per line: `key:0 "Text"`, where the number is an
+// optional version.
+package loc
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// Entry is a single localization key.
+type Entry struct {
+	Key        string
+	Version    string
+	Text       string
+	KeyRange   pdx.Range
+	ValueRange pdx.Range
+}
+
+// File is a parsed localization file.
+type File struct {
+	Path     string
+	Language string
+	Entries  []Entry
+	// Comments holds the # comments of the file, their text without the #.
+	Comments []pdx.Token
+	Errors   []pdx.Error
+}
+
+var (
+	headerPattern = regexp.MustCompile(`^\s*(l_[a-z_]+):\s*(#.*)?$`)
+	entryPattern  = regexp.MustCompile(`^(\s*)([A-Za-z0-9_.\-']+):(\d*)\s*"(.*)"\s*(#.*)?$`)
+)
+
+// Parse parses the contents of a localization file.
+func Parse(path, src string) *File {
+	f := &File{Path: path}
+	src = strings.TrimPrefix(src, "\uFEFF")
+	for n, line := range strings.Split(src, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			f.comment(n, line, strings.Index(line, "#"))
+			continue
+		}
+		if f.Language == "" {
+			if m := headerPattern.FindStringSubmatchIndex(line); m != nil {
+				f.Language = line[m[2]:m[3]]
+				if m[4] >= 0 {
+					f.comment(n, line, m[4])
+				}
+				continue
+			}
+			f.Errors = append(f.Errors, pdx.Error{Range: lineRange(n, line), Msg: "missing language header such as 'l_english:'"})
+			f.Language = "l_unknown"
+		}
+		m := entryPattern.FindStringSubmatchIndex(line)
+		if m == nil {
+			f.Errors = append(f.Errors, pdx.Error{Range: lineRange(n, line), Msg: "malformed localization entry; expected key:0 \"text\""})
+			continue
+		}
+		// Like the game, the value runs to the last quote on the line.
+		text := line[m[8]:m[9]]
+		f.Entries = append(f.Entries, Entry{
+			Key:        line[m[4]:m[5]],
+			Version:    line[m[6]:m[7]],
+			Text:       text,
+			KeyRange:   span(n, line, m[4], m[5]),
+			ValueRange: span(n, line, m[8]-1, m[9]+1),
+		})
+		if m[10] >= 0 {
+			f.comment(n, line, m[10])
+		}
+	}
+	return f
+}
+
+// comment records the comment of line n starting at byte offset start.
+func (f *File) comment(n int, line string, start int) {
+	f.Comments = append(f.Comments, pdx.Token{Kind: pdx.Comment, Text: line[start+1:], Range: span(n, line, start, len(line))})
+}
+
+// Lookup returns the entry for key, if present.
+func (f *File) Lookup(key string) (Entry, bool) {
+	for _, e := range f.Entries {
+		if e.Key == key {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+func lineRange(n int, line string) pdx.Range {
+	return span(n, line, 0, len(line))
+}
+
+// span converts byte offsets within line n to a range with UTF-16 columns.
+func span(n int, line string, start, end int) pdx.Range {
+	return pdx.Range{
+		Start: pdx.Pos{Line: n, Col: utf16Len(line[:start])},
+		End:   pdx.Pos{Line: n, Col: utf16Len(line[:end])},
+	}
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+		s = s[size:]
+	}
+	return n
+}
@@ -0,0 +1,38 @@
+package loc
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Placeholder is a `$variable$` or `[DataFunction]` of a localization text.
+// Text is its normalized form, the same in every language: formatting
+// after `|` and the display text of Concept('key','Text') are dropped.
+// Start and End are its byte offsets in the text.
+type Placeholder struct {
+	Text       string
+	Start, End int
+}
+
+var (
+	variablePattern    = regexp.MustCompile(`\$([^$\s]+)\$`)
+	expressionPattern  = regexp.MustCompile(`\[([^\]]+)\]`)
+	conceptTextPattern = regexp.MustCompile(`Concept\(\s*'([^']+)'\s*,\s*'[^']*'\s*\)`)
+)
+
+// Placeholders returns the variables and data functions of a text.
+func Placeholders(text string) []Placeholder {
+	var placeholders []Placeholder
+	for _, m := range variablePattern.FindAllStringSubmatchIndex(text, -1) {
+		name, _, _ := strings.Cut(text[m[2]:m[3]], "|")
+		placeholders = append(placeholders, Placeholder{Text: "$" + name + "$", Start: m[0], End: m[1]})
+	}
+	for _, m := range expressionPattern.FindAllStringSubmatchIndex(text, -1) {
+		expr := conceptTextPattern.ReplaceAllString(text[m[2]:m[3]], "Concept('$1')")
+		if i := strings.LastIndex(expr, "|"); i >= 0 && !strings.Contains(expr[i:], "'") {
+			expr = expr[:i]
+		}
+		placeholders = append(placeholders, Placeholder{Text: "[" + strings.Join(strings.Fields(expr), "") + "]", Start: m[0], End: m[1]})
+	}
+	return placeholders
+}
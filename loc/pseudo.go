@@ -0,0 +1,78 @@
+package loc
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// pseudoLetters are the accented stand-ins of ASCII letters. They stay
+// within Latin-1 so that the game's fonts can draw them.
+var pseudoLetters = map[rune]rune{
+	'a': 'á', 'e': 'é', 'i': 'í', 'o': 'ó', 'u': 'ú', 'y': 'ý', 'n': 'ñ', 'c': 'ç',
+	'A': 'Å', 'E': 'É', 'I': 'Î', 'O': 'Ø', 'U': 'Ü', 'Y': 'Ý', 'N': 'Ñ', 'C': 'Ç',
+	'D': 'Ð', 's': 'š', 'S': 'Š', 'z': 'ž', 'Z': 'Ž',
+}
+
+// Pseudo returns a pseudo-localized version of a text: the letters of its
+// prose are accented and it is padded with tildes by padding times its
+// length, while variables, data functions, formatting codes, icons and
+// escapes are kept as they are.
+func Pseudo(text string, padding float64) string {
+	var b strings.Builder
+	letters := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if end := markupEnd(text, i); end > i {
+			b.WriteString(text[i:end])
+			i = end
+			continue
+		}
+		if p, ok := pseudoLetters[r]; ok {
+			b.WriteRune(p)
+		} else {
+			b.WriteRune(r)
+		}
+		if r != ' ' {
+			letters++
+		}
+		i += size
+	}
+	if pad := int(float64(letters)*padding + 0.5); pad > 0 {
+		b.WriteString(" " + strings.Repeat("~", pad))
+	}
+	return b.String()
+}
+
+// markupEnd returns the end of the markup starting at byte i of text, or i
+// if text[i] does not start any: [functions], $variables$, #codes, #!,
+// @icons!, £icons£ and \ escapes.
+func markupEnd(text string, i int) int {
+	close := func(from int, c string) int {
+		if j := strings.Index(text[from:], c); j >= 0 {
+			return from + j + len(c)
+		}
+		return len(text)
+	}
+	switch {
+	case text[i] == '[':
+		return close(i+1, "]")
+	case text[i] == '$':
+		return close(i+1, "$")
+	case text[i] == '@':
+		return close(i+1, "!")
+	case strings.HasPrefix(text[i:], "£"):
+		return close(i+len("£"), "£")
+	case text[i] == '\\' && i+1 < len(text):
+		return i + 2
+	case text[i] == '#':
+		j := i + 1
+		for j < len(text) && text[j] != ' ' && text[j] != '#' {
+			j++
+		}
+		if j < len(text) && text[j] == ' ' {
+			j++
+		}
+		return j
+	}
+	return i
+}
@@ -0,0 +1,205 @@
+package loc
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Unit is a localization key to translate: its English source and the
+// translation, if any.
+type Unit struct {
+	Key string
+	// Version is the version of the source entry, which the translation
+	// takes on import.
+	Version string
+	Source  string
+	Target  string
+	// File is the virtual path of the source file, for context.
+	File string
+}
+
+// csvHeader is the header row of translation CSV files.
+var csvHeader = []string{"key", "version", "source", "target", "file"}
+
+// WriteCSV writes units as CSV with a header row.
+func WriteCSV(w io.Writer, units []Unit) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, u := range units {
+		if err := cw.Write([]string{u.Key, u.Version, u.Source, u.Target, u.File}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadCSV reads units written by WriteCSV. Columns are found by the header
+// row, so translators may reorder them or drop all but key and target. A
+// UTF-8 byte order mark, which spreadsheets add when saving, is skipped.
+func ReadCSV(r io.Reader) ([]Unit, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("empty CSV file")
+	}
+	col := map[string]int{}
+	for i, name := range rows[0] {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\uFEFF")
+		}
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := col["key"]; !ok {
+		return nil, errors.New("CSV file has no key column")
+	}
+	if _, ok := col["target"]; !ok {
+		return nil, errors.New("CSV file has no target column")
+	}
+	get := func(row []string, name string) string {
+		if i, ok := col[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+	var units []Unit
+	for _, row := range rows[1:] {
+		units = append(units, Unit{Key: get(row, "key"), Version: get(row, "version"), Source: get(row, "source"), Target: get(row, "target"), File: get(row, "file")})
+	}
+	return units, nil
+}
+
+// xliffLanguages maps game languages to the BCP 47 codes XLIFF uses.
+var xliffLanguages = map[string]string{
+	"l_english": "en", "l_french": "fr", "l_german": "de", "l_spanish": "es",
+	"l_russian": "ru", "l_korean": "ko", "l_simp_chinese": "zh-Hans", "l_polish": "pl",
+	"l_braz_por": "pt-BR", "l_japanese": "ja", "l_turkish": "tr",
+}
+
+type xliffFile struct {
+	XMLName xml.Name `xml:"xliff"`
+	Version string   `xml:"version,attr"`
+	File    struct {
+		Original       string      `xml:"original,attr"`
+		SourceLanguage string      `xml:"source-language,attr"`
+		TargetLanguage string      `xml:"target-language,attr"`
+		Datatype       string      `xml:"datatype,attr"`
+		Units          []xliffUnit `xml:"body>trans-unit"`
+	} `xml:"file"`
+}
+
+type xliffUnit struct {
+	ID      string `xml:"id,attr"`
+	Version string `xml:"version,attr,omitempty"`
+	Note    string `xml:"note,omitempty"`
+	Source  string `xml:"source"`
+	Target  struct {
+		State string `xml:"state,attr,omitempty"`
+		Text  string `xml:",chardata"`
+	} `xml:"target"`
+}
+
+// WriteXLIFF writes units as an XLIFF 1.2 document translating English
+// into language.
+func WriteXLIFF(w io.Writer, language string, units []Unit) error {
+	var doc xliffFile
+	doc.Version = "1.2"
+	doc.File.Original = "localization"
+	doc.File.SourceLanguage = xliffLanguages["l_english"]
+	doc.File.TargetLanguage = xliffLanguages[language]
+	if doc.File.TargetLanguage == "" {
+		doc.File.TargetLanguage = strings.TrimPrefix(language, "l_")
+	}
+	doc.File.Datatype = "plaintext"
+	for _, u := range units {
+		xu := xliffUnit{ID: u.Key, Version: u.Version, Note: u.File, Source: u.Source}
+		xu.Target.Text = u.Target
+		xu.Target.State = "needs-translation"
+		if u.Target != "" {
+			xu.Target.State = "needs-review-translation"
+		}
+		doc.File.Units = append(doc.File.Units, xu)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ReadXLIFF reads the trans-units of an XLIFF 1.2 document.
+func ReadXLIFF(r io.Reader) ([]Unit, error) {
+	var doc xliffFile
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid XLIFF: %w", err)
+	}
+	var units []Unit
+	for _, xu := range doc.File.Units {
+		units = append(units, Unit{Key: xu.ID, Version: xu.Version, Source: xu.Source, Target: xu.Target.Text, File: xu.Note})
+	}
+	return units, nil
+}
+
+// lineBreaks escapes the line breaks of a translated text.
+var lineBreaks = strings.NewReplacer("\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// SetEntries returns src, the text of a localization file of language,
+// with the text and version of existing entries replaced by updates and
+// the other updates appended in the given order. Lines, comments and the
+// order of the file are otherwise kept. An empty src starts a new file.
+// Line breaks in the texts are written as \n, as entries are single lines.
+func SetEntries(src, language string, updates map[string]Unit, order []string) string {
+	if strings.TrimSpace(strings.TrimPrefix(src, "\uFEFF")) == "" {
+		src = "\uFEFF" + language + ":\n"
+	}
+	newline := "\n"
+	if strings.Contains(src, "\r\n") {
+		newline = "\r\n"
+	}
+	lines := strings.Split(src, "\n")
+	done := map[string]bool{}
+	for i, line := range lines {
+		cr := strings.HasSuffix(line, "\r")
+		line = strings.TrimSuffix(line, "\r")
+		m := entryPattern.FindStringSubmatchIndex(line)
+		if m == nil {
+			continue
+		}
+		u, ok := updates[line[m[4]:m[5]]]
+		if !ok {
+			continue
+		}
+		done[u.Key] = true
+		line = line[:m[5]] + ":" + u.Version + line[m[7]:m[8]] + lineBreaks.Replace(u.Target) + line[m[9]:]
+		if cr {
+			line += "\r"
+		}
+		lines[i] = line
+	}
+	out := strings.Join(lines, "\n")
+	if !strings.HasSuffix(out, "\n") {
+		out += newline
+	}
+	for _, key := range order {
+		if u, ok := updates[key]; ok && !done[key] {
+			out += fmt.Sprintf(" %s:%s \"%s\"%s", key, u.Version, lineBreaks.Replace(u.Target), newline)
+			done[key] = true
+		}
+	}
+	return out
+}
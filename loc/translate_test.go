@@ -0,0 +1,165 @@
+package loc
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// units are texts as they appear in localization files, with the escapes,
+// quotes, markup and non-ASCII letters translators meet.
+var units = []Unit{
+	{Key: "my.0001.t", Version: "0", Source: "A Plain Title", File: "localization/english/my_l_english.yml"},
+	{Key: "my.0001.desc", Version: "2", Source: `[ROOT.Char.GetName] says: "Hello, cousin!"\nIt is $YEARS$ years, not #bold 5#!`, Target: `[ROOT.Char.GetName] dit : « Bonjour, cousin ! »\nCela fait $YEARS$ ans`, File: "localization/english/my_l_english.yml"},
+	{Key: "my.0001.a", Version: "1", Source: `Comma, "quotes" and a back\slash`, Target: "Virgule, \"guillemets\" et ; point-virgule"},
+	{Key: "my_unicode", Source: "Dvůr of Ærø — 東京 😀", Target: "Двор Эрё <&>"},
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, units); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadCSV(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, units) {
+		t.Errorf("ReadCSV(WriteCSV(units)) = %+v, want %+v", got, units)
+	}
+
+	// Spreadsheets save CSV with a byte order mark and CRLF line endings.
+	saved := "\uFEFF" + strings.ReplaceAll(buf.String(), "\n", "\r\n")
+	got, err = ReadCSV(strings.NewReader(saved))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, units) {
+		t.Errorf("ReadCSV with a byte order mark = %+v, want %+v", got, units)
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name, csv string
+		want      []Unit
+		err       string
+	}{
+		{
+			name: "reordered columns",
+			csv:  "Target,KEY\n\"Bonjour, \"\"toi\"\"\",my_key\n",
+			want: []Unit{{Key: "my_key", Target: `Bonjour, "toi"`}},
+		},
+		{
+			name: "byte order mark and short rows",
+			csv:  "\uFEFFkey,source,target\nmy_key,Hello\n",
+			want: []Unit{{Key: "my_key", Source: "Hello"}},
+		},
+		{
+			name: "line break in a cell",
+			csv:  "key,target\nmy_key,\"Ligne un\nLigne deux\"\n",
+			want: []Unit{{Key: "my_key", Target: "Ligne un\nLigne deux"}},
+		},
+		{name: "empty", csv: "", err: "empty CSV file"},
+		{name: "no key column", csv: "id,target\nmy_key,x\n", err: "CSV file has no key column"},
+		{name: "no target column", csv: "key,source\nmy_key,x\n", err: "CSV file has no target column"},
+		{name: "bad quoting", csv: "key,target\nmy_key,\"open\n", err: "extraneous or missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadCSV(strings.NewReader(tt.csv))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("ReadCSV error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadCSV = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXLIFFRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteXLIFF(&buf, "l_french", units); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`source-language="en"`, `target-language="fr"`, `state="needs-translation"`, `state="needs-review-translation"`, "&lt;&amp;&gt;"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("XLIFF lacks %s:\n%s", want, buf.String())
+		}
+	}
+	for _, saved := range []string{buf.String(), "\uFEFF" + buf.String()} {
+		got, err := ReadXLIFF(strings.NewReader(saved))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, units) {
+			t.Errorf("ReadXLIFF(WriteXLIFF(units)) = %+v, want %+v", got, units)
+		}
+	}
+
+	if _, err := ReadXLIFF(strings.NewReader("<xliff><file>")); err == nil || !strings.Contains(err.Error(), "invalid XLIFF") {
+		t.Errorf("ReadXLIFF of a truncated document: error = %v", err)
+	}
+}
+
+// TestSetEntries checks that imported translations parse back to the same
+// texts, in new files and in files with a byte order mark and CRLF line
+// endings.
+func TestSetEntries(t *testing.T) {
+	updates := map[string]Unit{}
+	var order []string
+	for _, u := range units {
+		if u.Target != "" {
+			updates[u.Key] = u
+			order = append(order, u.Key)
+		}
+	}
+	updates["my_multiline"] = Unit{Key: "my_multiline", Version: "0", Target: "Ligne un\nLigne deux\r\nLigne trois"}
+	order = append(order, "my_multiline")
+	want := map[string]string{
+		"my.0001.desc": units[1].Target,
+		"my.0001.a":    units[2].Target,
+		"my_unicode":   units[3].Target,
+		"my_multiline": `Ligne un\nLigne deux\nLigne trois`,
+	}
+
+	for name, src := range map[string]string{
+		"new file":  "",
+		"same keys": "\uFEFFl_french:\r\n # Events\r\n my.0001.desc:1 \"Old\" # outdated\r\n my.0001.a:0 \"\"\r\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			out := SetEntries(src, "l_french", updates, order)
+			if !strings.HasPrefix(out, "\uFEFFl_french:") {
+				t.Errorf("output does not start with a byte order mark and the header:\n%s", out)
+			}
+			if src != "" && strings.Count(out, "\r\n") != strings.Count(out, "\n") {
+				t.Errorf("output mixes line endings:\n%q", out)
+			}
+			f := Parse("my_l_french.yml", out)
+			if len(f.Errors) > 0 {
+				t.Fatalf("output has errors %v:\n%s", f.Errors, out)
+			}
+			got := map[string]string{}
+			for _, e := range f.Entries {
+				got[e.Key] = e.Text
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("entries = %q, want %q", got, want)
+			}
+			if e, _ := f.Lookup("my.0001.desc"); e.Version != "2" {
+				t.Errorf("my.0001.desc has version %q, want the English 2", e.Version)
+			}
+			if src != "" && (!strings.Contains(out, "# Events") || !strings.Contains(out, "# outdated")) {
+				t.Errorf("comments were dropped:\n%s", out)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package loc
+
+import (
+	"strconv"
+
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// VersionNumber returns the entry's version, 0 when it has none. Paradox
+// bumps the version of an English entry whenever its text changes, so a
+// translation with a lower version is stale.
+func (e Entry) VersionNumber() int {
+	n, _ := strconv.Atoi(e.Version)
+	return n
+}
+
+// VersionRange returns the span of the version digits after the key's
+// colon, empty when the entry has no version.
+func (e Entry) VersionRange() pdx.Range {
+	start := e.KeyRange.End
+	start.Col++
+	start.Offset++
+	end := start
+	end.Col += len(e.Version)
+	end.Offset += len(e.Version)
+	return pdx.Range{Start: start, End: end}
+}
+
+// Changed returns the entries of cur whose text differs from the entry of
+// the same key in old while their version is still the same.
+func Changed(old, cur *File) []Entry {
+	previous := map[string]Entry{}
+	for _, e := range old.Entries {
+		previous[e.Key] = e
+	}
+	var changed []Entry
+	for _, e := range cur.Entries {
+		if p, ok := previous[e.Key]; ok && p.Text != e.Text && p.Version == e.Version {
+			changed = append(changed, e)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,88 @@
+// Package mod reads a mod's descriptor and assembles its release archive.
+package mod
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// DescriptorFile is the name of the descriptor in the mod's root folder.
+const DescriptorFile = "descriptor.mod"
+
+// Descriptor holds the fields of descriptor.mod.
+type Descriptor struct {
+	Name             string
+	Version          string
+	SupportedVersion string
+	Tags             []string
+	Picture          string
+	RemoteFileID     string
+	// File is the parsed descriptor, for reporting positions.
+	File *pdx.File
+}
+
+// ParseDescriptor reads the fields of a descriptor.mod text.
+func ParseDescriptor(path, text string) *Descriptor {
+	file := pdx.Parse(path, text)
+	d := &Descriptor{File: file}
+	for _, f := range file.Root.Fields {
+		switch f.KeyText() {
+		case "name":
+			d.Name = f.ValueText()
+		case "version":
+			d.Version = f.ValueText()
+		case "supported_version":
+			d.SupportedVersion = f.ValueText()
+		case "picture":
+			d.Picture = f.ValueText()
+		case "remote_file_id":
+			d.RemoteFileID = f.ValueText()
+		case "tags":
+			if b := f.Block(); b != nil {
+				for _, tag := range b.Fields {
+					if tag.Key == nil && tag.Scalar() != nil {
+						d.Tags = append(d.Tags, tag.Scalar().Text)
+					}
+				}
+			}
+		}
+	}
+	return d
+}
+
+// ReadDescriptor reads the descriptor.mod of the mod in root.
+func ReadDescriptor(root string) (*Descriptor, error) {
+	path := filepath.Join(root, DescriptorFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDescriptor(path, string(data)), nil
+}
+
+// versionPattern matches game versions such as "1.13.2" or "1.13.*".
+var versionPattern = regexp.MustCompile(`^\d+(\.(\d+|\*))*$`)
+
+// Problems lists what keeps the descriptor from being uploaded.
+func (d *Descriptor) Problems() []string {
+	var problems []string
+	for _, err := range d.File.Errors {
+		problems = append(problems, err.Error())
+	}
+	if d.Name == "" {
+		problems = append(problems, "missing name")
+	}
+	if d.Version == "" {
+		problems = append(problems, "missing version")
+	}
+	switch {
+	case d.SupportedVersion == "":
+		problems = append(problems, "missing supported_version")
+	case !versionPattern.MatchString(d.SupportedVersion):
+		problems = append(problems, "supported_version '"+d.SupportedVersion+"' is not a game version such as 1.13.*")
+	}
+	return problems
+}
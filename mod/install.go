@@ -0,0 +1,140 @@
+package mod
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// gameFolder is the folder of CK3 inside a Steam library.
+const gameFolder = "Crusader Kings III"
+
+// appID is the Steam app id of CK3, naming its Workshop content folder.
+const appID = "1158310"
+
+// libraryPathPattern matches the library paths of Steam's
+// libraryfolders.vdf, such as `"path"		"D:\\SteamLibrary"`.
+var libraryPathPattern = regexp.MustCompile(`"path"\s+"((?:[^"\\]|\\.)*)"`)
+
+// DetectGamePath looks for the game folder of a CK3 installation in the
+// Steam libraries and the Xbox Game Pass folder of the platform, and
+// returns "" if none is found.
+func DetectGamePath() string {
+	for _, path := range gamePathCandidates(runtime.GOOS, home()) {
+		if IsGameFolder(path) {
+			return path
+		}
+	}
+	return ""
+}
+
+// DetectModsPath returns the folder the launcher reads local mods from,
+// such as Documents/Paradox Interactive/Crusader Kings III/mod, or "" if it
+// does not exist.
+func DetectModsPath() string {
+	for _, path := range modsPathCandidates(runtime.GOOS, home()) {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// WorkshopPath returns the folder Steam downloads the Workshop mods of the
+// game at gamePath to, steamapps/workshop/content/1158310 of the same
+// library, or "" if gamePath is not in a Steam library.
+func WorkshopPath(gamePath string) string {
+	common := filepath.Dir(filepath.Dir(filepath.Clean(gamePath)))
+	steamapps := filepath.Dir(common)
+	if filepath.Base(common) != "common" || filepath.Base(steamapps) != "steamapps" {
+		return ""
+	}
+	return filepath.Join(steamapps, "workshop", "content", appID)
+}
+
+// IsGameFolder reports whether path looks like the game folder of a CK3
+// installation.
+func IsGameFolder(path string) bool {
+	info, err := os.Stat(filepath.Join(path, "common"))
+	return err == nil && info.IsDir()
+}
+
+func home() string {
+	dir, _ := os.UserHomeDir()
+	return dir
+}
+
+// steamRoots returns the Steam installation folders of a platform.
+func steamRoots(goos, home string) []string {
+	switch goos {
+	case "windows":
+		var roots []string
+		for _, env := range []string{"ProgramFiles(x86)", "ProgramFiles"} {
+			if dir := os.Getenv(env); dir != "" {
+				roots = append(roots, filepath.Join(dir, "Steam"))
+			}
+		}
+		return append(roots, `C:\Program Files (x86)\Steam`)
+	case "darwin":
+		return []string{filepath.Join(home, "Library", "Application Support", "Steam")}
+	}
+	return []string{
+		filepath.Join(home, ".steam", "steam"),
+		filepath.Join(home, ".local", "share", "Steam"),
+		filepath.Join(home, ".var", "app", "com.valvesoftware.Steam", ".local", "share", "Steam"),
+	}
+}
+
+// steamLibraries returns the library folders of a Steam installation: the
+// installation itself and those listed in libraryfolders.vdf.
+func steamLibraries(root string) []string {
+	libraries := []string{root}
+	data, err := os.ReadFile(filepath.Join(root, "steamapps", "libraryfolders.vdf"))
+	if err != nil {
+		return libraries
+	}
+	for _, m := range libraryPathPattern.FindAllStringSubmatch(string(data), -1) {
+		libraries = append(libraries, strings.ReplaceAll(m[1], `\\`, `\`))
+	}
+	return libraries
+}
+
+// gamePathCandidates returns the possible game folders of a platform, most
+// likely first.
+func gamePathCandidates(goos, home string) []string {
+	var candidates []string
+	seen := map[string]bool{}
+	for _, root := range steamRoots(goos, home) {
+		for _, library := range steamLibraries(root) {
+			path := filepath.Join(library, "steamapps", "common", gameFolder, "game")
+			if !seen[path] {
+				seen[path] = true
+				candidates = append(candidates, path)
+			}
+		}
+	}
+	if goos == "windows" {
+		// Game Pass installs into an XboxGames folder at a drive's root.
+		for drive := 'C'; drive <= 'Z'; drive++ {
+			candidates = append(candidates, filepath.Join(string(drive)+`:\`, "XboxGames", gameFolder, "Content", "game"))
+		}
+	}
+	return candidates
+}
+
+// modsPathCandidates returns the possible local mod folders of a platform.
+func modsPathCandidates(goos, home string) []string {
+	documents := filepath.Join(home, "Documents")
+	if goos == "windows" {
+		if profile := os.Getenv("USERPROFILE"); profile != "" {
+			documents = filepath.Join(profile, "Documents")
+		}
+	}
+	candidates := []string{filepath.Join(documents, "Paradox Interactive", gameFolder, "mod")}
+	if goos == "linux" {
+		candidates = append([]string{filepath.Join(home, ".local", "share", "Paradox Interactive", gameFolder, "mod")}, candidates...)
+	}
+	return candidates
+}
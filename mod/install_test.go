@@ -0,0 +1,82 @@
+package mod
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+// TestDetectedGamePathVirtualPaths checks that the files of a detected
+// Steam installation, whose path holds steamapps/common, get the virtual
+// paths of the game's folders.
+func TestDetectedGamePathVirtualPaths(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the Steam layout of the test is the Linux one")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	game := filepath.Join(home, ".steam", "steam", "steamapps", "common", gameFolder, "game")
+	files := map[string]string{
+		"common/scripted_effects/00_effects.txt": "my_effect = { add_gold = 1 }\n",
+		"events/my_events.txt":                   "namespace = my\nmy.0001 = { type = character_event }\n",
+	}
+	for vpath, text := range files {
+		path := filepath.Join(game, filepath.FromSlash(vpath))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	detected := DetectGamePath()
+	if detected != game {
+		t.Fatalf("DetectGamePath() = %q, want %q", detected, game)
+	}
+	// Before the game folder is indexed as a root.
+	for vpath := range files {
+		if got := index.VirtualPath(filepath.Join(detected, filepath.FromSlash(vpath))); got != vpath {
+			t.Errorf("VirtualPath of %s = %q, want %q", vpath, got, vpath)
+		}
+	}
+
+	ix := index.New()
+	if _, err := ix.ScanFolders(detected, []string{"common", "events"}); err != nil {
+		t.Fatal(err)
+	}
+	for vpath := range files {
+		entry := ix.File(filepath.Join(detected, filepath.FromSlash(vpath)))
+		if entry == nil || entry.VirtualPath != vpath {
+			t.Errorf("indexed entry of %s = %+v, want virtual path %q", vpath, entry, vpath)
+		}
+	}
+	if len(ix.Definitions(index.KindScriptedEffect, "my_effect")) != 1 {
+		t.Error("the scripted effect of the game is not defined")
+	}
+	if len(ix.Definitions(index.KindEvent, "my.0001")) != 1 {
+		t.Error("the event of the game is not defined")
+	}
+}
+
+func TestWorkshopPath(t *testing.T) {
+	steamapps := filepath.Join("/home", "me", ".steam", "steam", "steamapps")
+	tests := []struct {
+		name, gamePath, want string
+	}{
+		{"steam", filepath.Join(steamapps, "common", gameFolder, "game"), filepath.Join(steamapps, "workshop", "content", "1158310")},
+		{"trailing separator", filepath.Join(steamapps, "common", gameFolder, "game") + string(filepath.Separator), filepath.Join(steamapps, "workshop", "content", "1158310")},
+		{"game pass", filepath.Join("/XboxGames", gameFolder, "Content", "game"), ""},
+		{"copy", filepath.Join("/home", "me", "ck3", "game"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WorkshopPath(tt.gamePath); got != tt.want {
+				t.Errorf("WorkshopPath(%q) = %q, want %q", tt.gamePath, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package mod
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// MetadataFile is the path of the launcher metadata, introduced with game
+// version 1.13, relative to the mod's root folder.
+var MetadataFile = filepath.Join(".metadata", "metadata.json")
+
+// Metadata holds the fields of .metadata/metadata.json that mirror the
+// descriptor. Other fields are kept as they are when the file is written.
+type Metadata struct {
+	Name                 string   `json:"name"`
+	Version              string   `json:"version"`
+	SupportedGameVersion string   `json:"supported_game_version"`
+	Tags                 []string `json:"tags"`
+}
+
+// ReadMetadata reads the metadata.json of the mod in root.
+func ReadMetadata(root string) (*Metadata, error) {
+	data, err := os.ReadFile(filepath.Join(root, MetadataFile))
+	if err != nil {
+		return nil, err
+	}
+	var m Metadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
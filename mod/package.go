@@ -0,0 +1,196 @@
+package mod
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/unLomTrois/gock3-lsp/index"
+)
+
+// PackageOptions configures Package.
+type PackageOptions struct {
+	// Ignore holds glob patterns of files to leave out, matched against the
+	// slash-separated path relative to the mod root and against the file
+	// name. A pattern ending in "/" leaves out a whole folder.
+	Ignore []string `json:"ignore"`
+}
+
+// PackageResult describes a written archive.
+type PackageResult struct {
+	Output string `json:"output"`
+	Files  int    `json:"files"`
+	// Fixed lists the files whose line endings or encoding were rewritten.
+	Fixed []string `json:"fixed"`
+}
+
+// rootFiles are the files outside the game folders that belong in a release.
+var rootFiles = map[string]bool{DescriptorFile: true, "thumbnail.png": true}
+
+// textExtensions are the file types whose line endings and encoding are
+// normalized.
+var textExtensions = map[string]bool{
+	".txt": true, ".gui": true, ".yml": true, ".mod": true, ".gfx": true,
+	".asset": true, ".settings": true, ".json": true,
+}
+
+// Package writes the mod in root to a zip archive at output, ready for
+// upload: only files the game loads are included, the descriptor must be
+// valid, text files get CRLF line endings and UTF-8 encoding, and
+// localization files get the byte order mark the game requires.
+func Package(root, output string, opts PackageOptions) (*PackageResult, error) {
+	d, err := ReadDescriptor(root)
+	if err != nil {
+		return nil, fmt.Errorf("reading descriptor: %w", err)
+	}
+	if problems := d.Problems(); len(problems) > 0 {
+		return nil, errors.New("invalid " + DescriptorFile + ": " + strings.Join(problems, "; "))
+	}
+
+	absOutput, _ := filepath.Abs(output)
+	var files []string
+	err = filepath.WalkDir(root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		top, _, nested := strings.Cut(rel, "/")
+		if entry.IsDir() {
+			if strings.HasPrefix(entry.Name(), ".") || !nested && !index.IsGameFolder(top) || ignored(rel+"/", opts.Ignore) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if abs, _ := filepath.Abs(p); abs == absOutput {
+			return nil
+		}
+		if !nested && !rootFiles[rel] || strings.HasPrefix(entry.Name(), ".") || ignored(rel, opts.Ignore) {
+			return nil
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := os.Create(output)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+	zw := zip.NewWriter(out)
+	result := &PackageResult{Output: output, Files: len(files), Fixed: []string{}}
+	for _, rel := range files {
+		src := filepath.Join(root, filepath.FromSlash(rel))
+		info, err := os.Stat(src)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return nil, err
+		}
+		if textExtensions[strings.ToLower(path.Ext(rel))] {
+			if fixed := normalizeText(data, strings.EqualFold(path.Ext(rel), ".yml")); !bytes.Equal(fixed, data) {
+				data = fixed
+				result.Fixed = append(result.Fixed, rel)
+			}
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: rel, Method: zip.Deflate, Modified: info.ModTime()})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return result, out.Close()
+}
+
+// DefaultOutput returns the archive path used when none is given: a zip
+// named after the mod folder and version, next to the mod folder.
+func DefaultOutput(root string) string {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	name := filepath.Base(root)
+	if d, err := ReadDescriptor(root); err == nil && d.Version != "" {
+		name += "-" + d.Version
+	}
+	return filepath.Join(filepath.Dir(root), name+".zip")
+}
+
+// ignored reports whether rel matches one of the ignore patterns.
+func ignored(rel string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "/") {
+			if strings.HasPrefix(rel, pattern) {
+				return true
+			}
+			continue
+		}
+		name := path.Base(rel)
+		if ok, _ := path.Match(pattern, strings.TrimSuffix(rel, "/")); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// normalizeText converts data to UTF-8 (reading invalid UTF-8 as
+// Windows-1252) with CRLF line endings, with a byte order mark if bom is set.
+func normalizeText(data []byte, bom bool) []byte {
+	text := bytes.TrimPrefix(data, utf8BOM)
+	if !utf8.Valid(text) {
+		text = decodeWindows1252(text)
+	}
+	text = bytes.ReplaceAll(text, []byte("\r\n"), []byte("\n"))
+	text = bytes.ReplaceAll(text, []byte("\r"), []byte("\n"))
+	text = bytes.ReplaceAll(text, []byte("\n"), []byte("\r\n"))
+	if bom || bytes.HasPrefix(data, utf8BOM) {
+		text = append(append([]byte(nil), utf8BOM...), text...)
+	}
+	return text
+}
+
+// windows1252 maps the bytes 0x80-0x9F of Windows-1252 to Unicode; the rest
+// of the code page matches Latin-1.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+func decodeWindows1252(data []byte) []byte {
+	var b bytes.Buffer
+	for _, c := range data {
+		switch {
+		case c < 0x80:
+			b.WriteByte(c)
+		case c < 0xA0:
+			b.WriteRune(windows1252[c-0x80])
+		default:
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,171 @@
+package mod
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files, by slash-separated path relative to root.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, text := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readZip returns the files of the archive at path.
+func readZip(t *testing.T, path string) map[string]string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	files := map[string]string{}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(data)
+	}
+	return files
+}
+
+const testDescriptor = "version = \"1.2\"\r\nname = \"My Mod\"\r\nsupported_version = \"1.13.*\"\r\n"
+
+func TestPackage(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "my_mod")
+	writeFiles(t, root, map[string]string{
+		DescriptorFile:                          testDescriptor,
+		"thumbnail.png":                         "\x89PNG\r\n",
+		"README.md":                             "# My Mod\n",
+		"notes/todo.txt":                        "later\n",
+		".git/config":                           "[core]\n",
+		"events/my_events.txt":                  "namespace = my\n",
+		"events/.my_events.txt.swp":             "swap",
+		"events/draft.txt":                      "namespace = draft\n",
+		"common/traits/my_traits.txt":           "my_trait = {\r\n\tcategory = personality\r\n}\r\n",
+		"common/traits/old/my_traits.txt":       "old = {}\n",
+		"localization/english/my_l_english.yml": "l_english:\n my_key:0 \"Caf\xe9\"\n",
+		"gfx/interface/icons/my_icon.dds":       "DDS \n\x00\n",
+	})
+
+	output := filepath.Join(root, "my_mod.zip")
+	result, err := Package(root, output, PackageOptions{Ignore: []string{"draft.txt", "common/traits/old/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		DescriptorFile:                          testDescriptor,
+		"thumbnail.png":                         "\x89PNG\r\n",
+		"events/my_events.txt":                  "namespace = my\r\n",
+		"common/traits/my_traits.txt":           "my_trait = {\r\n\tcategory = personality\r\n}\r\n",
+		"localization/english/my_l_english.yml": "\xef\xbb\xbfl_english:\r\n my_key:0 \"Café\"\r\n",
+		"gfx/interface/icons/my_icon.dds":       "DDS \n\x00\n",
+	}
+	if got := readZip(t, output); !reflect.DeepEqual(got, want) {
+		t.Errorf("archive = %q, want %q", got, want)
+	}
+	sort.Strings(result.Fixed)
+	if wantFixed := []string{"events/my_events.txt", "localization/english/my_l_english.yml"}; result.Files != len(want) || !reflect.DeepEqual(result.Fixed, wantFixed) {
+		t.Errorf("result = %+v, want %d files and %q fixed", result, len(want), wantFixed)
+	}
+
+	// Without the ignore patterns the draft and the old folder are packaged
+	// too, and the archive written in the mod folder still is not.
+	if result, err := Package(root, output, PackageOptions{}); err != nil || result.Files != len(want)+2 {
+		t.Errorf("second Package = %+v, %v, want %d files", result, err, len(want)+2)
+	}
+}
+
+func TestPackageInvalidDescriptor(t *testing.T) {
+	root := t.TempDir()
+	output := filepath.Join(t.TempDir(), "out.zip")
+	if _, err := Package(root, output, PackageOptions{}); err == nil || !strings.Contains(err.Error(), "reading descriptor") {
+		t.Errorf("Package without a descriptor: error = %v", err)
+	}
+	writeFiles(t, root, map[string]string{DescriptorFile: "name = \"My Mod\"\nsupported_version = \"latest\"\n"})
+	_, err := Package(root, output, PackageOptions{})
+	if want := "invalid descriptor.mod: missing version; supported_version 'latest' is not a game version such as 1.13.*"; err == nil || err.Error() != want {
+		t.Errorf("Package with an invalid descriptor: error = %v, want %q", err, want)
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Error("an archive was written for an invalid descriptor")
+	}
+}
+
+func TestDefaultOutput(t *testing.T) {
+	parent := t.TempDir()
+	root := filepath.Join(parent, "my_mod")
+	if got, want := DefaultOutput(root), filepath.Join(parent, "my_mod.zip"); got != want {
+		t.Errorf("DefaultOutput without a descriptor = %q, want %q", got, want)
+	}
+	writeFiles(t, root, map[string]string{DescriptorFile: testDescriptor})
+	if got, want := DefaultOutput(root), filepath.Join(parent, "my_mod-1.2.zip"); got != want {
+		t.Errorf("DefaultOutput = %q, want %q", got, want)
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	patterns := []string{"*.psd", "common/traits/old/", "events/draft_*.txt", "gfx/models"}
+	tests := []struct {
+		rel  string
+		want bool
+	}{
+		{"gfx/interface/icon.psd", true},
+		{"icon.psd", true},
+		{"common/traits/old/my_traits.txt", true},
+		{"common/traits/old/", true},
+		{"common/traits/my_traits.txt", false},
+		{"events/draft_1.txt", true},
+		{"events/sub/draft_1.txt", false},
+		{"gfx/models/", true},
+		{"gfx/models/x.mesh", false},
+		{"events/my_events.txt", false},
+	}
+	for _, tt := range tests {
+		if got := ignored(tt.rel, patterns); got != tt.want {
+			t.Errorf("ignored(%q) = %v, want %v", tt.rel, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name, data string
+		bom        bool
+		want       string
+	}{
+		{"LF", "a = b\nc = d\n", false, "a = b\r\nc = d\r\n"},
+		{"CRLF", "a = b\r\nc = d\r\n", false, "a = b\r\nc = d\r\n"},
+		{"CR and mixed", "a\rb\r\nc\n", false, "a\r\nb\r\nc\r\n"},
+		{"added BOM", "l_english:\n", true, "\xef\xbb\xbfl_english:\r\n"},
+		{"kept BOM", "\xef\xbb\xbfa = b\n", false, "\xef\xbb\xbfa = b\r\n"},
+		{"single BOM", "\xef\xbb\xbfa\r\n", true, "\xef\xbb\xbfa\r\n"},
+		{"UTF-8", "name = \"Dvůr Ærø\"\r\n", false, "name = \"Dvůr Ærø\"\r\n"},
+		{"Windows-1252", "name = \"Caf\xe9 \x80 \x93 \x9f\"\n", false, "name = \"Café € “ Ÿ\"\r\n"},
+	}
+	for _, tt := range tests {
+		if got := string(normalizeText([]byte(tt.data), tt.bom)); got != tt.want {
+			t.Errorf("%s: normalizeText = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package mod
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// Info is the part of a mod's identity that descriptor.mod and
+// metadata.json both record.
+type Info struct {
+	Name             string
+	Version          string
+	SupportedVersion string
+	Tags             []string
+}
+
+// Info returns the shared fields of the descriptor.
+func (d *Descriptor) Info() Info {
+	return Info{Name: d.Name, Version: d.Version, SupportedVersion: d.SupportedVersion, Tags: d.Tags}
+}
+
+// Info returns the shared fields of the metadata.
+func (m *Metadata) Info() Info {
+	return Info{Name: m.Name, Version: m.Version, SupportedVersion: m.SupportedGameVersion, Tags: m.Tags}
+}
+
+// Mismatch is a field on which descriptor.mod and metadata.json disagree.
+type Mismatch struct {
+	// Key is the descriptor key of the field.
+	Key        string
+	Descriptor string
+	Metadata   string
+}
+
+// Mismatches compares the descriptor with the metadata.
+func Mismatches(d *Descriptor, m *Metadata) []Mismatch {
+	var list []Mismatch
+	a, b := d.Info(), m.Info()
+	for _, field := range []struct{ key, d, m string }{
+		{"name", a.Name, b.Name},
+		{"version", a.Version, b.Version},
+		{"supported_version", a.SupportedVersion, b.SupportedVersion},
+		{"tags", tagList(a.Tags), tagList(b.Tags)},
+	} {
+		if field.d != field.m {
+			list = append(list, Mismatch{Key: field.key, Descriptor: field.d, Metadata: field.m})
+		}
+	}
+	return list
+}
+
+// tagList returns tags in a canonical order, since their order is not
+// significant.
+func tagList(tags []string) string {
+	sorted := append([]string(nil), tags...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ", ")
+}
+
+// Sync makes descriptor.mod and metadata.json of the mod in root agree,
+// taking the shared fields from source ("descriptor" or "metadata"; empty
+// picks the descriptor if there is one). The other file is created if
+// missing. It returns the paths of the files written.
+func Sync(root, source string) ([]string, error) {
+	d, derr := ReadDescriptor(root)
+	m, merr := ReadMetadata(root)
+	if source == "" {
+		source = "descriptor"
+		if derr != nil {
+			source = "metadata"
+		}
+	}
+	descriptorPath, metadataPath := filepath.Join(root, DescriptorFile), filepath.Join(root, MetadataFile)
+	switch source {
+	case "descriptor":
+		if derr != nil {
+			return nil, fmt.Errorf("reading %s: %w", DescriptorFile, derr)
+		}
+		if err := WriteMetadata(root, d.Info()); err != nil {
+			return nil, err
+		}
+		return []string{metadataPath}, nil
+	case "metadata":
+		if merr != nil {
+			return nil, fmt.Errorf("reading %s: %w", filepath.ToSlash(MetadataFile), merr)
+		}
+		text := ""
+		if derr == nil {
+			text = d.File.Text
+		} else if !errors.Is(derr, os.ErrNotExist) {
+			return nil, derr
+		}
+		if err := os.WriteFile(descriptorPath, []byte(UpdateDescriptor(text, m.Info())), 0o644); err != nil {
+			return nil, err
+		}
+		return []string{descriptorPath}, nil
+	}
+	return nil, fmt.Errorf("unknown source %q; expected descriptor or metadata", source)
+}
+
+// UpdateDescriptor sets the shared fields of a descriptor.mod text, keeping
+// every other field, and appends those that are missing. Written lines end
+// like the lines of text.
+func UpdateDescriptor(text string, info Info) string {
+	file := pdx.Parse(DescriptorFile, text)
+	newline := "\n"
+	if strings.Contains(text, "\r\n") {
+		newline = "\r\n"
+	}
+	values := map[string]string{
+		"name":              strconv.Quote(info.Name),
+		"version":           strconv.Quote(info.Version),
+		"supported_version": strconv.Quote(info.SupportedVersion),
+		"tags":              tagsBlock(info.Tags, newline),
+	}
+	type edit struct {
+		r     pdx.Range
+		value string
+	}
+	var edits []edit
+	for _, key := range []string{"version", "tags", "name", "supported_version"} {
+		if f := file.Root.Get(key); f != nil && f.Value != nil {
+			edits = append(edits, edit{f.Value.Range(), values[key]})
+			delete(values, key)
+		}
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].r.Start.Offset > edits[j].r.Start.Offset })
+	for _, e := range edits {
+		text = text[:e.r.Start.Offset] + e.value + text[e.r.End.Offset:]
+	}
+	for _, key := range []string{"version", "tags", "name", "supported_version"} {
+		if value, ok := values[key]; ok {
+			if text != "" && !strings.HasSuffix(text, "\n") {
+				text += newline
+			}
+			text += key + "=" + value + newline
+		}
+	}
+	return text
+}
+
+func tagsBlock(tags []string, newline string) string {
+	var b strings.Builder
+	b.WriteString("{" + newline)
+	for _, tag := range tags {
+		b.WriteString("\t" + strconv.Quote(tag) + newline)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// WriteMetadata sets the shared fields of the metadata.json of the mod in
+// root, creating the file if needed and keeping its other fields.
+func WriteMetadata(root string, info Info) error {
+	path := filepath.Join(root, MetadataFile)
+	fields := map[string]interface{}{}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return fmt.Errorf("reading %s: %w", filepath.ToSlash(MetadataFile), err)
+		}
+	}
+	tags := info.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	fields["name"] = info.Name
+	fields["version"] = info.Version
+	fields["supported_game_version"] = info.SupportedVersion
+	fields["tags"] = tags
+	data, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,157 @@
+package mod
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMismatches(t *testing.T) {
+	d := ParseDescriptor(DescriptorFile, "name = \"My Mod\"\nversion = \"1.2\"\nsupported_version = \"1.13.*\"\ntags = { \"Gameplay\" \"Events\" }\n")
+	m := &Metadata{Name: "My Mod", Version: "1.3", SupportedGameVersion: "1.13.*", Tags: []string{"Events", "Gameplay"}}
+	want := []Mismatch{{Key: "version", Descriptor: "1.2", Metadata: "1.3"}}
+	if got := Mismatches(d, m); !reflect.DeepEqual(got, want) {
+		t.Errorf("Mismatches = %+v, want %+v", got, want)
+	}
+
+	m = &Metadata{Name: "My Other Mod", Version: "1.2", Tags: []string{"Events"}}
+	want = []Mismatch{
+		{Key: "name", Descriptor: "My Mod", Metadata: "My Other Mod"},
+		{Key: "supported_version", Descriptor: "1.13.*", Metadata: ""},
+		{Key: "tags", Descriptor: "Events, Gameplay", Metadata: "Events"},
+	}
+	if got := Mismatches(d, m); !reflect.DeepEqual(got, want) {
+		t.Errorf("Mismatches = %+v, want %+v", got, want)
+	}
+}
+
+func TestUpdateDescriptor(t *testing.T) {
+	info := Info{Name: `My "Best" Mod`, Version: "2.0", SupportedVersion: "1.14.*", Tags: []string{"Events", "Dvůr"}}
+	tests := []struct {
+		name, text, want string
+	}{
+		{
+			name: "new",
+			text: "",
+			want: "version=\"2.0\"\ntags={\n\t\"Events\"\n\t\"Dvůr\"\n}\nname=\"My \\\"Best\\\" Mod\"\nsupported_version=\"1.14.*\"\n",
+		},
+		{
+			name: "existing fields",
+			text: "# My mod\nversion=\"1.0\"\ntags={\n\t\"Gameplay\"\n}\nname=\"My Mod\"\npicture=\"thumbnail.png\"\nsupported_version=\"1.13.*\"\nremote_file_id=\"123\"",
+			want: "# My mod\nversion=\"2.0\"\ntags={\n\t\"Events\"\n\t\"Dvůr\"\n}\nname=\"My \\\"Best\\\" Mod\"\npicture=\"thumbnail.png\"\nsupported_version=\"1.14.*\"\nremote_file_id=\"123\"",
+		},
+		{
+			name: "missing fields",
+			text: "name = \"My Mod\"\npath = \"mod/my_mod\"",
+			want: "name = \"My \\\"Best\\\" Mod\"\npath = \"mod/my_mod\"\nversion=\"2.0\"\ntags={\n\t\"Events\"\n\t\"Dvůr\"\n}\nsupported_version=\"1.14.*\"\n",
+		},
+		{
+			name: "CRLF",
+			text: "version=\"1.0\"\r\nname=\"My Mod\"\r\n",
+			want: "version=\"2.0\"\r\nname=\"My \\\"Best\\\" Mod\"\r\ntags={\r\n\t\"Events\"\r\n\t\"Dvůr\"\r\n}\r\nsupported_version=\"1.14.*\"\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UpdateDescriptor(tt.text, info)
+			if got != tt.want {
+				t.Errorf("UpdateDescriptor =\n%q\nwant\n%q", got, tt.want)
+			}
+			d := ParseDescriptor(DescriptorFile, got)
+			if len(d.File.Errors) > 0 || !reflect.DeepEqual(d.Info(), info) {
+				t.Errorf("the updated descriptor reads as %+v, %v, want %+v", d.Info(), d.File.Errors, info)
+			}
+		})
+	}
+}
+
+func TestSync(t *testing.T) {
+	const descriptor = "version=\"1.2\"\ntags={\n\t\"Events\"\n}\nname=\"My Mod\"\nsupported_version=\"1.13.*\"\npath=\"mod/my_mod\"\n"
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{DescriptorFile: descriptor})
+
+	// The metadata is created from the descriptor.
+	written, err := Sync(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(root, MetadataFile)}; !reflect.DeepEqual(written, want) {
+		t.Errorf("Sync wrote %q, want %q", written, want)
+	}
+	m, err := ReadMetadata(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Info{Name: "My Mod", Version: "1.2", SupportedVersion: "1.13.*", Tags: []string{"Events"}}); !reflect.DeepEqual(m.Info(), want) {
+		t.Errorf("metadata = %+v, want %+v", m.Info(), want)
+	}
+
+	// Fields of the metadata the descriptor lacks are kept.
+	writeFiles(t, root, map[string]string{filepath.ToSlash(MetadataFile): `{"name": "My Mod", "version": "1.3", "supported_game_version": "1.13.*", "tags": [], "id": "my.mod", "game_custom_data": {"multiplayer_synchronized": true}}`})
+	if _, err := Sync(root, "metadata"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, DescriptorFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(strings.Replace(descriptor, "1.2", "1.3", 1), "{\n\t\"Events\"\n}", "{\n}", 1); string(data) != want {
+		t.Errorf("descriptor =\n%s\nwant\n%s", data, want)
+	}
+	if _, err := Sync(root, "descriptor"); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(filepath.Join(root, MetadataFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["id"] != "my.mod" || fields["game_custom_data"] == nil || fields["version"] != "1.3" {
+		t.Errorf("metadata fields = %v", fields)
+	}
+	d, err := ReadDescriptor(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err = ReadMetadata(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := Mismatches(d, m); len(list) != 0 {
+		t.Errorf("mismatches after Sync = %+v", list)
+	}
+}
+
+func TestSyncErrors(t *testing.T) {
+	root := t.TempDir()
+	if _, err := Sync(root, ""); err == nil || !strings.Contains(err.Error(), "reading .metadata/metadata.json") {
+		t.Errorf("Sync of an empty folder: error = %v", err)
+	}
+	if _, err := Sync(root, "descriptor"); err == nil || !strings.Contains(err.Error(), "reading descriptor.mod") {
+		t.Errorf("Sync from a missing descriptor: error = %v", err)
+	}
+	if _, err := Sync(root, "launcher"); err == nil || err.Error() != `unknown source "launcher"; expected descriptor or metadata` {
+		t.Errorf("Sync from an unknown source: error = %v", err)
+	}
+
+	// The descriptor is created from the metadata.
+	writeFiles(t, root, map[string]string{filepath.ToSlash(MetadataFile): `{"name": "My Mod", "version": "1.0"}`})
+	if _, err := Sync(root, ""); err != nil {
+		t.Fatal(err)
+	}
+	d, err := ReadDescriptor(root)
+	if err != nil || d.Name != "My Mod" || d.Version != "1.0" {
+		t.Errorf("created descriptor = %+v, %v", d, err)
+	}
+
+	writeFiles(t, root, map[string]string{filepath.ToSlash(MetadataFile): `{"name": `})
+	if err := WriteMetadata(root, Info{Name: "My Mod"}); err == nil || !strings.Contains(err.Error(), "reading .metadata/metadata.json") {
+		t.Errorf("WriteMetadata over invalid JSON: error = %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package mod
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// launcherSettings are the places of launcher-settings.json, which records
+// the installed game version, relative to the game folder.
+var launcherSettings = []string{
+	filepath.Join("..", "launcher", "launcher-settings.json"),
+	filepath.Join("launcher-settings.json"),
+}
+
+var numericVersion = regexp.MustCompile(`\d+(\.\d+)+`)
+
+// GameVersion returns the version of the game installed at gamePath, such
+// as "1.13.2", or "" if it cannot be determined.
+func GameVersion(gamePath string) string {
+	for _, rel := range launcherSettings {
+		data, err := os.ReadFile(filepath.Join(gamePath, rel))
+		if err != nil {
+			continue
+		}
+		var settings struct {
+			RawVersion string `json:"rawVersion"`
+			Version    string `json:"version"`
+		}
+		if json.Unmarshal(data, &settings) != nil {
+			continue
+		}
+		for _, v := range []string{settings.RawVersion, settings.Version} {
+			if m := numericVersion.FindString(v); m != "" {
+				return m
+			}
+		}
+	}
+	return ""
+}
+
+// Outdated reports whether the installed game version is newer than every
+// version the supported_version pattern (e.g. "1.12.*") allows.
+func Outdated(supported, installed string) bool {
+	if supported == "" || installed == "" {
+		return false
+	}
+	want, have := strings.Split(supported, "."), strings.Split(installed, ".")
+	for i, part := range want {
+		if part == "*" {
+			return false
+		}
+		if i >= len(have) {
+			return false
+		}
+		w, err1 := strconv.Atoi(part)
+		h, err2 := strconv.Atoi(have[i])
+		if err1 != nil || err2 != nil || w != h {
+			return err1 == nil && err2 == nil && h > w
+		}
+	}
+	return false
+}
+
+// BumpVersion returns supported updated to cover installed, keeping its
+// shape: "1.12.*" becomes "1.13.*" for the installed version 1.13.2.
+func BumpVersion(supported, installed string) string {
+	want, have := strings.Split(supported, "."), strings.Split(installed, ".")
+	if n := len(want); n > 0 && want[n-1] == "*" && n <= len(have) {
+		return strings.Join(append(have[:n-1:n-1], "*"), ".")
+	}
+	return installed
+}
@@ -0,0 +1,181 @@
+package pdx
+
+// Op is the operator joining a field's key and value.
+type Op string
+
+const (
+	OpNone         Op = ""
+	OpAssign       Op = "="
+	OpEqual        Op = "=="
+	OpNotEqual     Op = "!="
+	OpLess         Op = "<"
+	OpLessEqual    Op = "<="
+	OpGreater      Op = ">"
+	OpGreaterEqual Op = ">="
+	OpExists       Op = "?="
+)
+
+// Error is a syntax problem found while lexing or parsing.
+type Error struct {
+	Range Range
+	Msg   string
+}
+
+func (e Error) Error() string {
+	return e.Range.Start.String() + ": " + e.Msg
+}
+
+// File is a parsed source file. Root is a synthetic block holding the
+// top-level fields.
+type File struct {
+	Path     string
+	Text     string
+	Root     *Block
+	Comments []Token
+	Errors   []Error
+}
+
+// Value is either a *Scalar or a *Block.
+type Value interface {
+	Range() Range
+}
+
+// Scalar is a bare word or a quoted string.
+type Scalar struct {
+	Text   string
+	Quoted bool
+	Loc    Range
+}
+
+func (s *Scalar) Range() Range { return s.Loc }
+
+// Block is a brace-delimited list of fields. Tag holds an optional word
+// written before the opening brace, as in `color = rgb { ... }` or
+// `template name { ... }`.
+type Block struct {
+	Tag    *Scalar
+	Fields []*Field
+	Loc    Range
+	Closed bool
+	Parent *Field
+}
+
+func (b *Block) Range() Range { return b.Loc }
+
+// Field is a single entry of a block: `key op value`, or a bare value in a
+// list such as `{ a b c }`, in which case Key is nil.
+type Field struct {
+	Key     *Scalar
+	Op      Op
+	OpRange Range
+	Value   Value
+	Parent  *Block
+}
+
+// Range spans the key (if any) through the end of the value.
+func (f *Field) Range() Range {
+	r := Range{}
+	if f.Value != nil {
+		r = f.Value.Range()
+	}
+	if f.Key != nil {
+		r.Start = f.Key.Loc.Start
+		if f.Value == nil {
+			r.End = f.Key.Loc.End
+			if f.Op != OpNone {
+				r.End = f.OpRange.End
+			}
+		}
+	}
+	return r
+}
+
+// KeyText returns the key of the field, or "" for bare values and a nil
+// field.
+func (f *Field) KeyText() string {
+	if f == nil || f.Key == nil {
+		return ""
+	}
+	return f.Key.Text
+}
+
+// Block returns the field's value as a block, or nil.
+func (f *Field) Block() *Block {
+	b, _ := f.Value.(*Block)
+	return b
+}
+
+// Scalar returns the field's value as a scalar, or nil.
+func (f *Field) Scalar() *Scalar {
+	s, _ := f.Value.(*Scalar)
+	return s
+}
+
+// ValueText returns the text of a scalar value, or "" for blocks.
+func (f *Field) ValueText() string {
+	if s := f.Scalar(); s != nil {
+		return s.Text
+	}
+	return ""
+}
+
+// ParentField returns the field whose block contains f, or nil at the top
+// level.
+func (f *Field) ParentField() *Field {
+	if f.Parent == nil {
+		return nil
+	}
+	return f.Parent.Parent
+}
+
+// Get returns the first field with the given key, or nil.
+func (b *Block) Get(key string) *Field {
+	for _, f := range b.Fields {
+		if f.KeyText() == key {
+			return f
+		}
+	}
+	return nil
+}
+
+// All returns every field with the given key, in source order.
+func (b *Block) All(key string) []*Field {
+	var fields []*Field
+	for _, f := range b.Fields {
+		if f.KeyText() == key {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// Walk calls fn for every field below b in depth-first source order. If fn
+// returns false the field's children are skipped.
+func Walk(b *Block, fn func(f *Field) bool) {
+	if b == nil {
+		return
+	}
+	for _, f := range b.Fields {
+		if fn(f) {
+			Walk(f.Block(), fn)
+		}
+	}
+}
+
+// PathAt returns the chain of fields enclosing pos, outermost first.
+func (file *File) PathAt(pos Pos) []*Field {
+	var path []*Field
+	b := file.Root
+	for b != nil {
+		var next *Block
+		for _, f := range b.Fields {
+			if f.Range().Contains(pos) {
+				path = append(path, f)
+				next = f.Block()
+				break
+			}
+		}
+		b = next
+	}
+	return path
+}
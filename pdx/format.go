@@ -0,0 +1,106 @@
+package pdx
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUnformattable is returned by Format for source with syntax errors,
+// whose structure the formatter cannot trust.
+var ErrUnformattable = errors.New("cannot format a file with syntax errors")
+
+// Format pretty-prints PDXScript source: every line is indented with one
+// tab per enclosing block, tokens on a line are separated by single spaces
+// (so `key=value` becomes `key = value` and `{a}` becomes `{ a }`), and
+// trailing whitespace is removed. Line breaks are kept where they are, so
+// line n of the result is line n of src reformatted, and the file ends in
+// exactly one newline. Comments, strings and inline math are copied
+// verbatim. Formatting formatted text returns it unchanged.
+func Format(src string) (string, error) {
+	if len(Parse("", src).Errors) > 0 {
+		return "", ErrUnformattable
+	}
+	newline := "\n"
+	if strings.Contains(src, "\r\n") {
+		newline = "\r\n"
+	}
+	var b strings.Builder
+	if strings.HasPrefix(src, bom) {
+		b.WriteString(bom)
+	}
+
+	lex := NewLexer(src)
+	depth := 0
+	prev := Token{Kind: EOF}
+	prevEnd := lex.pos.Offset
+	for {
+		tok := lex.Next()
+		gap := src[prevEnd:tok.Range.Start.Offset]
+		if tok.Kind == EOF {
+			b.WriteString(newline)
+			break
+		}
+		if tok.Kind == CloseBrace {
+			depth--
+		}
+		switch lines := strings.Count(gap, "\n"); {
+		case lines > 0 || prev.Kind == EOF:
+			b.WriteString(strings.Repeat(newline, lines))
+			b.WriteString(strings.Repeat("\t", depth))
+		case gap != "" || spaced(prev, tok):
+			b.WriteByte(' ')
+		}
+		text := src[tok.Range.Start.Offset:tok.Range.End.Offset]
+		if tok.Kind == Comment {
+			text = strings.TrimRight(text, " \t\r")
+		}
+		b.WriteString(text)
+		if tok.Kind == OpenBrace {
+			depth++
+		}
+		prev, prevEnd = tok, tok.Range.End.Offset
+	}
+	if prev.Kind == EOF {
+		return b.String()[:b.Len()-len(newline)], nil
+	}
+	return b.String(), nil
+}
+
+// spaced reports whether two tokens written without space between them
+// get one: around operators and braces, and before comments. Adjacent
+// words and strings, such as `"a"b`, stay together.
+func spaced(prev, tok Token) bool {
+	switch {
+	case prev.Kind == Operator || tok.Kind == Operator:
+		return true
+	case prev.Kind == OpenBrace || prev.Kind == CloseBrace, tok.Kind == OpenBrace || tok.Kind == CloseBrace:
+		return true
+	}
+	return tok.Kind == Comment
+}
+
+// LineDepth returns the number of tabs Format indents line n of src with:
+// the blocks open at the start of the line, less one if the line starts
+// with a closing brace. Unlike Format it works on incomplete source, as
+// while typing, and never goes below zero on unbalanced braces.
+func LineDepth(src string, n int) int {
+	lex := NewLexer(src)
+	depth := 0
+	for {
+		tok := lex.Next()
+		if tok.Kind == EOF || tok.Range.Start.Line > n {
+			return depth
+		}
+		switch {
+		case tok.Range.Start.Line == n:
+			if tok.Kind == CloseBrace && depth > 0 {
+				depth--
+			}
+			return depth
+		case tok.Kind == OpenBrace:
+			depth++
+		case tok.Kind == CloseBrace && depth > 0:
+			depth--
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package pdx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"spaces", "a=b\nc={d=1}\n", "a = b\nc = { d = 1 }\n"},
+		{"indentation", "a = {\nb = {\nc = yes\n}\n}\n", "a = {\n\tb = {\n\t\tc = yes\n\t}\n}\n"},
+		{"trailing space", "a = b   \n\n\n", "a = b\n"},
+		{"comments", "# header  \na=b # why\n  # inside\n", "# header\na = b # why\n# inside\n"},
+		{"strings", "a=\"x  =  {y}\"\n", "a = \"x  =  {y}\"\n"},
+		{"operators", "a>=1\nb!=c\nd?=e\n", "a >= 1\nb != c\nd ?= e\n"},
+		{"crlf", "a={\r\nb=c\r\n}\r\n", "a = {\r\n\tb = c\r\n}\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Format(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Format(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatIdempotent(t *testing.T) {
+	src := "namespace=my\n# The first event.\nmy.0001={type=character_event # a comment\ntitle = my.0001.t\n  option={\nname=my.0001.a\n    trigger={ age>=16 }\n# kept\n}\n    color = rgb{ 1 2 3 }\n}\n"
+	once, err := Format(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	twice, err := Format(once)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if once != twice {
+		t.Errorf("formatting again changed\n%s\ninto\n%s", once, twice)
+	}
+	for _, comment := range []string{"# The first event.", "# a comment", "# kept"} {
+		if !strings.Contains(once, comment) {
+			t.Errorf("comment %q is lost:\n%s", comment, once)
+		}
+	}
+	if strings.Count(once, "\n") != strings.Count(src, "\n") {
+		t.Errorf("the line count changed from %d to %d", strings.Count(src, "\n"), strings.Count(once, "\n"))
+	}
+}
+
+func TestFormatSyntaxError(t *testing.T) {
+	if _, err := Format("a = {\n"); err != ErrUnformattable {
+		t.Errorf("err = %v, want ErrUnformattable", err)
+	}
+}
@@ -0,0 +1,161 @@
+package pdx
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Lexer splits PDXScript source into tokens. It never fails: malformed input
+// is reported through Errors and lexing continues.
+type Lexer struct {
+	src    string
+	pos    Pos
+	Errors []Error
+}
+
+const bom = "\uFEFF"
+
+// NewLexer returns a lexer over src. A leading UTF-8 byte order mark, which
+// the game requires for localization and tolerates elsewhere, is skipped.
+func NewLexer(src string) *Lexer {
+	l := &Lexer{src: src}
+	if strings.HasPrefix(src, bom) {
+		l.pos.Offset = len(bom)
+	}
+	return l
+}
+
+// Next returns the next token, or a token of kind EOF at the end of input.
+func (l *Lexer) Next() Token {
+	l.skipSpace()
+	start := l.pos
+	if l.pos.Offset >= len(l.src) {
+		return Token{Kind: EOF, Range: Range{start, start}}
+	}
+
+	c := l.src[l.pos.Offset]
+	switch {
+	case c == '#':
+		for l.pos.Offset < len(l.src) && l.src[l.pos.Offset] != '\n' {
+			l.advance()
+		}
+		return l.token(Comment, start, l.src[start.Offset+1:l.pos.Offset])
+	case c == '{':
+		l.advance()
+		return l.token(OpenBrace, start, "{")
+	case c == '}':
+		l.advance()
+		return l.token(CloseBrace, start, "}")
+	case c == '"':
+		return l.lexString(start)
+	case isOperatorStart(c):
+		if op, ok := l.lexOperator(); ok {
+			return l.token(Operator, start, op)
+		}
+	}
+	return l.lexWord(start)
+}
+
+func (l *Lexer) token(kind TokenKind, start Pos, text string) Token {
+	return Token{Kind: kind, Text: text, Range: Range{start, l.pos}}
+}
+
+func (l *Lexer) lexString(start Pos) Token {
+	l.advance() // opening quote
+	var b strings.Builder
+	for {
+		if l.pos.Offset >= len(l.src) {
+			l.Errors = append(l.Errors, Error{Range{start, l.pos}, "unterminated string"})
+			return l.token(String, start, b.String())
+		}
+		c := l.src[l.pos.Offset]
+		if c == '"' {
+			l.advance()
+			return l.token(String, start, b.String())
+		}
+		if c == '\\' && l.pos.Offset+1 < len(l.src) {
+			l.advance()
+			c = l.src[l.pos.Offset]
+		}
+		r, _ := utf8.DecodeRuneInString(l.src[l.pos.Offset:])
+		b.WriteRune(r)
+		l.advance()
+	}
+}
+
+func (l *Lexer) lexOperator() (string, bool) {
+	rest := l.src[l.pos.Offset:]
+	for _, op := range []string{"==", "!=", "<=", ">=", "?=", "=", "<", ">"} {
+		if strings.HasPrefix(rest, op) {
+			for range op {
+				l.advance()
+			}
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (l *Lexer) lexWord(start Pos) Token {
+	// Inline math such as @[ a + b ] may contain spaces and operators.
+	if strings.HasPrefix(l.src[l.pos.Offset:], "@[") {
+		for l.pos.Offset < len(l.src) && l.src[l.pos.Offset] != ']' && l.src[l.pos.Offset] != '\n' {
+			l.advance()
+		}
+		if l.pos.Offset < len(l.src) && l.src[l.pos.Offset] == ']' {
+			l.advance()
+		} else {
+			l.Errors = append(l.Errors, Error{Range{start, l.pos}, "unterminated inline math expression"})
+		}
+		return l.token(Word, start, l.src[start.Offset:l.pos.Offset])
+	}
+	for l.pos.Offset < len(l.src) {
+		c := l.src[l.pos.Offset]
+		if isSpace(c) || c == '{' || c == '}' || c == '#' || c == '"' {
+			break
+		}
+		if isOperatorStart(c) && l.pos.Offset > start.Offset {
+			if _, ok := l.peekOperator(); ok {
+				break
+			}
+		}
+		l.advance()
+	}
+	return l.token(Word, start, l.src[start.Offset:l.pos.Offset])
+}
+
+func (l *Lexer) peekOperator() (string, bool) {
+	saved := l.pos
+	op, ok := l.lexOperator()
+	l.pos = saved
+	return op, ok
+}
+
+func (l *Lexer) skipSpace() {
+	for l.pos.Offset < len(l.src) && isSpace(l.src[l.pos.Offset]) {
+		l.advance()
+	}
+}
+
+// advance moves past one rune, keeping line and UTF-16 column in sync.
+func (l *Lexer) advance() {
+	r, size := utf8.DecodeRuneInString(l.src[l.pos.Offset:])
+	l.pos.Offset += size
+	switch {
+	case r == '\n':
+		l.pos.Line++
+		l.pos.Col = 0
+	case r >= 0x10000:
+		l.pos.Col += 2
+	default:
+		l.pos.Col++
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isOperatorStart(c byte) bool {
+	return c == '=' || c == '!' || c == '<' || c == '>' || c == '?'
+}
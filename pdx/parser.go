@@ -0,0 +1,164 @@
+package pdx
+
+// Parse parses src into a File. Parsing is tolerant: syntax errors are
+// collected in File.Errors and the parser recovers so that the rest of the
+// file still produces a usable tree.
+func Parse(path, src string) *File {
+	p := &parser{lex: NewLexer(src)}
+	p.next()
+
+	root := &Block{Closed: true}
+	root.Loc.Start = p.tok.Range.Start
+	p.parseFields(root, true)
+	root.Loc.End = p.tok.Range.End
+
+	errors := append(p.lex.Errors, p.errors...)
+	return &File{
+		Path:     path,
+		Text:     src,
+		Root:     root,
+		Comments: p.comments,
+		Errors:   errors,
+	}
+}
+
+type parser struct {
+	lex      *Lexer
+	tok      Token
+	ahead    []Token
+	comments []Token
+	errors   []Error
+}
+
+// next advances to the next non-comment token.
+func (p *parser) next() {
+	if len(p.ahead) > 0 {
+		p.tok = p.ahead[0]
+		p.ahead = p.ahead[1:]
+		return
+	}
+	p.tok = p.lexToken()
+}
+
+// peek returns the n-th token after the current one without consuming it.
+func (p *parser) peek(n int) Token {
+	for len(p.ahead) < n {
+		p.ahead = append(p.ahead, p.lexToken())
+	}
+	return p.ahead[n-1]
+}
+
+func (p *parser) lexToken() Token {
+	for {
+		t := p.lex.Next()
+		if t.Kind != Comment {
+			return t
+		}
+		p.comments = append(p.comments, t)
+	}
+}
+
+func (p *parser) errorf(r Range, msg string) {
+	p.errors = append(p.errors, Error{Range: r, Msg: msg})
+}
+
+// parseFields reads fields into b until the closing brace (or end of file
+// for the top level).
+func (p *parser) parseFields(b *Block, top bool) {
+	for {
+		switch p.tok.Kind {
+		case EOF:
+			return
+		case CloseBrace:
+			if !top {
+				return
+			}
+			p.errorf(p.tok.Range, "unexpected '}' without matching '{'")
+			p.next()
+		case Operator:
+			p.errorf(p.tok.Range, "unexpected operator '"+p.tok.Text+"' without a key")
+			p.next()
+		case OpenBrace:
+			f := &Field{Parent: b}
+			f.Value = p.parseBlock(f, nil)
+			b.Fields = append(b.Fields, f)
+		default:
+			b.Fields = append(b.Fields, p.parseField(b))
+		}
+	}
+}
+
+func (p *parser) parseField(parent *Block) *Field {
+	key := p.scalar()
+	f := &Field{Parent: parent}
+	next := p.peek(1)
+
+	switch {
+	case next.Kind == Operator:
+		p.next()
+		f.Key = key
+		f.Op = Op(p.tok.Text)
+		f.OpRange = p.tok.Range
+		p.next()
+		f.Value = p.parseValue(f)
+	case next.Kind == OpenBrace:
+		// `window { ... }` as written in GUI files.
+		p.next()
+		f.Key = key
+		f.Value = p.parseBlock(f, nil)
+	case (next.Kind == Word || next.Kind == String) && p.peek(2).Kind == OpenBrace:
+		// `template name { ... }`, `blockoverride "name" { ... }`.
+		p.next()
+		f.Key = key
+		tag := p.scalar()
+		p.next()
+		f.Value = p.parseBlock(f, tag)
+	default:
+		p.next()
+		f.Value = key
+	}
+	return f
+}
+
+func (p *parser) parseValue(f *Field) Value {
+	switch p.tok.Kind {
+	case OpenBrace:
+		return p.parseBlock(f, nil)
+	case Word, String:
+		s := p.scalar()
+		if p.peek(1).Kind == OpenBrace {
+			p.next()
+			return p.parseBlock(f, s)
+		}
+		p.next()
+		return s
+	default:
+		p.errorf(f.OpRange, "missing value after '"+string(f.Op)+"'")
+		return nil
+	}
+}
+
+// parseBlock parses a block starting at the current '{' token.
+func (p *parser) parseBlock(owner *Field, tag *Scalar) *Block {
+	b := &Block{Tag: tag, Parent: owner}
+	open := p.tok.Range
+	b.Loc.Start = open.Start
+	if tag != nil {
+		b.Loc.Start = tag.Loc.Start
+	}
+	p.next()
+	p.parseFields(b, false)
+	if p.tok.Kind == CloseBrace {
+		b.Closed = true
+		b.Loc.End = p.tok.Range.End
+		p.next()
+	} else {
+		p.errorf(open, "unclosed '{'")
+		b.Loc.End = p.tok.Range.End
+	}
+	return b
+}
+
+func (p *parser) scalar() *Scalar {
+	return &Scalar{Text: p.tok.Text, Quoted: p.tok.Kind == String, Loc: p.tok.Range}
+}
@@ -0,0 +1,136 @@
+package pdx
+
+import "testing"
+
+func TestParseOperators(t *testing.T) {
+	src := "a = 1\nb == 2\nc != 3\nd < 4\ne <= 5\nf > 6\ng >= 7\nh ?= 8\n"
+	file := Parse("", src)
+	if len(file.Errors) > 0 {
+		t.Fatalf("errors: %v", file.Errors)
+	}
+	want := []Op{OpAssign, OpEqual, OpNotEqual, OpLess, OpLessEqual, OpGreater, OpGreaterEqual, OpExists}
+	if len(file.Root.Fields) != len(want) {
+		t.Fatalf("got %d fields, want %d", len(file.Root.Fields), len(want))
+	}
+	for i, f := range file.Root.Fields {
+		if f.Op != want[i] {
+			t.Errorf("field %d: op %q, want %q", i, f.Op, want[i])
+		}
+		if f.Scalar() == nil {
+			t.Errorf("field %d: value %T, want a scalar", i, f.Value)
+		}
+	}
+}
+
+func TestParseBlocks(t *testing.T) {
+	src := "my.0001 = {\n\ttype = character_event\n\toption = {\n\t\tname = my.0001.a\n\t}\n\tlist = { a \"b c\" 3 }\n}\n"
+	file := Parse("", src)
+	if len(file.Errors) > 0 {
+		t.Fatalf("errors: %v", file.Errors)
+	}
+	event := file.Root.Get("my.0001")
+	if event == nil || event.Block() == nil || !event.Block().Closed {
+		t.Fatalf("event = %+v, want a closed block", event)
+	}
+	if got := event.Block().Get("type").ValueText(); got != "character_event" {
+		t.Errorf("type = %q, want character_event", got)
+	}
+	option := event.Block().Get("option")
+	if option.ParentField() != event {
+		t.Error("the parent of option is not the event")
+	}
+	if got := option.Block().Get("name").ValueText(); got != "my.0001.a" {
+		t.Errorf("option name = %q, want my.0001.a", got)
+	}
+	list := event.Block().Get("list").Block().Fields
+	if len(list) != 3 || list[0].Key != nil || list[1].ValueText() != "b c" || !list[1].Scalar().Quoted {
+		t.Errorf("list = %+v, want the bare values a, \"b c\" and 3", list)
+	}
+	r := event.Range()
+	if r.Start != (Pos{Offset: 0, Line: 0, Col: 0}) || r.End.Line != 6 || r.End.Col != 1 {
+		t.Errorf("event range = %v, want 0:0 to 6:1", r)
+	}
+}
+
+func TestParseTaggedBlocks(t *testing.T) {
+	src := "template my_template { size = { 10 20 } }\ntype my_button = button { text = \"x\" }\ncolor = rgb { 255 0 0 }\nwindow { }\n"
+	file := Parse("", src)
+	if len(file.Errors) > 0 {
+		t.Fatalf("errors: %v", file.Errors)
+	}
+	// `type a = b { }` is the bare word type, then a block keyed by a and
+	// tagged with its base type b.
+	tests := []struct {
+		key, tag string
+		op       Op
+		block    bool
+	}{
+		{"template", "my_template", OpNone, true},
+		{"", "", OpNone, false},
+		{"my_button", "button", OpAssign, true},
+		{"color", "rgb", OpAssign, true},
+		{"window", "", OpNone, true},
+	}
+	if len(file.Root.Fields) != len(tests) {
+		t.Fatalf("got %d fields, want %d", len(file.Root.Fields), len(tests))
+	}
+	for i, tt := range tests {
+		f := file.Root.Fields[i]
+		if f.KeyText() != tt.key || f.Op != tt.op || (f.Block() != nil) != tt.block {
+			t.Errorf("field %d = %q %q %T, want %q %q", i, f.KeyText(), f.Op, f.Value, tt.key, tt.op)
+			continue
+		}
+		if !tt.block {
+			if f.ValueText() != "type" {
+				t.Errorf("field %d = %q, want the bare word type", i, f.ValueText())
+			}
+			continue
+		}
+		tag := ""
+		if f.Block().Tag != nil {
+			tag = f.Block().Tag.Text
+		}
+		if tag != tt.tag {
+			t.Errorf("field %d: tag %q, want %q", i, tag, tt.tag)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name, src, msg string
+		start          Pos
+	}{
+		{"unterminated string", "a = \"never closed\nb = c\n", "unterminated string", Pos{Offset: 4, Line: 0, Col: 4}},
+		{"unclosed brace", "a = {\n\tb = c\n", "unclosed '{'", Pos{Offset: 4, Line: 0, Col: 4}},
+		{"extra brace", "a = b\n}\n", "unexpected '}' without matching '{'", Pos{Offset: 6, Line: 1, Col: 0}},
+		{"missing value", "a = {\n\tb =\n}\n", "missing value after '='", Pos{Offset: 9, Line: 1, Col: 3}},
+		{"operator without key", "= b\n", "unexpected operator '=' without a key", Pos{Offset: 0, Line: 0, Col: 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := Parse("", tt.src)
+			if len(file.Errors) == 0 {
+				t.Fatal("no errors")
+			}
+			for _, err := range file.Errors {
+				if err.Msg == tt.msg {
+					if err.Range.Start != tt.start {
+						t.Errorf("%q at %v, want %v", tt.msg, err.Range.Start, tt.start)
+					}
+					return
+				}
+			}
+			t.Errorf("errors = %v, want %q", file.Errors, tt.msg)
+		})
+	}
+}
+
+// TestParseTruncated checks that no prefix of a file, as while typing,
+// makes the parser panic.
+func TestParseTruncated(t *testing.T) {
+	src := "namespace = my\nmy.0001 = {\n\ttitle = \"my.0001.t\" # title\n\tcolor = rgb { 1 2 3 }\n\ttemplate x { a >= 1 }\n}\n"
+	for i := range src {
+		Parse("", src[:i])
+	}
+}
@@ -0,0 +1,75 @@
+// Package pdx implements a tolerant lexer and parser for PDXScript, the
+// key/value block language used by Crusader Kings 3 script, GUI and
+// descriptor files.
+package pdx
+
+import "fmt"
+
+// Pos is a position in a source file. Line and Col are zero-based, Col is
+// counted in UTF-16 code units to match the Language Server Protocol, and
+// Offset is the byte offset into the source text.
+type Pos struct {
+	Offset int
+	Line   int
+	Col    int
+}
+
+// Before reports whether p comes strictly before q. Only Line and Col are
+// compared so positions received from a client (without offsets) work too.
+func (p Pos) Before(q Pos) bool {
+	if p.Line != q.Line {
+		return p.Line < q.Line
+	}
+	return p.Col < q.Col
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line+1, p.Col+1)
+}
+
+// Range is a half-open span of source text.
+type Range struct {
+	Start Pos
+	End   Pos
+}
+
+// Contains reports whether p lies within r. The end position is treated as
+// inclusive so that a cursor placed right after a word still hits it.
+func (r Range) Contains(p Pos) bool {
+	return !p.Before(r.Start) && !r.End.Before(p)
+}
+
+// TokenKind identifies the lexical class of a Token.
+type TokenKind int
+
+const (
+	EOF TokenKind = iota
+	Word
+	String
+	Operator
+	OpenBrace
+	CloseBrace
+	Comment
+)
+
+var tokenKindNames = map[TokenKind]string{
+	EOF:        "end of file",
+	Word:       "word",
+	String:     "string",
+	Operator:   "operator",
+	OpenBrace:  "'{'",
+	CloseBrace: "'}'",
+	Comment:    "comment",
+}
+
+func (k TokenKind) String() string {
+	return tokenKindNames[k]
+}
+
+// Token is a single lexical element. For strings Text holds the unquoted
+// contents; for comments it holds everything after the '#'.
+type Token struct {
+	Kind  TokenKind
+	Text  string
+	Range Range
+}
@@ -0,0 +1,94 @@
+package scope
+
+import "github.com/unLomTrois/gock3-lsp/pdx"
+
+// Context tells whether a block holds triggers or effects.
+type Context int
+
+const (
+	ContextUnknown Context = iota
+	ContextTrigger
+	ContextEffect
+)
+
+func (c Context) String() string {
+	switch c {
+	case ContextTrigger:
+		return "trigger"
+	case ContextEffect:
+		return "effect"
+	}
+	return "unknown"
+}
+
+// triggerBlocks are the keys whose blocks hold triggers.
+var triggerBlocks = map[string]bool{
+	"trigger": true, "limit": true, "alternative_limit": true, "potential": true,
+	"allow": true, "is_shown": true, "is_valid": true, "is_valid_showing_failures_only": true,
+	"is_highlighted": true, "can_start": true, "can_start_showing_failures_only": true,
+	"can_send": true, "can_be_picked": true, "is_available": true, "show_as_unavailable": true,
+	"trigger_if": true, "trigger_else_if": true, "trigger_else": true, "modifier": true,
+	"AND": true, "OR": true, "NOT": true, "NOR": true, "NAND": true, "all_false": true, "any_false": true,
+	"can_be_picked_artifact": true, "is_valid_target": true, "exists": true, "valid": true,
+}
+
+// effectBlocks are the keys whose blocks hold effects.
+var effectBlocks = map[string]bool{
+	"immediate": true, "effect": true, "option": true, "after": true, "hidden_effect": true,
+	"if": true, "else_if": true, "else": true, "while": true, "random_list": true, "random": true,
+	"on_accept": true, "on_decline": true, "on_send": true, "on_auto_accept": true,
+	"on_start": true, "on_end": true, "on_invalidated": true, "on_monthly": true, "on_yearly": true,
+	"on_success": true, "on_failure": true, "on_complete": true, "on_cancel": true,
+	"on_discover": true, "on_expose": true, "on_owner_death": true, "show_as_tooltip": true,
+}
+
+// folderContexts are the contexts of the top-level definitions of script
+// folders made of bare triggers or effects.
+var folderContexts = map[string]Context{
+	"common/scripted_triggers/": ContextTrigger,
+	"common/scripted_effects/":  ContextEffect,
+}
+
+// ContextOf returns whether the field f of a file at vpath is written
+// among triggers or effects, going by the nearest enclosing block that
+// decides it.
+func ContextOf(f *pdx.Field, vpath string) Context {
+	for p := f.ParentField(); p != nil; p = p.ParentField() {
+		if c := BlockContext(p.KeyText()); c != ContextUnknown {
+			return c
+		}
+	}
+	if folder, ok := ScriptFolder(vpath); ok {
+		return folderContexts[folder]
+	}
+	return ContextUnknown
+}
+
+// BlockContext returns the context of the block of a field with the given
+// key, or ContextUnknown if the key does not decide it.
+func BlockContext(key string) Context {
+	switch {
+	case triggerBlocks[key]:
+		return ContextTrigger
+	case effectBlocks[key]:
+		return ContextEffect
+	}
+	if prefix, _, _, ok := Iterator(key); ok || prefix != "" && !IsNonIterator(key) {
+		if prefix == "any" {
+			return ContextTrigger
+		}
+		return ContextEffect
+	}
+	return ContextUnknown
+}
+
+// nonIterators are keys starting with an iterator prefix that are not
+// iterators.
+var nonIterators = map[string]bool{
+	"random_list": true, "any_false": true, "random_valid": true,
+}
+
+// IsNonIterator reports whether key merely looks like an iterator.
+func IsNonIterator(key string) bool {
+	return nonIterators[key]
+}
@@ -0,0 +1,310 @@
+package scope
+
+import (
+	"strings"
+
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+// Frame is the scope of a block.
+type Frame struct {
+	This Type
+	Root Type
+	// Prev is the frame the block was entered from, or nil at the top.
+	Prev *Frame
+	// Saved maps the names of saved scopes (scope:name) of the enclosing
+	// definition to their types.
+	Saved map[string]Type
+	// Field is the field whose block opened the frame, or nil at the top.
+	Field *pdx.Field
+}
+
+// PrevType returns the type of `prev`.
+func (f *Frame) PrevType() Type {
+	if f.Prev == nil {
+		return Unknown
+	}
+	return f.Prev.This
+}
+
+// ChainError describes the first invalid link of an event target chain.
+type ChainError struct {
+	// Start and End are the byte offsets of the link in the chain.
+	Start, End int
+	Msg        string
+}
+
+// Resolve follows a dotted event target chain such as
+// "root.liege.primary_title" from f and returns the resulting type. A link
+// the engine does not know gives Unknown without an error; a link that is
+// not valid for the scope before it gives an error.
+func (f *Frame) Resolve(chain string) (Type, *ChainError) {
+	t := f.This
+	offset := 0
+	for i, part := range strings.Split(chain, ".") {
+		start, end := offset, offset+len(part)
+		offset = end + 1
+		name := strings.ToLower(part)
+		switch {
+		case name == "root" && i == 0:
+			t = f.Root
+		case name == "this" && i == 0:
+			t = f.This
+		case name == "prev" && i == 0:
+			t = f.PrevType()
+		case strings.HasPrefix(name, "scope:") && i == 0:
+			t = f.Saved[part[len("scope:"):]]
+		case strings.Contains(name, ":"):
+			// Database lookups like title:k_france or var:x.
+			t = prefixTypes[name[:strings.Index(name, ":")]]
+		default:
+			l, ok := links[name]
+			if !ok {
+				t = Unknown
+				continue
+			}
+			if !l.ValidFrom(t) {
+				return Unknown, &ChainError{Start: start, End: end, Msg: linkError(part, t, l)}
+			}
+			t = l.To
+		}
+	}
+	return t, nil
+}
+
+// IsChain reports whether s reads as a dotted event target chain, such as
+// "root.liege" or "scope:target.primary_title", rather than an event ID,
+// a number or a localization key.
+func IsChain(s string) bool {
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 {
+		return false
+	}
+	for _, part := range parts {
+		if !isLinkName(part) {
+			return false
+		}
+	}
+	return IsChainHead(parts[0])
+}
+
+// IsChainHead reports whether name can start an event target chain.
+func IsChainHead(name string) bool {
+	name = strings.ToLower(name)
+	switch {
+	case name == "root", name == "this", name == "prev", strings.HasPrefix(name, "scope:"):
+		return true
+	case strings.Contains(name, ":"):
+		_, ok := prefixTypes[name[:strings.Index(name, ":")]]
+		return ok
+	}
+	_, ok := links[name]
+	return ok
+}
+
+// isLinkName reports whether part is a plain word usable as a chain link.
+func isLinkName(part string) bool {
+	if part == "" || part[0] >= '0' && part[0] <= '9' {
+		return false
+	}
+	for _, r := range part {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// prefixTypes are the types of database lookups like `title:k_france`.
+var prefixTypes = map[string]Type{
+	"title": LandedTitle, "character": Character, "faith": Faith, "religion": Religion,
+	"culture": Culture, "dynasty": Dynasty, "house": House, "province": Province,
+	"geographical_region": Region, "cp": Character,
+}
+
+func linkError(name string, t Type, l Link) string {
+	return name + " is not valid in " + string(t) + " scope; it needs " + TypeList(l.From)
+}
+
+// TypeList joins scope types as "a, b or c".
+func TypeList(types []Type) string {
+	var b strings.Builder
+	for i, t := range types {
+		switch {
+		case i == 0:
+		case i == len(types)-1:
+			b.WriteString(" or ")
+		default:
+			b.WriteString(", ")
+		}
+		b.WriteString(string(t))
+	}
+	return b.String()
+}
+
+// rootTypes maps script folders to the root scope type of their
+// definitions.
+var rootTypes = map[string]Type{
+	"events/":                           Character,
+	"common/decisions/":                 Character,
+	"common/character_interactions/":    Character,
+	"common/schemes/":                   Character,
+	"common/story_cycles/":              StoryCycle,
+	"common/activities/activity_types/": Activity,
+	"common/traits/":                    Character,
+	"common/casus_belli_types/":         Character,
+	"common/scripted_effects/":          Unknown,
+	"common/scripted_triggers/":         Unknown,
+	"common/script_values/":             Unknown,
+	"common/on_action/":                 Unknown,
+}
+
+// savedScopes are the scopes the game saves before running a definition,
+// by folder.
+var savedScopes = map[string]map[string]Type{
+	"common/character_interactions/": {"actor": Character, "recipient": Character, "secondary_actor": Character, "secondary_recipient": Character},
+	"common/schemes/":                {"owner": Character, "target": Character},
+	"common/casus_belli_types/":      {"attacker": Character, "defender": Character},
+}
+
+// eventScopes maps the `scope = x` of an event to its root type.
+var eventScopes = map[string]Type{
+	"none": None, "character": Character, "landed_title": LandedTitle, "province": Province,
+	"faith": Faith, "culture": Culture, "dynasty": Dynasty, "dynasty_house": House,
+	"war": War, "activity": Activity, "scheme": Scheme, "struggle": Struggle,
+}
+
+// EventRoot returns the root type of the event defined by b: that of its
+// `scope = x`, or character.
+func EventRoot(b *pdx.Block) Type {
+	if s := b.Get("scope"); s != nil {
+		return eventScopes[s.ValueText()]
+	}
+	return Character
+}
+
+// ScriptFolder reports whether files at vpath hold script whose scopes the
+// engine tracks, and returns the folder prefix.
+func ScriptFolder(vpath string) (string, bool) {
+	for prefix := range rootTypes {
+		if strings.HasPrefix(vpath, prefix) {
+			return prefix, true
+		}
+	}
+	return "", false
+}
+
+// Tree holds the inferred frame of every block of a file.
+type Tree struct {
+	file   *pdx.File
+	frames map[*pdx.Block]*Frame
+}
+
+// Analyze infers the scopes of a script file at the virtual path vpath. It
+// returns nil for files outside the folders the engine knows.
+func Analyze(file *pdx.File, vpath string) *Tree {
+	folder, ok := ScriptFolder(vpath)
+	if !ok || file == nil {
+		return nil
+	}
+	t := &Tree{file: file, frames: map[*pdx.Block]*Frame{}}
+	for _, f := range file.Root.Fields {
+		b := f.Block()
+		if b == nil {
+			continue
+		}
+		root := rootTypes[folder]
+		if folder == "events/" {
+			root = EventRoot(b)
+		}
+		known := savedScopes[folder]
+		if folder == "common/on_action/" {
+			// The game sets the root and saved scopes of its own
+			// on_actions, which mods append to.
+			if oa, ok := onActions[f.KeyText()]; ok {
+				root, known = oa.Root, oa.Saved
+			}
+		}
+		saved := map[string]Type{}
+		for name, typ := range known {
+			saved[name] = typ
+		}
+		frame := &Frame{This: root, Root: root, Saved: saved, Field: f}
+		t.walk(b, frame)
+	}
+	return t
+}
+
+// walk records frame for b and infers the frames of its sub-blocks.
+func (t *Tree) walk(b *pdx.Block, frame *Frame) {
+	t.frames[b] = frame
+	for _, f := range b.Fields {
+		switch f.KeyText() {
+		case "save_scope_as", "save_temporary_scope_as":
+			if name := f.ValueText(); name != "" {
+				frame.Saved[name] = frame.This
+			}
+		}
+		sub := f.Block()
+		if sub == nil {
+			continue
+		}
+		if typ, ok := t.switchTo(frame, f); ok {
+			t.walk(sub, &Frame{This: typ, Root: frame.Root, Prev: frame, Saved: frame.Saved, Field: f})
+		} else {
+			t.walk(sub, frame)
+		}
+	}
+}
+
+// switchTo returns the scope type a block-valued field moves to, if its key
+// is an iterator, an event target or a chain.
+func (t *Tree) switchTo(frame *Frame, f *pdx.Field) (Type, bool) {
+	key := f.KeyText()
+	if key == "" {
+		return Unknown, false
+	}
+	if _, list, _, ok := Iterator(key); ok {
+		return list.To, true
+	}
+	if _, ok := links[key]; ok || strings.ContainsAny(key, ".:") || key == "root" || key == "prev" || key == "this" {
+		typ, _ := frame.Resolve(key)
+		return typ, true
+	}
+	return Unknown, false
+}
+
+// FrameOf returns the frame of a block of the file, or nil.
+func (t *Tree) FrameOf(b *pdx.Block) *Frame {
+	if t == nil {
+		return nil
+	}
+	return t.frames[b]
+}
+
+// At returns the frame of the innermost block containing pos, or nil
+// outside any definition.
+func (t *Tree) At(pos pdx.Pos) *Frame {
+	if t == nil {
+		return nil
+	}
+	var frame *Frame
+	for _, f := range t.file.PathAt(pos) {
+		if b := f.Block(); b != nil && b.Loc.Contains(pos) {
+			if fr := t.frames[b]; fr != nil {
+				frame = fr
+			}
+		}
+	}
+	return frame
+}
+
+// Parent returns the frame a field is written in.
+func (t *Tree) Parent(f *pdx.Field) *Frame {
+	if t == nil || f.Parent == nil {
+		return nil
+	}
+	return t.frames[f.Parent]
+}
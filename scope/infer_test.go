@@ -0,0 +1,247 @@
+package scope
+
+import (
+	"testing"
+
+	"github.com/unLomTrois/gock3-lsp/pdx"
+)
+
+func TestResolve(t *testing.T) {
+	frame := &Frame{
+		This:  Province,
+		Root:  Character,
+		Prev:  &Frame{This: LandedTitle},
+		Saved: map[string]Type{"target": Character, "war": War},
+	}
+	tests := []struct {
+		chain      string
+		want       Type
+		start, end int
+		msg        string
+	}{
+		{chain: "root", want: Character},
+		{chain: "this", want: Province},
+		{chain: "prev", want: LandedTitle},
+		{chain: "root.liege.primary_title.holder", want: Character},
+		{chain: "ROOT.Liege", want: Character},
+		{chain: "scope:target.faith.religion", want: Religion},
+		{chain: "scope:war.primary_attacker", want: Character},
+		{chain: "title:k_france.holder", want: Character},
+		{chain: "county.holder", want: Character},
+		{chain: "prev.holder.capital_province", want: Province},
+		// Links the engine does not know give no error and an unknown type,
+		// which accepts any link after it.
+		{chain: "root.my_link.holder", want: Character},
+		{chain: "scope:unknown.holder", want: Character},
+		{chain: "var:x.holder", want: Character},
+		// Invalid links are reported with their offsets.
+		{chain: "liege", want: Unknown, start: 0, end: 5, msg: "liege is not valid in province scope; it needs character"},
+		{chain: "root.holder", want: Unknown, start: 5, end: 11, msg: "holder is not valid in character scope; it needs landed_title"},
+		{chain: "root.primary_title.faith.dynasty", want: Unknown, start: 25, end: 32, msg: "dynasty is not valid in faith scope; it needs character or dynasty_house"},
+		{chain: "scope:war.location", want: Unknown, start: 10, end: 18, msg: "location is not valid in war scope; it needs character, army, activity or epidemic"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.chain, func(t *testing.T) {
+			got, err := frame.Resolve(tt.chain)
+			if got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.chain, got, tt.want)
+			}
+			switch {
+			case tt.msg == "" && err != nil:
+				t.Errorf("Resolve(%q) error = %+v, want none", tt.chain, err)
+			case tt.msg != "" && err == nil:
+				t.Errorf("Resolve(%q) gave no error, want %q", tt.chain, tt.msg)
+			case err != nil && (err.Start != tt.start || err.End != tt.end || err.Msg != tt.msg):
+				t.Errorf("Resolve(%q) error = %+v, want {Start:%d End:%d Msg:%s}", tt.chain, *err, tt.start, tt.end, tt.msg)
+			}
+		})
+	}
+}
+
+func TestIsChain(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"root.liege", true},
+		{"scope:target.primary_title", true},
+		{"title:k_france.holder", true},
+		{"liege.primary_title", true},
+		{"root", false},
+		{"my.0001", false},
+		{"my_event.desc", false},
+		{"0.5", false},
+		{"root.liege-1", false},
+		{"bogus:x.holder", false},
+	}
+	for _, tt := range tests {
+		if got := IsChain(tt.s); got != tt.want {
+			t.Errorf("IsChain(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+// TestAnalyze checks the frames inferred for the blocks of script files,
+// found by the marker fields written in them.
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name, vpath, src string
+		// want maps markers to the this, root and prev types of the block
+		// they are in.
+		want map[string][3]Type
+	}{
+		{
+			name:  "event",
+			vpath: "events/my_events.txt",
+			src: `my.0001 = {
+	at_event = yes
+	immediate = {
+		at_immediate = yes
+		liege = {
+			at_liege = yes
+			primary_title = { at_title = yes }
+		}
+		every_vassal = {
+			at_vassal = yes
+			save_scope_as = vassal
+		}
+		scope:vassal = { at_saved = yes }
+		root.capital_province = { at_chain = yes }
+		title:k_france = { at_lookup = yes }
+		my_link = { at_unknown = yes }
+	}
+}
+`,
+			want: map[string][3]Type{
+				"at_event":     {Character, Character, Unknown},
+				"at_immediate": {Character, Character, Unknown},
+				"at_liege":     {Character, Character, Character},
+				"at_title":     {LandedTitle, Character, Character},
+				"at_vassal":    {Character, Character, Character},
+				"at_saved":     {Character, Character, Character},
+				"at_chain":     {Province, Character, Character},
+				"at_lookup":    {LandedTitle, Character, Character},
+				"at_unknown":   {Character, Character, Unknown},
+			},
+		},
+		{
+			name:  "event scope",
+			vpath: "events/my_events.txt",
+			src: `my.0002 = {
+	scope = landed_title
+	immediate = {
+		at_event = yes
+		holder = { prev = { at_prev = yes } }
+	}
+}
+`,
+			want: map[string][3]Type{
+				"at_event": {LandedTitle, LandedTitle, Unknown},
+				"at_prev":  {LandedTitle, LandedTitle, Character},
+			},
+		},
+		{
+			name:  "saved scopes of the folder",
+			vpath: "common/schemes/my_schemes.txt",
+			src: `my_scheme = {
+	on_start = {
+		scope:target = { at_target = yes }
+		scope:owner.primary_title = { at_title = yes }
+	}
+}
+`,
+			want: map[string][3]Type{
+				"at_target": {Character, Character, Character},
+				"at_title":  {LandedTitle, Character, Character},
+			},
+		},
+		{
+			name:  "on_action",
+			vpath: "common/on_action/my_on_actions.txt",
+			src: `on_game_start = { effect = { at_start = yes } }
+my_on_action = { effect = { every_ruler = { at_ruler = yes } } }
+`,
+			want: map[string][3]Type{
+				"at_start": {None, None, Unknown},
+				"at_ruler": {Character, Unknown, Unknown},
+			},
+		},
+		{
+			name:  "scripted effect",
+			vpath: "common/scripted_effects/my_effects.txt",
+			src:   "my_effect = { at_effect = yes liege = { at_liege = yes } }\n",
+			want: map[string][3]Type{
+				"at_effect": {Unknown, Unknown, Unknown},
+				"at_liege":  {Character, Unknown, Unknown},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := pdx.Parse(tt.vpath, tt.src)
+			tree := Analyze(file, tt.vpath)
+			if tree == nil {
+				t.Fatalf("Analyze(%s) = nil", tt.vpath)
+			}
+			found := map[string]bool{}
+			pdx.Walk(file.Root, func(f *pdx.Field) bool {
+				want, ok := tt.want[f.KeyText()]
+				if !ok {
+					return true
+				}
+				found[f.KeyText()] = true
+				frame := tree.Parent(f)
+				if frame == nil {
+					t.Errorf("%s: no frame", f.KeyText())
+					return true
+				}
+				if got := [3]Type{frame.This, frame.Root, frame.PrevType()}; got != want {
+					t.Errorf("%s: this, root, prev = %q, want %q", f.KeyText(), got, want)
+				}
+				if at := tree.At(f.Key.Loc.Start); at != frame {
+					t.Errorf("%s: At(%v) differs from the parent frame", f.KeyText(), f.Key.Loc.Start)
+				}
+				return true
+			})
+			for marker := range tt.want {
+				if !found[marker] {
+					t.Errorf("marker %s not found", marker)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzeOtherFolders(t *testing.T) {
+	for _, vpath := range []string{"gfx/interface/my.gui", "localization/english/my_l_english.yml", "history/characters/my.txt"} {
+		if tree := Analyze(pdx.Parse(vpath, "a = { b = c }\n"), vpath); tree != nil {
+			t.Errorf("Analyze(%s) = %v, want nil", vpath, tree)
+		}
+	}
+}
+
+func TestBlockContext(t *testing.T) {
+	tests := []struct {
+		key  string
+		want Context
+	}{
+		{"limit", ContextTrigger},
+		{"trigger_if", ContextTrigger},
+		{"any_vassal", ContextTrigger},
+		{"any_bogus_list", ContextTrigger},
+		{"immediate", ContextEffect},
+		{"every_vassal", ContextEffect},
+		{"random_courtier", ContextEffect},
+		{"ordered_child", ContextEffect},
+		{"random_list", ContextEffect},
+		{"any_false", ContextTrigger},
+		{"random_valid", ContextUnknown},
+		{"liege", ContextUnknown},
+		{"desc", ContextUnknown},
+	}
+	for _, tt := range tests {
+		if got := BlockContext(tt.key); got != tt.want {
+			t.Errorf("BlockContext(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
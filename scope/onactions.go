@@ -0,0 +1,184 @@
+package scope
+
+// OnAction describes an on_action the game fires by itself.
+type OnAction struct {
+	// Fired tells when the game fires the on_action.
+	Fired string
+	// Root is the type of the root scope, and RootDesc what it is.
+	Root     Type
+	RootDesc string
+	// Saved are the saved scopes the game sets before firing it, by name.
+	Saved map[string]Type
+}
+
+// onActions are the on_actions of the game, by name, as documented in
+// common/on_action/_on_actions.info.
+var onActions = map[string]OnAction{
+	"on_game_start": {
+		Fired: "Once when a new game starts, after history is applied and before the player picks a character.",
+		Root:  None,
+	},
+	"on_game_start_after_lobby": {
+		Fired: "Once when a new game starts, after the players picked their characters and left the lobby.",
+		Root:  None,
+	},
+	"yearly_global_pulse": {
+		Fired: "Once a year, on the first of January.",
+		Root:  None,
+	},
+	"yearly_playable_pulse": {
+		Fired:    "Once a year for every playable character, at a random day of the year.",
+		Root:     Character,
+		RootDesc: "the playable character",
+	},
+	"quarterly_playable_pulse": {
+		Fired:    "Every three months for every playable character.",
+		Root:     Character,
+		RootDesc: "the playable character",
+	},
+	"three_year_playable_pulse": {
+		Fired:    "Every three years for every playable character.",
+		Root:     Character,
+		RootDesc: "the playable character",
+	},
+	"five_year_playable_pulse": {
+		Fired:    "Every five years for every playable character.",
+		Root:     Character,
+		RootDesc: "the playable character",
+	},
+	"random_yearly_playable_pulse": {
+		Fired:    "Once a year for every playable character, picking one of its random events.",
+		Root:     Character,
+		RootDesc: "the playable character",
+	},
+	"random_yearly_everyone_pulse": {
+		Fired:    "Once a year for every living character, picking one of its random events.",
+		Root:     Character,
+		RootDesc: "the character",
+	},
+	"on_birth_child": {
+		Fired:    "When a character is born.",
+		Root:     Character,
+		RootDesc: "the newborn child",
+		Saved:    map[string]Type{"mother": Character, "father": Character, "real_father": Character},
+	},
+	"on_birth_mother": {
+		Fired:    "When a character gives birth.",
+		Root:     Character,
+		RootDesc: "the mother",
+		Saved:    map[string]Type{"child": Character, "father": Character, "real_father": Character},
+	},
+	"on_birth_father": {
+		Fired:    "When the child of a character is born.",
+		Root:     Character,
+		RootDesc: "the father the child is assumed to have",
+		Saved:    map[string]Type{"child": Character, "mother": Character, "real_father": Character},
+	},
+	"on_birth_real_father": {
+		Fired:    "When the child of a character is born.",
+		Root:     Character,
+		RootDesc: "the biological father",
+		Saved:    map[string]Type{"child": Character, "mother": Character, "father": Character},
+	},
+	"on_pregnancy_mother": {
+		Fired:    "When a character becomes pregnant.",
+		Root:     Character,
+		RootDesc: "the mother",
+		Saved:    map[string]Type{"father": Character, "real_father": Character},
+	},
+	"on_birthday": {
+		Fired:    "On every birthday of a character.",
+		Root:     Character,
+		RootDesc: "the character",
+	},
+	"on_16th_birthday": {
+		Fired:    "When a character turns 16 and comes of age.",
+		Root:     Character,
+		RootDesc: "the character",
+	},
+	"on_death": {
+		Fired:    "When a character dies, before the titles are inherited.",
+		Root:     Character,
+		RootDesc: "the dying character",
+		Saved:    map[string]Type{"killer": Character},
+	},
+	"on_marriage": {
+		Fired:    "When two characters marry, for each of them.",
+		Root:     Character,
+		RootDesc: "the character marrying",
+		Saved:    map[string]Type{"spouse": Character},
+	},
+	"on_divorce": {
+		Fired:    "When a marriage is dissolved by divorce.",
+		Root:     Character,
+		RootDesc: "the character divorcing",
+		Saved:    map[string]Type{"spouse": Character},
+	},
+	"on_title_gain": {
+		Fired:    "When a character gains a title, by any means.",
+		Root:     Character,
+		RootDesc: "the new holder",
+		Saved:    map[string]Type{"title": LandedTitle, "previous_holder": Character, "transfer_type": Flag},
+	},
+	"on_title_gain_usurpation": {
+		Fired:    "When a character usurps a title.",
+		Root:     Character,
+		RootDesc: "the usurper",
+		Saved:    map[string]Type{"title": LandedTitle, "previous_holder": Character},
+	},
+	"on_title_lost": {
+		Fired:    "When a character loses a title, by any means.",
+		Root:     Character,
+		RootDesc: "the former holder",
+		Saved:    map[string]Type{"title": LandedTitle, "new_holder": Character},
+	},
+	"on_imprison": {
+		Fired:    "When a character is imprisoned.",
+		Root:     Character,
+		RootDesc: "the prisoner",
+		Saved:    map[string]Type{"imprisoner": Character},
+	},
+	"on_release_from_prison": {
+		Fired:    "When a prisoner is released.",
+		Root:     Character,
+		RootDesc: "the former prisoner",
+		Saved:    map[string]Type{"imprisoner": Character},
+	},
+	"on_join_war_as_secondary": {
+		Fired:    "When a character joins a war as an ally of the attacker or the defender.",
+		Root:     Character,
+		RootDesc: "the joining character",
+		Saved:    map[string]Type{"war": War},
+	},
+	"on_character_faith_change": {
+		Fired:    "When a character converts to another faith.",
+		Root:     Character,
+		RootDesc: "the converting character",
+		Saved:    map[string]Type{"old_faith": Faith},
+	},
+	"on_character_culture_change": {
+		Fired:    "When a character adopts another culture.",
+		Root:     Character,
+		RootDesc: "the character",
+		Saved:    map[string]Type{"old_culture": Culture},
+	},
+	"on_county_faith_change": {
+		Fired:    "When the faith of a county changes.",
+		Root:     LandedTitle,
+		RootDesc: "the county",
+		Saved:    map[string]Type{"old_faith": Faith},
+	},
+	"on_county_culture_change": {
+		Fired:    "When the culture of a county changes.",
+		Root:     LandedTitle,
+		RootDesc: "the county",
+		Saved:    map[string]Type{"old_culture": Culture},
+	},
+}
+
+// OnActionInfo returns what is known of the on_action called name, if the
+// game fires it.
+func OnActionInfo(name string) (OnAction, bool) {
+	oa, ok := onActions[name]
+	return oa, ok
+}
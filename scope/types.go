@@ -0,0 +1,289 @@
+// Package scope infers the scope type of every block of a script file:
+// the object that `this` refers to, as changed by links like `liege`,
+// iterators like `every_vassal`, saved scopes and dotted event target
+// chains.
+package scope
+
+import (
+	"sort"
+	"strings"
+)
+
+// Type is the type of a scope, named as in the game's script docs.
+type Type string
+
+// Unknown is the type of a scope that cannot be inferred, such as the root
+// of a scripted effect.
+const Unknown Type = ""
+
+const (
+	None           Type = "none"
+	Character      Type = "character"
+	LandedTitle    Type = "landed_title"
+	Province       Type = "province"
+	Faith          Type = "faith"
+	Religion       Type = "religion"
+	Culture        Type = "culture"
+	Dynasty        Type = "dynasty"
+	House          Type = "dynasty_house"
+	Army           Type = "army"
+	Activity       Type = "activity"
+	Artifact       Type = "artifact"
+	Scheme         Type = "scheme"
+	Secret         Type = "secret"
+	StoryCycle     Type = "story_cycle"
+	War            Type = "war"
+	CasusBelli     Type = "casus_belli"
+	Faction        Type = "faction"
+	HolyOrder      Type = "holy_order"
+	GreatHolyWar   Type = "great_holy_war"
+	Domicile       Type = "domicile"
+	Inspiration    Type = "inspiration"
+	TravelPlan     Type = "travel_plan"
+	Memory         Type = "character_memory"
+	Struggle       Type = "struggle"
+	Epidemic       Type = "epidemic"
+	Legend         Type = "legend"
+	MercenaryBand  Type = "mercenary_company"
+	CouncilTask    Type = "council_task"
+	Region         Type = "geographical_region"
+	Accolade       Type = "accolade"
+	CourtPosition  Type = "court_position"
+	Value          Type = "value"
+	Flag           Type = "flag"
+	Bool           Type = "bool"
+	TitleAndVassal Type = "title_and_vassal_change"
+)
+
+// Link is a way to get from one scope to another: an event target such as
+// `liege`, or the list an iterator such as `every_vassal` walks.
+type Link struct {
+	// From lists the scope types the link is valid in; nil means any.
+	From []Type
+	To   Type
+}
+
+// ValidFrom reports whether the link can be used in a scope of type t. An
+// unknown type is always accepted.
+func (l Link) ValidFrom(t Type) bool {
+	if t == Unknown || l.From == nil {
+		return true
+	}
+	for _, from := range l.From {
+		if from == t {
+			return true
+		}
+	}
+	return false
+}
+
+func from(types ...Type) []Type { return types }
+
+// links are the event targets, by name.
+var links = map[string]Link{
+	// Characters.
+	"liege":                {from(Character), Character},
+	"top_liege":            {from(Character), Character},
+	"liege_or_court_owner": {from(Character), Character},
+	"court_owner":          {from(Character), Character},
+	"host":                 {from(Character), Character},
+	"employer":             {from(Character), Character},
+	"father":               {from(Character), Character},
+	"mother":               {from(Character), Character},
+	"real_father":          {from(Character), Character},
+	"primary_spouse":       {from(Character), Character},
+	"betrothed":            {from(Character), Character},
+	"killer":               {from(Character), Character},
+	"designated_heir":      {from(Character), Character},
+	"player_heir":          {from(Character), Character},
+	"primary_heir":         {from(Character), Character},
+	"realm_priest":         {from(Character), Character},
+	"diarch":               {from(Character), Character},
+	"matchmaker":           {from(Character), Character},
+	"primary_title":        {from(Character), LandedTitle},
+	"capital_county":       {from(Character), LandedTitle},
+	"capital_province":     {from(Character), Province},
+	"location":             {from(Character, Army, Activity, Epidemic), Province},
+	"dynasty":              {from(Character, House), Dynasty},
+	"house":                {from(Character), House},
+	"faith":                {from(Character, LandedTitle, Province, HolyOrder), Faith},
+	"culture":              {from(Character, LandedTitle, Province), Culture},
+	"domicile":             {from(Character), Domicile},
+	"inspiration":          {from(Character), Inspiration},
+	"current_travel_plan":  {from(Character), TravelPlan},
+	"involved_activity":    {from(Character), Activity},
+	"joined_faction":       {from(Character), Faction},
+	// Titles.
+	"holder":               {from(LandedTitle), Character},
+	"previous_holder":      {from(LandedTitle), Character},
+	"de_jure_liege":        {from(LandedTitle), LandedTitle},
+	"title_province":       {from(LandedTitle), Province},
+	"title_capital_county": {from(LandedTitle), LandedTitle},
+	"county":               {from(Province, LandedTitle), LandedTitle},
+	"duchy":                {from(Province, LandedTitle), LandedTitle},
+	"kingdom":              {from(Province, LandedTitle), LandedTitle},
+	"empire":               {from(Province, LandedTitle), LandedTitle},
+	// Provinces.
+	"barony":            {from(Province), LandedTitle},
+	"province_owner":    {from(Province), Character},
+	"barony_controller": {from(Province), Character},
+	"county_controller": {from(Province, LandedTitle), Character},
+	// Religion and culture.
+	"religion":             {from(Faith), Religion},
+	"religious_head":       {from(Faith), Character},
+	"religious_head_title": {from(Faith), LandedTitle},
+	"great_holy_war":       {from(Faith), GreatHolyWar},
+	"culture_head":         {from(Culture), Character},
+	// Families.
+	"dynast":        {from(Dynasty), Character},
+	"house_head":    {from(House), Character},
+	"house_founder": {from(House), Character},
+	// Other objects.
+	"army_owner":        {from(Army), Character},
+	"army_commander":    {from(Army), Character},
+	"scheme_owner":      {from(Scheme), Character},
+	"scheme_target":     {from(Scheme), Character},
+	"secret_owner":      {from(Secret), Character},
+	"secret_target":     {from(Secret), Character},
+	"story_owner":       {from(StoryCycle), Character},
+	"artifact_owner":    {from(Artifact), Character},
+	"activity_host":     {from(Activity), Character},
+	"activity_location": {from(Activity), Province},
+	"primary_attacker":  {from(War), Character},
+	"primary_defender":  {from(War), Character},
+	"casus_belli":       {from(War), CasusBelli},
+	"faction_leader":    {from(Faction), Character},
+	"faction_target":    {from(Faction), Character},
+	"leader":            {from(HolyOrder, MercenaryBand), Character},
+	"domicile_owner":    {from(Domicile), Character},
+	"domicile_location": {from(Domicile), Province},
+	"inspiration_owner": {from(Inspiration), Character},
+	"travel_plan_owner": {from(TravelPlan), Character},
+}
+
+// lists are the lists walked by every_, random_, ordered_ and any_
+// iterators, by name.
+var lists = map[string]Link{
+	// Global lists.
+	"living_character":           {nil, Character},
+	"ruler":                      {nil, Character},
+	"independent_ruler":          {nil, Character},
+	"player":                     {nil, Character},
+	"pool_character":             {nil, Character},
+	"character_with_royal_court": {nil, Character},
+	"county_in_region":           {nil, LandedTitle},
+	"religion_global":            {nil, Religion},
+	"culture_global":             {nil, Culture},
+	"in_list":                    {nil, Unknown},
+	"in_global_list":             {nil, Unknown},
+	"in_local_list":              {nil, Unknown},
+	// Characters.
+	"vassal":                            {from(Character), Character},
+	"vassal_or_below":                   {from(Character), Character},
+	"courtier":                          {from(Character), Character},
+	"courtier_or_guest":                 {from(Character), Character},
+	"guest":                             {from(Character), Character},
+	"pool_guest":                        {from(Character), Character},
+	"child":                             {from(Character), Character},
+	"spouse":                            {from(Character), Character},
+	"consort":                           {from(Character), Character},
+	"concubine":                         {from(Character), Character},
+	"sibling":                           {from(Character), Character},
+	"close_family_member":               {from(Character), Character},
+	"close_or_extended_family_member":   {from(Character), Character},
+	"extended_family_member":            {from(Character), Character},
+	"relation":                          {from(Character), Character},
+	"ally":                              {from(Character), Character},
+	"knight":                            {from(Character), Character},
+	"councillor":                        {from(Character), Character},
+	"prisoner":                          {from(Character), Character},
+	"heir":                              {from(Character), Character},
+	"war_ally":                          {from(Character), Character},
+	"war_enemy":                         {from(Character), Character},
+	"neighboring_top_liege_realm_owner": {from(Character), Character},
+	"traveling_family_member":           {from(Character), Character},
+	"held_title":                        {from(Character), LandedTitle},
+	"realm_county":                      {from(Character), LandedTitle},
+	"sub_realm_county":                  {from(Character), LandedTitle},
+	"sub_realm_barony":                  {from(Character), LandedTitle},
+	"claim":                             {from(Character), LandedTitle},
+	"realm_province":                    {from(Character), Province},
+	"known_secret":                      {from(Character), Secret},
+	"secret":                            {from(Character), Secret},
+	"scheme":                            {from(Character), Scheme},
+	"targeting_scheme":                  {from(Character), Scheme},
+	"character_war":                     {from(Character), War},
+	"memory":                            {from(Character), Memory},
+	"character_artifact":                {from(Character), Artifact},
+	"owned_story":                       {from(Character), StoryCycle},
+	"targeting_faction":                 {from(Character), Faction},
+	// Titles and provinces.
+	"de_jure_county":              {from(LandedTitle), LandedTitle},
+	"in_de_jure_hierarchy":        {from(LandedTitle), LandedTitle},
+	"in_de_facto_hierarchy":       {from(LandedTitle), LandedTitle},
+	"this_title_or_de_jure_above": {from(LandedTitle), LandedTitle},
+	"county_province":             {from(LandedTitle), Province},
+	"title_heir":                  {from(LandedTitle), Character},
+	"claimant":                    {from(LandedTitle), Character},
+	"neighboring_county":          {from(LandedTitle), LandedTitle},
+	"neighboring_province":        {from(Province), Province},
+	// Other objects.
+	"faith_holy_order":           {from(Faith), HolyOrder},
+	"faith_in_religion":          {from(Religion), Faith},
+	"dynasty_member":             {from(Dynasty), Character},
+	"dynasty_house":              {from(Dynasty), House},
+	"house_member":               {from(House), Character},
+	"war_attacker":               {from(War), Character},
+	"war_defender":               {from(War), Character},
+	"war_participant":            {from(War), Character},
+	"faction_member":             {from(Faction), Character},
+	"army_in_location":           {from(Province), Army},
+	"scheme_agent":               {from(Scheme), Character},
+	"active_accolade":            {from(Character), Accolade},
+	"epidemic_infected_province": {from(Epidemic), Province},
+}
+
+// LookupLink returns the event target with the given name.
+func LookupLink(name string) (Link, bool) {
+	l, ok := links[name]
+	return l, ok
+}
+
+// Iterator splits a key like "every_vassal" into its prefix ("every",
+// "random", "ordered" or "any") and the list it walks. ok is false unless
+// the list is known.
+func Iterator(key string) (prefix string, list Link, name string, ok bool) {
+	for _, p := range []string{"every_", "random_", "ordered_", "any_"} {
+		if strings.HasPrefix(key, p) {
+			name = strings.TrimPrefix(key, p)
+			list, ok = lists[name]
+			return strings.TrimSuffix(p, "_"), list, name, ok
+		}
+	}
+	return "", Link{}, "", false
+}
+
+// Links returns the names of the event targets valid from a scope of type
+// t, for completion.
+func Links(t Type) []string {
+	var names []string
+	for name, l := range links {
+		if l.ValidFrom(t) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lists returns the names of the lists iterable from a scope of type t.
+func Lists(t Type) []string {
+	var names []string
+	for name, l := range lists {
+		if l.ValidFrom(t) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,150 @@
+// Package spell checks the prose of localization texts against word lists.
+package spell
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Dictionary is a set of known words, matched case-insensitively.
+type Dictionary struct {
+	// Language is the localization language the words are of, such as
+	// "l_english".
+	Language string
+	words    map[string]bool
+	// byFirst groups the words by their first letter, for suggestions.
+	byFirst map[rune][]string
+}
+
+// New returns a dictionary of a language holding the CK3 terms and the
+// given words.
+func New(language string, words ...string) *Dictionary {
+	d := &Dictionary{Language: language, words: map[string]bool{}, byFirst: map[rune][]string{}}
+	d.Add(terms...)
+	d.Add(words...)
+	return d
+}
+
+// Add adds words to the dictionary.
+func (d *Dictionary) Add(words ...string) {
+	for _, w := range words {
+		w = strings.ToLower(strings.TrimSpace(w))
+		if w == "" || d.words[w] {
+			continue
+		}
+		d.words[w] = true
+		r, _ := utf8.DecodeRuneInString(w)
+		d.byFirst[r] = append(d.byFirst[r], w)
+	}
+}
+
+// Load adds the words of a word list file: one word per line, as in
+// /usr/share/dict/words or a Hunspell .dic file, whose affix flags after a
+// "/" are ignored, as is the word count on its first line.
+func (d *Dictionary) Load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		word, _, _ := strings.Cut(scanner.Text(), "/")
+		if word = strings.TrimSpace(word); word != "" && !isNumber(word) {
+			d.Add(word)
+		}
+	}
+	return scanner.Err()
+}
+
+// Len returns the number of words in the dictionary.
+func (d *Dictionary) Len() int {
+	return len(d.words)
+}
+
+// Known reports whether word is spelled correctly: it or its form without
+// a possessive "'s" is in the dictionary.
+func (d *Dictionary) Known(word string) bool {
+	w := strings.ToLower(word)
+	if d.words[w] {
+		return true
+	}
+	for _, suffix := range []string{"'s", "’s", "'", "’"} {
+		if base, ok := strings.CutSuffix(w, suffix); ok && d.words[base] {
+			return true
+		}
+	}
+	return false
+}
+
+// Suggest returns up to max known words close to word, closest first.
+func (d *Dictionary) Suggest(word string, max int) []string {
+	w := strings.ToLower(word)
+	r, _ := utf8.DecodeRuneInString(w)
+	limit := 1
+	if len(w) > 5 {
+		limit = 2
+	}
+	byDist := make([][]string, limit+1)
+	for _, c := range d.byFirst[r] {
+		if diff := len(c) - len(w); diff > limit || diff < -limit {
+			continue
+		}
+		if dist := editDistance(w, c); dist <= limit {
+			byDist[dist] = append(byDist[dist], c)
+		}
+	}
+	var suggestions []string
+	for _, words := range byDist {
+		for _, c := range words {
+			if len(suggestions) == max {
+				return suggestions
+			}
+			suggestions = append(suggestions, matchCase(c, word))
+		}
+	}
+	return suggestions
+}
+
+// matchCase capitalizes suggestion like word.
+func matchCase(suggestion, word string) string {
+	r, _ := utf8.DecodeRuneInString(word)
+	if unicode.IsUpper(r) {
+		s, size := utf8.DecodeRuneInString(suggestion)
+		return string(unicode.ToUpper(s)) + suggestion[size:]
+	}
+	return suggestion
+}
+
+func isNumber(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,97 @@
+package spell
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Word is a word of the prose of a localization text. Start and End are
+// its byte offsets in the text. Words with digits, like "3rd", are left
+// out.
+type Word struct {
+	Text       string
+	Start, End int
+	// SentenceStart is set for the first word of a sentence, whose capital
+	// does not mark a proper noun.
+	SentenceStart bool
+}
+
+// Words returns the words of the human-readable prose of a localization
+// text, skipping data functions ([Character.GetName]), variables
+// ($value$), formatting codes (#bold ... #!), icons (@gold_icon!, £gold£)
+// and escapes (\n).
+func Words(text string) []Word {
+	var words []Word
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			w := strings.Trim(text[start:end], "'’-")
+			if w != "" && !strings.ContainsFunc(w, unicode.IsDigit) {
+				offset := start + strings.Index(text[start:end], w)
+				words = append(words, Word{Text: w, Start: offset, End: offset + len(w), SentenceStart: sentenceStart(text[:offset])})
+			}
+			start = -1
+		}
+	}
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == '[':
+			flush(i)
+			i = skipTo(text, i+1, ']')
+			continue
+		case r == '$':
+			flush(i)
+			i = skipTo(text, i+1, '$')
+			continue
+		case r == '£':
+			flush(i)
+			i = skipTo(text, i+size, '£')
+			continue
+		case r == '@':
+			flush(i)
+			i = skipTo(text, i+1, '!')
+			continue
+		case r == '#':
+			// A formatting code runs to the next space; "#!" closes one.
+			flush(i)
+			j := i + 1
+			for j < len(text) && text[j] != ' ' && text[j] != '#' {
+				j++
+			}
+			i = j
+			continue
+		case r == '\\':
+			flush(i)
+			i += 2
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || (start >= 0 && (r == '\'' || r == '’' || r == '-')):
+			if start < 0 {
+				start = i
+			}
+		default:
+			flush(i)
+		}
+		i += size
+	}
+	flush(len(text))
+	return words
+}
+
+// skipTo returns the offset just past the next c at or after i, or the end
+// of text.
+func skipTo(text string, i int, c rune) int {
+	if j := strings.IndexRune(text[i:], c); j >= 0 {
+		return i + j + utf8.RuneLen(c)
+	}
+	return len(text)
+}
+
+// sentenceStart reports whether a word after before starts a sentence.
+func sentenceStart(before string) bool {
+	before = strings.TrimRightFunc(before, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(".!?", r)
+	})
+	return before == "" || strings.ContainsAny(before[len(before)-1:], ".!?")
+}
@@ -0,0 +1,24 @@
+package spell
+
+// terms are CK3 words missing from general dictionaries.
+var terms = []string{
+	"ck3", "crusader", "liege", "lieges", "vassal", "vassals", "vassalage", "casus", "belli",
+	"suzerain", "suzerains", "tributary", "tributaries", "theocracy", "theocratic", "dynast",
+	"dynasts", "cadet", "primogeniture", "ultimogeniture", "seniority", "gavelkind",
+	"elective", "tanistry", "feudal", "clan", "clans", "tribal", "jarl", "jarls", "konungr",
+	"thane", "thanes", "emir", "emirs", "sultan", "sultans", "caliph", "caliphate", "caliphs",
+	"raja", "rajas", "maharaja", "basileus", "despot", "despots", "doge", "patrician",
+	"patricians", "mayor", "burgh", "barony", "baronies", "county", "counties", "duchy",
+	"duchies", "kingdom", "kingdoms", "empire", "empires", "demesne", "domicile", "domiciles",
+	"chancellor", "marshal", "steward", "spymaster", "councillor", "councillors", "councilor",
+	"courtier", "courtiers", "knight", "knights", "prowess", "diplomacy", "martial",
+	"stewardship", "intrigue", "learning", "piety", "prestige", "renown", "dread", "stress",
+	"tyranny", "legitimacy", "influence", "herd", "hof", "hofs", "faction", "factions",
+	"scheme", "schemes", "secret", "secrets", "hook", "hooks", "lifestyle", "lifestyles",
+	"perk", "perks", "trait", "traits", "heresy", "heretic", "heretics", "excommunicated",
+	"excommunication", "zealot", "zealots", "cynic", "cynical", "paragon", "legend", "legends",
+	"artifact", "artifacts", "inspiration", "inspirations", "accolade", "accolades",
+	"acclaimed", "struggle", "struggles", "varangian", "varangians", "norse", "byzantine",
+	"byzantium", "abbasid", "umayyad", "fatimid", "karling", "karlings", "wessex", "mercia",
+	"northumbria", "bohemia", "hungary", "aquitaine", "burgundy", "lotharingia",
+}